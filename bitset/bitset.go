@@ -2,6 +2,7 @@ package bitset
 
 import (
 	"fmt"
+	"math/bits"
 	"strings"
 
 	"github.com/rjo67/chess/square"
@@ -41,6 +42,12 @@ var NotFile1 = BitSet{0x7F7F7F7F7F7F7F7F}
 // NotFile8 is a bitset with everything set except file8 (h1..h8)
 var NotFile8 = BitSet{0xFEFEFEFEFEFEFEFE}
 
+// LightSquares is a bitset with all light squares (h1, g2, ...) set
+var LightSquares = BitSet{0xAA55AA55AA55AA55}
+
+// DarkSquares is a bitset with all dark squares (a1, b2, ...) set
+var DarkSquares = BitSet{0x55AA55AA55AA55AA}
+
 // New creates a Bitset from the given value.
 func New(val uint64) BitSet {
 	return BitSet{val: val}
@@ -174,6 +181,36 @@ func (bs BitSet) Shift(shift int) BitSet {
 	return BitSet{val: v}
 }
 
+// FlipVertical flips the bitset about the horizontal axis between the 4th and 5th ranks, i.e. A1 becomes A8
+func (bs BitSet) FlipVertical() BitSet {
+	return BitSet{val: bits.ReverseBytes64(bs.val)}
+}
+
+// MirrorHorizontal mirrors the bitset about the vertical axis between the d- and e-files, i.e. A1 becomes H1
+func (bs BitSet) MirrorHorizontal() BitSet {
+	const k1, k2, k4 = 0x5555555555555555, 0x3333333333333333, 0x0F0F0F0F0F0F0F0F
+	v := bs.val
+	v = ((v >> 1) & k1) | ((v & k1) << 1)
+	v = ((v >> 2) & k2) | ((v & k2) << 2)
+	v = ((v >> 4) & k4) | ((v & k4) << 4)
+	return BitSet{val: v}
+}
+
+// Transpose flips the bitset about the A1-H8 diagonal, i.e. H1 becomes A8.
+// https://www.chessprogramming.org/Flipping_Mirroring_and_Rotating
+func (bs BitSet) Transpose() BitSet {
+	// the algorithm on the chessprogramming wiki expects A1 at bit 0, which is the horizontally mirrored layout
+	const k1, k2, k4 = 0x5500550055005500, 0x3333000033330000, 0x0F0F0F0F00000000
+	v := bs.MirrorHorizontal().val
+	t := k4 & (v ^ (v << 28))
+	v ^= t ^ (t >> 28)
+	t = k2 & (v ^ (v << 14))
+	v ^= t ^ (t >> 14)
+	t = k1 & (v ^ (v << 7))
+	v ^= t ^ (t >> 7)
+	return BitSet{val: v}.MirrorHorizontal()
+}
+
 // ClearSquare clears the bit at the given square
 func (bs *BitSet) ClearSquare(sq square.Square) *BitSet {
 	return bs.Clear(uint(sq))
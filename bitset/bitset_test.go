@@ -185,3 +185,47 @@ func checkBits(t *testing.T, bs BitSet, setBits []uint, checkIfSet bool) {
 		}
 	}
 }
+
+func TestSquareColours(t *testing.T) {
+	for _, sq := range []square.Square{square.H1, square.B1, square.A2, square.A8, square.D5} {
+		if !LightSquares.IsSet(uint(sq)) || DarkSquares.IsSet(uint(sq)) {
+			t.Errorf("%s should be a light square", sq.String())
+		}
+	}
+	for _, sq := range []square.Square{square.A1, square.G1, square.H8, square.E5} {
+		if !DarkSquares.IsSet(uint(sq)) || LightSquares.IsSet(uint(sq)) {
+			t.Errorf("%s should be a dark square", sq.String())
+		}
+	}
+	if LightSquares.Cardinality() != 32 || LightSquares.Or(DarkSquares) != New(0).Not() {
+		t.Errorf("light and dark squares must cover the board")
+	}
+}
+
+func TestTransforms(t *testing.T) {
+	data := []struct {
+		from, flipped, mirrored, transposed square.Square
+	}{
+		{square.A1, square.A8, square.H1, square.A1},
+		{square.H1, square.H8, square.A1, square.A8},
+		{square.B1, square.B8, square.G1, square.A2},
+		{square.C6, square.C3, square.F6, square.F3},
+		{square.H8, square.H1, square.A8, square.H8},
+	}
+	for _, d := range data {
+		bs := NewFromSquares(d.from)
+		if got := bs.FlipVertical(); got != NewFromSquares(d.flipped) {
+			t.Errorf("FlipVertical(%s): expected %s but got %v", d.from.String(), d.flipped.String(), got.SetBits())
+		}
+		if got := bs.MirrorHorizontal(); got != NewFromSquares(d.mirrored) {
+			t.Errorf("MirrorHorizontal(%s): expected %s but got %v", d.from.String(), d.mirrored.String(), got.SetBits())
+		}
+		if got := bs.Transpose(); got != NewFromSquares(d.transposed) {
+			t.Errorf("Transpose(%s): expected %s but got %v", d.from.String(), d.transposed.String(), got.SetBits())
+		}
+	}
+	bs := New(0x123456789ABCDEF0)
+	if bs.FlipVertical().FlipVertical() != bs || bs.MirrorHorizontal().MirrorHorizontal() != bs || bs.Transpose().Transpose() != bs {
+		t.Errorf("transforms should be self-inverse")
+	}
+}
@@ -0,0 +1,265 @@
+// Package clock implements chess clocks for the usual time controls: sudden death, Fischer increment, Bronstein delay,
+// simple (US) delay, multi-period controls and hourglass.
+package clock
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rjo67/chess/piece/colour"
+)
+
+// Unlimited is returned as the remaining time if the time control has no time limit
+const Unlimited = time.Duration(math.MaxInt64)
+
+// ErrFlagFall is returned by Press if the player's time has run out
+var ErrFlagFall = errors.New("flag fall")
+
+// Source returns the current time. It can be replaced in tests
+type Source interface {
+	Now() time.Time
+}
+
+type systemSource struct{}
+
+func (systemSource) Now() time.Time {
+	return time.Now()
+}
+
+// System is the time source using the system clock
+var System Source = systemSource{}
+
+// Clock is a chess clock for two players. Its methods can be called concurrently, e.g. to display the times
+// while a player is thinking.
+type Clock struct {
+	// Tolerance is the time by which a player may exceed the time before the flag falls, e.g. to allow for communication delays
+	Tolerance time.Duration
+
+	tc        TimeControl
+	source    Source
+	remaining [2]time.Duration // not including the time used for the current move
+	moves     [2]int           // number of moves completed by each player
+	periods   [2]int           // the current period of each player
+	active    colour.Colour    // the player whose clock is running (or paused)
+	started   bool
+	running   bool          // false before Start, after a flag fall and while paused
+	moveStart time.Time     // when the clock of the active player was last started or resumed
+	used      time.Duration // time used for the current move before the clock was paused
+	flagged   bool
+	mutex     sync.Mutex
+}
+
+// New creates a clock with the time of the first period on both sides. The clock is started with Start.
+func New(tc TimeControl, source Source) *Clock {
+	c := &Clock{tc: tc, source: source}
+	if !tc.Unlimited() {
+		c.remaining = [2]time.Duration{tc.Periods[0].Time, tc.Periods[0].Time}
+	}
+	return c
+}
+
+// TimeControl returns the time control of the clock
+func (c *Clock) TimeControl() TimeControl {
+	return c.tc
+}
+
+// Start starts the clock of the given player, who is to move
+func (c *Clock) Start(col colour.Colour) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.active, c.started, c.running, c.used = col, true, true, 0
+	c.moveStart = c.source.Now()
+}
+
+// Active returns the player whose clock is running (or paused)
+func (c *Clock) Active() colour.Colour {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.active
+}
+
+// Running returns true if the clock has been started and is neither paused nor stopped by a flag fall
+func (c *Clock) Running() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.running
+}
+
+// usedLocked returns the time used by the active player for the current move
+func (c *Clock) usedLocked() time.Duration {
+	if !c.running {
+		return c.used
+	}
+	return c.used + c.source.Now().Sub(c.moveStart)
+}
+
+// chargedLocked returns the time deducted from the clock for the given time used, taking a simple delay into account
+func (c *Clock) chargedLocked(used time.Duration) time.Duration {
+	if p := c.tc.period(c.periods[c.active]); p.Mode == SimpleDelay {
+		if used <= p.Delay {
+			return 0
+		}
+		return used - p.Delay
+	}
+	return used
+}
+
+// remainingLocked returns the remaining time of the player, including the current move
+func (c *Clock) remainingLocked(col colour.Colour) time.Duration {
+	if c.tc.Unlimited() {
+		return Unlimited
+	}
+	remaining := c.remaining[col]
+	if c.started {
+		charged := c.chargedLocked(c.usedLocked())
+		switch {
+		case col == c.active:
+			remaining -= charged
+		case c.tc.Periods[0].Mode == Hourglass:
+			remaining += charged
+		}
+	}
+	return remaining
+}
+
+// Remaining returns the remaining time of the player. This is negative if the player has exceeded the time
+// (within the tolerance), and Unlimited if the time control has no time limit.
+func (c *Clock) Remaining(col colour.Colour) time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.remainingLocked(col)
+}
+
+// flaggedLocked returns true if the active player's flag has fallen, and stops the clock in this case
+func (c *Clock) flaggedLocked() bool {
+	if !c.flagged && c.started && !c.tc.Unlimited() && c.remainingLocked(c.active) < -c.Tolerance {
+		c.used = c.usedLocked()
+		c.flagged, c.running = true, false
+	}
+	return c.flagged
+}
+
+// Flagged returns the player whose flag has fallen, if any. The clock stops when a flag falls.
+func (c *Clock) Flagged() (colour.Colour, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.active, c.flaggedLocked()
+}
+
+// TimeUntilFlag returns the time until the active player's flag falls (including the tolerance and a simple delay)
+// if the clock is not paused, e.g. as the deadline of an engine search. Returns Unlimited if there is no time limit.
+func (c *Clock) TimeUntilFlag() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.tc.Unlimited() {
+		return Unlimited
+	}
+	if c.flaggedLocked() {
+		return 0
+	}
+	untilFlag := c.remainingLocked(c.active) + c.Tolerance
+	if p := c.tc.period(c.periods[c.active]); p.Mode == SimpleDelay {
+		if used := c.usedLocked(); used < p.Delay {
+			untilFlag += p.Delay - used
+		}
+	}
+	return untilFlag
+}
+
+// Press is called when the active player has completed a move: the time used is deducted, the increment or delay
+// of the period applied, the time of the next period added if the player has completed a period, and the opponent's
+// clock started. Returns ErrFlagFall (and leaves the clock stopped) if the player's time had run out.
+// Does nothing if there is no time limit apart from switching the clocks.
+func (c *Clock) Press() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.flaggedLocked() {
+		return ErrFlagFall
+	}
+	col := c.active
+	if !c.tc.Unlimited() {
+		used := c.usedLocked()
+		p := c.tc.period(c.periods[col])
+		c.remaining[col] -= c.chargedLocked(used)
+		switch p.Mode {
+		case Fischer:
+			c.remaining[col] += p.Delay
+		case Bronstein:
+			if used < p.Delay {
+				c.remaining[col] += used
+			} else {
+				c.remaining[col] += p.Delay
+			}
+		case Hourglass:
+			c.remaining[col.Other()] += used
+		}
+		c.moves[col]++
+		if p.Moves > 0 && c.movesInPeriodLocked(col) == p.Moves {
+			c.periods[col]++
+			c.remaining[col] += c.tc.period(c.periods[col]).Time
+		}
+	}
+	c.active, c.used = col.Other(), 0
+	c.moveStart = c.source.Now()
+	c.running = c.started
+	return nil
+}
+
+// movesInPeriodLocked returns the number of moves the player has completed in the current period
+func (c *Clock) movesInPeriodLocked(col colour.Colour) int {
+	moves := c.moves[col]
+	for i := 0; i < c.periods[col]; i++ {
+		moves -= c.tc.period(i).Moves
+	}
+	return moves
+}
+
+// MovesToGo returns the number of moves the player has to make until the end of the current period,
+// or 0 if the period is for the rest of the game
+func (c *Clock) MovesToGo(col colour.Colour) int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.tc.Unlimited() {
+		return 0
+	}
+	p := c.tc.period(c.periods[col])
+	if p.Moves == 0 {
+		return 0
+	}
+	return p.Moves - c.movesInPeriodLocked(col)
+}
+
+// Increment returns the increment of the player's current period, or 0 if the period has no Fischer increment
+func (c *Clock) Increment(col colour.Colour) time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.tc.Unlimited() {
+		return 0
+	}
+	if p := c.tc.period(c.periods[col]); p.Mode == Fischer {
+		return p.Delay
+	}
+	return 0
+}
+
+// Pause stops the clock, e.g. during an adjournment. The time used so far for the current move is kept.
+func (c *Clock) Pause() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.running && !c.flaggedLocked() {
+		c.used = c.usedLocked()
+		c.running = false
+	}
+}
+
+// Resume restarts the clock of the active player after Pause
+func (c *Clock) Resume() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.started && !c.running && !c.flagged {
+		c.running = true
+		c.moveStart = c.source.Now()
+	}
+}
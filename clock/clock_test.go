@@ -0,0 +1,235 @@
+package clock
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rjo67/chess/piece/colour"
+)
+
+// fakeSource is a time source which only moves when advanced
+type fakeSource struct {
+	now time.Time
+}
+
+func (f *fakeSource) Now() time.Time {
+	return f.now
+}
+
+func (f *fakeSource) advance(d time.Duration) {
+	f.now = f.now.Add(d)
+}
+
+func newClock(t *testing.T, str string) (*Clock, *fakeSource) {
+	t.Helper()
+	tc, err := ParseTimeControl(str)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	source := &fakeSource{now: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)}
+	return New(tc, source), source
+}
+
+// play makes moves, alternately for white and black, using the given times
+func play(t *testing.T, c *Clock, source *fakeSource, times ...time.Duration) {
+	t.Helper()
+	for _, d := range times {
+		source.advance(d)
+		if err := c.Press(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+}
+
+func checkRemaining(t *testing.T, c *Clock, white, black time.Duration) {
+	t.Helper()
+	if got := c.Remaining(colour.White); got != white {
+		t.Errorf("expected %s for white but got %s", white, got)
+	}
+	if got := c.Remaining(colour.Black); got != black {
+		t.Errorf("expected %s for black but got %s", black, got)
+	}
+}
+
+func TestSuddenDeath(t *testing.T) {
+	c, source := newClock(t, "60")
+	c.Start(colour.White)
+	play(t, c, source, 10*time.Second, 5*time.Second)
+	checkRemaining(t, c, 50*time.Second, 55*time.Second)
+	source.advance(20 * time.Second)
+	checkRemaining(t, c, 30*time.Second, 55*time.Second)
+	if _, flagged := c.Flagged(); flagged {
+		t.Errorf("unexpected flag fall")
+	}
+	source.advance(31 * time.Second)
+	if col, flagged := c.Flagged(); !flagged || col != colour.White {
+		t.Errorf("expected flag fall of white")
+	}
+	if err := c.Press(); !errors.Is(err, ErrFlagFall) {
+		t.Errorf("expected flag fall but got %v", err)
+	}
+	// the clock has stopped
+	source.advance(time.Minute)
+	checkRemaining(t, c, -time.Second, 55*time.Second)
+	if c.Running() {
+		t.Errorf("clock still running")
+	}
+}
+
+func TestFischer(t *testing.T) {
+	c, source := newClock(t, "60+2")
+	c.Start(colour.White)
+	play(t, c, source, 10*time.Second, time.Second)
+	checkRemaining(t, c, 52*time.Second, 61*time.Second)
+	if c.Increment(colour.White) != 2*time.Second || c.MovesToGo(colour.White) != 0 {
+		t.Errorf("unexpected increment or moves to go")
+	}
+}
+
+func TestBronstein(t *testing.T) {
+	c, source := newClock(t, "60b3")
+	c.Start(colour.White)
+	// white gets back 3 of 10 seconds, black the whole second
+	play(t, c, source, 10*time.Second, time.Second)
+	checkRemaining(t, c, 53*time.Second, 60*time.Second)
+	// while thinking the full time is deducted
+	source.advance(2 * time.Second)
+	checkRemaining(t, c, 51*time.Second, 60*time.Second)
+	if c.Increment(colour.White) != 0 {
+		t.Errorf("unexpected increment")
+	}
+}
+
+func TestSimpleDelay(t *testing.T) {
+	c, source := newClock(t, "60d5")
+	c.Start(colour.White)
+	play(t, c, source, 10*time.Second, 3*time.Second)
+	checkRemaining(t, c, 55*time.Second, 60*time.Second)
+	source.advance(4 * time.Second)
+	checkRemaining(t, c, 55*time.Second, 60*time.Second)
+	// the flag falls 55 seconds after the delay
+	if got := c.TimeUntilFlag(); got != 56*time.Second {
+		t.Errorf("expected 56s until flag fall but got %s", got)
+	}
+	source.advance(56*time.Second + time.Millisecond)
+	if _, flagged := c.Flagged(); !flagged {
+		t.Errorf("expected flag fall")
+	}
+	if got := c.TimeUntilFlag(); got != 0 {
+		t.Errorf("expected 0 until flag fall but got %s", got)
+	}
+}
+
+func TestMultiPeriod(t *testing.T) {
+	// 2 moves in 10 seconds, then 1 move in 5 seconds (repeated), with an increment of 1 second in the first period
+	c, source := newClock(t, "2/10+1:1/5")
+	c.Start(colour.White)
+	if c.MovesToGo(colour.White) != 2 {
+		t.Errorf("expected 2 moves to go but got %d", c.MovesToGo(colour.White))
+	}
+	play(t, c, source, time.Second, time.Second)
+	checkRemaining(t, c, 10*time.Second, 10*time.Second)
+	if c.MovesToGo(colour.White) != 1 {
+		t.Errorf("expected 1 move to go but got %d", c.MovesToGo(colour.White))
+	}
+	// end of the first period: +1 increment, +5 of the second period
+	play(t, c, source, 2*time.Second, 3*time.Second)
+	checkRemaining(t, c, 14*time.Second, 13*time.Second)
+	if c.MovesToGo(colour.White) != 1 || c.Increment(colour.White) != 0 {
+		t.Errorf("unexpected values for the second period")
+	}
+	// the second period is repeated
+	play(t, c, source, 4*time.Second)
+	checkRemaining(t, c, 15*time.Second, 13*time.Second)
+}
+
+func TestHourglass(t *testing.T) {
+	c, source := newClock(t, "*30")
+	c.Start(colour.White)
+	source.advance(10 * time.Second)
+	checkRemaining(t, c, 20*time.Second, 40*time.Second)
+	play(t, c, source, 0, 5*time.Second)
+	checkRemaining(t, c, 25*time.Second, 35*time.Second)
+}
+
+func TestPauseResume(t *testing.T) {
+	c, source := newClock(t, "60")
+	c.Start(colour.White)
+	source.advance(10 * time.Second)
+	c.Pause()
+	source.advance(time.Hour)
+	checkRemaining(t, c, 50*time.Second, 60*time.Second)
+	if c.Running() {
+		t.Errorf("clock running while paused")
+	}
+	c.Resume()
+	play(t, c, source, 5*time.Second)
+	checkRemaining(t, c, 45*time.Second, 60*time.Second)
+	if c.Active() != colour.Black || !c.Running() {
+		t.Errorf("expected black's clock to run")
+	}
+}
+
+func TestTolerance(t *testing.T) {
+	c, source := newClock(t, "10")
+	c.Tolerance = time.Second
+	c.Start(colour.Black)
+	if got := c.TimeUntilFlag(); got != 11*time.Second {
+		t.Errorf("expected 11s until flag fall but got %s", got)
+	}
+	play(t, c, source, 10500*time.Millisecond)
+	checkRemaining(t, c, 10*time.Second, -500*time.Millisecond)
+}
+
+func TestUnlimited(t *testing.T) {
+	c, source := newClock(t, "-")
+	c.Start(colour.White)
+	play(t, c, source, time.Hour, time.Hour)
+	checkRemaining(t, c, Unlimited, Unlimited)
+	if _, flagged := c.Flagged(); flagged || c.TimeUntilFlag() != Unlimited {
+		t.Errorf("unexpected flag fall")
+	}
+}
+
+func TestParseTimeControl(t *testing.T) {
+	for _, d := range []struct {
+		str      string
+		expected []Period
+	}{
+		{"300", []Period{{Time: 300 * time.Second}}},
+		{"10+0.1", []Period{{Time: 10 * time.Second, Mode: Fischer, Delay: 100 * time.Millisecond}}},
+		{"40/5400+30:1800+30", []Period{{Moves: 40, Time: 90 * time.Minute, Mode: Fischer, Delay: 30 * time.Second},
+			{Time: 30 * time.Minute, Mode: Fischer, Delay: 30 * time.Second}}},
+		{"40/9000:3600", []Period{{Moves: 40, Time: 9000 * time.Second}, {Time: time.Hour}}},
+		{"*180", []Period{{Time: 3 * time.Minute, Mode: Hourglass}}},
+		{"300d5", []Period{{Time: 5 * time.Minute, Mode: SimpleDelay, Delay: 5 * time.Second}}},
+		{"300b5", []Period{{Time: 5 * time.Minute, Mode: Bronstein, Delay: 5 * time.Second}}},
+	} {
+		tc, err := ParseTimeControl(d.str)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", d.str, err)
+			continue
+		}
+		if len(tc.Periods) != len(d.expected) {
+			t.Errorf("%s: expected %v but got %v", d.str, d.expected, tc.Periods)
+			continue
+		}
+		for i := range tc.Periods {
+			if tc.Periods[i] != d.expected[i] {
+				t.Errorf("%s: expected %v but got %v", d.str, d.expected, tc.Periods)
+			}
+		}
+		if tc.String() != d.str {
+			t.Errorf("expected '%s' but got '%s'", d.str, tc.String())
+		}
+	}
+	if tc, err := ParseTimeControl("-"); err != nil || !tc.Unlimited() || tc.String() != "-" {
+		t.Errorf("unexpected result %v, %v", tc, err)
+	}
+	for _, str := range []string{"?", "", "0", "x", "-5", "1e3", "40/", "/60", "0/60", "60:40/60", "*60:60", "60+", "60+x", "*", "40/60++1"} {
+		if _, err := ParseTimeControl(str); err == nil {
+			t.Errorf("expected error for '%s'", str)
+		}
+	}
+}
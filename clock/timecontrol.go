@@ -0,0 +1,170 @@
+package clock
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mode is the way in which the time of a player is adjusted for each move
+type Mode int
+
+// the modes of a period
+const (
+	SuddenDeath Mode = iota // no adjustment
+	Fischer                 // the increment is added after each move
+	Bronstein               // after each move, the time used for the move is added back, up to the delay
+	SimpleDelay             // the clock only starts running after the delay (US delay)
+	Hourglass               // the time used by one player is added to the opponent's time
+)
+
+// Period of a time control: a number of moves to be played in the given time
+type Period struct {
+	Moves int           // number of moves of the period, 0 for the rest of the game
+	Time  time.Duration // time added to the clock at the start of the period
+	Mode  Mode
+	Delay time.Duration // the increment (Fischer) or delay (Bronstein, SimpleDelay)
+}
+
+// TimeControl consists of one or more periods. If the last period has a number of moves, it is repeated.
+// A time control without periods means that there is no time limit.
+type TimeControl struct {
+	Periods []Period
+}
+
+// Unlimited returns true if there is no time limit
+func (tc TimeControl) Unlimited() bool {
+	return len(tc.Periods) == 0
+}
+
+// period returns the period with the given index, taking the repetition of the last period into account
+func (tc TimeControl) period(index int) Period {
+	if index >= len(tc.Periods) {
+		index = len(tc.Periods) - 1
+	}
+	return tc.Periods[index]
+}
+
+// ParseTimeControl parses a time control in the format of the PGN tag TimeControl: one or more periods separated by ':',
+// each period being "moves/seconds" (e.g. "40/5400"), "seconds" (sudden death, e.g. "3600"), "seconds+increment"
+// (e.g. "300+2", also allowed after "moves/") or "*seconds" (hourglass), or "-" for no time control.
+// As an extension, a delay can be given instead of an increment: "seconds d delay" for a simple (US) delay,
+// "seconds b delay" for a Bronstein delay, e.g. "300d5". Times can have fractions, e.g. "10+0.1".
+// For example "40/5400+30:1800+30" is 90 minutes for 40 moves followed by 30 minutes for the rest of the game,
+// with an increment of 30 seconds from the first move.
+func ParseTimeControl(str string) (TimeControl, error) {
+	switch str {
+	case "-":
+		return TimeControl{}, nil
+	case "?", "":
+		return TimeControl{}, fmt.Errorf("unknown time control '%s'", str)
+	}
+	var tc TimeControl
+	fields := strings.Split(str, ":")
+	for i, field := range fields {
+		p, err := parsePeriod(field)
+		if err != nil {
+			return TimeControl{}, fmt.Errorf("invalid time control '%s': %w", str, err)
+		}
+		if p.Moves == 0 && i < len(fields)-1 {
+			return TimeControl{}, fmt.Errorf("invalid time control '%s': only the last period can be for the rest of the game", str)
+		}
+		if p.Mode == Hourglass && len(fields) > 1 {
+			return TimeControl{}, fmt.Errorf("invalid time control '%s': hourglass must be the only period", str)
+		}
+		tc.Periods = append(tc.Periods, p)
+	}
+	return tc, nil
+}
+
+// parsePeriod parses one period of a time control
+func parsePeriod(str string) (Period, error) {
+	var p Period
+	if strings.HasPrefix(str, "*") {
+		t, err := parseSeconds(str[1:])
+		if err != nil {
+			return p, err
+		}
+		return Period{Time: t, Mode: Hourglass}, nil
+	}
+	if i := strings.Index(str, "/"); i >= 0 {
+		moves, err := strconv.Atoi(str[:i])
+		if err != nil || moves <= 0 {
+			return p, fmt.Errorf("invalid number of moves '%s'", str[:i])
+		}
+		p.Moves, str = moves, str[i+1:]
+	}
+	if i := strings.IndexAny(str, "+db"); i >= 0 {
+		switch str[i] {
+		case '+':
+			p.Mode = Fischer
+		case 'd':
+			p.Mode = SimpleDelay
+		case 'b':
+			p.Mode = Bronstein
+		}
+		delay, err := parseSeconds(str[i+1:])
+		if err != nil {
+			return p, err
+		}
+		p.Delay, str = delay, str[:i]
+	}
+	t, err := parseSeconds(str)
+	if err != nil {
+		return p, err
+	}
+	if t == 0 {
+		return p, fmt.Errorf("no time given")
+	}
+	p.Time = t
+	return p, nil
+}
+
+// parseSeconds parses a non-negative number of seconds
+func parseSeconds(str string) (time.Duration, error) {
+	// only digits and a decimal point, e.g. no signs or exponents
+	if strings.Trim(str, "0123456789.") != "" {
+		return 0, fmt.Errorf("invalid number of seconds '%s'", str)
+	}
+	seconds, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number of seconds '%s'", str)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// String returns the time control in the format of ParseTimeControl
+func (tc TimeControl) String() string {
+	if tc.Unlimited() {
+		return "-"
+	}
+	strs := make([]string, len(tc.Periods))
+	for i, p := range tc.Periods {
+		strs[i] = p.String()
+	}
+	return strings.Join(strs, ":")
+}
+
+func (p Period) String() string {
+	str := formatSeconds(p.Time)
+	if p.Mode == Hourglass {
+		return "*" + str
+	}
+	if p.Moves > 0 {
+		str = strconv.Itoa(p.Moves) + "/" + str
+	}
+	switch p.Mode {
+	case Fischer:
+		str += "+" + formatSeconds(p.Delay)
+	case SimpleDelay:
+		str += "d" + formatSeconds(p.Delay)
+	case Bronstein:
+		str += "b" + formatSeconds(p.Delay)
+	}
+	return str
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
@@ -0,0 +1,52 @@
+// chessd serves the library as a JSON API over HTTP, e.g. for a front-end.
+//
+// All endpoints take a POST request with a JSON object containing the position as "fen" (and "chess960": true for
+// Chess960 positions) and return a JSON object:
+//
+//	/legal-moves  the legal moves of the side to move
+//	/make-move    makes the "move" (UCI or SAN) and returns the new position
+//	/perft        the number of leaf nodes of the move tree of the given "depth", per move
+//	/attacks      the pieces (of the optional "colour") which attack the "square"
+//	/best-move    the best move found by a search to the given "depth"
+//
+// Errors are returned as {"error":{"code":"...","message":"..."}} with a suitable HTTP status.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8080", "address to listen on")
+	maxBody := flag.Int64("maxbody", 16*1024, "maximum size of a request body in bytes")
+	timeout := flag.Duration("timeout", 10*time.Second, "maximum time to handle a request")
+	concurrency := flag.Int("concurrency", 4, "maximum number of requests handled at the same time")
+	maxSearchDepth := flag.Int("maxsearchdepth", 6, "maximum depth of /best-move")
+	maxPerftDepth := flag.Int("maxperftdepth", 5, "maximum depth of /perft")
+	flag.Parse()
+
+	if *maxBody < 1 || *timeout <= 0 || *concurrency < 1 || *maxSearchDepth < 1 || *maxPerftDepth < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	s := newServer(config{maxBody: *maxBody, timeout: *timeout, concurrency: *concurrency,
+		maxSearchDepth: *maxSearchDepth, maxPerftDepth: *maxPerftDepth})
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           s.handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       *timeout,
+		WriteTimeout:      *timeout + 5*time.Second,
+		IdleTimeout:       time.Minute,
+	}
+	log.Printf("listening on %s", *addr)
+	if err := srv.ListenAndServe(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
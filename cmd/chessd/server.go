@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/search"
+	"github.com/rjo67/chess/square"
+)
+
+// config of the server
+type config struct {
+	maxBody        int64         // maximum size of a request body in bytes
+	timeout        time.Duration // maximum time to handle a request
+	concurrency    int           // maximum number of requests handled at the same time
+	maxSearchDepth int
+	maxPerftDepth  int
+}
+
+// server handles the requests. Each request works on its own position, so requests can be handled concurrently.
+type server struct {
+	cfg   config
+	slots chan struct{} // a request must take a slot before it is handled
+}
+
+func newServer(cfg config) *server {
+	return &server{cfg: cfg, slots: make(chan struct{}, cfg.concurrency)}
+}
+
+// apiError is an error returned to the client as {"error":{...}}
+type apiError struct {
+	status     int
+	Code       string   `json:"code"` // e.g. "invalid_fen"
+	Message    string   `json:"message"`
+	Field      int      `json:"field,omitempty"`      // for invalid_fen: the FEN field (from 1) containing the error
+	Violations []string `json:"violations,omitempty"` // for illegal_position: the reasons
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func newError(status int, code, format string, args ...interface{}) *apiError {
+	return &apiError{status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// handlerFunc handles the request of an endpoint: it decodes the request body and returns the response object
+type handlerFunc func(ctx context.Context, decode func(v interface{}) error) (interface{}, error)
+
+// handler returns the handler for all endpoints
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/legal-moves", s.endpoint(s.legalMoves))
+	mux.Handle("/make-move", s.endpoint(s.makeMove))
+	mux.Handle("/perft", s.endpoint(s.perft))
+	mux.Handle("/attacks", s.endpoint(s.attacks))
+	mux.Handle("/best-move", s.endpoint(s.bestMove))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, newError(http.StatusNotFound, "not_found", "unknown endpoint '%s'", r.URL.Path))
+	})
+	return mux
+}
+
+// endpoint wraps the handler function with the checks and limits common to all endpoints
+func (s *server) endpoint(h handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeError(w, newError(http.StatusMethodNotAllowed, "method_not_allowed", "method %s not allowed, use POST", r.Method))
+			return
+		}
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+		default:
+			w.Header().Set("Retry-After", "1")
+			writeError(w, newError(http.StatusServiceUnavailable, "busy", "too many concurrent requests"))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), s.cfg.timeout)
+		defer cancel()
+
+		body := http.MaxBytesReader(w, r.Body, s.cfg.maxBody)
+		decode := func(v interface{}) error {
+			dec := json.NewDecoder(body)
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(v); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					return newError(http.StatusRequestEntityTooLarge, "request_too_large", "request body larger than %d bytes", s.cfg.maxBody)
+				}
+				return newError(http.StatusBadRequest, "invalid_request", "invalid request: %s", err)
+			}
+			if _, err := dec.Token(); err != io.EOF {
+				return newError(http.StatusBadRequest, "invalid_request", "invalid request: data after the JSON object")
+			}
+			return nil
+		}
+		resp, err := h(ctx, decode)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+// writeError writes the error as JSON; errors from parsing a position are mapped to invalid_fen or illegal_position
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *apiError
+	var parseErr position.ParseError
+	var validationErr position.ValidationError
+	switch {
+	case errors.As(err, &apiErr):
+	case errors.As(err, &parseErr):
+		apiErr = newError(http.StatusBadRequest, "invalid_fen", "invalid fen: %s", parseErr)
+		apiErr.Field = parseErr.Field()
+	case errors.As(err, &validationErr):
+		apiErr = newError(http.StatusUnprocessableEntity, "illegal_position", "%s", validationErr)
+		for _, v := range validationErr.Violations {
+			apiErr.Violations = append(apiErr.Violations, v.Error())
+		}
+	case errors.Is(err, context.DeadlineExceeded):
+		apiErr = newError(http.StatusGatewayTimeout, "timeout", "the request took too long")
+	default:
+		apiErr = newError(http.StatusInternalServerError, "internal", "%s", err)
+	}
+	writeJSON(w, apiErr.status, struct {
+		Error *apiError `json:"error"`
+	}{apiErr})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// positionRequest is the part of a request which specifies the position
+type positionRequest struct {
+	Fen      string `json:"fen"`
+	Chess960 bool   `json:"chess960,omitempty"`
+}
+
+// position parses the position, which must be legal (see position.ParseFenStrict)
+func (r positionRequest) position() (position.Position, error) {
+	if r.Fen == "" {
+		return position.Position{}, newError(http.StatusBadRequest, "invalid_request", "'fen' is required")
+	}
+	posn, err := position.ParseFenStrict(r.Fen)
+	if err != nil {
+		return position.Position{}, err
+	}
+	posn.SetChess960(r.Chess960)
+	return posn, nil
+}
+
+// moveJSON is a move in both notations
+type moveJSON struct {
+	Uci string `json:"uci"`
+	San string `json:"san"`
+}
+
+func newMoveJSON(posn position.Position, m move.Move) moveJSON {
+	return moveJSON{Uci: posn.UciString(m), San: posn.San(m)}
+}
+
+// status describes whether the game is over
+type status struct {
+	Check         bool   `json:"check"`
+	Checkmate     bool   `json:"checkmate"`
+	Stalemate     bool   `json:"stalemate"`
+	Draw          string `json:"draw,omitempty"` // the reason, if the position is drawn
+	DrawClaimable bool   `json:"drawClaimable,omitempty"`
+}
+
+func newStatus(posn position.Position) status {
+	st := status{Check: posn.InCheck(), Checkmate: posn.IsCheckmate(), Stalemate: posn.IsStalemate()}
+	if reason := posn.DrawReason(true); reason != position.NoDraw {
+		st.Draw, st.DrawClaimable = reason.String(), reason.Claimable()
+	}
+	return st
+}
+
+func (s *server) legalMoves(ctx context.Context, decode func(v interface{}) error) (interface{}, error) {
+	var req positionRequest
+	if err := decode(&req); err != nil {
+		return nil, err
+	}
+	posn, err := req.position()
+	if err != nil {
+		return nil, err
+	}
+	moves := []moveJSON{}
+	for _, m := range posn.FindMoves(posn.ActiveColour()) {
+		moves = append(moves, newMoveJSON(posn, m))
+	}
+	return struct {
+		Moves []moveJSON `json:"moves"`
+		status
+	}{moves, newStatus(posn)}, nil
+}
+
+func (s *server) makeMove(ctx context.Context, decode func(v interface{}) error) (interface{}, error) {
+	var req struct {
+		positionRequest
+		Move string `json:"move"` // UCI or SAN
+	}
+	if err := decode(&req); err != nil {
+		return nil, err
+	}
+	posn, err := req.position()
+	if err != nil {
+		return nil, err
+	}
+	m, err := posn.ParseUci(req.Move)
+	if err != nil {
+		if m, err = posn.ParseSan(req.Move); err != nil {
+			return nil, newError(http.StatusUnprocessableEntity, "illegal_move", "illegal move '%s'", req.Move)
+		}
+	}
+	played := newMoveJSON(posn, m)
+	posn.MakeMove(m)
+	return struct {
+		Fen  string   `json:"fen"`
+		Move moveJSON `json:"move"`
+		status
+	}{posn.Fen(), played, newStatus(posn)}, nil
+}
+
+func (s *server) perft(ctx context.Context, decode func(v interface{}) error) (interface{}, error) {
+	var req struct {
+		positionRequest
+		Depth int `json:"depth"`
+	}
+	if err := decode(&req); err != nil {
+		return nil, err
+	}
+	if req.Depth < 1 || req.Depth > s.cfg.maxPerftDepth {
+		return nil, newError(http.StatusBadRequest, "invalid_depth", "depth must be between 1 and %d", s.cfg.maxPerftDepth)
+	}
+	posn, err := req.position()
+	if err != nil {
+		return nil, err
+	}
+	// the moves are counted one by one, so that the request can be abandoned when the time is up
+	nodes := 0
+	divide := make(map[string]int)
+	for _, m := range posn.FindMoves(posn.ActiveColour()) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		uci := posn.UciString(m)
+		posn.MakeMove(m)
+		divide[uci] = posn.Perft(req.Depth - 1)
+		posn.UnmakeMove(m)
+		nodes += divide[uci]
+	}
+	return struct {
+		Nodes  int            `json:"nodes"`
+		Divide map[string]int `json:"divide"` // the number of nodes after each move (UCI)
+	}{nodes, divide}, nil
+}
+
+func (s *server) attacks(ctx context.Context, decode func(v interface{}) error) (interface{}, error) {
+	var req struct {
+		positionRequest
+		Square string         `json:"square"`
+		Colour *colour.Colour `json:"colour,omitempty"` // only pieces of this colour; all pieces if not given
+	}
+	if err := decode(&req); err != nil {
+		return nil, err
+	}
+	sq, err := square.FromString(req.Square)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid_square", "invalid square '%s'", req.Square)
+	}
+	posn, err := req.position()
+	if err != nil {
+		return nil, err
+	}
+	col := colour.AnyColour
+	if req.Colour != nil {
+		col = *req.Colour
+	}
+	attackers := []string{}
+	for _, attacker := range posn.Attacks(sq, col).SetBits() {
+		attackers = append(attackers, square.Square(attacker).String())
+	}
+	return struct {
+		Attackers []string `json:"attackers"`
+	}{attackers}, nil
+}
+
+func (s *server) bestMove(ctx context.Context, decode func(v interface{}) error) (interface{}, error) {
+	var req struct {
+		positionRequest
+		Depth int `json:"depth"`
+	}
+	if err := decode(&req); err != nil {
+		return nil, err
+	}
+	if req.Depth < 1 || req.Depth > s.cfg.maxSearchDepth {
+		return nil, newError(http.StatusBadRequest, "invalid_depth", "depth must be between 1 and %d", s.cfg.maxSearchDepth)
+	}
+	posn, err := req.position()
+	if err != nil {
+		return nil, err
+	}
+	// the search stops early when the time is up, the result then has a lower depth than requested
+	result, err := search.Iterate(ctx, posn, req.Depth, nil)
+	if err != nil {
+		return nil, newError(http.StatusUnprocessableEntity, "game_over", "%s", err)
+	}
+	pv := make([]moveJSON, 0, len(result.PV))
+	for _, m := range result.PV {
+		pv = append(pv, newMoveJSON(posn, m))
+		posn.MakeMove(m)
+	}
+	for i := len(result.PV) - 1; i >= 0; i-- {
+		posn.UnmakeMove(result.PV[i])
+	}
+	resp := struct {
+		Move   moveJSON   `json:"move"`
+		Score  int        `json:"score"`            // centipawns from the view of the side to move
+		MateIn int        `json:"mateIn,omitempty"` // moves until mate, negative if the side to move is mated
+		PV     []moveJSON `json:"pv"`
+		Depth  int        `json:"depth"`
+		Nodes  int        `json:"nodes"`
+	}{newMoveJSON(posn, result.Move), result.Score, result.MateIn(), pv, result.Depth, result.Nodes}
+	return resp, nil
+}
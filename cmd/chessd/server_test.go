@@ -0,0 +1,233 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	s := newServer(config{maxBody: 1024, timeout: 10 * time.Second, concurrency: 2, maxSearchDepth: 4, maxPerftDepth: 3})
+	ts := httptest.NewServer(s.handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// post sends the request and decodes the response into a map
+func post(t *testing.T, ts *httptest.Server, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	resp, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s: unexpected content type '%s'", path, ct)
+	}
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("%s: invalid response: %s", path, err)
+	}
+	return resp.StatusCode, result
+}
+
+// errorCode returns the code of an error response
+func errorCode(result map[string]interface{}) string {
+	e, ok := result["error"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	return e["code"].(string)
+}
+
+const startFen = `"fen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"`
+
+func TestLegalMoves(t *testing.T) {
+	ts := newTestServer(t)
+	status, result := post(t, ts, "/legal-moves", "{"+startFen+"}")
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %v", status, result)
+	}
+	moves := result["moves"].([]interface{})
+	if len(moves) != 20 {
+		t.Errorf("expected 20 moves but got %d", len(moves))
+	}
+	found := false
+	for _, m := range moves {
+		mv := m.(map[string]interface{})
+		if mv["uci"] == "g1f3" && mv["san"] == "Nf3" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("move Nf3 not found in %v", moves)
+	}
+	if result["check"] != false || result["checkmate"] != false {
+		t.Errorf("unexpected status %v", result)
+	}
+
+	// fool's mate
+	_, result = post(t, ts, "/legal-moves", `{"fen":"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"}`)
+	if len(result["moves"].([]interface{})) != 0 || result["checkmate"] != true || result["check"] != true {
+		t.Errorf("expected checkmate but got %v", result)
+	}
+}
+
+func TestMakeMove(t *testing.T) {
+	ts := newTestServer(t)
+	for _, mv := range []string{"e2e4", "e4"} {
+		status, result := post(t, ts, "/make-move", "{"+startFen+`,"move":"`+mv+`"}`)
+		if status != http.StatusOK {
+			t.Fatalf("unexpected status %d: %v", status, result)
+		}
+		if result["fen"] != "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1" {
+			t.Errorf("unexpected fen %v", result["fen"])
+		}
+		if m := result["move"].(map[string]interface{}); m["uci"] != "e2e4" || m["san"] != "e4" {
+			t.Errorf("unexpected move %v", m)
+		}
+	}
+	status, result := post(t, ts, "/make-move", "{"+startFen+`,"move":"e2e5"}`)
+	if status != http.StatusUnprocessableEntity || errorCode(result) != "illegal_move" {
+		t.Errorf("expected illegal_move but got %d %v", status, result)
+	}
+}
+
+func TestPerft(t *testing.T) {
+	ts := newTestServer(t)
+	status, result := post(t, ts, "/perft", "{"+startFen+`,"depth":3}`)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %v", status, result)
+	}
+	if result["nodes"] != 8902.0 {
+		t.Errorf("expected 8902 nodes but got %v", result["nodes"])
+	}
+	if divide := result["divide"].(map[string]interface{}); len(divide) != 20 || divide["e2e4"] != 600.0 {
+		t.Errorf("unexpected divide %v", divide)
+	}
+	status, result = post(t, ts, "/perft", "{"+startFen+`,"depth":4}`)
+	if status != http.StatusBadRequest || errorCode(result) != "invalid_depth" {
+		t.Errorf("expected invalid_depth but got %d %v", status, result)
+	}
+}
+
+func TestAttacks(t *testing.T) {
+	ts := newTestServer(t)
+	_, result := post(t, ts, "/attacks", "{"+startFen+`,"square":"f3"}`)
+	if got := result["attackers"].([]interface{}); len(got) != 3 {
+		t.Errorf("expected 3 attackers but got %v", got)
+	}
+	_, result = post(t, ts, "/attacks", "{"+startFen+`,"square":"f6","colour":"black"}`)
+	if got := result["attackers"].([]interface{}); len(got) != 3 {
+		t.Errorf("expected 3 attackers but got %v", got)
+	}
+	_, result = post(t, ts, "/attacks", "{"+startFen+`,"square":"f6","colour":"white"}`)
+	if got := result["attackers"].([]interface{}); len(got) != 0 {
+		t.Errorf("expected no attackers but got %v", got)
+	}
+	status, result := post(t, ts, "/attacks", "{"+startFen+`,"square":"z9"}`)
+	if status != http.StatusBadRequest || errorCode(result) != "invalid_square" {
+		t.Errorf("expected invalid_square but got %d %v", status, result)
+	}
+}
+
+func TestBestMove(t *testing.T) {
+	ts := newTestServer(t)
+	// mate in one
+	status, result := post(t, ts, "/best-move", `{"fen":"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1","depth":2}`)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected status %d: %v", status, result)
+	}
+	if m := result["move"].(map[string]interface{}); m["san"] != "Ra8#" {
+		t.Errorf("expected Ra8# but got %v", m)
+	}
+	if result["mateIn"] != 1.0 {
+		t.Errorf("expected mate in 1 but got %v", result["mateIn"])
+	}
+	status, result = post(t, ts, "/best-move", `{"fen":"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3","depth":2}`)
+	if status != http.StatusUnprocessableEntity || errorCode(result) != "game_over" {
+		t.Errorf("expected game_over but got %d %v", status, result)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts := newTestServer(t)
+	status, result := post(t, ts, "/legal-moves", `{"fen":"7k/8/8/8/8/8/8/7K x - - 0 1"}`)
+	if status != http.StatusBadRequest || errorCode(result) != "invalid_fen" {
+		t.Errorf("expected invalid_fen but got %d %v", status, result)
+	}
+	if field := result["error"].(map[string]interface{})["field"]; field != 2.0 {
+		t.Errorf("expected field 2 but got %v", field)
+	}
+	for fen, field := range map[string]float64{
+		"7k/8/8/8/8/8/8/7X w - - 0 1":  1,
+		"7k/8/8/8/8/8/8/7K w X - 0 1":  3,
+		"7k/8/8/8/8/8/8/7K w - z9 0 1": 4,
+		"7k/8/8/8/8/8/8/7K w - a3 0 1": 4,
+		"7k/8/8/8/8/8/8/7K w - - x 1":  5,
+		"7k/8/8/8/8/8/8/7K w - - 0 y":  6,
+	} {
+		status, result = post(t, ts, "/legal-moves", `{"fen":"`+fen+`"}`)
+		if status != http.StatusBadRequest || errorCode(result) != "invalid_fen" {
+			t.Errorf("%s: expected invalid_fen but got %d %v", fen, status, result)
+		} else if f := result["error"].(map[string]interface{})["field"]; f != field {
+			t.Errorf("%s: expected field %v but got %v", fen, field, f)
+		}
+	}
+	status, result = post(t, ts, "/legal-moves", `{"fen":"7k/8/8/8/8/8/8/K6R w - - 0 1"}`)
+	if status != http.StatusUnprocessableEntity || errorCode(result) != "illegal_position" {
+		t.Errorf("expected illegal_position but got %d %v", status, result)
+	}
+	for _, body := range []string{`{}`, `{"fen":`, `{"fen":"x","unknown":1}`, `{"fen":"x"} {}`} {
+		status, result = post(t, ts, "/legal-moves", body)
+		if status != http.StatusBadRequest || errorCode(result) != "invalid_request" {
+			t.Errorf("%s: expected invalid_request but got %d %v", body, status, result)
+		}
+	}
+	status, result = post(t, ts, "/legal-moves", `{"fen":"`+strings.Repeat(" ", 2000)+`"}`)
+	if status != http.StatusRequestEntityTooLarge || errorCode(result) != "request_too_large" {
+		t.Errorf("expected request_too_large but got %d %v", status, result)
+	}
+	status, result = post(t, ts, "/unknown", `{}`)
+	if status != http.StatusNotFound || errorCode(result) != "not_found" {
+		t.Errorf("expected not_found but got %d %v", status, result)
+	}
+
+	resp, err := http.Get(ts.URL + "/legal-moves")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != http.MethodPost {
+		t.Errorf("expected method not allowed but got %d", resp.StatusCode)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	s := newServer(config{maxBody: 1024, timeout: time.Nanosecond, concurrency: 1, maxSearchDepth: 4, maxPerftDepth: 3})
+	ts := httptest.NewServer(s.handler())
+	defer ts.Close()
+
+	// the time is up before the first move has been counted
+	status, result := post(t, ts, "/perft", "{"+startFen+`,"depth":3}`)
+	if status != http.StatusGatewayTimeout || errorCode(result) != "timeout" {
+		t.Errorf("expected timeout but got %d %v", status, result)
+	}
+	// the search returns the result of depth 1
+	status, result = post(t, ts, "/best-move", "{"+startFen+`,"depth":4}`)
+	if status != http.StatusOK || result["depth"] != 1.0 {
+		t.Errorf("expected result of depth 1 but got %d %v", status, result)
+	}
+
+	// all slots taken
+	s.slots <- struct{}{}
+	status, result = post(t, ts, "/legal-moves", "{"+startFen+"}")
+	if status != http.StatusServiceUnavailable || errorCode(result) != "busy" {
+		t.Errorf("expected busy but got %d %v", status, result)
+	}
+	<-s.slots
+}
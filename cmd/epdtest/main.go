@@ -0,0 +1,155 @@
+// epdtest runs EPD test suites (e.g. WAC, STS) against a UCI engine,
+// or validates the move generator against the D1, D2, ... entries of a perft EPD file
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/rjo67/chess/position"
+)
+
+func main() {
+	engineCmd := flag.String("engine", "", "command line of the UCI engine")
+	movetime := flag.Duration("time", time.Second, "time limit per position")
+	perft := flag.Bool("perft", false, "validate the perft counts (D1, D2, ...) of the records instead of running an engine")
+	maxDepth := flag.Int("depth", 0, "perft: only validate up to this depth (0: no limit)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] file.epd ...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 || (!*perft && *engineCmd == "") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var records []position.EPD
+	for _, filename := range flag.Args() {
+		f, err := os.Open(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fileRecords, err := position.ReadEpd(f)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", filename, err)
+			os.Exit(1)
+		}
+		records = append(records, fileRecords...)
+	}
+
+	var ok bool
+	if *perft {
+		ok = runPerft(records, *maxDepth)
+	} else {
+		var err error
+		if ok, err = runSuite(records, *engineCmd, *movetime); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// name returns the id of the record, or its number if no id is given
+func name(e position.EPD, recordNbr int) string {
+	if id := e.ID(); id != "" {
+		return id
+	}
+	return fmt.Sprintf("#%d", recordNbr+1)
+}
+
+// runSuite lets the engine search every record with a 'bm' or 'am' operation.
+// A record is solved if the engine plays one of the best moves and none of the moves to avoid.
+// Returns true if all records were solved.
+func runSuite(records []position.EPD, engineCmd string, movetime time.Duration) (bool, error) {
+	e, err := startEngine(engineCmd)
+	if err != nil {
+		return false, err
+	}
+	defer e.Close()
+
+	solved, total := 0, 0
+	for recordNbr, record := range records {
+		bestMoves, err := record.BestMoves()
+		if err != nil {
+			return false, fmt.Errorf("%s: %s", name(record, recordNbr), err)
+		}
+		avoidMoves, err := record.AvoidMoves()
+		if err != nil {
+			return false, fmt.Errorf("%s: %s", name(record, recordNbr), err)
+		}
+		if len(bestMoves) == 0 && len(avoidMoves) == 0 {
+			continue
+		}
+		total++
+		m, err := e.bestMove(record.Position, movetime)
+		if err != nil {
+			return false, fmt.Errorf("%s: %s", name(record, recordNbr), err)
+		}
+		engineMove := record.Position.UciString(m)
+
+		ok := len(bestMoves) == 0
+		for _, m := range bestMoves {
+			if record.Position.UciString(m) == engineMove {
+				ok = true
+			}
+		}
+		for _, m := range avoidMoves {
+			if record.Position.UciString(m) == engineMove {
+				ok = false
+			}
+		}
+		result := "unsolved"
+		if ok {
+			solved++
+			result = "solved"
+		}
+		bm, _ := record.Operands("bm")
+		am, _ := record.Operands("am")
+		fmt.Printf("%-12s %-8s engine: %-6s bm: %v am: %v\n", name(record, recordNbr), result, engineMove, bm, am)
+	}
+	fmt.Printf("solved %d of %d\n", solved, total)
+	return solved == total, nil
+}
+
+// runPerft compares the perft counts of the records with the move generator.
+// Returns true if all counts match.
+func runPerft(records []position.EPD, maxDepth int) bool {
+	failures := 0
+	for recordNbr, record := range records {
+		counts, err := record.PerftCounts()
+		if err != nil {
+			fmt.Printf("%s: %s\n", name(record, recordNbr), err)
+			failures++
+			continue
+		}
+		depths := make([]int, 0, len(counts))
+		for depth := range counts {
+			if maxDepth == 0 || depth <= maxDepth {
+				depths = append(depths, depth)
+			}
+		}
+		sort.Ints(depths)
+		for _, depth := range depths {
+			start := time.Now()
+			nodes := record.Position.Perft(depth)
+			result := "ok"
+			if nodes != counts[depth] {
+				result = fmt.Sprintf("FAILED, expected %d", counts[depth])
+				failures++
+			}
+			fmt.Printf("%s D%d: %d %s (%s)\n", name(record, recordNbr), depth, nodes, result, time.Since(start).Round(time.Millisecond))
+		}
+	}
+	fmt.Printf("%d failure(s)\n", failures)
+	return failures == 0
+}
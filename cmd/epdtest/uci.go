@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rjo67/chess/engine"
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/position"
+)
+
+// grace period for the engine to respond after the time limit has expired
+const grace = 5 * time.Second
+
+// uciEngine is the engine under test, see the package engine
+type uciEngine struct {
+	*engine.Engine
+	chess960 bool // current value of the option UCI_Chess960
+}
+
+// startEngine starts the engine (command line split at whitespace) and initialises the UCI protocol.
+// The engine process has ended if an error is returned.
+func startEngine(command string) (*uciEngine, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no engine specified")
+	}
+	e, err := engine.Start(context.Background(), args[0], args[1:]...)
+	if err != nil {
+		return nil, err
+	}
+	e.Timeout = grace
+	if err := e.IsReady(context.Background()); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return &uciEngine{Engine: e}, nil
+}
+
+// bestMove lets the engine search the position for the given time and returns the move found.
+// If the engine does not respect the time limit, the search is stopped after the grace period.
+func (e *uciEngine) bestMove(posn position.Position, movetime time.Duration) (move.Move, error) {
+	if posn.Chess960() != e.chess960 {
+		if err := e.SetOption("UCI_Chess960", fmt.Sprint(posn.Chess960())); err != nil {
+			return move.Move{}, err
+		}
+		e.chess960 = posn.Chess960()
+	}
+	if err := e.NewGame(context.Background()); err != nil {
+		return move.Move{}, err
+	}
+	if err := e.SetPosition(posn, nil); err != nil {
+		return move.Move{}, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), movetime+grace)
+	defer cancel()
+	result, err := e.Go(ctx, engine.Limits{MoveTime: movetime}, nil)
+	if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return move.Move{}, err
+	}
+	return result.BestMove, nil
+}
@@ -0,0 +1,45 @@
+// gentb generates distance-to-mate endgame tables, e.g. gentb -dir tables KQK KRK KPK KBNK
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rjo67/chess/endgame"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "directory in which the tables are stored")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-dir directory] material...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	set := endgame.NewSet(*dir)
+	for _, arg := range flag.Args() {
+		m, err := endgame.ParseMaterial(arg)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		start := time.Now()
+		t, err := set.Generate(m)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: longest win: %s (%s)\n", t.Material(), t.MaxResult(), time.Since(start).Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,53 @@
+// makebook creates a Polyglot opening book (.bin) from a PGN collection
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rjo67/chess/polyglot"
+)
+
+func main() {
+	pgnFile := flag.String("pgn", "", "PGN file containing the games")
+	binFile := flag.String("bin", "book.bin", "name of the book to create")
+	var opts polyglot.BuildOptions
+	flag.IntVar(&opts.MaxPly, "max-ply", 0, "only consider the first n half-moves of each game (0: no limit)")
+	flag.IntVar(&opts.MinGames, "min-games", 1, "only store moves which have been played in at least n games")
+	flag.IntVar(&opts.MinWeight, "min-weight", 0, "only store moves with at least this weight (win=2, draw=1, loss=0 points per game)")
+	flag.Parse()
+
+	if *pgnFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	in, err := os.Open(*pgnFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer in.Close()
+
+	book, err := polyglot.Build(in, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	out, err := os.Create(*binFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := book.Write(out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := out.Close(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("wrote %d entries to %s\n", book.Len(), *binFile)
+}
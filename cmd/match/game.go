@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rjo67/chess/clock"
+	"github.com/rjo67/chess/engine"
+	"github.com/rjo67/chess/pgn"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+)
+
+// adjudication rules, a value of 0 switches the rule off
+type adjudication struct {
+	drawMoveNumber  int // the draw rule applies from this move number onwards
+	drawMoveCount   int // a game is drawn if both engines' scores have been within drawScore for this number of consecutive moves
+	drawScore       int
+	resignMoveCount int // an engine loses if its score has been at least resignScore below zero for this number of consecutive moves
+	resignScore     int
+	maxMoves        int // a game is drawn after this number of moves
+}
+
+// config of the games
+type config struct {
+	tc           *clock.TimeControl // nil: no clock
+	moveTime     time.Duration      // time per move, 0: not limited
+	depth        int                // depth per move, 0: not limited
+	timeMargin   time.Duration      // an engine only loses on time if it exceeds its time by more than this margin
+	adjudication adjudication
+	event        string
+}
+
+// mate scores are recorded as this value (minus the number of moves to mate) for the adjudication
+const mateScore = 100000
+
+// gameResult is the outcome of one game
+type gameResult struct {
+	number  int       // game number, from 0
+	white   int       // index of the engine playing white
+	result  string    // pgn.WhiteWins etc.
+	reason  string    // e.g. "White mates"
+	crashed [2]bool   // the engine (by index) has crashed or stopped responding and must be restarted
+	game    *pgn.Game // nil if the game has been cancelled
+}
+
+// playGame plays one game between the engines, the engine with the index white plays white.
+// If the game could not be started or the context is done, the result has no game.
+func playGame(ctx context.Context, cfg config, engines [2]*engine.Engine, names [2]string, white, number int, op opening) gameResult {
+	res := gameResult{number: number, white: white, result: pgn.Unfinished}
+	start, posn, moves, sans, err := op.position()
+	if err != nil {
+		res.reason = err.Error()
+		return res
+	}
+	for i, e := range engines {
+		if err := e.NewGame(ctx); err != nil {
+			res.reason, res.crashed[i] = err.Error(), true
+			return res
+		}
+	}
+	// the engine index for each colour
+	byColour := [2]int{white, 1 - white}
+
+	var c *clock.Clock
+	if cfg.tc != nil {
+		c = clock.New(*cfg.tc, clock.System)
+		c.Tolerance = cfg.timeMargin
+		c.Start(posn.ActiveColour())
+	}
+	var scores [2][]int // the scores reported by the engines of each colour, from their view
+	termination := "normal"
+
+	for res.reason == "" {
+		col := posn.ActiveColour()
+		if result, reason := rulesResult(posn); result != "" {
+			res.result, res.reason = result, reason
+			break
+		}
+		if cfg.adjudication.maxMoves > 0 && posn.FullmoveNbr() > cfg.adjudication.maxMoves {
+			res.result, res.reason, termination = pgn.Draw, "Draw by adjudication: maximum number of moves", "adjudication"
+			break
+		}
+
+		e := engines[byColour[col]]
+		limits := engine.Limits{Depth: cfg.depth, MoveTime: cfg.moveTime}
+		if c != nil {
+			limits.WhiteTime, limits.BlackTime = nonNegative(c.Remaining(colour.White)), nonNegative(c.Remaining(colour.Black))
+			limits.WhiteInc, limits.BlackInc = c.Increment(colour.White), c.Increment(colour.Black)
+			limits.MovesToGo = c.MovesToGo(col)
+		}
+		moveCtx, cancel := ctx, context.CancelFunc(func() {})
+		if deadline := cfg.moveDeadline(c); deadline != clock.Unlimited {
+			moveCtx, cancel = context.WithTimeout(ctx, deadline)
+		}
+		err := e.SetPosition(start, moves)
+		var result engine.Result
+		if err == nil {
+			result, err = e.Go(moveCtx, limits, nil)
+		}
+		cancel()
+		flagFall := false
+		if c != nil && err == nil {
+			flagFall = errors.Is(c.Press(), clock.ErrFlagFall)
+		}
+		switch {
+		case ctx.Err() != nil:
+			// the match has been cancelled
+			res.reason = "cancelled"
+			return res
+		case errors.Is(err, context.DeadlineExceeded), flagFall:
+			res.result, res.reason, termination = loses(col), fmt.Sprintf("%s loses on time", colourName(col)), "time forfeit"
+		case err != nil:
+			res.result, res.reason, termination = loses(col), fmt.Sprintf("%s %s", colourName(col), err), "rules infraction"
+			res.crashed[byColour[col]] = true
+		}
+		if res.reason != "" {
+			break
+		}
+
+		if s, ok := lastScore(result); ok {
+			scores[col] = append(scores[col], s)
+		}
+		sans = append(sans, posn.San(result.BestMove))
+		moves = append(moves, result.BestMove)
+		posn.MakeMove(result.BestMove)
+
+		if reason := cfg.adjudication.resigns(scores[col]); reason != "" {
+			res.result, res.reason, termination = loses(col), colourName(col)+" "+reason, "adjudication"
+		} else if cfg.adjudication.drawn(posn.FullmoveNbr(), scores) {
+			res.result, res.reason, termination = pgn.Draw, "Draw by adjudication", "adjudication"
+		}
+	}
+
+	res.game = &pgn.Game{Tags: map[string]string{
+		"Event":       cfg.event,
+		"Site":        "?",
+		"Date":        time.Now().Format("2006.01.02"),
+		"Round":       strconv.Itoa(number + 1),
+		"White":       names[white],
+		"Black":       names[1-white],
+		"PlyCount":    strconv.Itoa(len(sans)),
+		"Termination": termination,
+	}, Moves: sans, Result: res.result}
+	if start.Fen() != position.StartPosition().Fen() {
+		res.game.Tags["FEN"], res.game.Tags["SetUp"] = start.Fen(), "1"
+	}
+	if cfg.tc != nil {
+		res.game.Tags["TimeControl"] = cfg.tc.String()
+	}
+	return res
+}
+
+// rulesResult returns the result and the reason if the game is over according to the rules.
+// Draws which must be claimed (threefold repetition, fifty-move rule) end the game as well.
+func rulesResult(posn position.Position) (string, string) {
+	switch {
+	case posn.IsCheckmate():
+		col := posn.ActiveColour()
+		return loses(col), colourName(col.Other()) + " mates"
+	case posn.DrawReason(true) != position.NoDraw:
+		return pgn.Draw, "Draw by " + posn.DrawReason(true).String()
+	}
+	return "", ""
+}
+
+// lastScore returns the score of the principal variation sent last, with mate scores converted to centipawns
+func lastScore(result engine.Result) (int, bool) {
+	for i := len(result.Infos) - 1; i >= 0; i-- {
+		info := result.Infos[i]
+		if info.Score == nil || info.MultiPV > 1 {
+			continue
+		}
+		switch {
+		case info.Score.Mate > 0:
+			return mateScore - info.Score.Mate, true
+		case info.Score.Mate < 0:
+			return -mateScore - info.Score.Mate, true
+		}
+		return info.Score.CP, true
+	}
+	return 0, false
+}
+
+// resigns returns the reason if the engine with the given scores (latest last) loses by adjudication
+func (a adjudication) resigns(scores []int) string {
+	if a.resignMoveCount == 0 || len(scores) < a.resignMoveCount {
+		return ""
+	}
+	for _, s := range scores[len(scores)-a.resignMoveCount:] {
+		if s > -a.resignScore {
+			return ""
+		}
+	}
+	return "resigns (adjudication)"
+}
+
+// drawn returns true if the game is drawn by adjudication
+func (a adjudication) drawn(moveNbr int, scores [2][]int) bool {
+	if a.drawMoveCount == 0 || moveNbr < a.drawMoveNumber {
+		return false
+	}
+	for _, s := range scores {
+		if len(s) < a.drawMoveCount {
+			return false
+		}
+		for _, v := range s[len(s)-a.drawMoveCount:] {
+			if v > a.drawScore || v < -a.drawScore {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// moveDeadline returns the time the engine to move has for its move: it must move before its flag falls and,
+// with -movetime, before the time per move (plus the margin) is up, whichever comes first
+func (cfg config) moveDeadline(c *clock.Clock) time.Duration {
+	deadline := clock.Unlimited
+	if c != nil {
+		deadline = c.TimeUntilFlag()
+	}
+	if cfg.moveTime > 0 && cfg.moveTime+cfg.timeMargin < deadline {
+		deadline = cfg.moveTime + cfg.timeMargin
+	}
+	return deadline
+}
+
+// nonNegative returns the remaining time for the engine's limits, which is 0 if the time has been exceeded
+// within the margin
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// loses returns the result if the given colour loses
+func loses(col colour.Colour) string {
+	if col == colour.White {
+		return pgn.BlackWins
+	}
+	return pgn.WhiteWins
+}
+
+func colourName(col colour.Colour) string {
+	if col == colour.White {
+		return "White"
+	}
+	return "Black"
+}
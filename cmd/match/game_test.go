@@ -0,0 +1,108 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rjo67/chess/clock"
+	"github.com/rjo67/chess/engine"
+)
+
+func TestMoveDeadline(t *testing.T) {
+	newClock := func(tc string) *clock.Clock {
+		control, err := clock.ParseTimeControl(tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := clock.New(control, clock.System)
+		c.Tolerance = 100 * time.Millisecond
+		return c
+	}
+	for i, d := range []struct {
+		cfg      config
+		c        *clock.Clock
+		expected time.Duration
+	}{
+		{config{timeMargin: 100 * time.Millisecond}, nil, clock.Unlimited},
+		{config{moveTime: time.Second, timeMargin: 100 * time.Millisecond}, nil, 1100 * time.Millisecond},
+		{config{timeMargin: 100 * time.Millisecond}, newClock("60"), 60100 * time.Millisecond},
+		{config{moveTime: time.Second, timeMargin: 100 * time.Millisecond}, newClock("60"), 1100 * time.Millisecond},  // the time per move is up first
+		{config{moveTime: time.Minute, timeMargin: 100 * time.Millisecond}, newClock("10"), 10100 * time.Millisecond}, // the flag falls first
+		{config{moveTime: time.Second, timeMargin: 100 * time.Millisecond}, newClock("-"), 1100 * time.Millisecond},
+	} {
+		if got := d.cfg.moveDeadline(d.c); got != d.expected {
+			t.Errorf("%d: expected %v but got %v", i, d.expected, got)
+		}
+	}
+}
+
+func TestLastScore(t *testing.T) {
+	info := func(multiPV int, score *engine.Score) engine.Info {
+		return engine.Info{Depth: 1, MultiPV: multiPV, Score: score}
+	}
+	for i, d := range []struct {
+		infos    []engine.Info
+		expected int
+		ok       bool
+	}{
+		{nil, 0, false},
+		{[]engine.Info{info(0, nil)}, 0, false},
+		{[]engine.Info{info(0, &engine.Score{CP: 35})}, 35, true},
+		{[]engine.Info{info(0, &engine.Score{CP: 35}), info(0, &engine.Score{CP: -12})}, -12, true},
+		{[]engine.Info{info(0, &engine.Score{CP: 35}), info(0, nil)}, 35, true},
+		{[]engine.Info{info(0, &engine.Score{Mate: 3})}, mateScore - 3, true},
+		{[]engine.Info{info(0, &engine.Score{Mate: -2})}, -mateScore + 2, true},
+		{[]engine.Info{info(1, &engine.Score{CP: 50}), info(2, &engine.Score{CP: -80})}, 50, true}, // only the best line counts
+	} {
+		got, ok := lastScore(engine.Result{Infos: d.infos})
+		if got != d.expected || ok != d.ok {
+			t.Errorf("%d: expected %d, %t but got %d, %t", i, d.expected, d.ok, got, ok)
+		}
+	}
+}
+
+func TestResigns(t *testing.T) {
+	a := adjudication{resignMoveCount: 3, resignScore: 500}
+	for i, d := range []struct {
+		scores  []int
+		resigns bool
+	}{
+		{nil, false},
+		{[]int{-600, -700}, false}, // not enough moves
+		{[]int{-600, -700, -500}, true},
+		{[]int{0, -600, -700, -800}, true},
+		{[]int{-600, -499, -700}, false},
+		{[]int{-600, -700, -800, 100}, false},
+		{[]int{-mateScore + 5, -mateScore + 4, -mateScore + 3}, true},
+	} {
+		if got := a.resigns(d.scores) != ""; got != d.resigns {
+			t.Errorf("%d: expected %t but got %t", i, d.resigns, got)
+		}
+	}
+	if got := (adjudication{}).resigns([]int{-1000, -1000, -1000}); got != "" {
+		t.Errorf("rule switched off, but got '%s'", got)
+	}
+}
+
+func TestDrawn(t *testing.T) {
+	a := adjudication{drawMoveNumber: 40, drawMoveCount: 2, drawScore: 10}
+	for i, d := range []struct {
+		moveNbr int
+		scores  [2][]int
+		drawn   bool
+	}{
+		{40, [2][]int{{5, 0}, {-10, 10}}, true},
+		{39, [2][]int{{5, 0}, {-10, 10}}, false}, // too early
+		{40, [2][]int{{5}, {-10, 10}}, false},    // not enough moves
+		{50, [2][]int{{200, 5, 0}, {-10, 10}}, true},
+		{50, [2][]int{{5, 11}, {-10, 10}}, false},
+		{50, [2][]int{{5, 0}, {-11, 10}}, false},
+	} {
+		if got := a.drawn(d.moveNbr, d.scores); got != d.drawn {
+			t.Errorf("%d: expected %t but got %t", i, d.drawn, got)
+		}
+	}
+	if (adjudication{}).drawn(100, [2][]int{{0, 0}, {0, 0}}) {
+		t.Error("rule switched off, but the game was drawn")
+	}
+}
@@ -0,0 +1,252 @@
+// match plays games between two UCI engines and reports the Elo difference, e.g. to test a change to an engine.
+// The games are played in pairs with the same opening and reversed colours.
+// The match can be stopped early by a sequential probability ratio test (SPRT).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjo67/chess/clock"
+	"github.com/rjo67/chess/engine"
+	"github.com/rjo67/chess/pgn"
+	"github.com/rjo67/chess/position"
+)
+
+func main() {
+	engineCmds := [2]*string{
+		flag.String("engine1", "", "command line of the first engine"),
+		flag.String("engine2", "", "command line of the second engine"),
+	}
+	engineNames := [2]*string{
+		flag.String("name1", "", "name of the first engine (default: as reported by the engine)"),
+		flag.String("name2", "", "name of the second engine (default: as reported by the engine)"),
+	}
+	games := flag.Int("games", 100, "number of games, rounded up to an even number")
+	concurrency := flag.Int("concurrency", 1, "number of games played at the same time")
+	tcStr := flag.String("tc", "", "time control in the format of the PGN tag TimeControl, e.g. 40/60, 10+0.1 or 40/60+1:30+1")
+	moveTime := flag.Duration("movetime", 0, "time per move")
+	depth := flag.Int("depth", 0, "depth per move")
+	timeMargin := flag.Duration("timemargin", 100*time.Millisecond, "an engine loses on time only if it exceeds its time by more than this")
+	openingsFile := flag.String("openings", "", "EPD or PGN file with the openings (default: the start position)")
+	plies := flag.Int("plies", 8, "maximum number of plies taken from each game of a PGN openings file")
+	random := flag.Bool("random", false, "play the openings in random order")
+	pgnOut := flag.String("pgnout", "", "write the games to this PGN file")
+	event := flag.String("event", "Engine match", "name of the event in the PGN output")
+	var adj adjudication
+	flag.IntVar(&adj.drawMoveNumber, "drawmovenumber", 40, "draw adjudication: applies from this move number onwards")
+	flag.IntVar(&adj.drawMoveCount, "drawmovecount", 0, "draw adjudication: number of consecutive moves of both engines with a score within drawscore (0: off)")
+	flag.IntVar(&adj.drawScore, "drawscore", 10, "draw adjudication: maximum absolute score in centipawns")
+	flag.IntVar(&adj.resignMoveCount, "resignmovecount", 0, "resign adjudication: number of consecutive moves of an engine with a score of at most -resignscore (0: off)")
+	flag.IntVar(&adj.resignScore, "resignscore", 800, "resign adjudication: score in centipawns")
+	flag.IntVar(&adj.maxMoves, "maxmoves", 0, "a game is drawn after this number of moves (0: no limit)")
+	useSprt := flag.Bool("sprt", false, "stop the match as soon as the SPRT accepts one of the hypotheses")
+	var test sprt
+	flag.Float64Var(&test.elo0, "elo0", 0, "SPRT: Elo difference of the null hypothesis H0")
+	flag.Float64Var(&test.elo1, "elo1", 5, "SPRT: Elo difference of the alternative hypothesis H1")
+	flag.Float64Var(&test.alpha, "alpha", 0.05, "SPRT: probability of accepting H1 if H0 is true")
+	flag.Float64Var(&test.beta, "beta", 0.05, "SPRT: probability of accepting H0 if H1 is true")
+	flag.Parse()
+
+	cfg := config{moveTime: *moveTime, depth: *depth, timeMargin: *timeMargin, adjudication: adj, event: *event}
+	if *tcStr != "" {
+		tc, err := clock.ParseTimeControl(*tcStr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if !tc.Unlimited() {
+			cfg.tc = &tc
+		}
+	}
+	if *engineCmds[0] == "" || *engineCmds[1] == "" || *games < 1 || *concurrency < 1 ||
+		(cfg.tc == nil && cfg.moveTime == 0 && cfg.depth == 0) {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *useSprt && (test.elo0 >= test.elo1 || test.alpha <= 0 || test.alpha >= 1 || test.beta <= 0 || test.beta >= 1) {
+		fmt.Fprintln(os.Stderr, "invalid SPRT parameters")
+		os.Exit(2)
+	}
+
+	openings := []opening{{fen: position.StartPosition().Fen()}}
+	if *openingsFile != "" {
+		var err error
+		if openings, err = loadOpenings(*openingsFile, *plies, *random); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	commands := [2]string{*engineCmds[0], *engineCmds[1]}
+	var names [2]string
+	for i, command := range commands {
+		e, err := startEngine(command)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", command, err)
+			os.Exit(1)
+		}
+		names[i] = e.Name
+		e.Close()
+		if *engineNames[i] != "" {
+			names[i] = *engineNames[i]
+		}
+	}
+	if names[0] == names[1] {
+		names[1] += " (2)"
+	}
+
+	var out io.Writer
+	if *pgnOut != "" {
+		f, err := os.Create(*pgnOut)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var sprtTest *sprt
+	if *useSprt {
+		sprtTest = &test
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, cfg, commands, names, openings, (*games+1)/2*2, *concurrency, sprtTest, out); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// startEngine starts the engine, the command line is split at whitespace
+func startEngine(command string) (*engine.Engine, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("no engine specified")
+	}
+	return engine.Start(context.Background(), args[0], args[1:]...)
+}
+
+// run plays the games and prints the results. Game 2k and 2k+1 are played with the opening k (modulo the number of openings),
+// the first engine plays white in the games with even numbers.
+// The match ends early if the context is done or the SPRT (if not nil) accepts a hypothesis.
+func run(ctx context.Context, cfg config, commands [2]string, names [2]string, openings []opening, games, concurrency int,
+	test *sprt, pgnOut io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := 0; i < games; i++ {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	results := make(chan gameResult)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker(ctx, cfg, commands, names, openings, jobs, results)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var s score
+	var err error
+	for res := range results {
+		if res.game == nil {
+			if ctx.Err() == nil {
+				fmt.Printf("Game %d was not played: %s\n", res.number+1, res.reason)
+			}
+			continue
+		}
+		fmt.Printf("Finished game %d (%s vs %s): %s {%s}\n", res.number+1, names[res.white], names[1-res.white], res.result, res.reason)
+		switch {
+		case res.result == pgn.Draw:
+			s.draws++
+		case (res.result == pgn.WhiteWins) == (res.white == 0):
+			s.wins++
+		default:
+			s.losses++
+		}
+		printScore(names, s)
+		if pgnOut != nil && err == nil {
+			// the match continues, the error is reported at the end
+			err = res.game.Write(pgnOut)
+		}
+		if test != nil {
+			lower, upper := test.bounds()
+			fmt.Printf("SPRT: llr %.3f, lbound %.3f, ubound %.3f\n", test.llr(s), lower, upper)
+			if decision := test.decision(s); decision != "" && ctx.Err() == nil {
+				fmt.Printf("SPRT (%s): %s was accepted\n", test, decision)
+				cancel()
+			}
+		}
+	}
+	return err
+}
+
+// worker plays the games received from the channel jobs, the engines are started once and restarted after a crash
+func worker(ctx context.Context, cfg config, commands [2]string, names [2]string, openings []opening, jobs <-chan int,
+	results chan<- gameResult) {
+	var engines [2]*engine.Engine
+	defer func() {
+		for _, e := range engines {
+			if e != nil {
+				e.Close()
+			}
+		}
+	}()
+	for number := range jobs {
+		res := gameResult{number: number, white: number % 2}
+		for i := range engines {
+			if engines[i] == nil {
+				e, err := startEngine(commands[i])
+				if err != nil {
+					res.reason = fmt.Sprintf("cannot start %s: %s", names[i], err)
+					break
+				}
+				engines[i] = e
+			}
+		}
+		if res.reason == "" {
+			res = playGame(ctx, cfg, engines, names, number%2, number, openings[(number/2)%len(openings)])
+		}
+		for i, crashed := range res.crashed {
+			if crashed && engines[i] != nil {
+				engines[i].Close()
+				engines[i] = nil
+			}
+		}
+		results <- res
+	}
+}
+
+// printScore prints the score of the first engine and the Elo difference with its 95% confidence interval
+func printScore(names [2]string, s score) {
+	fmt.Printf("Score of %s vs %s: %d - %d - %d  [%.3f] %d\n", names[0], names[1], s.wins, s.losses, s.draws, s.mean(), s.games())
+	diff, margin := s.eloDifference()
+	if math.IsInf(diff, 0) || math.IsNaN(margin) || math.IsInf(margin, 0) {
+		fmt.Printf("Elo difference: %.1f\n", diff)
+		return
+	}
+	fmt.Printf("Elo difference: %.1f +/- %.1f\n", diff, margin)
+}
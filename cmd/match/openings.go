@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/pgn"
+	"github.com/rjo67/chess/position"
+)
+
+// opening is the start position of a game, given as FEN plus moves in UCI notation, from which every game
+// creates its own position (positions cannot be shared between concurrent games)
+type opening struct {
+	fen   string
+	moves []string
+}
+
+// position returns the start position, the position after the moves of the opening, and the moves with their SAN
+func (o opening) position() (start, posn position.Position, moves []move.Move, sans []string, err error) {
+	if start, err = position.ParseFen(o.fen); err != nil {
+		return
+	}
+	posn, _ = position.ParseFen(o.fen)
+	for _, str := range o.moves {
+		var m move.Move
+		if m, err = posn.ParseUci(str); err != nil {
+			return
+		}
+		moves = append(moves, m)
+		sans = append(sans, posn.San(m))
+		posn.MakeMove(m)
+	}
+	return
+}
+
+// loadOpenings reads the openings from an EPD file (extension .epd) or a PGN file, taking up to the given number
+// of plies from each game of a PGN file. If shuffle is set, the order of the openings is randomised.
+func loadOpenings(filename string, plies int, shuffle bool) ([]opening, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var openings []opening
+	if strings.ToLower(filepath.Ext(filename)) == ".epd" {
+		records, err := position.ReadEpd(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		for _, record := range records {
+			openings = append(openings, opening{fen: record.Position.Fen()})
+		}
+	} else {
+		games, err := pgn.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		for i, game := range games {
+			o, err := pgnOpening(game, plies)
+			if err != nil {
+				return nil, fmt.Errorf("%s: game %d: %w", filename, i+1, err)
+			}
+			openings = append(openings, o)
+		}
+	}
+	if len(openings) == 0 {
+		return nil, fmt.Errorf("%s: no openings found", filename)
+	}
+	if shuffle {
+		rand.Shuffle(len(openings), func(i, j int) { openings[i], openings[j] = openings[j], openings[i] })
+	}
+	return openings, nil
+}
+
+// pgnOpening returns the opening consisting of the first plies of the game
+func pgnOpening(game *pgn.Game, plies int) (opening, error) {
+	posn := position.StartPosition()
+	if fen, ok := game.Tags["FEN"]; ok {
+		var err error
+		if posn, err = position.ParseFen(fen); err != nil {
+			return opening{}, err
+		}
+	}
+	o := opening{fen: posn.Fen()}
+	for i, san := range game.Moves {
+		if i == plies {
+			break
+		}
+		m, err := posn.ParseSan(san)
+		if err != nil {
+			return opening{}, err
+		}
+		o.moves = append(o.moves, posn.UciString(m))
+		posn.MakeMove(m)
+	}
+	return o, nil
+}
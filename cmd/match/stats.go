@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// score is the result of the match from the view of the first engine
+type score struct {
+	wins, losses, draws int
+}
+
+func (s score) games() int {
+	return s.wins + s.losses + s.draws
+}
+
+// mean returns the average points per game
+func (s score) mean() float64 {
+	return (float64(s.wins) + float64(s.draws)/2) / float64(s.games())
+}
+
+// variance returns the variance of the points of one game
+func (s score) variance() float64 {
+	n, mu := float64(s.games()), s.mean()
+	return (float64(s.wins)*math.Pow(1-mu, 2) + float64(s.losses)*math.Pow(mu, 2) + float64(s.draws)*math.Pow(0.5-mu, 2)) / n
+}
+
+// elo returns the Elo difference corresponding to the expected score (0..1)
+func elo(expectedScore float64) float64 {
+	return 400 * math.Log10(expectedScore/(1-expectedScore))
+}
+
+// expectedScore returns the expected score (0..1) corresponding to the Elo difference
+func expectedScore(eloDifference float64) float64 {
+	return 1 / (1 + math.Pow(10, -eloDifference/400))
+}
+
+// eloDifference returns the Elo difference and the margin of the 95% confidence interval.
+// The values are infinite if one side has won all games.
+func (s score) eloDifference() (float64, float64) {
+	mu := s.mean()
+	margin := 1.959964 * math.Sqrt(s.variance()/float64(s.games()))
+	return elo(mu), (elo(math.Min(mu+margin, 1)) - elo(math.Max(mu-margin, 0))) / 2
+}
+
+// sprt is a sequential probability ratio test of the hypotheses H0: the Elo difference is elo0 and H1: it is elo1,
+// with the error probabilities alpha (of accepting H1 if H0 is true) and beta (of accepting H0 if H1 is true)
+type sprt struct {
+	elo0, elo1, alpha, beta float64
+}
+
+// bounds returns the lower and upper bound of the log-likelihood ratio
+func (t sprt) bounds() (float64, float64) {
+	return math.Log(t.beta / (1 - t.alpha)), math.Log((1 - t.beta) / t.alpha)
+}
+
+// llr returns the log-likelihood ratio of the score, using the normal approximation of the trinomial distribution
+func (t sprt) llr(s score) float64 {
+	if s.games() == 0 {
+		return 0
+	}
+	variance := s.variance()
+	if variance == 0 {
+		return 0
+	}
+	s0, s1 := expectedScore(t.elo0), expectedScore(t.elo1)
+	return float64(s.games()) * (s1 - s0) * (2*s.mean() - s0 - s1) / (2 * variance)
+}
+
+// decision returns "H0" or "H1" if the corresponding hypothesis has been accepted, otherwise ""
+func (t sprt) decision(s score) string {
+	lower, upper := t.bounds()
+	switch llr := t.llr(s); {
+	case llr <= lower:
+		return "H0"
+	case llr >= upper:
+		return "H1"
+	}
+	return ""
+}
+
+func (t sprt) String() string {
+	return fmt.Sprintf("elo0=%g elo1=%g alpha=%g beta=%g", t.elo0, t.elo1, t.alpha, t.beta)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEloDifference(t *testing.T) {
+	for _, d := range []struct {
+		s           score
+		elo, margin float64
+	}{
+		{score{60, 40, 0}, 70.44, 70.57},
+		{score{40, 60, 0}, -70.44, 70.57},
+		{score{30, 20, 50}, 34.86, 48.47},
+		{score{10, 10, 0}, 0, 163.32},
+		{score{0, 0, 10}, 0, 0},
+	} {
+		elo, margin := d.s.eloDifference()
+		if math.Abs(elo-d.elo) > 0.01 || math.Abs(margin-d.margin) > 0.01 {
+			t.Errorf("%v: expected %.2f +/- %.2f but got %.2f +/- %.2f", d.s, d.elo, d.margin, elo, margin)
+		}
+	}
+	if elo, _ := (score{10, 0, 0}).eloDifference(); !math.IsInf(elo, 1) {
+		t.Errorf("expected +Inf if all games were won but got %g", elo)
+	}
+}
+
+func TestExpectedScore(t *testing.T) {
+	for _, e := range []float64{-400, -70, 0, 35, 200} {
+		if got := elo(expectedScore(e)); math.Abs(got-e) > 1e-9 {
+			t.Errorf("expected %g but got %g", e, got)
+		}
+	}
+	if got := expectedScore(400); math.Abs(got-10.0/11) > 1e-12 {
+		t.Errorf("expected 10/11 but got %g", got)
+	}
+}
+
+func TestSprtBounds(t *testing.T) {
+	lower, upper := sprt{0, 5, 0.05, 0.05}.bounds()
+	if math.Abs(lower+2.944) > 0.001 || math.Abs(upper-2.944) > 0.001 {
+		t.Errorf("expected bounds -2.944, 2.944 but got %g, %g", lower, upper)
+	}
+	lower, upper = sprt{0, 5, 0.05, 0.1}.bounds()
+	if math.Abs(lower-math.Log(0.1/0.95)) > 1e-12 || math.Abs(upper-math.Log(0.9/0.05)) > 1e-12 {
+		t.Errorf("unexpected bounds %g, %g", lower, upper)
+	}
+}
+
+func TestSprtDecision(t *testing.T) {
+	test := sprt{0, 5, 0.05, 0.05}
+	for _, d := range []struct {
+		s        score
+		llr      float64
+		decision string
+	}{
+		{score{600, 400, 1000}, 5.451, "H1"},  // above the upper bound
+		{score{400, 600, 1000}, -6.296, "H0"}, // below the lower bound
+		{score{510, 490, 1000}, 0.161, ""},    // between the bounds
+		{score{100, 100, 100}, -0.047, ""},    // equal score, but not enough games
+		{score{60, 40, 0}, 0.289, ""},
+		{score{0, 0, 0}, 0, ""},  // no games
+		{score{0, 0, 20}, 0, ""}, // no variance
+	} {
+		llr := test.llr(d.s)
+		if math.Abs(llr-d.llr) > 0.001 {
+			t.Errorf("%v: expected llr %.3f but got %.3f", d.s, d.llr, llr)
+		}
+		if got := test.decision(d.s); got != d.decision {
+			t.Errorf("%v: expected decision '%s' but got '%s' (llr %.3f)", d.s, d.decision, got, llr)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// play is an interactive game in the terminal against a simple computer opponent
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/render"
+	"github.com/rjo67/chess/search"
+)
+
+const help = `commands:
+  e2e4, e7e8q   make a move (coordinate notation)
+  undo          take back the last move of each side
+  flip          turn the board around
+  fen           show the FEN of the current position
+  load <fen>    continue from the given position
+  hint          suggest a move
+  help          show this text
+  quit          end the program`
+
+// game stores the state of the session
+type game struct {
+	posn        position.Position
+	moves       []move.Move // the moves made since the start or the last 'load', for 'undo'
+	human       colour.Colour
+	orientation colour.Colour
+	depth       int
+	opts        render.Options
+	out         io.Writer
+}
+
+func main() {
+	depth := flag.Int("depth", 3, "search depth of the computer in plies")
+	side := flag.String("colour", "white", "the colour you play (white or black)")
+	fen := flag.String("fen", "", "start position (default: normal start position)")
+	ascii := flag.Bool("ascii", false, "show the pieces as letters instead of chess symbols")
+	ansi := flag.Bool("ansi", false, "colour the board with ANSI escape codes")
+	flag.Parse()
+
+	if *depth < 1 || (*side != "white" && *side != "black") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	g := &game{posn: position.StartPosition(), depth: *depth, out: os.Stdout,
+		opts: render.Options{Coordinates: true, Unicode: !*ascii, ANSI: *ansi}}
+	if *side == "black" {
+		g.human = colour.Black
+	}
+	g.orientation = g.human
+	if *fen != "" {
+		posn, err := position.ParseFen(*fen)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		g.posn = posn
+	}
+	g.run(os.Stdin)
+}
+
+// run reads and executes commands until 'quit' or the end of the input
+func (g *game) run(in io.Reader) {
+	fmt.Fprintln(g.out, help)
+	g.showBoard()
+	g.computerMoves()
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(g.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(g.out)
+			return
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "quit", "exit":
+			return
+		case "help":
+			fmt.Fprintln(g.out, help)
+		case "undo":
+			g.undo()
+		case "flip":
+			g.orientation = g.orientation.Other()
+			g.showBoard()
+		case "fen":
+			fmt.Fprintln(g.out, g.posn.Fen())
+		case "load":
+			g.load(strings.Join(fields[1:], " "))
+		case "hint":
+			g.hint()
+		default:
+			g.humanMoves(fields[0])
+		}
+	}
+}
+
+// humanMoves makes the given move (if legal) and lets the computer reply
+func (g *game) humanMoves(str string) {
+	if g.gameOver() {
+		fmt.Fprintln(g.out, "the game is over, use 'undo' or 'load'")
+		return
+	}
+	if g.posn.ActiveColour() != g.human {
+		fmt.Fprintln(g.out, "it's not your move")
+		return
+	}
+	m, err := g.posn.ParseUci(str)
+	if err != nil {
+		fmt.Fprintf(g.out, "%s (type 'help' for a list of commands)\n", err)
+		return
+	}
+	g.makeMove(m)
+	g.computerMoves()
+}
+
+// computerMoves lets the computer move if it is its turn and the game is not over
+func (g *game) computerMoves() {
+	if g.posn.ActiveColour() == g.human || g.gameOver() {
+		return
+	}
+	result, err := search.Search(g.posn, g.depth)
+	if err != nil {
+		fmt.Fprintln(g.out, err)
+		return
+	}
+	fmt.Fprintf(g.out, "computer plays %s (%s)\n", g.posn.San(result.Move), formatScore(result))
+	g.makeMove(result.Move)
+}
+
+// makeMove makes the move, shows the board and announces check, mate or a draw
+func (g *game) makeMove(m move.Move) {
+	g.posn.MakeMove(m)
+	g.moves = append(g.moves, m)
+	g.showBoard()
+	switch {
+	case g.posn.IsCheckmate():
+		fmt.Fprintf(g.out, "checkmate, %s wins\n", colourName(g.posn.ActiveColour().Other()))
+	case g.posn.IsStalemate():
+		fmt.Fprintln(g.out, "stalemate, the game is drawn")
+	case g.posn.InCheck():
+		fmt.Fprintln(g.out, "check")
+	}
+	if reason := g.posn.DrawReason(true); reason != position.NoDraw && reason != position.Stalemate {
+		if reason.Claimable() {
+			fmt.Fprintf(g.out, "a draw can be claimed: %s\n", reason)
+		} else {
+			fmt.Fprintf(g.out, "the game is drawn: %s\n", reason)
+		}
+	}
+}
+
+// gameOver returns true after checkmate, stalemate or an automatic draw
+func (g *game) gameOver() bool {
+	if len(g.posn.FindMoves(g.posn.ActiveColour())) == 0 {
+		return true
+	}
+	reason := g.posn.DrawReason(true)
+	return reason != position.NoDraw && !reason.Claimable()
+}
+
+// undo takes back moves until it is the human's turn again
+func (g *game) undo() {
+	if len(g.moves) == 0 {
+		fmt.Fprintln(g.out, "no moves to take back")
+		return
+	}
+	for len(g.moves) > 0 {
+		m := g.moves[len(g.moves)-1]
+		g.posn.UnmakeMove(m)
+		g.moves = g.moves[:len(g.moves)-1]
+		if g.posn.ActiveColour() == g.human {
+			break
+		}
+	}
+	g.showBoard()
+	// e.g. after 'load' of a position with the computer to move
+	g.computerMoves()
+}
+
+// load replaces the current position
+func (g *game) load(fen string) {
+	posn, err := position.ParseFen(fen)
+	if err != nil {
+		fmt.Fprintln(g.out, err)
+		return
+	}
+	g.posn = posn
+	g.moves = nil
+	g.showBoard()
+	g.computerMoves()
+}
+
+// hint shows the move the computer would play
+func (g *game) hint() {
+	result, err := search.Search(g.posn, g.depth)
+	if err != nil {
+		fmt.Fprintln(g.out, err)
+		return
+	}
+	fmt.Fprintf(g.out, "hint: %s (%s)\n", g.posn.San(result.Move), formatScore(result))
+}
+
+// showBoard prints the board, highlighting the last move
+func (g *game) showBoard() {
+	opts := g.opts
+	opts.Orientation = g.orientation
+	if len(g.moves) > 0 {
+		opts.LastMove = &g.moves[len(g.moves)-1]
+	}
+	if err := render.Text(g.out, g.posn, opts); err != nil {
+		fmt.Fprintln(g.out, err)
+	}
+	fmt.Fprintf(g.out, "%s to move\n", colourName(g.posn.ActiveColour()))
+}
+
+// formatScore returns the score of the search result from the view of the side to move, in pawns or as mate in n moves
+func formatScore(result search.Result) string {
+	switch n := result.MateIn(); {
+	case n > 0:
+		return fmt.Sprintf("mate in %d", n)
+	case n < 0:
+		return fmt.Sprintf("mated in %d", -n)
+	}
+	return fmt.Sprintf("%+.2f", float64(result.Score)/100)
+}
+
+func colourName(col colour.Colour) string {
+	if col == colour.White {
+		return "white"
+	}
+	return "black"
+}
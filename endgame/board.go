@@ -0,0 +1,320 @@
+package endgame
+
+import (
+	"math/bits"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/ray"
+	"github.com/rjo67/chess/square"
+)
+
+// board is a compact representation of a position with few pieces, used by the generator.
+// Index 0 is always the white king, index 1 the black king. The other pieces follow in the order given by the material.
+type board struct {
+	squares [MaxPieces]square.Square
+	colours [MaxPieces]colour.Colour
+	pieces  [MaxPieces]piece.Piece
+	n       int
+	stm     colour.Colour
+}
+
+// successor is a position reached by a legal move
+type successor struct {
+	b    board
+	exit bool // capture or promotion, i.e. the material has changed
+}
+
+// newBoard creates a board for the given material with all pieces on square 0
+func newBoard(m Material) board {
+	b := board{n: 2}
+	b.pieces[0], b.pieces[1] = piece.KING, piece.KING
+	b.colours[0], b.colours[1] = colour.White, colour.Black
+	for _, col := range colour.AllColours {
+		for _, p := range m.pieces[col] {
+			b.pieces[b.n] = p
+			b.colours[b.n] = col
+			b.n++
+		}
+	}
+	return b
+}
+
+// material returns the material of the board
+func (b board) material() Material {
+	var m Material
+	for i := 2; i < b.n; i++ {
+		m.pieces[b.colours[i]] = append(m.pieces[b.colours[i]], b.pieces[i])
+	}
+	m.sort()
+	return m
+}
+
+// normalise sorts the pieces (apart from the kings) into material order
+func (b *board) normalise() {
+	for i := 3; i < b.n; i++ {
+		for j := i; j > 2 && b.less(j, j-1); j-- {
+			b.squares[j], b.squares[j-1] = b.squares[j-1], b.squares[j]
+			b.colours[j], b.colours[j-1] = b.colours[j-1], b.colours[j]
+			b.pieces[j], b.pieces[j-1] = b.pieces[j-1], b.pieces[j]
+		}
+	}
+}
+
+func (b board) less(i, j int) bool {
+	if b.colours[i] != b.colours[j] {
+		return b.colours[i] < b.colours[j]
+	}
+	return pieceOrder[b.pieces[i]] < pieceOrder[b.pieces[j]]
+}
+
+// swapColours exchanges the colours of the pieces and mirrors the board vertically
+func (b *board) swapColours() {
+	for i := 0; i < b.n; i++ {
+		b.colours[i] = b.colours[i].Other()
+		b.squares[i] = square.FromRankAndFile(9-b.squares[i].Rank(), b.squares[i].File())
+	}
+	b.squares[0], b.squares[1] = b.squares[1], b.squares[0]
+	b.colours[0], b.colours[1] = b.colours[1], b.colours[0]
+	b.stm = b.stm.Other()
+	b.normalise()
+}
+
+// remove removes the piece with index i (not a king)
+func (b *board) remove(i int) {
+	copy(b.squares[i:], b.squares[i+1:b.n])
+	copy(b.colours[i:], b.colours[i+1:b.n])
+	copy(b.pieces[i:], b.pieces[i+1:b.n])
+	b.n--
+}
+
+func (b board) occupied() bitset.BitSet {
+	var bs bitset.BitSet
+	for i := 0; i < b.n; i++ {
+		bs.SetSquare(b.squares[i])
+	}
+	return bs
+}
+
+// pieceAt returns the index of the piece on the given square, or -1
+func (b board) pieceAt(sq square.Square) int {
+	for i := 0; i < b.n; i++ {
+		if b.squares[i] == sq {
+			return i
+		}
+	}
+	return -1
+}
+
+// directions in which the ray squares have increasing bit numbers
+var increasingDirection = [8]bool{ray.NORTH: true, ray.NORTHEAST: true, ray.WEST: true, ray.NORTHWEST: true}
+
+// sliderAttacks returns the squares attacked from 'sq' in the given directions
+func sliderAttacks(sq square.Square, directions []ray.Direction, occupied bitset.BitSet) bitset.BitSet {
+	var attacks bitset.BitSet
+	for _, dir := range directions {
+		attackRay := ray.AttackRays[sq][dir]
+		blockers := attackRay.And(occupied).Val()
+		if blockers != 0 {
+			var blocker int
+			if increasingDirection[dir] {
+				blocker = bits.TrailingZeros64(blockers) + 1
+			} else {
+				blocker = 64 - bits.LeadingZeros64(blockers)
+			}
+			attackRay = attackRay.AndNot(ray.AttackRays[blocker][dir])
+		}
+		attacks = attacks.Or(attackRay)
+	}
+	return attacks
+}
+
+// attacks returns the squares attacked by the piece with index i
+func (b board) attacks(i int, occupied bitset.BitSet) bitset.BitSet {
+	sq := b.squares[i]
+	switch b.pieces[i] {
+	case piece.KING:
+		return ray.KingAttackBitSets[sq]
+	case piece.KNIGHT:
+		return ray.KnightAttackBitSets[sq]
+	case piece.BISHOP:
+		return sliderAttacks(sq, ray.AllBishopDirections, occupied)
+	case piece.ROOK:
+		return sliderAttacks(sq, ray.AllRookDirections, occupied)
+	case piece.QUEEN:
+		return sliderAttacks(sq, ray.AllDirections, occupied)
+	}
+	// pawn
+	bs := bitset.NewFromSquares(sq)
+	if b.colours[i] == colour.White {
+		return bs.And(bitset.NotFile1).Shift(9).Or(bs.And(bitset.NotFile8).Shift(7))
+	}
+	return bs.And(bitset.NotFile1).Shift(-7).Or(bs.And(bitset.NotFile8).Shift(-9))
+}
+
+// isAttacked returns true if a piece of the given colour attacks the square
+func (b board) isAttacked(sq square.Square, by colour.Colour, occupied bitset.BitSet) bool {
+	for i := 0; i < b.n; i++ {
+		if b.colours[i] == by && b.squares[i] != sq && b.attacks(i, occupied).IsSet(uint(sq)) {
+			return true
+		}
+	}
+	return false
+}
+
+// inCheck returns true if the king of the given colour is attacked
+func (b board) inCheck(col colour.Colour) bool {
+	return b.isAttacked(b.squares[col], col.Other(), b.occupied())
+}
+
+// legal returns true if the board represents a legal position (ignoring the question of how it could have been reached)
+func (b board) legal() bool {
+	for i := 0; i < b.n; i++ {
+		if b.pieces[i] == piece.PAWN && (b.squares[i].Rank() == 1 || b.squares[i].Rank() == 8) {
+			return false
+		}
+		for j := i + 1; j < b.n; j++ {
+			if b.squares[i] == b.squares[j] {
+				return false
+			}
+		}
+	}
+	return !b.inCheck(b.stm.Other())
+}
+
+// successors appends the positions reached by all legal moves to dst
+func (b board) successors(dst []successor) []successor {
+	occupied := b.occupied()
+	var own bitset.BitSet
+	for i := 0; i < b.n; i++ {
+		if b.colours[i] == b.stm {
+			own.SetSquare(b.squares[i])
+		}
+	}
+	for i := 0; i < b.n; i++ {
+		if b.colours[i] != b.stm {
+			continue
+		}
+		if b.pieces[i] == piece.PAWN {
+			dst = b.pawnSuccessors(dst, i, occupied)
+			continue
+		}
+		for _, to := range b.attacks(i, occupied).AndNot(own).SetBits() {
+			dst = b.addSuccessor(dst, i, square.Square(to), piece.PAWN)
+		}
+	}
+	return dst
+}
+
+func (b board) pawnSuccessors(dst []successor, i int, occupied bitset.BitSet) []successor {
+	from := b.squares[i]
+	forward := 8
+	startRank, lastRank := 2, 8
+	if b.colours[i] == colour.Black {
+		forward = -8
+		startRank, lastRank = 7, 1
+	}
+	targets := make([]square.Square, 0, 4)
+	to := square.Square(int(from) + forward)
+	if !occupied.IsSet(uint(to)) {
+		targets = append(targets, to)
+		to2 := square.Square(int(to) + forward)
+		if from.Rank() == startRank && !occupied.IsSet(uint(to2)) {
+			targets = append(targets, to2)
+		}
+	}
+	for _, to := range b.attacks(i, occupied).SetBits() {
+		if j := b.pieceAt(square.Square(to)); j >= 0 && b.colours[j] != b.stm {
+			targets = append(targets, square.Square(to))
+		}
+	}
+	for _, to := range targets {
+		if to.Rank() == lastRank {
+			for _, promotedPiece := range piece.PromotedPawnPieceCandidates {
+				dst = b.addSuccessor(dst, i, to, promotedPiece)
+			}
+		} else {
+			dst = b.addSuccessor(dst, i, to, piece.PAWN)
+		}
+	}
+	return dst
+}
+
+// addSuccessor plays the move of piece i to 'to' and appends the resulting position if the move is legal.
+// promotedPiece is only relevant for pawn moves to the last rank.
+func (b board) addSuccessor(dst []successor, i int, to square.Square, promotedPiece piece.Piece) []successor {
+	next := b
+	exit := false
+	if j := next.pieceAt(to); j >= 0 {
+		if next.pieces[j] == piece.KING {
+			return dst // cannot happen in a legal position
+		}
+		next.remove(j)
+		if j < i {
+			i--
+		}
+		exit = true
+	}
+	next.squares[i] = to
+	if next.pieces[i] != promotedPiece && next.pieces[i] == piece.PAWN {
+		next.pieces[i] = promotedPiece
+		exit = true
+	}
+	if next.inCheck(b.stm) {
+		return dst
+	}
+	next.stm = b.stm.Other()
+	if exit {
+		next.normalise()
+	}
+	return append(dst, successor{b: next, exit: exit})
+}
+
+// predecessors appends all positions from which this position can be reached with a move which
+// is neither a capture nor a promotion. The resulting positions are not checked for legality.
+func (b board) predecessors(dst []board) []board {
+	mover := b.stm.Other()
+	occupied := b.occupied()
+	for i := 0; i < b.n; i++ {
+		if b.colours[i] != mover {
+			continue
+		}
+		if b.pieces[i] == piece.PAWN {
+			dst = b.pawnPredecessors(dst, i, occupied)
+			continue
+		}
+		// all pieces apart from pawns move symmetrically
+		for _, from := range b.attacks(i, occupied).AndNot(occupied).SetBits() {
+			dst = append(dst, b.unmove(i, square.Square(from)))
+		}
+	}
+	return dst
+}
+
+func (b board) pawnPredecessors(dst []board, i int, occupied bitset.BitSet) []board {
+	to := b.squares[i]
+	backward := -8
+	startRank := 2
+	if b.colours[i] == colour.Black {
+		backward = 8
+		startRank = 7
+	}
+	from := square.Square(int(to) + backward)
+	if from.Rank() == 1 || from.Rank() == 8 || occupied.IsSet(uint(from)) {
+		return dst
+	}
+	dst = append(dst, b.unmove(i, from))
+	from2 := square.Square(int(from) + backward)
+	if from2.Rank() == startRank && !occupied.IsSet(uint(from2)) {
+		dst = append(dst, b.unmove(i, from2))
+	}
+	return dst
+}
+
+func (b board) unmove(i int, from square.Square) board {
+	prev := b
+	prev.squares[i] = from
+	prev.stm = b.stm.Other()
+	return prev
+}
@@ -0,0 +1,295 @@
+package endgame
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+func TestParseMaterial(t *testing.T) {
+	data := []struct {
+		str, expected string
+		canonical     bool
+	}{
+		{"KQK", "KQK", true},
+		{"knbk", "KBNK", true},
+		{"KKQ", "KKQ", false},
+		{"KPKP", "KPKP", true},
+		{"KRKQ", "KRKQ", false},
+		{"KNKB", "KNKB", false},
+	}
+	for _, d := range data {
+		m, err := ParseMaterial(d.str)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", d.str, err)
+			continue
+		}
+		if m.String() != d.expected || m.isCanonical() != d.canonical {
+			t.Errorf("%s: expected %s (canonical: %t) but got %s (%t)", d.str, d.expected, d.canonical, m, m.isCanonical())
+		}
+	}
+	for _, str := range []string{"", "QK", "KQ", "KXK", "KQRKR", "KKK"} {
+		if _, err := ParseMaterial(str); err == nil {
+			t.Errorf("expected error for '%s'", str)
+		}
+	}
+}
+
+func TestSubMaterials(t *testing.T) {
+	var names []string
+	for _, m := range subMaterials(MustParseMaterial("KPKR")) {
+		names = append(names, m.String())
+	}
+	expected := "KKR KQKR KQK KRKR KRK KBKR KBK KNKR KNK KPK"
+	if strings.Join(names, " ") != expected {
+		t.Errorf("expected %s but got %s", expected, strings.Join(names, " "))
+	}
+}
+
+func TestIndex(t *testing.T) {
+	for _, name := range []string{"KRK", "KPK", "KBNK"} {
+		ix := newIndexer(MustParseMaterial(name))
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < 1000; i++ {
+			idx := rnd.Intn(ix.size())
+			b := ix.board(idx)
+			got := ix.index(b)
+			// mirror images of positions with the white king on the diagonal map to the lower index
+			onDiagonal := !ix.hasPawns && b.squares[0].Rank() == b.squares[0].File()
+			if got != idx && (!onDiagonal || got > idx) {
+				t.Fatalf("%s: index %d decoded and encoded to %d", name, idx, got)
+			}
+		}
+	}
+
+	// symmetries: white king c2, rook f7, black king g5
+	ix := newIndexer(MustParseMaterial("KRK"))
+	b := newBoard(ix.material)
+	b.squares = [MaxPieces]square.Square{square.C2, square.G5, square.F7}
+	expected := ix.index(b)
+	for transformation := 1; transformation < 8; transformation++ {
+		b2 := b
+		for i := range b2.squares[:b2.n] {
+			b2.squares[i] = transform(b.squares[i], transformation)
+		}
+		if got := ix.index(b2); got != expected {
+			t.Errorf("transformation %d: expected index %d but got %d", transformation, expected, got)
+		}
+	}
+}
+
+// generated once for all tests
+var testSet = NewSet("")
+
+func probe(t *testing.T, set *Set, fen string) Result {
+	t.Helper()
+	posn, err := position.ParseFen(fen)
+	if err != nil {
+		t.Fatalf("error parsing fen '%s': %s", fen, err)
+	}
+	r, err := set.Probe(posn)
+	if err != nil {
+		t.Fatalf("fen %s: unexpected error: %s", fen, err)
+	}
+	return r
+}
+
+func TestGenerate(t *testing.T) {
+	for _, d := range []struct {
+		material string
+		maxMate  int
+	}{{"KQK", 10}, {"KRK", 16}, {"KBK", 0}} {
+		table, err := testSet.Generate(MustParseMaterial(d.material))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if max := table.MaxResult(); (max.Plies+1)/2 != d.maxMate {
+			t.Errorf("%s: expected longest mate %d but got %s", d.material, d.maxMate, max)
+		}
+	}
+	if _, err := testSet.Generate(MustParseMaterial("KPK")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data := []struct {
+		fen      string
+		expected string
+	}{
+		{"k7/8/1K6/8/8/8/8/6Q1 w - - 0 1", "mate in 1"},
+		{"6q1/8/8/8/8/1k6/8/K7 b - - 0 1", "mate in 1"}, // colours swapped
+		{"k7/8/1Q6/8/8/8/8/7K b - - 0 1", "draw"},       // stalemate
+		{"R1k5/8/2K5/8/8/8/8/8 b - - 0 1", "checkmated"},
+		{"k7/8/1K6/8/8/8/8/7R b - - 0 1", "mated in 1"},
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", "mate in 11"}, // promotes and mates with the queen
+		{"k7/8/8/8/8/8/P7/K7 w - - 0 1", "draw"},
+		{"8/8/8/3k4/8/8/8/2B1K3 w - - 0 1", "draw"},
+		{"8/8/8/3k4/8/8/8/4K3 w - - 0 1", "draw"},
+	}
+	for _, d := range data {
+		if r := probe(t, testSet, d.fen); r.String() != d.expected {
+			t.Errorf("fen %s: expected %s but got %s", d.fen, d.expected, r)
+		}
+	}
+}
+
+// the mate with bishop and knight: the longest mate is 33 moves, and mate can only be forced in a corner of the
+// bishop's colour
+func TestKBNK(t *testing.T) {
+	table, err := testSet.Generate(MustParseMaterial("KBNK"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if max := table.MaxResult(); max.String() != "mate in 33" {
+		t.Errorf("expected longest mate in 33 but got %s", max)
+	}
+	data := []struct {
+		fen      string
+		expected string
+	}{
+		{"7k/5K2/8/6N1/8/8/8/2B5 w - - 0 1", "mate in 1"}, // Bb2#
+		{"6k1/8/5KN1/8/8/8/8/4B3 w - - 0 1", "mate in 7"}, // the king is already in the corner of the bishop's colour
+		{"7k/8/5K2/8/8/8/8/4BN2 b - - 0 1", "mated in 7"},
+		{"k7/8/2K5/8/8/8/8/4BN2 w - - 0 1", "mate in 22"}, // the king must be driven from the wrong corner
+		{"8/8/8/8/8/8/2k5/1BN1K3 b - - 0 1", "draw"},      // the bishop is lost
+	}
+	for _, d := range data {
+		if r := probe(t, testSet, d.fen); r.String() != d.expected {
+			t.Errorf("fen %s: expected %s but got %s", d.fen, d.expected, r)
+		}
+	}
+}
+
+// fen returns the FEN of the board
+func (b board) fen() string {
+	var sb strings.Builder
+	for rank := 8; rank >= 1; rank-- {
+		empty := 0
+		for file := 1; file <= 8; file++ {
+			i := b.pieceAt(square.FromRankAndFile(rank, file))
+			if i < 0 {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteByte(byte('0' + empty))
+				empty = 0
+			}
+			sb.WriteString(b.pieces[i].String(b.colours[i]))
+		}
+		if empty > 0 {
+			sb.WriteByte(byte('0' + empty))
+		}
+		if rank > 1 {
+			sb.WriteByte('/')
+		}
+	}
+	if b.stm == colour.White {
+		sb.WriteString(" w - - 0 1")
+	} else {
+		sb.WriteString(" b - - 0 1")
+	}
+	return sb.String()
+}
+
+// checks the stored values against the move generator of the position package
+func TestConsistency(t *testing.T) {
+	for _, name := range []string{"KPK", "KRK"} {
+		table, err := testSet.Generate(MustParseMaterial(name))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		rnd := rand.New(rand.NewSource(2))
+		checked := 0
+		for checked < 300 {
+			idx := rnd.Intn(table.ix.size())
+			if table.values[idx] == illegal {
+				continue
+			}
+			checked++
+			fen := table.ix.board(idx).fen()
+			posn, err := position.ParseFen(fen)
+			if err != nil {
+				t.Fatalf("error parsing fen '%s': %s", fen, err)
+			}
+			expected := Result{Outcome: Draw}
+			moves := posn.FindMoves(posn.ActiveColour())
+			if len(moves) == 0 {
+				kingSq := square.Square(posn.Pieces(posn.ActiveColour(), piece.KING).SetBits()[0])
+				if posn.AnyPieceAttacksSquare(posn.ActiveColour().Other(), kingSq) {
+					expected = Result{Outcome: Loss}
+				}
+			}
+			best := -1 << 30
+			for _, m := range moves {
+				child := posn
+				child.MakeMove(m)
+				r, err := testSet.Probe(child)
+				child.UnmakeMove(m)
+				if err != nil {
+					t.Fatalf("fen %s, move %s: unexpected error: %s", fen, m.String(), err)
+				}
+				// score from the point of view of the parent: quick wins and slow losses are best
+				var score int
+				switch r.Outcome {
+				case Loss:
+					score = 1000 - r.Plies
+				case Win:
+					score = -1000 + r.Plies
+				}
+				if score > best {
+					best = score
+					switch r.Outcome {
+					case Loss:
+						expected = Result{Outcome: Win, Plies: r.Plies + 1}
+					case Win:
+						expected = Result{Outcome: Loss, Plies: r.Plies + 1}
+					default:
+						expected = Result{Outcome: Draw}
+					}
+				}
+			}
+			if got := newResult(table.values[idx]); got != expected {
+				t.Errorf("%s: fen %s: expected %s but got %s", name, fen, expected, got)
+			}
+		}
+	}
+}
+
+func TestSaveAndProbe(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewSet(dir).Generate(MustParseMaterial("KKR")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "KRK.dtm")); err != nil {
+		t.Fatalf("table not written: %s", err)
+	}
+	set := NewSet(dir)
+	if r := probe(t, set, "k7/8/1K6/8/8/8/8/7R b - - 0 1"); r.String() != "mated in 1" {
+		t.Errorf("expected 'mated in 1' but got %s", r)
+	}
+
+	posn, _ := position.ParseFen("2k5/8/2K5/8/8/8/8/1Q6 b - - 0 1")
+	if _, err := set.Probe(posn); !errors.Is(err, ErrNoTable) {
+		t.Errorf("expected ErrNoTable but got %v", err)
+	}
+	posn, _ = position.ParseFen("2k5/8/8/8/8/8/8/R3K3 w Q - 0 1")
+	if _, err := set.Probe(posn); err == nil {
+		t.Errorf("expected error for position with castling rights")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "KQK.dtm"), []byte("RJEG\x03KQK\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	posn, _ = position.ParseFen("2k5/8/2K5/8/8/8/8/1Q6 b - - 0 1")
+	if _, err := set.Probe(posn); err == nil || errors.Is(err, ErrNoTable) {
+		t.Errorf("expected error for truncated table but got %v", err)
+	}
+}
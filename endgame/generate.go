@@ -0,0 +1,176 @@
+package endgame
+
+import (
+	"fmt"
+	"math"
+)
+
+// values stored in the tables, from the point of view of the side to move:
+// 0: draw, n > 0: win with mate in n plies, n < 0: loss, mated in -n-1 plies
+const (
+	illegal  int16 = math.MinInt16
+	maxPlies       = math.MaxInt16 - 1
+)
+
+func winValue(plies int) int16  { return int16(plies) }
+func lossValue(plies int) int16 { return int16(-plies - 1) }
+
+// lookupFunc returns the stored value of a position with different material (after a capture or promotion)
+type lookupFunc func(b board) (int16, error)
+
+// generator stores the state of the retrograde analysis of one table
+type generator struct {
+	ix     indexer
+	values []int16
+	lookup lookupFunc
+	succ   []successor // buffers
+	pred   []board
+}
+
+// generate creates the table for the given material using retrograde analysis.
+//
+// All positions which are mated are found first. Then, ply by ply, the predecessors of positions lost in n-1 plies
+// are won in n plies, and the predecessors of positions won in n-1 plies are lost in n plies if all their moves
+// lead to positions won by the opponent. Moves which change the material are looked up in the tables of the
+// resulting material. Positions which are not resolved at the end are draws.
+//
+// Enpassant captures are not considered.
+func generate(m Material, lookup lookupFunc) (*Table, error) {
+	g := &generator{ix: newIndexer(m), lookup: lookup}
+	g.values = make([]int16, g.ix.size())
+
+	// for positions where a capture or promotion wins, or all of them lose, the resolution ply is known in advance
+	exitWins := make(map[int][]int)
+	exitLosses := make(map[int][]int)
+	maxExitPly := 0
+	var lost, won []int // positions resolved in the previous ply
+
+	for idx := range g.values {
+		b := g.ix.board(idx)
+		if !b.legal() || g.ix.index(b) != idx {
+			g.values[idx] = illegal // also set for the unused mirror images of positions with the king on the diagonal
+			continue
+		}
+		g.succ = b.successors(g.succ[:0])
+		if len(g.succ) == 0 {
+			if b.inCheck(b.stm) {
+				g.values[idx] = lossValue(0)
+				lost = append(lost, idx)
+			}
+			continue // stalemate is a draw
+		}
+		minWin, maxLoss := math.MaxInt32, -1
+		allExitsLose := true
+		for _, s := range g.succ {
+			if !s.exit {
+				continue
+			}
+			v, err := g.lookup(s.b)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case v < 0: // opponent loses
+				if plies := int(-v-1) + 1; plies < minWin {
+					minWin = plies
+				}
+			case v > 0:
+				if int(v) > maxLoss {
+					maxLoss = int(v)
+				}
+			default:
+				allExitsLose = false
+			}
+		}
+		if minWin != math.MaxInt32 {
+			exitWins[minWin] = append(exitWins[minWin], idx)
+			if minWin > maxExitPly {
+				maxExitPly = minWin
+			}
+		} else if allExitsLose && maxLoss >= 0 {
+			exitLosses[maxLoss+1] = append(exitLosses[maxLoss+1], idx)
+			if maxLoss+1 > maxExitPly {
+				maxExitPly = maxLoss + 1
+			}
+		}
+	}
+
+	for ply := 1; ply <= maxExitPly || len(lost) > 0 || len(won) > 0; ply++ {
+		if ply > maxPlies {
+			return nil, fmt.Errorf("%s: distance to mate too large", m)
+		}
+		if ply%2 == 1 {
+			won = won[:0]
+			for _, idx := range lost {
+				g.pred = g.ix.board(idx).predecessors(g.pred[:0])
+				for _, p := range g.pred {
+					if predIdx := g.ix.index(p); g.values[predIdx] == 0 {
+						g.values[predIdx] = winValue(ply)
+						won = append(won, predIdx)
+					}
+				}
+			}
+			for _, idx := range exitWins[ply] {
+				if g.values[idx] == 0 {
+					g.values[idx] = winValue(ply)
+					won = append(won, idx)
+				}
+			}
+			lost = lost[:0]
+		} else {
+			lost = lost[:0]
+			var candidates []int
+			for _, idx := range won {
+				g.pred = g.ix.board(idx).predecessors(g.pred[:0])
+				for _, p := range g.pred {
+					candidates = append(candidates, g.ix.index(p))
+				}
+			}
+			candidates = append(candidates, exitLosses[ply]...)
+			for _, idx := range candidates {
+				if g.values[idx] != 0 {
+					continue
+				}
+				isLoss, err := g.lostIn(idx, ply)
+				if err != nil {
+					return nil, err
+				}
+				if isLoss {
+					g.values[idx] = lossValue(ply)
+					lost = append(lost, idx)
+				}
+			}
+			won = won[:0]
+		}
+	}
+	return &Table{material: m, ix: g.ix, values: g.values}, nil
+}
+
+// lostIn returns true if all moves of the position lead to positions won by the opponent
+// and the longest of these wins takes ply-1 plies
+func (g *generator) lostIn(idx int, ply int) (bool, error) {
+	b := g.ix.board(idx)
+	g.succ = b.successors(g.succ[:0])
+	if len(g.succ) == 0 {
+		return false, nil
+	}
+	maxWin := 0
+	for _, s := range g.succ {
+		var v int16
+		if s.exit {
+			var err error
+			if v, err = g.lookup(s.b); err != nil {
+				return false, err
+			}
+		} else {
+			v = g.values[g.ix.index(s.b)]
+		}
+		if v <= 0 {
+			return false, nil
+		}
+		if int(v) > maxWin {
+			maxWin = int(v)
+		}
+	}
+	return maxWin == ply-1, nil
+}
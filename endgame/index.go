@@ -0,0 +1,137 @@
+package endgame
+
+import (
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+// Positions are indexed as
+//
+//	((stm * kingSlots + whiteKingSlot) * 64 + blackKing) * 64 + piece3 ...
+//
+// The board symmetries are used to reduce the number of squares of the white king:
+// without pawns the board can be mirrored horizontally, vertically and along the diagonal, and the white king
+// is always placed in the triangle a1-d1-d4 (10 squares). With pawns only horizontal mirroring is possible
+// and the white king is on files a-d (32 squares).
+// If the white king is on the a1-h8 diagonal, the position and its mirror image have different indices;
+// only the lower one is used.
+
+var (
+	triangleSlot    [65]int // slot of the square in the a1-d1-d4 triangle, or -1
+	triangleSquares [10]square.Square
+)
+
+func init() {
+	slot := 0
+	for sq := square.Square(1); sq <= 64; sq++ {
+		triangleSlot[sq] = -1
+	}
+	for rank := 1; rank <= 4; rank++ {
+		for file := rank; file <= 4; file++ {
+			sq := square.FromRankAndFile(rank, file)
+			triangleSlot[sq] = slot
+			triangleSquares[slot] = sq
+			slot++
+		}
+	}
+}
+
+// transform applies one of the 8 symmetries of the board to the square:
+// bit 2: mirror along the a1-h8 diagonal, bit 0: mirror files, bit 1: mirror ranks
+func transform(sq square.Square, t int) square.Square {
+	rank, file := sq.Rank(), sq.File()
+	if t&4 != 0 {
+		rank, file = file, rank
+	}
+	if t&1 != 0 {
+		file = 9 - file
+	}
+	if t&2 != 0 {
+		rank = 9 - rank
+	}
+	return square.FromRankAndFile(rank, file)
+}
+
+// indexer maps positions of one material to indices
+type indexer struct {
+	material  Material
+	hasPawns  bool
+	kingSlots int
+}
+
+func newIndexer(m Material) indexer {
+	ix := indexer{material: m, hasPawns: m.hasPawns(), kingSlots: 10}
+	if ix.hasPawns {
+		ix.kingSlots = 32
+	}
+	return ix
+}
+
+// size returns the number of indices
+func (ix indexer) size() int {
+	size := 2 * ix.kingSlots * 64
+	for i := 2; i < ix.material.count(); i++ {
+		size *= 64
+	}
+	return size
+}
+
+// index returns the index of the board, which must have the pieces in material order
+func (ix indexer) index(b board) int {
+	t := 0
+	wk := b.squares[0]
+	if ix.hasPawns {
+		if wk.File() > 4 {
+			t = 1
+		}
+		return ix.encode(b, t)
+	}
+	for triangleSlot[transform(wk, t)] < 0 {
+		t++
+	}
+	idx := ix.encode(b, t)
+	// if the white king is on the diagonal, the position can also be mirrored along the diagonal: use the lower index
+	if sq := transform(wk, t); sq.Rank() == sq.File() {
+		mirrored := b
+		for i := 0; i < b.n; i++ {
+			mirrored.squares[i] = transform(transform(b.squares[i], t), 4)
+		}
+		if mirroredIdx := ix.encode(mirrored, 0); mirroredIdx < idx {
+			idx = mirroredIdx
+		}
+	}
+	return idx
+}
+
+// encode returns the index of the board after applying the transformation t
+func (ix indexer) encode(b board, t int) int {
+	wk := transform(b.squares[0], t)
+	var kingSlot int
+	if ix.hasPawns {
+		kingSlot = (wk.Rank()-1)*4 + wk.File() - 1
+	} else {
+		kingSlot = triangleSlot[wk]
+	}
+	idx := int(b.stm)*ix.kingSlots + kingSlot
+	for i := 1; i < b.n; i++ {
+		idx = idx*64 + int(transform(b.squares[i], t)) - 1
+	}
+	return idx
+}
+
+// board returns the position with the given index
+func (ix indexer) board(idx int) board {
+	b := newBoard(ix.material)
+	for i := b.n - 1; i > 0; i-- {
+		b.squares[i] = square.Square(idx%64 + 1)
+		idx /= 64
+	}
+	kingSlot := idx % ix.kingSlots
+	if ix.hasPawns {
+		b.squares[0] = square.FromRankAndFile(kingSlot/4+1, kingSlot%4+1)
+	} else {
+		b.squares[0] = triangleSquares[kingSlot]
+	}
+	b.stm = colour.Colour(idx / ix.kingSlots)
+	return b
+}
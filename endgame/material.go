@@ -0,0 +1,160 @@
+package endgame
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+)
+
+// MaxPieces is the maximum number of pieces (including the kings) supported by the generator
+const MaxPieces = 4
+
+// Material describes the pieces of an endgame, e.g. KBNK.
+// The kings are implicit.
+type Material struct {
+	pieces [2][]piece.Piece // per colour, sorted by pieceOrder
+}
+
+// the order of the pieces in the material name
+var pieceOrder = map[piece.Piece]int{piece.QUEEN: 0, piece.ROOK: 1, piece.BISHOP: 2, piece.KNIGHT: 3, piece.PAWN: 4}
+
+// used to decide which side is stronger
+var pieceValues = map[piece.Piece]int{piece.QUEEN: 9, piece.ROOK: 5, piece.BISHOP: 3, piece.KNIGHT: 3, piece.PAWN: 1}
+
+// ParseMaterial parses a material description such as KQK, KBNK or KPKP.
+// The white pieces come first, each side starts with its king.
+func ParseMaterial(str string) (Material, error) {
+	var m Material
+	upper := strings.ToUpper(str)
+	if len(upper) < 2 || upper[0] != 'K' {
+		return m, fmt.Errorf("unrecognised material '%s'", str)
+	}
+	second := strings.IndexByte(upper[1:], 'K')
+	if second < 0 {
+		return m, fmt.Errorf("unrecognised material '%s'", str)
+	}
+	sides := []string{upper[1 : second+1], upper[second+2:]}
+	for col, side := range sides {
+		for _, ch := range side {
+			p, ok := piece.StringToPiece[string(ch)]
+			if !ok || p == piece.KING {
+				return m, fmt.Errorf("unrecognised material '%s'", str)
+			}
+			m.pieces[col] = append(m.pieces[col], p)
+		}
+	}
+	if m.count() > MaxPieces {
+		return m, fmt.Errorf("material '%s' has more than %d pieces", str, MaxPieces)
+	}
+	m.sort()
+	return m, nil
+}
+
+// MustParseMaterial is like ParseMaterial but panics if the material cannot be parsed
+func MustParseMaterial(str string) Material {
+	m, err := ParseMaterial(str)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func (m *Material) sort() {
+	for col := range m.pieces {
+		pieces := m.pieces[col]
+		sort.SliceStable(pieces, func(i, j int) bool { return pieceOrder[pieces[i]] < pieceOrder[pieces[j]] })
+	}
+}
+
+// String returns the name of the material, e.g. KRKN
+func (m Material) String() string {
+	var sb strings.Builder
+	for col := range m.pieces {
+		sb.WriteString(piece.WhiteKingString)
+		for _, p := range m.pieces[col] {
+			sb.WriteString(p.String(colour.White))
+		}
+	}
+	return sb.String()
+}
+
+// count returns the number of pieces including the kings
+func (m Material) count() int {
+	return 2 + len(m.pieces[colour.White]) + len(m.pieces[colour.Black])
+}
+
+// hasPawns returns true if either side has a pawn
+func (m Material) hasPawns() bool {
+	for col := range m.pieces {
+		for _, p := range m.pieces[col] {
+			if p == piece.PAWN {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// swapped returns the material with the colours exchanged
+func (m Material) swapped() Material {
+	return Material{pieces: [2][]piece.Piece{m.pieces[colour.Black], m.pieces[colour.White]}}
+}
+
+// isCanonical returns true if the tables for this material are stored with the colours as given.
+// The stronger side is always white.
+func (m Material) isCanonical() bool {
+	value := func(pieces []piece.Piece) int {
+		total := 0
+		for _, p := range pieces {
+			total += pieceValues[p]
+		}
+		return total
+	}
+	white, black := value(m.pieces[colour.White]), value(m.pieces[colour.Black])
+	if white != black {
+		return white > black
+	}
+	if len(m.pieces[colour.White]) != len(m.pieces[colour.Black]) {
+		return len(m.pieces[colour.White]) > len(m.pieces[colour.Black])
+	}
+	return m.String() <= m.swapped().String()
+}
+
+// canonical returns the material as stored in the tables
+func (m Material) canonical() Material {
+	if m.isCanonical() {
+		return m
+	}
+	return m.swapped()
+}
+
+// without returns the material without the given piece (after a capture)
+func (m Material) without(col colour.Colour, p piece.Piece) Material {
+	result := m.copy()
+	for i, pc := range result.pieces[col] {
+		if pc == p {
+			result.pieces[col] = append(result.pieces[col][:i], result.pieces[col][i+1:]...)
+			break
+		}
+	}
+	return result
+}
+
+// with returns the material with an additional piece
+func (m Material) with(col colour.Colour, p piece.Piece) Material {
+	result := m.copy()
+	result.pieces[col] = append(result.pieces[col], p)
+	result.sort()
+	return result
+}
+
+func (m Material) copy() Material {
+	var result Material
+	for col := range m.pieces {
+		result.pieces[col] = append([]piece.Piece(nil), m.pieces[col]...)
+	}
+	return result
+}
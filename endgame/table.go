@@ -0,0 +1,290 @@
+// Package endgame generates and probes distance-to-mate tables for endgames with up to 4 pieces.
+package endgame
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// Outcome of a position from the point of view of the side to move
+type Outcome int
+
+// the outcomes
+const (
+	Draw Outcome = iota
+	Win
+	Loss
+)
+
+// Result is the value of a position stored in a table
+type Result struct {
+	Outcome Outcome
+	Plies   int // number of plies (half-moves) until mate. 0 for a draw or if the side to move has been mated
+}
+
+func (r Result) String() string {
+	switch r.Outcome {
+	case Win:
+		return fmt.Sprintf("mate in %d", (r.Plies+1)/2)
+	case Loss:
+		if r.Plies == 0 {
+			return "checkmated"
+		}
+		return fmt.Sprintf("mated in %d", r.Plies/2)
+	}
+	return "draw"
+}
+
+func newResult(v int16) Result {
+	switch {
+	case v > 0:
+		return Result{Outcome: Win, Plies: int(v)}
+	case v < 0:
+		return Result{Outcome: Loss, Plies: int(-v - 1)}
+	}
+	return Result{Outcome: Draw}
+}
+
+// Table stores the values of all positions of one material
+type Table struct {
+	material Material
+	ix       indexer
+	values   []int16
+}
+
+// Material returns the material of the table
+func (t *Table) Material() Material {
+	return t.material
+}
+
+// MaxResult returns the longest win stored in the table
+func (t *Table) MaxResult() Result {
+	var max int16
+	for _, v := range t.values {
+		if v > max {
+			max = v
+		}
+	}
+	return newResult(max)
+}
+
+// file format: magic number, length of material name, material name, values (little endian)
+var tableMagic = []byte{'R', 'J', 'E', 'G'}
+
+const fileSuffix = ".dtm"
+
+// Write stores the table
+func (t *Table) Write(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	name := t.material.String()
+	writer.Write(tableMagic)
+	writer.WriteByte(byte(len(name)))
+	writer.WriteString(name)
+	if err := binary.Write(writer, binary.LittleEndian, t.values); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// ReadTable reads a table written by Write
+func ReadTable(r io.Reader) (*Table, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, len(tableMagic)+1)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, fmt.Errorf("error reading table header: %s", err)
+	}
+	if string(header[:len(tableMagic)]) != string(tableMagic) {
+		return nil, fmt.Errorf("bad magic number")
+	}
+	name := make([]byte, header[len(tableMagic)])
+	if _, err := io.ReadFull(reader, name); err != nil {
+		return nil, fmt.Errorf("error reading table header: %s", err)
+	}
+	m, err := ParseMaterial(string(name))
+	if err != nil {
+		return nil, err
+	}
+	t := &Table{material: m, ix: newIndexer(m)}
+	t.values = make([]int16, t.ix.size())
+	if err := binary.Read(reader, binary.LittleEndian, t.values); err != nil {
+		return nil, fmt.Errorf("error reading table %s: %s", m, err)
+	}
+	return t, nil
+}
+
+// ErrNoTable is returned if a position cannot be probed because the table is not available
+var ErrNoTable = errors.New("no table available")
+
+// Set stores the tables of several materials.
+// If a directory is given, tables are read from and written to this directory.
+type Set struct {
+	dir    string
+	tables map[string]*Table
+}
+
+// NewSet creates a new set of tables. dir can be empty, in which case the tables are only held in memory.
+func NewSet(dir string) *Set {
+	return &Set{dir: dir, tables: make(map[string]*Table)}
+}
+
+// Generate returns the table for the given material, generating it (and the tables of all materials which can be
+// reached by captures or promotions) if necessary.
+// Materials which are stored with the colours swapped, e.g. KKQ, are converted to the stored form, e.g. KQK.
+func (s *Set) Generate(m Material) (*Table, error) {
+	m = m.canonical()
+	if t, err := s.table(m); err == nil {
+		return t, nil
+	} else if !errors.Is(err, ErrNoTable) {
+		return nil, err
+	}
+	for _, sub := range subMaterials(m) {
+		if _, err := s.Generate(sub); err != nil {
+			return nil, err
+		}
+	}
+	t, err := generate(m, s.lookup)
+	if err != nil {
+		return nil, err
+	}
+	if s.dir != "" {
+		if err := s.save(t); err != nil {
+			return nil, err
+		}
+	}
+	s.tables[m.String()] = t
+	return t, nil
+}
+
+// subMaterials returns the materials which can be reached from m by a capture or promotion
+func subMaterials(m Material) []Material {
+	var result []Material
+	for _, col := range colour.AllColours {
+		for _, p := range m.pieces[col] {
+			captured := m.without(col, p)
+			result = append(result, captured)
+			if p != piece.PAWN {
+				continue
+			}
+			for _, promotedPiece := range piece.PromotedPawnPieceCandidates {
+				promoted := m.without(col, piece.PAWN).with(col, promotedPiece)
+				result = append(result, promoted)
+				for _, q := range promoted.pieces[col.Other()] {
+					result = append(result, promoted.without(col.Other(), q))
+				}
+			}
+		}
+	}
+	// only kings left: no table required
+	filtered := result[:0]
+	for _, sub := range result {
+		if sub.count() > 2 {
+			filtered = append(filtered, sub)
+		}
+	}
+	return filtered
+}
+
+func (s *Set) filename(m Material) string {
+	return filepath.Join(s.dir, m.String()+fileSuffix)
+}
+
+func (s *Set) save(t *Table) error {
+	f, err := os.Create(s.filename(t.material))
+	if err != nil {
+		return err
+	}
+	if err := t.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// table returns the table for the (canonical) material, reading it from the directory if necessary
+func (s *Set) table(m Material) (*Table, error) {
+	if t, ok := s.tables[m.String()]; ok {
+		return t, nil
+	}
+	if s.dir == "" {
+		return nil, fmt.Errorf("%w for %s", ErrNoTable, m)
+	}
+	f, err := os.Open(s.filename(m))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w for %s", ErrNoTable, m)
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	t, err := ReadTable(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", s.filename(m), err)
+	}
+	s.tables[m.String()] = t
+	return t, nil
+}
+
+// lookup returns the stored value of the given board
+func (s *Set) lookup(b board) (int16, error) {
+	if b.n == 2 {
+		return 0, nil // KvK
+	}
+	m := b.material()
+	if !m.isCanonical() {
+		b.swapColours()
+		m = m.swapped()
+	}
+	t, err := s.table(m)
+	if err != nil {
+		return 0, err
+	}
+	return t.values[t.ix.index(b)], nil
+}
+
+// Probe returns the distance to mate of the given position.
+// The position may not have castling rights; enpassant captures are not taken into account.
+func (s *Set) Probe(p position.Position) (Result, error) {
+	for _, col := range colour.AllColours {
+		if p.CastlingAvailabilityKingsSide(col) || p.CastlingAvailabilityQueensSide(col) {
+			return Result{}, fmt.Errorf("cannot probe position with castling rights")
+		}
+	}
+	if n := p.OccupiedSquares().Cardinality(); n > MaxPieces {
+		return Result{}, fmt.Errorf("too many pieces (%d) for endgame tables", n)
+	}
+	b := board{n: 2, stm: p.ActiveColour()}
+	for _, col := range colour.AllColours {
+		for _, pieceType := range piece.AllPieces {
+			for _, sq := range p.Pieces(col, pieceType).SetBits() {
+				if pieceType == piece.KING {
+					b.squares[col] = square.Square(sq)
+					b.pieces[col] = piece.KING
+					b.colours[col] = col
+				} else {
+					b.squares[b.n] = square.Square(sq)
+					b.pieces[b.n] = pieceType
+					b.colours[b.n] = col
+					b.n++
+				}
+			}
+		}
+	}
+	b.normalise()
+	v, err := s.lookup(b)
+	if err != nil {
+		return Result{}, err
+	}
+	if v == illegal {
+		return Result{}, fmt.Errorf("illegal position")
+	}
+	return newResult(v), nil
+}
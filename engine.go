@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/search"
+)
+
+// time allowed for a move if the GUI sends neither a time control nor a depth
+const defaultMoveTime = 5 * time.Second
+
+// number of moves expected until the end of the game if the time control does not say
+const defaultMovesToGo = 30
+
+// engine stores the state shared by the protocols: the current position and the search running in the background.
+// The search makes moves on the position, therefore stopSearch must be called before the position is used or changed.
+type engine struct {
+	posn position.Position
+
+	out      io.Writer
+	outMutex sync.Mutex
+
+	cancel  context.CancelFunc // cancels the running search, nil if no search is running
+	done    chan struct{}      // closed when the running search has finished
+	discard bool               // set by stopSearch if the result of the running search is not wanted
+	mutex   sync.Mutex         // protects discard
+}
+
+// limits of a search
+type limits struct {
+	depth    int           // 0: no limit
+	moveTime time.Duration // 0: no limit
+	infinite bool          // do not finish before the search has been stopped
+}
+
+func newEngine(out io.Writer) *engine {
+	return &engine{posn: position.StartPosition(), out: out}
+}
+
+// send writes a line to the GUI. It is called both by the protocol and by the search
+func (e *engine) send(format string, args ...interface{}) {
+	e.outMutex.Lock()
+	defer e.outMutex.Unlock()
+	fmt.Fprintf(e.out, format+"\n", args...)
+}
+
+// startSearch starts a search of the current position in the background. The function report (if not nil) is called
+// after each completed iteration, finish is called with the best move when the search has finished, unless the search
+// was stopped with stopSearch(false). Both are called by the search goroutine, while the position is at the root.
+// If the side to move has no legal moves, finish is called with an empty result.
+func (e *engine) startSearch(l limits, report func(search.Result), finish func(search.Result)) {
+	e.stopSearch(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	if l.moveTime > 0 && !l.infinite {
+		ctx, cancel = context.WithTimeout(context.Background(), l.moveTime)
+	}
+	e.cancel, e.done, e.discard = cancel, make(chan struct{}), false
+	go func(posn position.Position, done chan struct{}) {
+		defer close(done)
+		result, err := search.Iterate(ctx, posn, l.depth, report)
+		if err != nil {
+			result = search.Result{}
+		}
+		if l.infinite {
+			<-ctx.Done()
+		}
+		e.mutex.Lock()
+		defer e.mutex.Unlock()
+		if !e.discard {
+			finish(result)
+		}
+	}(e.posn, e.done)
+}
+
+// stopSearch stops the running search (if any) and waits for it to finish.
+// If useResult is set, the search finishes normally, i.e. the best move found so far is passed to the finish function.
+func (e *engine) stopSearch(useResult bool) {
+	if e.cancel == nil {
+		return
+	}
+	e.mutex.Lock()
+	e.discard = !useResult
+	e.mutex.Unlock()
+	e.cancel()
+	<-e.done
+	e.cancel, e.done = nil, nil
+}
+
+// allocateTime returns the time to spend on the next move, given the remaining time on the clock, the increment per move
+// and the number of moves until the next time control (0 if unknown)
+func allocateTime(remaining, increment time.Duration, movesToGo int) time.Duration {
+	if movesToGo <= 0 {
+		movesToGo = defaultMovesToGo
+	}
+	allocated := remaining/time.Duration(movesToGo+1) + increment*3/4
+	if allocated > remaining/2 {
+		allocated = remaining / 2
+	}
+	return allocated
+}
+
+// pvString returns the moves of the principal variation, each converted to a string by the function notation
+// in the position reached after the preceding moves.
+// The moves are made on the position and unmade again.
+func pvString(posn position.Position, pv []move.Move, notation func(posn position.Position, m move.Move) string) string {
+	strs := make([]string, len(pv))
+	for i, m := range pv {
+		strs[i] = notation(posn, m)
+		posn.MakeMove(m)
+	}
+	for i := len(pv) - 1; i >= 0; i-- {
+		posn.UnmakeMove(pv[i])
+	}
+	return strings.Join(strs, " ")
+}
@@ -0,0 +1,400 @@
+// Package engine runs an external chess engine which speaks the UCI protocol,
+// see https://www.shredderchess.com/download/div/uci.zip
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/position"
+)
+
+// DefaultTimeout is the default time to wait for the engine to reply to a command which does not start a search,
+// and for the best move after a search has been stopped
+const DefaultTimeout = 10 * time.Second
+
+// ErrTimeout is returned if the engine does not reply in time. The engine process is killed in this case.
+var ErrTimeout = errors.New("engine did not reply in time")
+
+// ErrTerminated is returned if the engine process has ended
+var ErrTerminated = errors.New("engine terminated")
+
+// Option is an option offered by the engine in reply to "uci"
+type Option struct {
+	Name    string
+	Type    string // check, spin, combo, button or string
+	Default string
+	Min     int      // spin only
+	Max     int      // spin only
+	Vars    []string // the values of a combo
+}
+
+// Limits of a search. Zero values are not sent; if no value is set the engine searches until the context is done.
+type Limits struct {
+	Depth      int
+	Nodes      int64
+	Mate       int // search for a mate in this number of moves
+	MoveTime   time.Duration
+	WhiteTime  time.Duration
+	BlackTime  time.Duration
+	WhiteInc   time.Duration
+	BlackInc   time.Duration
+	MovesToGo  int
+	SearchMove []move.Move // restrict the search to these moves
+}
+
+// Result of a search
+type Result struct {
+	BestMove move.Move
+	Ponder   *move.Move // the move the engine expects in reply, if sent
+	Infos    []Info     // all info lines sent during the search, apart from those containing only a string
+}
+
+// Lines returns the last info line with a PV for each of the lines (see Info.MultiPV) of the search, best line first
+func (r Result) Lines() []Info {
+	var lines []Info
+	for _, info := range r.Infos {
+		if len(info.PV) == 0 {
+			continue
+		}
+		index := info.MultiPV - 1
+		if index < 0 {
+			index = 0
+		}
+		for len(lines) <= index {
+			lines = append(lines, Info{})
+		}
+		lines[index] = info
+	}
+	return lines
+}
+
+// Engine is a running engine process. The methods must not be called concurrently.
+type Engine struct {
+	Name    string            // from "id name"
+	Author  string            // from "id author"
+	Options map[string]Option // the options offered by the engine, by name
+	Timeout time.Duration     // see DefaultTimeout
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string // the lines written by the engine, closed when the output ends
+	posn  position.Position
+}
+
+// Start starts the engine and performs the UCI handshake. The context only limits the handshake; use Close to end the engine.
+func Start(ctx context.Context, path string, args ...string) (*Engine, error) {
+	cmd := exec.Command(path, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	e := &Engine{Options: make(map[string]Option), Timeout: DefaultTimeout, cmd: cmd, stdin: stdin,
+		lines: make(chan string, 100), posn: position.StartPosition()}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			e.lines <- strings.TrimSpace(scanner.Text())
+		}
+		close(e.lines)
+	}()
+
+	if err := e.send("uci"); err != nil {
+		e.kill()
+		return nil, err
+	}
+	err = e.readUntil(ctx, "uciok", e.Timeout, func(fields []string) error {
+		switch {
+		case len(fields) > 2 && fields[0] == "id" && fields[1] == "name":
+			e.Name = strings.Join(fields[2:], " ")
+		case len(fields) > 2 && fields[0] == "id" && fields[1] == "author":
+			e.Author = strings.Join(fields[2:], " ")
+		case fields[0] == "option":
+			opt, err := parseOption(fields[1:])
+			if err != nil {
+				return err
+			}
+			e.Options[opt.Name] = opt
+		}
+		return nil
+	})
+	if err != nil {
+		e.kill()
+		return nil, err
+	}
+	return e, nil
+}
+
+// parseOption parses an option line (without "option")
+func parseOption(args []string) (Option, error) {
+	var opt Option
+	keywords := map[string]bool{"name": true, "type": true, "default": true, "min": true, "max": true, "var": true}
+	for i := 0; i < len(args); {
+		key := args[i]
+		if !keywords[key] {
+			return Option{}, fmt.Errorf("invalid option: unexpected '%s'", key)
+		}
+		// the value extends to the next keyword, e.g. names and values can contain spaces
+		j := i + 1
+		for j < len(args) && !keywords[args[j]] {
+			j++
+		}
+		value := strings.Join(args[i+1:j], " ")
+		var err error
+		switch key {
+		case "name":
+			opt.Name = value
+		case "type":
+			opt.Type = value
+		case "default":
+			opt.Default = value
+		case "min":
+			opt.Min, err = strconv.Atoi(value)
+		case "max":
+			opt.Max, err = strconv.Atoi(value)
+		case "var":
+			opt.Vars = append(opt.Vars, value)
+		}
+		if err != nil {
+			return Option{}, fmt.Errorf("invalid option: '%s %s'", key, value)
+		}
+		i = j
+	}
+	if opt.Name == "" {
+		return Option{}, fmt.Errorf("invalid option: no name")
+	}
+	return opt, nil
+}
+
+// SetOption sets the value of an option. The value is ignored for options of type button.
+// Returns an error if the engine does not offer the option.
+func (e *Engine) SetOption(name, value string) error {
+	opt, ok := e.Options[name]
+	if !ok {
+		return fmt.Errorf("unknown option '%s'", name)
+	}
+	if opt.Type == "button" {
+		return e.send("setoption name " + name)
+	}
+	return e.send("setoption name " + name + " value " + value)
+}
+
+// IsReady waits until the engine has processed all previous commands
+func (e *Engine) IsReady(ctx context.Context) error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+	return e.readUntil(ctx, "readyok", e.Timeout, nil)
+}
+
+// NewGame tells the engine that the next position is from a different game, and waits until the engine is ready
+func (e *Engine) NewGame(ctx context.Context) error {
+	if err := e.send("ucinewgame"); err != nil {
+		return err
+	}
+	return e.IsReady(ctx)
+}
+
+// SetPosition sets the position to search: the start position followed by the moves, which must be legal.
+// The position is not changed. Chess960 castling moves are sent as 'king takes rook' if start.Chess960() is set,
+// in which case the option UCI_Chess960 must have been set.
+func (e *Engine) SetPosition(start position.Position, moves []move.Move) error {
+	// the engine keeps its own copy of the position for the conversion of the PVs
+	posn, err := position.ParseFen(start.Fen())
+	if err != nil {
+		return err
+	}
+	posn.SetChess960(start.Chess960())
+	cmd := "position fen " + start.Fen()
+	if cmd == "position fen "+position.StartPosition().Fen() && !start.Chess960() {
+		cmd = "position startpos"
+	}
+	if len(moves) > 0 {
+		cmd += " moves"
+	}
+	for i, m := range moves {
+		legal, err := posn.ParseUci(posn.UciString(m))
+		if err != nil || legal.Encode() != m.Encode() {
+			return fmt.Errorf("move %d (%s) is not legal", i+1, m.String())
+		}
+		cmd += " " + posn.UciString(legal)
+		posn.MakeMove(legal)
+	}
+	if err := e.send(cmd); err != nil {
+		return err
+	}
+	e.posn = posn
+	return nil
+}
+
+// Go searches the position set by SetPosition. The function info (if not nil) is called for each info line;
+// lines which cannot be parsed are ignored. When the context is done the search is stopped; the engine's best move is then returned together with the context's error.
+func (e *Engine) Go(ctx context.Context, limits Limits, info func(Info)) (Result, error) {
+	if err := e.send(e.goCommand(limits)); err != nil {
+		return Result{}, err
+	}
+	var result Result
+	var bestMove []string
+	handle := func(fields []string) error {
+		switch fields[0] {
+		case "info":
+			inf, err := parseInfo(fields[1:], &e.posn)
+			if err != nil {
+				return nil
+			}
+			if info != nil {
+				info(inf)
+			}
+			if inf.String == "" {
+				result.Infos = append(result.Infos, inf)
+			}
+		case "bestmove":
+			bestMove = fields[1:]
+		}
+		return nil
+	}
+
+	var err error
+	if err = e.readUntil(ctx, "bestmove", 0, handle); err != nil && err == ctx.Err() {
+		// stop the search, the best move must be sent nevertheless
+		if err := e.send("stop"); err != nil {
+			return Result{}, err
+		}
+		if err := e.readUntil(context.Background(), "bestmove", e.Timeout, handle); err != nil {
+			return Result{}, err
+		}
+	} else if err != nil {
+		return Result{}, err
+	}
+	if len(bestMove) == 0 || bestMove[0] == "0000" || bestMove[0] == "(none)" {
+		return Result{}, fmt.Errorf("engine sent no move")
+	}
+	if result.BestMove, err = e.posn.ParseUci(bestMove[0]); err != nil {
+		return Result{}, fmt.Errorf("engine sent an illegal move: %w", err)
+	}
+	if len(bestMove) == 3 && bestMove[1] == "ponder" {
+		if pv := parsePV([]string{bestMove[0], bestMove[2]}, &e.posn); len(pv) == 2 {
+			result.Ponder = &pv[1]
+		}
+	}
+	return result, ctx.Err()
+}
+
+// goCommand returns the "go" command for the limits
+func (e *Engine) goCommand(limits Limits) string {
+	var sb strings.Builder
+	sb.WriteString("go")
+	addInt := func(name string, value int64) {
+		if value > 0 {
+			fmt.Fprintf(&sb, " %s %d", name, value)
+		}
+	}
+	addTime := func(name string, value time.Duration) {
+		addInt(name, value.Milliseconds())
+	}
+	if len(limits.SearchMove) > 0 {
+		sb.WriteString(" searchmoves")
+		for _, m := range limits.SearchMove {
+			sb.WriteString(" " + e.posn.UciString(m))
+		}
+	}
+	addTime("wtime", limits.WhiteTime)
+	addTime("btime", limits.BlackTime)
+	addTime("winc", limits.WhiteInc)
+	addTime("binc", limits.BlackInc)
+	addInt("movestogo", int64(limits.MovesToGo))
+	addInt("depth", int64(limits.Depth))
+	addInt("nodes", limits.Nodes)
+	addInt("mate", int64(limits.Mate))
+	addTime("movetime", limits.MoveTime)
+	if sb.Len() == len("go") {
+		sb.WriteString(" infinite")
+	}
+	return sb.String()
+}
+
+// Close sends "quit" and waits for the engine to end; the engine is killed if it does not end within the timeout
+func (e *Engine) Close() error {
+	// errors are ignored, e.g. the engine may already have terminated
+	_ = e.send("quit")
+	_ = e.stdin.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), e.Timeout)
+	defer cancel()
+	for {
+		select {
+		case _, ok := <-e.lines:
+			if !ok {
+				return e.cmd.Wait()
+			}
+		case <-ctx.Done():
+			e.kill()
+			return ErrTimeout
+		}
+	}
+}
+
+// send writes a command to the engine
+func (e *Engine) send(cmd string) error {
+	if _, err := io.WriteString(e.stdin, cmd+"\n"); err != nil {
+		return fmt.Errorf("%w: %s", ErrTerminated, err)
+	}
+	return nil
+}
+
+// readUntil reads lines from the engine until a line starting with the given word, each line (including the last one)
+// is passed to the function handle (if not nil). Returns the context's error if it is done first.
+// If the line is not received within the timeout (unless 0), the engine is killed and ErrTimeout is returned.
+func (e *Engine) readUntil(ctx context.Context, word string, timeout time.Duration, handle func(fields []string) error) error {
+	var timedOut <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		timedOut = timer.C
+	}
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return ErrTerminated
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			if handle != nil {
+				if err := handle(fields); err != nil {
+					return err
+				}
+			}
+			if fields[0] == word {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timedOut:
+			e.kill()
+			return ErrTimeout
+		}
+	}
+}
+
+// kill ends the engine process
+func (e *Engine) kill() {
+	_ = e.cmd.Process.Kill()
+	_ = e.cmd.Wait()
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/position"
+)
+
+// path of the fake engine built from testdata/fakeengine, empty if it could not be built
+var fakeEngine string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "fakeengine")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fakeEngine = filepath.Join(dir, "fakeengine")
+	if out, err := exec.Command("go", "build", "-o", fakeEngine, "./testdata/fakeengine").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "cannot build fake engine: %s\n%s", err, out)
+		fakeEngine = ""
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func startFakeEngine(t *testing.T) *Engine {
+	t.Helper()
+	if fakeEngine == "" {
+		t.Skip("fake engine not available")
+	}
+	e, err := Start(context.Background(), fakeEngine)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func uciMoves(t *testing.T, posn position.Position, strs ...string) []move.Move {
+	t.Helper()
+	moves := make([]move.Move, len(strs))
+	for i, str := range strs {
+		m, err := posn.ParseUci(str)
+		if err != nil {
+			t.Fatalf("cannot parse '%s': %s", str, err)
+		}
+		posn.MakeMove(m)
+		moves[i] = m
+	}
+	for i := len(moves) - 1; i >= 0; i-- {
+		posn.UnmakeMove(moves[i])
+	}
+	return moves
+}
+
+func TestStart(t *testing.T) {
+	e := startFakeEngine(t)
+	if e.Name != "Fake Engine 1.0" || e.Author != "The Tests" {
+		t.Errorf("unexpected name '%s' or author '%s'", e.Name, e.Author)
+	}
+	expected := map[string]Option{
+		"Hash":       {Name: "Hash", Type: "spin", Default: "16", Min: 1, Max: 1024},
+		"Style":      {Name: "Style", Type: "combo", Default: "Normal", Vars: []string{"Solid", "Normal", "Risky"}},
+		"Clear Hash": {Name: "Clear Hash", Type: "button"},
+		"Hang":       {Name: "Hang", Type: "button"},
+	}
+	if !reflect.DeepEqual(e.Options, expected) {
+		t.Errorf("expected options %v but got %v", expected, e.Options)
+	}
+	if err := e.SetOption("Hash", "64"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := e.SetOption("Threads", "4"); err == nil {
+		t.Errorf("expected error for unknown option")
+	}
+	if err := e.NewGame(context.Background()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := e.Close(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if _, err := Start(context.Background(), filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Errorf("expected error for missing engine")
+	}
+}
+
+func TestGo(t *testing.T) {
+	e := startFakeEngine(t)
+	start := position.StartPosition()
+	if err := e.SetPosition(start, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var infos []Info
+	result, err := e.Go(context.Background(), Limits{Depth: 2}, func(info Info) { infos = append(infos, info) })
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(infos) != 5 || infos[0].String != "searching" {
+		t.Errorf("unexpected infos %v", infos)
+	}
+	if len(result.Infos) != 4 {
+		t.Errorf("expected 4 infos but got %d", len(result.Infos))
+	}
+	if result.BestMove.UciString() != "e2e4" || result.Ponder == nil || result.Ponder.UciString() != "e7e5" {
+		t.Errorf("unexpected best move %s / ponder %v", result.BestMove, result.Ponder)
+	}
+	lines := result.Lines()
+	expected := []Info{
+		{Depth: 2, SelDepth: 3, MultiPV: 1, Score: &Score{CP: 30, LowerBound: true}, Nodes: 400, NPS: 20000, Time: 20 * time.Millisecond,
+			// the illegal third move is dropped
+			PV: uciMoves(t, start, "e2e4", "e7e5")},
+		{Depth: 2, SelDepth: 3, MultiPV: 2, Score: &Score{Mate: -3}, Nodes: 450, NPS: 20000, Time: 22 * time.Millisecond,
+			PV: uciMoves(t, start, "d2d4", "d7d5")},
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected lines\n%v but got\n%v", expected, lines)
+	}
+
+	// the fake engine always plays e2e4
+	if err := e.SetPosition(start, uciMoves(t, start, "e2e4")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := e.Go(context.Background(), Limits{Depth: 1}, nil); err == nil {
+		t.Errorf("expected error for illegal best move")
+	}
+	if _, err := e.Go(context.Background(), Limits{Mate: 2}, nil); err == nil {
+		t.Errorf("expected error for missing best move")
+	}
+	if start.Fen() != position.StartPosition().Fen() {
+		t.Errorf("start position changed")
+	}
+}
+
+func TestGoCancelled(t *testing.T) {
+	e := startFakeEngine(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	result, err := e.Go(ctx, Limits{}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded but got %v", err)
+	}
+	if result.BestMove.UciString() != "d2d4" || len(result.Infos) != 1 {
+		t.Errorf("unexpected result %v", result)
+	}
+	// the engine can still be used
+	if err := e.IsReady(context.Background()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	e := startFakeEngine(t)
+	e.Timeout = 50 * time.Millisecond
+	if err := e.SetOption("Hang", ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := e.IsReady(context.Background()); !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected timeout but got %v", err)
+	}
+	// the engine has been killed
+	if err := e.IsReady(context.Background()); err == nil {
+		t.Errorf("expected error")
+	}
+}
+
+func TestSetPosition(t *testing.T) {
+	e := startFakeEngine(t)
+	posn, _ := position.ParseFen("4k3/8/8/8/8/8/8/4K2R w K - 0 1")
+	if err := e.SetPosition(posn, uciMoves(t, posn, "e1g1")); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	// the move is not legal in the start position
+	if err := e.SetPosition(position.StartPosition(), uciMoves(t, posn, "h1h8")); err == nil {
+		t.Errorf("expected error for illegal move")
+	}
+}
+
+func TestGoCommand(t *testing.T) {
+	e := &Engine{posn: position.StartPosition()}
+	for _, d := range []struct {
+		limits   Limits
+		expected string
+	}{
+		{Limits{}, "go infinite"},
+		{Limits{Depth: 5, Nodes: 1000}, "go depth 5 nodes 1000"},
+		{Limits{MoveTime: 1500 * time.Millisecond}, "go movetime 1500"},
+		{Limits{WhiteTime: time.Minute, BlackTime: 30 * time.Second, WhiteInc: time.Second, BlackInc: time.Second, MovesToGo: 20},
+			"go wtime 60000 btime 30000 winc 1000 binc 1000 movestogo 20"},
+		{Limits{Mate: 3, SearchMove: uciMoves(t, e.posn, "e2e4")}, "go searchmoves e2e4 mate 3"},
+	} {
+		if got := e.goCommand(d.limits); got != d.expected {
+			t.Errorf("expected '%s' but got '%s'", d.expected, got)
+		}
+	}
+}
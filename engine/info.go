@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/position"
+)
+
+// Score is the evaluation reported by an engine, from the view of the side to move
+type Score struct {
+	CP         int  // centipawns, if Mate is 0
+	Mate       int  // mate in this number of moves (negative if the side to move is mated), 0 if not a mate score
+	LowerBound bool // the score is only a lower bound
+	UpperBound bool // the score is only an upper bound
+}
+
+// IsMate returns true if the score is a mate score
+func (s Score) IsMate() bool {
+	return s.Mate != 0
+}
+
+func (s Score) String() string {
+	str := fmt.Sprintf("cp %d", s.CP)
+	if s.IsMate() {
+		str = fmt.Sprintf("mate %d", s.Mate)
+	}
+	if s.LowerBound {
+		str += " lowerbound"
+	}
+	if s.UpperBound {
+		str += " upperbound"
+	}
+	return str
+}
+
+// Info stores the values of an "info" line sent by the engine during a search. Values which were not sent are zero.
+type Info struct {
+	Depth    int
+	SelDepth int
+	MultiPV  int    // number of the line (1 is the best line) if the engine reports several lines, otherwise 0
+	Score    *Score // nil if no score was sent
+	Nodes    int64
+	NPS      int64
+	Time     time.Duration
+	PV       []move.Move // principal variation
+	String   string      // text after "string", which takes up the rest of the line
+}
+
+// parseInfo parses the info line (without "info"). The moves of the principal variation must be legal in the given position,
+// the PV stops at the first illegal move. Unknown keys and their value are skipped.
+func parseInfo(args []string, posn *position.Position) (Info, error) {
+	var info Info
+	for i := 0; i < len(args); i++ {
+		key := args[i]
+		switch key {
+		case "string":
+			info.String = strings.Join(args[i+1:], " ")
+			return info, nil
+		case "pv":
+			info.PV = parsePV(args[i+1:], posn)
+			return info, nil
+		case "refutation", "currline":
+			// followed by a list of moves, which is not stored
+			return info, nil
+		case "score":
+			i++
+			if i+1 >= len(args) {
+				return Info{}, fmt.Errorf("missing value for 'score'")
+			}
+			value, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return Info{}, fmt.Errorf("invalid score '%s %s'", args[i], args[i+1])
+			}
+			score := Score{}
+			switch args[i] {
+			case "cp":
+				score.CP = value
+			case "mate":
+				score.Mate = value
+			default:
+				return Info{}, fmt.Errorf("invalid score type '%s'", args[i])
+			}
+			i++
+			for ; i+1 < len(args) && (args[i+1] == "lowerbound" || args[i+1] == "upperbound"); i++ {
+				score.LowerBound = score.LowerBound || args[i+1] == "lowerbound"
+				score.UpperBound = score.UpperBound || args[i+1] == "upperbound"
+			}
+			info.Score = &score
+			continue
+		}
+
+		// all other keys have one value
+		i++
+		if i >= len(args) {
+			return Info{}, fmt.Errorf("missing value for '%s'", key)
+		}
+		switch key {
+		case "depth", "seldepth", "multipv", "nodes", "nps", "time":
+		default:
+			continue
+		}
+		value, err := strconv.ParseInt(args[i], 10, 64)
+		if err != nil {
+			return Info{}, fmt.Errorf("invalid value for '%s': %s", key, args[i])
+		}
+		switch key {
+		case "depth":
+			info.Depth = int(value)
+		case "seldepth":
+			info.SelDepth = int(value)
+		case "multipv":
+			info.MultiPV = int(value)
+		case "nodes":
+			info.Nodes = value
+		case "nps":
+			info.NPS = value
+		case "time":
+			info.Time = time.Duration(value) * time.Millisecond
+		}
+	}
+	return info, nil
+}
+
+// parsePV converts the moves in UCI notation, as far as they are legal. The moves are made on the position and unmade again.
+func parsePV(strs []string, posn *position.Position) []move.Move {
+	pv := make([]move.Move, 0, len(strs))
+	for _, str := range strs {
+		m, err := posn.ParseUci(str)
+		if err != nil {
+			break
+		}
+		posn.MakeMove(m)
+		pv = append(pv, m)
+	}
+	for i := len(pv) - 1; i >= 0; i-- {
+		posn.UnmakeMove(pv[i])
+	}
+	return pv
+}
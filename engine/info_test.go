@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjo67/chess/position"
+)
+
+func TestParseInfo(t *testing.T) {
+	posn := position.StartPosition()
+	for _, d := range []struct {
+		line     string
+		expected Info
+	}{
+		{"depth 3 seldepth 5 time 120 nodes 3000 nps 25000 hashfull 10 tbhits 0",
+			Info{Depth: 3, SelDepth: 5, Time: 120 * time.Millisecond, Nodes: 3000, NPS: 25000}},
+		{"multipv 2 score mate 4 upperbound", Info{MultiPV: 2, Score: &Score{Mate: 4, UpperBound: true}}},
+		{"score cp -35 currmove e2e4 currmovenumber 1", Info{Score: &Score{CP: -35}}},
+		{"string depth 3 pv e2e4", Info{String: "depth 3 pv e2e4"}},
+		{"depth 1 pv g1f3 g8f6 zz", Info{Depth: 1, PV: uciMoves(t, posn, "g1f3", "g8f6")}},
+	} {
+		info, err := parseInfo(strings.Fields(d.line), &posn)
+		if err != nil {
+			t.Errorf("'%s': unexpected error: %s", d.line, err)
+		} else if !reflect.DeepEqual(info, d.expected) {
+			t.Errorf("'%s': expected %v but got %v", d.line, d.expected, info)
+		}
+	}
+	for _, line := range []string{"depth", "depth x", "score", "score cp", "score cp x", "score pawns 3"} {
+		if _, err := parseInfo(strings.Fields(line), &posn); err == nil {
+			t.Errorf("'%s': expected error", line)
+		}
+	}
+	if posn.Fen() != position.StartPosition().Fen() {
+		t.Errorf("position changed")
+	}
+}
+
+func TestScoreString(t *testing.T) {
+	for _, d := range []struct {
+		score    Score
+		expected string
+	}{
+		{Score{CP: 25}, "cp 25"},
+		{Score{Mate: -2}, "mate -2"},
+		{Score{CP: 10, LowerBound: true}, "cp 10 lowerbound"},
+	} {
+		if got := d.score.String(); got != d.expected {
+			t.Errorf("expected '%s' but got '%s'", d.expected, got)
+		}
+	}
+}
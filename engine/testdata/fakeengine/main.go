@@ -0,0 +1,60 @@
+// fakeengine is a minimal UCI engine for the tests of package engine. It does not play chess, its replies are fixed:
+//
+//	go depth n   info lines for depths 1..n, then "bestmove e2e4 ponder e7e5"
+//	go infinite  waits for "stop", then "bestmove d2d4"
+//	go mate n    "bestmove 0000"
+//
+// After "setoption name Hang" it no longer replies to any command.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	hang := false
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || hang {
+			continue
+		}
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name Fake Engine 1.0")
+			fmt.Println("id author The Tests")
+			fmt.Println("option name Hash type spin default 16 min 1 max 1024")
+			fmt.Println("option name Style type combo default Normal var Solid var Normal var Risky")
+			fmt.Println("option name Clear Hash type button")
+			fmt.Println("option name Hang type button")
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "setoption":
+			hang = strings.Join(fields, " ") == "setoption name Hang"
+		case "go":
+			switch {
+			case len(fields) > 1 && fields[1] == "infinite":
+				fmt.Println("info depth 1 score cp 10 pv d2d4")
+				for scanner.Scan() && scanner.Text() != "stop" {
+				}
+				fmt.Println("bestmove d2d4")
+			case len(fields) > 2 && fields[1] == "mate":
+				fmt.Println("bestmove 0000")
+			default:
+				fmt.Println("info string searching")
+				fmt.Println("info depth 1 seldepth 1 multipv 1 score cp 20 nodes 20 nps 2000 time 10 pv e2e4")
+				fmt.Println("info depth 1 seldepth 1 multipv 2 score cp 15 nodes 25 nps 2000 time 12 pv d2d4")
+				fmt.Println("info depth 2 seldepth 3 multipv 1 score cp 30 lowerbound nodes 400 nps 20000 time 20 pv e2e4 e7e5 e1e3")
+				fmt.Println("info depth x")
+				fmt.Println("info depth 2 seldepth 3 multipv 2 score mate -3 nodes 450 nps 20000 time 22 pv d2d4 d7d5")
+				fmt.Println("bestmove e2e4 ponder e7e5")
+			}
+		case "quit":
+			return
+		}
+	}
+}
@@ -1,14 +1,42 @@
+// go-chess is a chess engine which speaks both the UCI protocol and the Chess Engine Communication Protocol (CECP, XBoard).
+// The protocol is selected by the first command received: 'uci' or 'xboard'.
 package main
 
 import (
+	"bufio"
 	"fmt"
-
-	"github.com/rjo67/chess/position"
+	"os"
+	"strings"
 )
 
-func main() {
-	posn := position.StartPosition()
-
-	fmt.Printf("Start position:\n%s", posn.String())
+// protocol handles one command, returning false after 'quit'
+type protocol interface {
+	handle(line string) bool
+}
 
+func main() {
+	e := newEngine(os.Stdout)
+	var proto protocol
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if proto == nil {
+			switch strings.Fields(line)[0] {
+			case "uci":
+				proto = &uci{e: e}
+			case "xboard", "protover":
+				proto = newXboard(e)
+			default:
+				fmt.Fprintf(os.Stderr, "expected 'uci' or 'xboard' but got '%s'\n", line)
+				os.Exit(1)
+			}
+		}
+		if !proto.handle(line) {
+			return
+		}
+	}
+	e.stopSearch(false)
 }
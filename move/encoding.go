@@ -0,0 +1,63 @@
+package move
+
+import (
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/square"
+)
+
+// Encoded is a compact 16-bit encoding of a move:
+//
+//	bits 0-5   'from' square (0..63)
+//	bits 6-11  'to' square (0..63), for castling moves the start square of the rook
+//	bits 12-13 promotion piece: knight (0), bishop (1), rook (2), queen (3)
+//	bits 14-15 flags: normal move (0), promotion (1), enpassant (2), castles (3)
+//
+// The moving and captured pieces are not stored, use position.DecodeMove to restore the complete move.
+type Encoded uint16
+
+// flags of an encoded move
+const (
+	EncodedNormal Encoded = iota << 14
+	EncodedPromotion
+	EncodedEnpassant
+	EncodedCastles
+)
+
+const encodedFlagsMask Encoded = 0xC000
+
+// promotion pieces in the order of the encoding
+var encodedPromotionPieces = []piece.Piece{piece.KNIGHT, piece.BISHOP, piece.ROOK, piece.QUEEN}
+
+// Encode returns the 16-bit encoding of the move
+func (m Move) Encode() Encoded {
+	to := m.To()
+	flags := EncodedNormal
+	var promotion Encoded
+	switch {
+	case m.IsCastles():
+		to = m.CastlingRookFrom()
+		flags = EncodedCastles
+	case m.IsEnpassant():
+		flags = EncodedEnpassant
+	case m.IsPromotion():
+		flags = EncodedPromotion
+		for i, p := range encodedPromotionPieces {
+			if p == m.PromotedPiece() {
+				promotion = Encoded(i)
+			}
+		}
+	}
+	return Encoded(m.From()-1) | Encoded(to-1)<<6 | promotion<<12 | flags
+}
+
+// From returns the 'from' square of the encoded move
+func (e Encoded) From() square.Square { return square.Square(e&0x3F + 1) }
+
+// To returns the 'to' square of the encoded move. For castling moves this is the start square of the rook.
+func (e Encoded) To() square.Square { return square.Square(e>>6&0x3F + 1) }
+
+// Flags returns the flags of the encoded move, one of EncodedNormal, EncodedPromotion, EncodedEnpassant or EncodedCastles
+func (e Encoded) Flags() Encoded { return e & encodedFlagsMask }
+
+// PromotedPiece returns the promotion piece of the encoded move (only valid if Flags() is EncodedPromotion)
+func (e Encoded) PromotedPiece() piece.Piece { return encodedPromotionPieces[e>>12&0x3] }
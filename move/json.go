@@ -0,0 +1,175 @@
+package move
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+// the flags of a move in its JSON representation
+const (
+	FlagCapture   = "capture"
+	FlagEnpassant = "enpassant"
+	FlagPromotion = "promotion"
+	FlagCastles   = "castles"
+)
+
+// JSON is the JSON representation of a move, e.g.
+//
+//	{"uci":"e7f8q","san":"exf8=Q+","from":"e7","to":"f8","piece":"pawn","captured":"rook","promotion":"queen","flags":["capture","promotion"]}
+//
+// San is only set if the move was converted in the context of a position (see position.MoveJSON).
+// For castling moves Rook contains the start square of the rook.
+type JSON struct {
+	Uci       string         `json:"uci"`
+	San       string         `json:"san,omitempty"`
+	From      square.Square  `json:"from"`
+	To        square.Square  `json:"to"`
+	Piece     piece.Piece    `json:"piece"`
+	Captured  *piece.Piece   `json:"captured,omitempty"`
+	Promotion *piece.Piece   `json:"promotion,omitempty"`
+	Rook      *square.Square `json:"rook,omitempty"`
+	Flags     []string       `json:"flags,omitempty"`
+}
+
+// ToJSON returns the JSON representation of the move, without SAN
+func (m Move) ToJSON() JSON {
+	j := JSON{Uci: m.UciString(), From: m.From(), To: m.To(), Piece: m.PieceType()}
+	if m.IsCapture() {
+		captured := m.CapturedPiece()
+		j.Captured = &captured
+		j.Flags = append(j.Flags, FlagCapture)
+	}
+	if m.IsEnpassant() {
+		j.Flags = append(j.Flags, FlagEnpassant)
+	}
+	if m.IsPromotion() {
+		promotion := m.PromotedPiece()
+		j.Promotion = &promotion
+		j.Flags = append(j.Flags, FlagPromotion)
+	}
+	if m.IsCastles() {
+		rook := m.CastlingRookFrom()
+		j.Rook = &rook
+		j.Flags = append(j.Flags, FlagCastles)
+	}
+	return j
+}
+
+// Move returns the move described by the JSON representation.
+// An error is returned if the fields are inconsistent, e.g. a promotion flag without a promotion piece
+// or a UCI string which does not match the squares. The SAN is not checked, since this requires the position.
+func (j JSON) Move() (Move, error) {
+	flags := make(map[string]bool)
+	for _, flag := range j.Flags {
+		switch flag {
+		case FlagCapture, FlagEnpassant, FlagPromotion, FlagCastles:
+		default:
+			return Move{}, fmt.Errorf("unrecognised flag '%s'", flag)
+		}
+		if flags[flag] {
+			return Move{}, fmt.Errorf("duplicate flag '%s'", flag)
+		}
+		flags[flag] = true
+	}
+	if j.From == 0 || j.To == 0 {
+		return Move{}, fmt.Errorf("'from' and 'to' are required")
+	}
+	// in Chess960 the king may stay on its square when castling
+	if j.From == j.To && !flags[FlagCastles] {
+		return Move{}, fmt.Errorf("'from' and 'to' are both %s", lower(j.From))
+	}
+	if flags[FlagCapture] != (j.Captured != nil) {
+		return Move{}, fmt.Errorf("flag '%s' does not match the captured piece", FlagCapture)
+	}
+	if flags[FlagPromotion] != (j.Promotion != nil) {
+		return Move{}, fmt.Errorf("flag '%s' does not match the promotion piece", FlagPromotion)
+	}
+	if flags[FlagCastles] != (j.Rook != nil) {
+		return Move{}, fmt.Errorf("flag '%s' does not match the rook square", FlagCastles)
+	}
+	if j.Captured != nil && *j.Captured == piece.KING {
+		return Move{}, fmt.Errorf("cannot capture a king")
+	}
+
+	// the colour is only relevant for pawn moves, and can be derived from their direction
+	col := colour.White
+	if j.To.Rank() < j.From.Rank() {
+		col = colour.Black
+	}
+	if d := j.To.Rank() - j.From.Rank(); j.Piece == piece.PAWN && (d == 0 || d > 2 || d < -2) {
+		return Move{}, fmt.Errorf("invalid pawn move %s%s", lower(j.From), lower(j.To))
+	}
+
+	var m Move
+	switch {
+	case flags[FlagCastles]:
+		rook := *j.Rook
+		kingsside := j.To.File() == 7
+		if j.Piece != piece.KING || j.Captured != nil || j.Promotion != nil || flags[FlagEnpassant] ||
+			(j.To.File() != 7 && j.To.File() != 3) || j.To.Rank() != j.From.Rank() || rook.Rank() != j.From.Rank() ||
+			kingsside != (rook.File() > j.From.File()) {
+			return Move{}, fmt.Errorf("invalid castling move %s%s with rook on %s", lower(j.From), lower(j.To), lower(rook))
+		}
+		m = NewCastles(col, j.From, rook, kingsside)
+	case flags[FlagEnpassant]:
+		epRank := 6
+		if col == colour.Black {
+			epRank = 3
+		}
+		if j.Piece != piece.PAWN || j.Captured == nil || *j.Captured != piece.PAWN || j.Promotion != nil || j.To.Rank() != epRank {
+			return Move{}, fmt.Errorf("invalid enpassant move %s%s", lower(j.From), lower(j.To))
+		}
+		m = NewEpCapture(col, j.From, j.To)
+	case flags[FlagPromotion]:
+		promotion := *j.Promotion
+		if j.Piece != piece.PAWN || (j.To.Rank() != 8 && j.To.Rank() != 1) || promotion == piece.PAWN || promotion == piece.KING {
+			return Move{}, fmt.Errorf("invalid promotion %s%s to %s", lower(j.From), lower(j.To), promotion.String(col))
+		}
+		if j.Captured != nil {
+			m = NewPromotionCapture(col, j.From, j.To, promotion, *j.Captured)
+		} else {
+			m = NewPromotion(col, j.From, j.To, promotion)
+		}
+	case j.Captured != nil:
+		m = NewCapture(col, j.From, j.To, j.Piece, *j.Captured)
+	default:
+		m = New(col, j.From, j.To, j.Piece)
+	}
+
+	if j.Uci != m.UciString() && j.Uci != m.Chess960UciString() {
+		return Move{}, fmt.Errorf("uci '%s' does not match move %s", j.Uci, m.String())
+	}
+	return m, nil
+}
+
+// lower returns the square in lowercase, as in the JSON representation
+func lower(sq square.Square) string {
+	return strings.ToLower(sq.String())
+}
+
+// MarshalJSON implements json.Marshaler, see JSON
+func (m Move) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.ToJSON())
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see JSON and JSON.Move. Unknown fields are rejected
+func (m *Move) UnmarshalJSON(data []byte) error {
+	var j JSON
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&j); err != nil {
+		return fmt.Errorf("invalid move: %w", err)
+	}
+	decoded, err := j.Move()
+	if err != nil {
+		return fmt.Errorf("invalid move: %w", err)
+	}
+	*m = decoded
+	return nil
+}
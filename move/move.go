@@ -2,6 +2,7 @@ package move
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/rjo67/chess/bitset"
 	"github.com/rjo67/chess/piece"
@@ -10,12 +11,6 @@ import (
 	"github.com/rjo67/chess/square"
 )
 
-// various masks to access bits in 'castlingInfo'
-var myColourKingssideMask uint32 = 0x1
-var myColourQueenssideMask = myColourKingssideMask << 1
-var opponentsColourKingssideMask uint32 = 0x4
-var opponentsColourQueenssideMask = opponentsColourKingssideMask << 1
-
 var fromSquareMask uint32 = 0x3F                                  // bits 1-6
 var toSquareMask uint32 = 0xFC0                                   // bits 7-12
 var castlingKingssideMask uint32 = 0x1000                         // bit 13
@@ -25,8 +20,10 @@ var movingPieceMask uint32 = 0x1C000                              // bit 15-17
 var enpassantMask = castlingQueenssideMask << 4                   // bit 18
 var promotionMask = enpassantMask << 1                            // bit 19
 var promotionPieceMask uint32 = 0x180000                          // bit 20..21
+var castlingRookMask uint32 = 0x7E00000                           // bit 22..27
 
-// Move stores information about a move.
+// Move stores information about a move. A move is an immutable value, the state required to undo a move
+// (e.g. the previous castling rights) is kept by the position.
 // info: bits 1..6  'from' square   (0..63)
 //       bits 7..12 'to' square     (0..63)
 //       bits 13-14 if the move was castles: King-side (1), Queen-side (2)
@@ -34,13 +31,9 @@ var promotionPieceMask uint32 = 0x180000                          // bit 20..21
 //       bit 18 if the move was enpassant
 //       bit 19 if the move was promotion
 //       bits 20-21 promotion piece type
-//
-// Castling info is stored in the int 'castlingInfo':
-//   Bit 1, 2: whether could castle kingsside/queensside before making this move (mask: myColourKingssideMask, myColourQueenssideMask)
-//   Bit 3, 4: whether OPPONENT could castle kingsside/queenside before making this move (mask: opponentsColourKingssideMask, opponentsColourQueensssideMask)
+//       bits 22-27 if the move was castles: start square of the rook (0..63)
 type Move struct {
 	info                      uint32         // info about the move (see above)
-	castlingInfo              uint32         // stores if we or opponent could castle before making this move. See description above (set during posn.MakeMove)
 	capturedPiece             *piece.Piece   // set if capture
 	enpassantPawnRealLocation *bitset.BitSet // set if e.p., contains the 'real' square where the pawn was, e.g. move.To()==E6, enpassantPawnRealLocation==E5
 	enpassantSquare           *square.Square // set to the enpassant square if this move is a pawn move from rank2 to rank4
@@ -105,25 +98,32 @@ func NewPromotionCapture(col colour.Colour, from, to square.Square, toPiece piec
 
 // CastleKingsSide creates O-O
 func CastleKingsSide(col colour.Colour) Move {
-	var m Move
 	if col == colour.White {
-		m = New(col, square.E1, square.G1, piece.KING)
-	} else {
-		m = New(col, square.E8, square.G8, piece.KING)
+		return NewCastles(col, square.E1, square.H1, true)
 	}
-	m.info |= castlingKingssideMask
-	return m
+	return NewCastles(col, square.E8, square.H8, true)
 }
 
 // CastleQueensSide creates O-O-O
 func CastleQueensSide(col colour.Colour) Move {
-	var m Move
 	if col == colour.White {
-		m = New(col, square.E1, square.C1, piece.KING)
+		return NewCastles(col, square.E1, square.A1, false)
+	}
+	return NewCastles(col, square.E8, square.A8, false)
+}
+
+// NewCastles creates a castling move with the king and rook on the given squares (which can be anywhere on the back rank in Chess960).
+// As in normal chess, the king ends up on the g- or c-file and the rook on the f- or d-file.
+func NewCastles(col colour.Colour, kingFrom, rookFrom square.Square, kingsside bool) Move {
+	var m Move
+	if kingsside {
+		m = New(col, kingFrom, square.FromRankAndFile(kingFrom.Rank(), 7), piece.KING)
+		m.info |= castlingKingssideMask
 	} else {
-		m = New(col, square.E8, square.C8, piece.KING)
+		m = New(col, kingFrom, square.FromRankAndFile(kingFrom.Rank(), 3), piece.KING)
+		m.info |= castlingQueenssideMask
 	}
-	m.info |= castlingQueenssideMask
+	m.info |= uint32(rookFrom-1) << 21
 	return m
 }
 
@@ -161,6 +161,19 @@ func (m Move) HasEnpassantSquare() bool { return m.enpassantSquare != nil }
 // EnpassantSquare returns the enpassant square
 func (m Move) EnpassantSquare() square.Square { return *m.enpassantSquare }
 
+// CastlingRookFrom returns the start square of the rook (only call if IsCastles()==true)
+func (m Move) CastlingRookFrom() square.Square {
+	return square.Square((m.info&castlingRookMask)>>21 + 1)
+}
+
+// CastlingRookTo returns the target square of the rook (only call if IsCastles()==true)
+func (m Move) CastlingRookTo() square.Square {
+	if m.IsKingsSideCastles() {
+		return square.FromRankAndFile(m.From().Rank(), 6)
+	}
+	return square.FromRankAndFile(m.From().Rank(), 4)
+}
+
 // CapturedPiece returns the captured piece (only call if IsCapture()==true)
 func (m Move) CapturedPiece() piece.Piece { return *m.capturedPiece }
 
@@ -176,38 +189,39 @@ func (m Move) To() square.Square { return square.Square((m.info&toSquareMask)>>6
 // PieceType returns the move's piece
 func (m Move) PieceType() piece.Piece { return piece.Piece((m.info & movingPieceMask) >> 14) }
 
-// CouldCastleBeforeMove returns true if it was possible to castle before this move
-func (m Move) CouldCastleBeforeMove(kingsside bool) bool {
-	if kingsside {
-		return m.castlingInfo&myColourKingssideMask == myColourKingssideMask
-	}
-	return m.castlingInfo&myColourQueenssideMask == myColourQueenssideMask
+// Mirror returns the move mirrored about the horizontal axis between the 4th and 5th ranks, i.e. the corresponding move
+// of the other side in the mirrored position (see position.Mirror)
+func (m Move) Mirror() Move {
+	return m.transform(square.Square.Mirror, bitset.BitSet.FlipVertical)
 }
 
-// SetCastleBeforeMove sets the flag indicating whether it was possible to castle before this move
-func (m *Move) SetCastleBeforeMove(kingsside bool) {
-	if kingsside {
-		m.castlingInfo |= myColourKingssideMask
-	} else {
-		m.castlingInfo |= myColourQueenssideMask
+// FlipFile returns the move mirrored about the vertical axis between the d- and e-files.
+// Castling moves are returned unchanged, since castling is not symmetrical in this direction.
+func (m Move) FlipFile() Move {
+	if m.IsCastles() {
+		return m
 	}
+	return m.transform(square.Square.FlipFile, bitset.BitSet.MirrorHorizontal)
 }
 
-// OpponentCouldCastleBeforeMove returns true if it was possible FOR THE OTHER SIDE to castle before this move
-func (m Move) OpponentCouldCastleBeforeMove(kingsside bool) bool {
-	if kingsside {
-		return m.castlingInfo&opponentsColourKingssideMask == opponentsColourKingssideMask
+// transform applies the given functions to all squares of the move
+func (m Move) transform(transformSquare func(square.Square) square.Square, transformBitSet func(bitset.BitSet) bitset.BitSet) Move {
+	t := m
+	t.info &^= fromSquareMask | toSquareMask | castlingRookMask
+	t.info |= uint32(transformSquare(m.From()) - 1)
+	t.info |= uint32(transformSquare(m.To())-1) << 6
+	if m.IsCastles() {
+		t.info |= uint32(transformSquare(m.CastlingRookFrom())-1) << 21
 	}
-	return m.castlingInfo&opponentsColourQueenssideMask == opponentsColourQueenssideMask
-}
-
-// SetOpponentCastleBeforeMove sets the flag indicating whether it was possible FOR THE OTHER SIDE to castle before this move
-func (m *Move) SetOpponentCastleBeforeMove(kingsside bool) {
-	if kingsside {
-		m.castlingInfo |= opponentsColourKingssideMask
-	} else {
-		m.castlingInfo |= opponentsColourQueenssideMask
+	if m.enpassantSquare != nil {
+		sq := transformSquare(*m.enpassantSquare)
+		t.enpassantSquare = &sq
+	}
+	if m.enpassantPawnRealLocation != nil {
+		bs := transformBitSet(*m.enpassantPawnRealLocation)
+		t.enpassantPawnRealLocation = &bs
 	}
+	return t
 }
 
 func (m Move) String() string {
@@ -226,6 +240,24 @@ func (m Move) String() string {
 	return fmt.Sprintf("%s%s%s", m.From().String(), m.To().String(), promotion)
 }
 
+// UciString returns the move in the notation of the UCI protocol, e.g. "e2e4", "e7e8q" or "e1g1" for O-O
+func (m Move) UciString() string {
+	var promotion string
+	if m.IsPromotion() {
+		promotion = m.PromotedPiece().String(colour.Black)
+	}
+	return strings.ToLower(m.From().String()+m.To().String()) + promotion
+}
+
+// Chess960UciString returns the move in the notation of the UCI protocol for Chess960,
+// where castling is given as 'king takes rook', e.g. "e1h1" for O-O
+func (m Move) Chess960UciString() string {
+	if m.IsCastles() {
+		return strings.ToLower(m.From().String() + m.CastlingRookFrom().String())
+	}
+	return m.UciString()
+}
+
 // Search2 implements the algorithm as described in secition 2 of http://www.craftychess.com/hyatt/bitmaps.html
 // i.e. using 'normal' bitmaps.
 // The returned bitset contains all possible squares which can be moved to in the given direction.
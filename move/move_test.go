@@ -1,6 +1,7 @@
 package move
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"testing"
@@ -79,48 +80,6 @@ func TestPieceType(t *testing.T) {
 	}
 }
 
-func TestCastlingBits(t *testing.T) {
-	m := New(colour.White, square.A5, square.B7, piece.KNIGHT)
-	if m.CouldCastleBeforeMove(true) {
-		t.Fatalf("castling not possible")
-	}
-	m.SetCastleBeforeMove(true)
-	if !m.CouldCastleBeforeMove(true) {
-		t.Fatalf("castling should be possible")
-	}
-	if m.CouldCastleBeforeMove(false) {
-		t.Fatalf("castling not possible")
-	}
-	m.SetCastleBeforeMove(false)
-	if !m.CouldCastleBeforeMove(false) {
-		t.Fatalf("castling should be possible")
-	}
-
-	// opponent info
-
-	if m.OpponentCouldCastleBeforeMove(true) {
-		t.Fatalf("opponent castling not possible")
-	}
-	if m.OpponentCouldCastleBeforeMove(false) {
-		t.Fatalf("opponent castling not possible")
-	}
-
-	m.SetOpponentCastleBeforeMove(true)
-	if !m.OpponentCouldCastleBeforeMove(true) {
-		t.Fatalf("opponent castling should be possible")
-	}
-	if m.OpponentCouldCastleBeforeMove(false) {
-		t.Fatalf("opponent castling not possible")
-	}
-	m.SetOpponentCastleBeforeMove(false)
-	if !m.OpponentCouldCastleBeforeMove(true) {
-		t.Fatalf("opponent castling should be possible")
-	}
-	if !m.OpponentCouldCastleBeforeMove(false) {
-		t.Fatalf("opponent castling should be possible")
-	}
-}
-
 func TestSearch(t *testing.T) {
 	occupiedSquares := bitset.NewFromByteArray([8]byte{0x00, 0x00, 0x40, 0x00, 0x20, 0x80, 0x02, 0x10})
 	/*
@@ -171,3 +130,120 @@ func checkBits(testNbr int, bs bitset.BitSet, expectedSetBits []int, t *testing.
 		t.Errorf("test %d: found %d errors (%v) for bitset:\n%s", testNbr, len(errors), errors, bs.String())
 	}
 }
+
+func TestMirror(t *testing.T) {
+	data := []struct {
+		m, mirrored, flipped Move
+	}{
+		{New(colour.White, square.E2, square.E4, piece.PAWN), New(colour.Black, square.E7, square.E5, piece.PAWN), New(colour.White, square.D2, square.D4, piece.PAWN)},
+		{NewCapture(colour.White, square.B1, square.C3, piece.KNIGHT, piece.BISHOP), NewCapture(colour.Black, square.B8, square.C6, piece.KNIGHT, piece.BISHOP),
+			NewCapture(colour.White, square.G1, square.F3, piece.KNIGHT, piece.BISHOP)},
+		{NewEpCapture(colour.White, square.E5, square.D6), NewEpCapture(colour.Black, square.E4, square.D3), NewEpCapture(colour.White, square.D5, square.E6)},
+		{NewPromotionCapture(colour.Black, square.B2, square.A1, piece.QUEEN, piece.ROOK), NewPromotionCapture(colour.White, square.B7, square.A8, piece.QUEEN, piece.ROOK),
+			NewPromotionCapture(colour.Black, square.G2, square.H1, piece.QUEEN, piece.ROOK)},
+		{CastleQueensSide(colour.White), CastleQueensSide(colour.Black), CastleQueensSide(colour.White)},
+		{NewCastles(colour.Black, square.B8, square.G8, true), NewCastles(colour.White, square.B1, square.G1, true), NewCastles(colour.Black, square.B8, square.G8, true)},
+	}
+	for _, d := range data {
+		if got := d.m.Mirror(); !reflect.DeepEqual(got, d.mirrored) {
+			t.Errorf("Mirror(%s): expected %s but got %s", d.m.String(), d.mirrored.String(), got.String())
+		}
+		if got := d.m.FlipFile(); !reflect.DeepEqual(got, d.flipped) {
+			t.Errorf("FlipFile(%s): expected %s but got %s", d.m.String(), d.flipped.String(), got.String())
+		}
+		if got := d.m.Mirror().Mirror(); !reflect.DeepEqual(got, d.m) {
+			t.Errorf("Mirror should be self-inverse for %s", d.m.String())
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	data := []struct {
+		m        Move
+		from, to square.Square
+		flags    Encoded
+	}{
+		{New(colour.White, square.E2, square.E4, piece.PAWN), square.E2, square.E4, EncodedNormal},
+		{NewCapture(colour.Black, square.B8, square.C6, piece.KNIGHT, piece.BISHOP), square.B8, square.C6, EncodedNormal},
+		{NewEpCapture(colour.White, square.E5, square.D6), square.E5, square.D6, EncodedEnpassant},
+		{NewPromotionCapture(colour.Black, square.B2, square.A1, piece.KNIGHT, piece.ROOK), square.B2, square.A1, EncodedPromotion},
+		{NewPromotion(colour.White, square.H7, square.H8, piece.QUEEN), square.H7, square.H8, EncodedPromotion},
+		{CastleQueensSide(colour.White), square.E1, square.A1, EncodedCastles},
+		{NewCastles(colour.Black, square.B8, square.G8, true), square.B8, square.G8, EncodedCastles},
+	}
+	for _, d := range data {
+		e := d.m.Encode()
+		if e.From() != d.from || e.To() != d.to || e.Flags() != d.flags {
+			t.Errorf("move %s: unexpected encoding %016b", d.m.String(), e)
+		}
+		if d.m.IsPromotion() && e.PromotedPiece() != d.m.PromotedPiece() {
+			t.Errorf("move %s: expected promotion piece %d but got %d", d.m.String(), d.m.PromotedPiece(), e.PromotedPiece())
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data := []struct {
+		m        Move
+		expected string
+	}{
+		{New(colour.White, square.E2, square.E4, piece.PAWN), `{"uci":"e2e4","from":"e2","to":"e4","piece":"pawn"}`},
+		{NewCapture(colour.Black, square.B8, square.C6, piece.KNIGHT, piece.BISHOP),
+			`{"uci":"b8c6","from":"b8","to":"c6","piece":"knight","captured":"bishop","flags":["capture"]}`},
+		{NewEpCapture(colour.Black, square.E4, square.D3),
+			`{"uci":"e4d3","from":"e4","to":"d3","piece":"pawn","captured":"pawn","flags":["capture","enpassant"]}`},
+		{NewPromotionCapture(colour.White, square.E7, square.F8, piece.QUEEN, piece.ROOK),
+			`{"uci":"e7f8q","from":"e7","to":"f8","piece":"pawn","captured":"rook","promotion":"queen","flags":["capture","promotion"]}`},
+		{NewPromotion(colour.Black, square.H2, square.H1, piece.KNIGHT),
+			`{"uci":"h2h1n","from":"h2","to":"h1","piece":"pawn","promotion":"knight","flags":["promotion"]}`},
+		{CastleQueensSide(colour.White), `{"uci":"e1c1","from":"e1","to":"c1","piece":"king","rook":"a1","flags":["castles"]}`},
+		{NewCastles(colour.Black, square.B8, square.H8, true), `{"uci":"b8g8","from":"b8","to":"g8","piece":"king","rook":"h8","flags":["castles"]}`},
+	}
+	for _, d := range data {
+		got, err := json.Marshal(d.m)
+		if err != nil {
+			t.Fatalf("move %s: unexpected error: %s", d.m.String(), err)
+		}
+		if string(got) != d.expected {
+			t.Errorf("move %s: expected %s but got %s", d.m.String(), d.expected, got)
+		}
+		var decoded Move
+		if err := json.Unmarshal(got, &decoded); err != nil {
+			t.Fatalf("move %s: unexpected error: %s", d.m.String(), err)
+		}
+		if !reflect.DeepEqual(decoded, d.m) {
+			t.Errorf("move %s: got %s after decoding", d.m.String(), decoded.String())
+		}
+	}
+	// Chess960 notation of castling is also accepted
+	var m Move
+	if err := json.Unmarshal([]byte(`{"uci":"b8h8","from":"b8","to":"g8","piece":"king","rook":"h8","flags":["castles"]}`), &m); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	for _, str := range []string{
+		`[]`,
+		`{"uci":"e2e4","from":"e2","to":"e4","piece":"pawn","extra":1}`,
+		`{"uci":"e2e4","from":"E2","to":"e4","piece":"pawn"}`,
+		`{"uci":"e2e4","from":"e2","to":"e4","piece":"Pawn"}`,
+		`{"uci":"e2e4","to":"e4","piece":"pawn"}`,
+		`{"uci":"e2e4","from":"e2","to":"e2","piece":"pawn"}`,
+		`{"uci":"e2e5","from":"e2","to":"e5","piece":"pawn"}`,
+		`{"uci":"e2e3","from":"e2","to":"e4","piece":"pawn"}`,
+		`{"uci":"e2e4","from":"e2","to":"e4","piece":"pawn","flags":["check"]}`,
+		`{"uci":"e2e4","from":"e2","to":"e4","piece":"pawn","flags":["capture"]}`,
+		`{"uci":"e4d5","from":"e4","to":"d5","piece":"pawn","captured":"king","flags":["capture"]}`,
+		`{"uci":"e7e8","from":"e7","to":"e8","piece":"pawn","flags":["promotion"]}`,
+		`{"uci":"e7e8k","from":"e7","to":"e8","piece":"pawn","promotion":"king","flags":["promotion"]}`,
+		`{"uci":"e5d6","from":"e5","to":"d6","piece":"pawn","flags":["enpassant"]}`,
+		`{"uci":"e1g1","from":"e1","to":"g1","piece":"king","flags":["castles"]}`,
+		`{"uci":"e1g1","from":"e1","to":"g1","piece":"king","rook":"a1","flags":["castles"]}`,
+	} {
+		var m Move
+		if err := json.Unmarshal([]byte(str), &m); err == nil {
+			t.Errorf("expected error for %s", str)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Package opening classifies openings by their ECO (Encyclopaedia of Chess Openings) code and name.
+// The classification compares positions rather than move sequences, so transpositions are recognised.
+package opening
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/position"
+)
+
+// the ECO table, see the comment at the start of the file for the format
+//
+//go:embed eco.tsv
+var ecoTable string
+
+// Opening is one line of the ECO table
+type Opening struct {
+	ECO   string   // e.g. "C60"
+	Name  string   // e.g. "Ruy Lopez"
+	Moves []string // the moves of the line in SAN, from the start position
+	Fen   string   // the position after the moves
+}
+
+func (o Opening) String() string {
+	return o.ECO + " " + o.Name
+}
+
+var (
+	loadOnce sync.Once
+	openings []Opening
+	byKey    map[uint64]int   // index of the opening by the key of its position
+	byCode   map[string][]int // indices of the openings with the ECO code
+)
+
+// load parses the ECO table when it is first needed. The table is part of the package, so errors are fatal.
+func load() {
+	loadOnce.Do(func() {
+		var err error
+		if openings, byKey, byCode, err = parseTable(ecoTable); err != nil {
+			panic(err)
+		}
+	})
+}
+
+// parseTable parses the lines of the ECO table and plays the moves of each line
+func parseTable(table string) ([]Opening, map[uint64]int, map[string][]int, error) {
+	var list []Opening
+	keys := make(map[uint64]int)
+	codes := make(map[string][]int)
+	for i, line := range strings.Split(table, "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 || len(fields[0]) != 3 || fields[1] == "" {
+			return nil, nil, nil, fmt.Errorf("ECO table line %d: invalid line '%s'", i+1, line)
+		}
+		o := Opening{ECO: fields[0], Name: fields[1], Moves: strings.Fields(fields[2])}
+		posn := position.StartPosition()
+		for _, san := range o.Moves {
+			m, err := posn.ParseSan(san)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("ECO table line %d: %w", i+1, err)
+			}
+			posn.MakeMove(m)
+		}
+		o.Fen = posn.Fen()
+		if j, ok := keys[posn.Key()]; ok {
+			return nil, nil, nil, fmt.Errorf("ECO table line %d: same position as %s", i+1, list[j])
+		}
+		keys[posn.Key()] = len(list)
+		codes[o.ECO] = append(codes[o.ECO], len(list))
+		list = append(list, o)
+	}
+	return list, keys, codes, nil
+}
+
+// Openings returns all lines of the ECO table, ordered by ECO code
+func Openings() []Opening {
+	load()
+	return append([]Opening(nil), openings...)
+}
+
+// ByCode returns the lines with the given ECO code (e.g. "B90") in the order of the table; nil if there are none
+func ByCode(eco string) []Opening {
+	load()
+	var result []Opening
+	for _, i := range byCode[strings.ToUpper(eco)] {
+		result = append(result, openings[i])
+	}
+	return result
+}
+
+// ClassifyPosition returns the line of the ECO table leading to the position, if any.
+// Only the position itself is compared (see position.Key), not the moves which led to it.
+func ClassifyPosition(posn position.Position) (Opening, bool) {
+	load()
+	if i, ok := byKey[posn.Key()]; ok {
+		return openings[i], true
+	}
+	return Opening{}, false
+}
+
+// Classify returns the deepest line of the ECO table reached by the moves from the start position, i.e. the line
+// of the last position of the game found in the table. Returns false if none of the positions is in the table.
+// The moves are made on a copy of the start position.
+func Classify(start position.Position, moves []move.Move) (Opening, bool) {
+	posn, err := position.ParseFen(start.Fen())
+	if err != nil {
+		return Opening{}, false
+	}
+	best, found := ClassifyPosition(posn)
+	for _, m := range moves {
+		posn.MakeMove(m)
+		if o, ok := ClassifyPosition(posn); ok {
+			best, found = o, true
+		}
+	}
+	return best, found
+}
+
+// ClassifySan is like Classify for a game from the start position with the moves in SAN, e.g. as read from a PGN file.
+// Returns an error if one of the moves is illegal.
+func ClassifySan(sans []string) (Opening, bool, error) {
+	posn := position.StartPosition()
+	moves := make([]move.Move, 0, len(sans))
+	for i, san := range sans {
+		m, err := posn.ParseSan(san)
+		if err != nil {
+			return Opening{}, false, fmt.Errorf("move %d: %w", i+1, err)
+		}
+		moves = append(moves, m)
+		posn.MakeMove(m)
+	}
+	o, ok := Classify(position.StartPosition(), moves)
+	return o, ok, nil
+}
@@ -0,0 +1,128 @@
+package opening
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/position"
+)
+
+func TestTable(t *testing.T) {
+	all := Openings()
+	if len(all) < 200 {
+		t.Errorf("expected at least 200 lines but got %d", len(all))
+	}
+	if !sort.SliceIsSorted(all, func(i, j int) bool { return all[i].ECO < all[j].ECO }) {
+		t.Errorf("table is not ordered by ECO code")
+	}
+	for _, o := range all {
+		if o.ECO[0] < 'A' || o.ECO[0] > 'E' || strings.Trim(o.ECO[1:], "0123456789") != "" {
+			t.Errorf("invalid ECO code: %s", o)
+		}
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	for _, table := range []string{
+		"C20\tKing's Pawn Game\te4 e6x\n",
+		"C20\tKing's Pawn Game\n",
+		"C2\tKing's Pawn Game\te4 e5\n",
+		"C20\tKing's Pawn Game\te4 e5\nC20\tAgain\tNf3 Nc6 Ng1 Nb8 e4 e5\n",
+	} {
+		if _, _, _, err := parseTable(table); err == nil {
+			t.Errorf("expected error for table '%s'", table)
+		}
+	}
+}
+
+func TestClassifySan(t *testing.T) {
+	for _, d := range []struct {
+		moves string
+		eco   string
+		name  string
+	}{
+		{"e4", "B00", "King's Pawn Game"},
+		{"e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 a6", "B90", "Sicilian Defence: Najdorf Variation"},
+		{"e4 c5 Nf3 d6 d4 cxd4 Nxd4 Nf6 Nc3 a6 Bg5 e6 f4", "B90", "Sicilian Defence: Najdorf Variation"},
+		{"e4 e5 Nf3 Nc6 Bb5 a6 Ba4 Nf6 O-O Be7 Re1 b5 Bb3 O-O c3 d5 exd5 Nxd5", "C89", "Ruy Lopez: Marshall Attack"},
+		{"e4 e5 Nf3 Nc6 Bb5 Nf6 O-O Nxe4 d4 Nd6 Bxc6 dxc6 dxe5 Nf5 Qxd8+ Kxd8", "C67", "Ruy Lopez: Berlin Defence, Berlin Wall"},
+		{"e4 e6 d4 d5 Nc3 Bb4", "C15", "French Defence: Winawer Variation"},
+		{"e4 c6 d4 d5 e5 Bf5", "B12", "Caro-Kann Defence: Advance Variation"},
+		{"d4 Nf6 c4 g6 Nc3 d5 cxd5 Nxd5 e4 Nxc3 bxc3 Bg7", "D85", "Grünfeld Defence: Exchange Variation, Main Line"},
+		{"d4 Nf6 c4 e6 Nc3 Bb4 Qc2 O-O", "E32", "Nimzo-Indian Defence: Classical Variation"},
+		{"d4 Nf6 c4 g6 Nc3 Bg7 e4 d6 Nf3 O-O Be2 e5 O-O Nc6 d5 Ne7", "E97", "King's Indian Defence: Mar del Plata Variation"},
+		{"c4 e5 Nc3 Nf6", "A22", "English Opening: King's English Variation, Two Knights Variation"},
+		// transpositions
+		{"Nf3 d5 d4", "D02", "Queen's Pawn Game: Zukertort Variation"},
+		{"c4 e6 Nc3 d5 d4", "D31", "Queen's Gambit Declined: Queen's Knight Variation"},
+		{"d4 Nf6 c4 e6 Nf3 d5 Nc3", "D37", "Queen's Gambit Declined: Three Knights Variation"},
+		{"Nf3 Nf6 c4 g6 Nc3 Bg7 d4 d6 e4", "E90", "King's Indian Defence: Classical Variation"},
+		{"e4 e5 Nc3 Nf6 Nf3 Nc6", "C47", "Four Knights Game"},
+		{"e4 e5 Bc4 Nc6 Nf3 Nf6", "C55", "Italian Game: Two Knights Defence"},
+	} {
+		o, ok, err := ClassifySan(strings.Fields(d.moves))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", d.moves, err)
+			continue
+		}
+		if !ok || o.ECO != d.eco || o.Name != d.name {
+			t.Errorf("%s: expected %s %s but got %s (%v)", d.moves, d.eco, d.name, o, ok)
+		}
+	}
+
+	if _, ok, err := ClassifySan(nil); ok || err != nil {
+		t.Errorf("expected no classification for no moves")
+	}
+	if _, _, err := ClassifySan([]string{"e4", "e4"}); err == nil {
+		t.Errorf("expected error for illegal move")
+	}
+}
+
+func TestClassifyFromPosition(t *testing.T) {
+	start, err := position.ParseFen("rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	m, err := start.ParseSan("Nc6")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	fen := start.Fen()
+	o, ok := Classify(start, []move.Move{m})
+	if !ok || o.ECO != "C44" {
+		t.Errorf("expected C44 but got %s", o)
+	}
+	if start.Fen() != fen {
+		t.Errorf("start position has been changed")
+	}
+	// the start position itself is classified if no move reaches the table
+	o, ok = Classify(start, nil)
+	if !ok || o.Name != "King's Knight Opening" {
+		t.Errorf("expected King's Knight Opening but got %s", o)
+	}
+}
+
+func TestClassifyPosition(t *testing.T) {
+	posn, err := position.ParseFen("r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b KQkq - 3 3")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if o, ok := ClassifyPosition(posn); !ok || o.String() != "C60 Ruy Lopez" || o.Fen != posn.Fen() {
+		t.Errorf("expected C60 Ruy Lopez but got %s, %s", o, o.Fen)
+	}
+	if _, ok := ClassifyPosition(position.StartPosition()); ok {
+		t.Errorf("unexpected classification of the start position")
+	}
+}
+
+func TestByCode(t *testing.T) {
+	lines := ByCode("c50")
+	if len(lines) != 4 || lines[0].Name != "Italian Game" || strings.Join(lines[0].Moves, " ") != "e4 e5 Nf3 Nc6 Bc4" {
+		t.Errorf("unexpected lines for C50: %v", lines)
+	}
+	if lines := ByCode("Z99"); lines != nil {
+		t.Errorf("unexpected lines for Z99: %v", lines)
+	}
+}
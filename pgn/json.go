@@ -0,0 +1,36 @@
+package pgn
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// UnmarshalJSON implements json.Unmarshaler. Unknown fields are rejected, and the result must be one of
+// WhiteWins, BlackWins, Draw or Unfinished. The moves must not be empty or contain whitespace; whether they are
+// legal is not checked.
+func (g *Game) UnmarshalJSON(data []byte) error {
+	type plainGame Game // without the UnmarshalJSON method
+	var game plainGame
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&game); err != nil {
+		return fmt.Errorf("invalid game: %w", err)
+	}
+	switch game.Result {
+	case WhiteWins, BlackWins, Draw, Unfinished:
+	default:
+		return fmt.Errorf("invalid game: unrecognised result '%s'", game.Result)
+	}
+	for i, m := range game.Moves {
+		if m == "" || strings.ContainsAny(m, " \t\r\n") {
+			return fmt.Errorf("invalid game: bad move '%s' at index %d", m, i)
+		}
+	}
+	if game.Tags == nil {
+		game.Tags = make(map[string]string)
+	}
+	*g = Game(game)
+	return nil
+}
@@ -0,0 +1,228 @@
+package pgn
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// the possible game results
+const (
+	WhiteWins  = "1-0"
+	BlackWins  = "0-1"
+	Draw       = "1/2-1/2"
+	Unfinished = "*"
+)
+
+// Game stores the information about one game read from a PGN file
+// https://en.wikipedia.org/wiki/Portable_Game_Notation
+// A game can be converted to and from JSON, e.g. {"tags":{"White":"Kasparov, Garry"},"moves":["e4","e5"],"result":"*"}
+type Game struct {
+	Tags   map[string]string `json:"tags"`   // the tag pairs, e.g. "White" -> "Kasparov, Garry"
+	Moves  []string          `json:"moves"`  // the moves of the main line in SAN, without move numbers, comments, NAGs or variations
+	Result string            `json:"result"` // result as given in the movetext (or the 'Result' tag if the movetext did not specify a result)
+}
+
+// Reader reads games from PGN input. Create with NewReader
+type Reader struct {
+	r    *bufio.Reader
+	line int // current line nbr, for error messages
+}
+
+// NewReader returns a reader for PGN input
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), line: 1}
+}
+
+// ReadAll reads all games from the given PGN input
+func ReadAll(r io.Reader) ([]*Game, error) {
+	reader := NewReader(r)
+	games := make([]*Game, 0, 10)
+	for {
+		game, err := reader.Next()
+		if err == io.EOF {
+			return games, nil
+		}
+		if err != nil {
+			return games, err
+		}
+		games = append(games, game)
+	}
+}
+
+// Next returns the next game. Returns io.EOF if there are no more games
+func (r *Reader) Next() (*Game, error) {
+	game := &Game{Tags: make(map[string]string), Moves: make([]string, 0, 80)}
+	started := false // set as soon as a tag or movetext has been read
+	seenMoves := false
+	variationDepth := 0
+	for {
+		ch, err := r.readRune()
+		if err == io.EOF {
+			if !started {
+				return nil, io.EOF
+			}
+			return r.finish(game), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case unicode.IsSpace(ch):
+			// skip
+		case ch == '[' && variationDepth == 0:
+			if seenMoves {
+				// a new game has started without a result in the previous one
+				r.r.UnreadRune()
+				return r.finish(game), nil
+			}
+			name, value, err := r.readTag()
+			if err != nil {
+				return nil, err
+			}
+			game.Tags[name] = value
+			started = true
+		case ch == '{':
+			if err := r.skipUntil('}'); err != nil {
+				return nil, err
+			}
+		case ch == ';':
+			if err := r.skipUntil('\n'); err != nil && err != io.EOF {
+				return nil, err
+			}
+		case ch == '%':
+			// escape mechanism: rest of line is ignored
+			if err := r.skipUntil('\n'); err != nil && err != io.EOF {
+				return nil, err
+			}
+		case ch == '(':
+			variationDepth++
+		case ch == ')':
+			if variationDepth == 0 {
+				return nil, r.errorf("unexpected ')'")
+			}
+			variationDepth--
+		default:
+			token := r.readToken(ch)
+			started = true
+			if variationDepth > 0 || strings.HasPrefix(token, "$") {
+				// ignore moves in variations and NAGs
+				continue
+			}
+			switch token {
+			case WhiteWins, BlackWins, Draw, Unfinished:
+				game.Result = token
+				return r.finish(game), nil
+			}
+			// strip move numbers, e.g. "12." or "12..." (possibly directly followed by the move)
+			// (castling may have been written as "0-0", therefore the '.' is required)
+			if i := strings.IndexFunc(token, func(c rune) bool { return c < '0' || c > '9' }); i > 0 && token[i] == '.' {
+				token = strings.TrimLeft(token[i:], ".")
+			}
+			if token != "" {
+				game.Moves = append(game.Moves, token)
+				seenMoves = true
+			}
+		}
+	}
+}
+
+// finish sets the game result from the 'Result' tag if not specified in the movetext
+func (r *Reader) finish(game *Game) *Game {
+	if game.Result == "" {
+		if result, ok := game.Tags["Result"]; ok {
+			game.Result = result
+		} else {
+			game.Result = Unfinished
+		}
+	}
+	return game
+}
+
+func (r *Reader) readRune() (rune, error) {
+	ch, _, err := r.r.ReadRune()
+	if ch == '\n' {
+		r.line++
+	}
+	return ch, err
+}
+
+// skipUntil reads until (and including) the given rune
+func (r *Reader) skipUntil(end rune) error {
+	for {
+		ch, err := r.readRune()
+		if err != nil {
+			if err == io.EOF && end != '\n' {
+				return r.errorf("missing '%c'", end)
+			}
+			return err
+		}
+		if ch == end {
+			return nil
+		}
+	}
+}
+
+// readToken reads a token starting with the given rune. The token ends at whitespace or one of the PGN delimiters
+func (r *Reader) readToken(first rune) string {
+	var sb strings.Builder
+	sb.WriteRune(first)
+	for {
+		ch, err := r.readRune()
+		if err != nil {
+			return sb.String()
+		}
+		if unicode.IsSpace(ch) || strings.ContainsRune("[]{}();", ch) {
+			if ch == '\n' {
+				r.line--
+			}
+			r.r.UnreadRune()
+			return sb.String()
+		}
+		sb.WriteRune(ch)
+	}
+}
+
+// readTag reads a tag pair of the form: [Name "value"]. The opening bracket has already been read
+func (r *Reader) readTag() (string, string, error) {
+	var name, value strings.Builder
+	inValue := false
+	for {
+		ch, err := r.readRune()
+		if err != nil {
+			return "", "", r.errorf("unterminated tag")
+		}
+		if !inValue {
+			switch {
+			case ch == '"':
+				inValue = true
+			case ch == ']' || ch == '\n':
+				return "", "", r.errorf("tag '%s' has no value", name.String())
+			case !unicode.IsSpace(ch):
+				name.WriteRune(ch)
+			}
+			continue
+		}
+		switch ch {
+		case '\\':
+			ch, err = r.readRune()
+			if err != nil {
+				return "", "", r.errorf("unterminated tag")
+			}
+			value.WriteRune(ch)
+		case '"':
+			if err := r.skipUntil(']'); err != nil {
+				return "", "", err
+			}
+			return name.String(), value.String(), nil
+		default:
+			value.WriteRune(ch)
+		}
+	}
+}
+
+func (r *Reader) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("pgn line %d: %s", r.line, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,165 @@
+package pgn
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const twoGames = `[Event "Test"]
+[White "Player, A"]
+[Black "Player \"B\""]
+[Result "1-0"]
+
+1. e4 e5 2. Nf3 {a comment} Nc6 (2... d6 3. d4) 3. Bb5 $1 a6 ; rest of line ignored
+4.Ba4 Nf6 5. O-O 1-0
+
+[Event "Test 2"]
+[Result "1/2-1/2"]
+
+1. d4 d5 2. c4 e6 *
+`
+
+func TestReadAll(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(twoGames))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("expected 2 games but got %d", len(games))
+	}
+	if games[0].Tags["Black"] != `Player "B"` {
+		t.Errorf("bad tag value: '%s'", games[0].Tags["Black"])
+	}
+	expectedMoves := []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Ba4", "Nf6", "O-O"}
+	if !reflect.DeepEqual(games[0].Moves, expectedMoves) {
+		t.Errorf("expected moves %v but got %v", expectedMoves, games[0].Moves)
+	}
+	if games[0].Result != WhiteWins {
+		t.Errorf("bad result: %s", games[0].Result)
+	}
+	if games[1].Result != Unfinished {
+		t.Errorf("bad result: %s", games[1].Result)
+	}
+	if len(games[1].Moves) != 4 {
+		t.Errorf("expected 4 moves but got %v", games[1].Moves)
+	}
+}
+
+func TestMissingResult(t *testing.T) {
+	reader := NewReader(strings.NewReader("[Result \"0-1\"]\n1. f3 e5 2. g4 Qh4#\n[Event \"next\"]\n1. e4 *"))
+	game, err := reader.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if game.Result != BlackWins || len(game.Moves) != 4 {
+		t.Errorf("bad game: %v", game)
+	}
+	game, err = reader.Next()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if game.Tags["Event"] != "next" || len(game.Moves) != 1 {
+		t.Errorf("bad game: %v", game)
+	}
+	if _, err = reader.Next(); err != io.EOF {
+		t.Errorf("expected EOF but got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, input := range []string{"[Event \"x\"", "1. e4 {comment", "1. e4 ) *", "[Event]"} {
+		if _, err := ReadAll(strings.NewReader(input)); err == nil {
+			t.Errorf("expected error for input '%s'", input)
+		}
+	}
+}
+
+func TestGameJSON(t *testing.T) {
+	games, err := ReadAll(strings.NewReader(twoGames))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data, err := json.Marshal(games[1])
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := `{"tags":{"Event":"Test 2","Result":"1/2-1/2"},"moves":["d4","d5","c4","e6"],"result":"*"}`
+	if string(data) != expected {
+		t.Errorf("expected %s but got %s", expected, data)
+	}
+	var game Game
+	if err := json.Unmarshal(data, &game); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(&game, games[1]) {
+		t.Errorf("expected %v but got %v", games[1], game)
+	}
+
+	for _, str := range []string{
+		`{"moves":["e4"],"result":"2-0"}`,
+		`{"moves":["e4"]}`,
+		`{"moves":["e4",""],"result":"*"}`,
+		`{"moves":["e4 e5"],"result":"*"}`,
+		`{"moves":["e4"],"result":"*","event":"x"}`,
+		`{"moves":"e4","result":"*"}`,
+	} {
+		if err := json.Unmarshal([]byte(str), &game); err == nil {
+			t.Errorf("expected error for %s", str)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	game := &Game{Tags: map[string]string{"White": `Player "A"`, "Event": "Test", "Annotator": "x", "ECO": "C60"},
+		Moves: []string{"e4", "e5", "Nf3"}, Result: Unfinished}
+	var sb strings.Builder
+	if err := game.Write(&sb); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := `[Event "Test"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "Player \"A\""]
+[Black "?"]
+[Result "*"]
+[Annotator "x"]
+[ECO "C60"]
+
+1. e4 e5 2. Nf3 *
+
+`
+	if sb.String() != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, sb.String())
+	}
+
+	// black to move in the start position, long movetext
+	game = &Game{Tags: map[string]string{"FEN": "8/8/8/8/8/8/k7/4K2R b K - 0 30", "SetUp": "1"}, Result: Draw}
+	for i := 0; i < 40; i++ {
+		game.Moves = append(game.Moves, "Ka1", "Kf2")
+	}
+	sb.Reset()
+	if err := game.Write(&sb); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(sb.String(), "\n30... Ka1 31. Kf2 Ka1 32. Kf2") {
+		t.Errorf("unexpected movetext:\n%s", sb.String())
+	}
+	for _, line := range strings.Split(sb.String(), "\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line too long: '%s'", line)
+		}
+	}
+	games, err := ReadAll(strings.NewReader(sb.String()))
+	if err != nil || len(games) != 1 {
+		t.Fatalf("cannot read game: %v %s", games, err)
+	}
+	game.Tags["Event"], game.Tags["Site"], game.Tags["Date"], game.Tags["Round"] = "?", "?", "????.??.??", "?"
+	game.Tags["White"], game.Tags["Black"], game.Tags["Result"] = "?", "?", Draw
+	if !reflect.DeepEqual(games[0], game) {
+		t.Errorf("expected %v but got %v", game, games[0])
+	}
+}
@@ -0,0 +1,110 @@
+package pgn
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// the tags of the Seven Tag Roster, which are written first and in this order, with their default values
+var sevenTagRoster = []struct{ name, value string }{
+	{"Event", "?"}, {"Site", "?"}, {"Date", "????.??.??"}, {"Round", "?"}, {"White", "?"}, {"Black", "?"}, {"Result", Unfinished},
+}
+
+// maximum length of a line of movetext
+const maxLineLength = 80
+
+// Write writes the game in PGN export format: the Seven Tag Roster (with default values for missing tags and the
+// 'Result' tag taken from g.Result), the other tags sorted by name, and the movetext with move numbers.
+// The move numbers start at the fullmove number of the 'FEN' tag if present.
+// Games are separated by an empty line, which is written after the movetext.
+func (g *Game) Write(w io.Writer) error {
+	var sb strings.Builder
+	result := g.Result
+	if result == "" {
+		result = Unfinished
+	}
+	for _, tag := range sevenTagRoster {
+		value, ok := g.Tags[tag.name]
+		if !ok || value == "" {
+			value = tag.value
+		}
+		if tag.name == "Result" {
+			value = result
+		}
+		writeTag(&sb, tag.name, value)
+	}
+	var names []string
+	for name := range g.Tags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !isSevenTagRoster(name) {
+			writeTag(&sb, name, g.Tags[name])
+		}
+	}
+	sb.WriteString("\n")
+
+	moveNbr, blackToMove := startMove(g.Tags["FEN"])
+	lineLength := 0
+	addToken := func(token string) {
+		if lineLength > 0 && lineLength+1+len(token) > maxLineLength {
+			sb.WriteString("\n")
+			lineLength = 0
+		}
+		if lineLength > 0 {
+			sb.WriteString(" ")
+			lineLength++
+		}
+		sb.WriteString(token)
+		lineLength += len(token)
+	}
+	for i, m := range g.Moves {
+		switch {
+		case !blackToMove:
+			addToken(fmt.Sprintf("%d. %s", moveNbr, m))
+		case i == 0:
+			addToken(fmt.Sprintf("%d... %s", moveNbr, m))
+		default:
+			addToken(m)
+		}
+		if blackToMove {
+			moveNbr++
+		}
+		blackToMove = !blackToMove
+	}
+	addToken(result)
+	sb.WriteString("\n\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func writeTag(sb *strings.Builder, name, value string) {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	fmt.Fprintf(sb, "[%s \"%s\"]\n", name, value)
+}
+
+func isSevenTagRoster(name string) bool {
+	for _, tag := range sevenTagRoster {
+		if tag.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// startMove returns the number of the first move and whether black moves first, according to the FEN (if given)
+func startMove(fen string) (int, bool) {
+	fields := strings.Fields(fen)
+	moveNbr := 1
+	if len(fields) >= 6 {
+		if n, err := strconv.Atoi(fields[5]); err == nil && n > 0 {
+			moveNbr = n
+		}
+	}
+	return moveNbr, len(fields) >= 2 && fields[1] == "b"
+}
@@ -0,0 +1,31 @@
+package colour
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var colourNames = []string{"white", "black"}
+
+// MarshalJSON implements json.Marshaler, the colour is given as "white" or "black"
+func (c Colour) MarshalJSON() ([]byte, error) {
+	if c != White && c != Black {
+		return nil, fmt.Errorf("cannot marshal colour %d", c)
+	}
+	return json.Marshal(colourNames[c])
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting only "white" or "black"
+func (c *Colour) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("colour must be a string: %w", err)
+	}
+	for i, name := range colourNames {
+		if str == name {
+			*c = Colour(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognised colour '%s'", str)
+}
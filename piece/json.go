@@ -0,0 +1,31 @@
+package piece
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var pieceNames = []string{"pawn", "rook", "knight", "bishop", "queen", "king"}
+
+// MarshalJSON implements json.Marshaler, the piece is given by its name, e.g. "knight"
+func (p Piece) MarshalJSON() ([]byte, error) {
+	if int(p) >= len(pieceNames) {
+		return nil, fmt.Errorf("cannot marshal piece %d", p)
+	}
+	return json.Marshal(pieceNames[p])
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting only the lowercase names of the pieces
+func (p *Piece) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("piece must be a string: %w", err)
+	}
+	for i, name := range pieceNames {
+		if str == name {
+			*p = Piece(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognised piece '%s'", str)
+}
@@ -0,0 +1,199 @@
+package polyglot
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/position"
+)
+
+// entrySize is the size in bytes of one entry in a Polyglot book
+const entrySize = 16
+
+// Entry is one entry of a Polyglot book
+type Entry struct {
+	Key    uint64 // Polyglot key of the position, see Hash
+	Move   uint16 // move encoded in Polyglot format
+	Weight uint16 // relative weight of the move
+	Learn  uint32 // learning information (not used)
+}
+
+// Book stores the entries of a Polyglot book, sorted by key
+type Book struct {
+	entries []Entry
+}
+
+// Open reads the Polyglot book from the given file
+func Open(filename string) (*Book, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Read reads a Polyglot book
+func Read(r io.Reader) (*Book, error) {
+	book := &Book{entries: make([]Entry, 0, 1000)}
+	reader := bufio.NewReader(r)
+	var buf [entrySize]byte
+	for {
+		_, err := io.ReadFull(reader, buf[:])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading book entry %d: %s", len(book.entries)+1, err)
+		}
+		book.entries = append(book.entries, Entry{
+			Key:    binary.BigEndian.Uint64(buf[0:8]),
+			Move:   binary.BigEndian.Uint16(buf[8:10]),
+			Weight: binary.BigEndian.Uint16(buf[10:12]),
+			Learn:  binary.BigEndian.Uint32(buf[12:16]),
+		})
+	}
+	// books should already be sorted, but make sure since Entries depends on this
+	sort.SliceStable(book.entries, func(i, j int) bool { return book.entries[i].Key < book.entries[j].Key })
+	return book, nil
+}
+
+// NewBook creates a book from the given entries
+func NewBook(entries []Entry) *Book {
+	book := &Book{entries: make([]Entry, len(entries))}
+	copy(book.entries, entries)
+	sort.SliceStable(book.entries, func(i, j int) bool { return book.entries[i].Key < book.entries[j].Key })
+	return book
+}
+
+// Write writes the book in Polyglot format
+func (b *Book) Write(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	var buf [entrySize]byte
+	for _, e := range b.entries {
+		binary.BigEndian.PutUint64(buf[0:8], e.Key)
+		binary.BigEndian.PutUint16(buf[8:10], e.Move)
+		binary.BigEndian.PutUint16(buf[10:12], e.Weight)
+		binary.BigEndian.PutUint32(buf[12:16], e.Learn)
+		if _, err := writer.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// Len returns the number of entries in the book
+func (b *Book) Len() int {
+	return len(b.entries)
+}
+
+// Entries returns all entries for the given key
+func (b *Book) Entries(key uint64) []Entry {
+	start := sort.Search(len(b.entries), func(i int) bool { return b.entries[i].Key >= key })
+	end := start
+	for end < len(b.entries) && b.entries[end].Key == key {
+		end++
+	}
+	return b.entries[start:end]
+}
+
+// BookMove is a legal move found in the book for a particular position
+type BookMove struct {
+	Move   move.Move
+	Weight uint16
+}
+
+// Moves returns the legal moves stored in the book for the given position, ordered by descending weight.
+// Book entries which do not correspond to a legal move are ignored.
+func (b *Book) Moves(p position.Position) []BookMove {
+	entries := b.Entries(Hash(p))
+	if len(entries) == 0 {
+		return nil
+	}
+	legalMoves := p.FindMoves(p.ActiveColour())
+	moves := make([]BookMove, 0, len(entries))
+	for _, e := range entries {
+		if m, ok := DecodeMove(e.Move, legalMoves); ok {
+			moves = append(moves, BookMove{Move: m, Weight: e.Weight})
+		}
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].Weight > moves[j].Weight })
+	return moves
+}
+
+// BestMove returns the move with the highest weight for the given position.
+// Returns false if the position is not in the book.
+func (b *Book) BestMove(p position.Position) (move.Move, bool) {
+	moves := b.Moves(p)
+	if len(moves) == 0 {
+		return move.Move{}, false
+	}
+	return moves[0].Move, true
+}
+
+// WeightedMove chooses a move at random for the given position, with probability proportional to the move's weight.
+// Returns false if the position is not in the book.
+func (b *Book) WeightedMove(p position.Position, rnd *rand.Rand) (move.Move, bool) {
+	moves := b.Moves(p)
+	if len(moves) == 0 {
+		return move.Move{}, false
+	}
+	total := 0
+	for _, m := range moves {
+		total += int(m.Weight)
+	}
+	if total == 0 {
+		return moves[rnd.Intn(len(moves))].Move, true
+	}
+	choice := rnd.Intn(total)
+	for _, m := range moves {
+		choice -= int(m.Weight)
+		if choice < 0 {
+			return m.Move, true
+		}
+	}
+	return moves[len(moves)-1].Move, true // not reachable
+}
+
+// polyglot promotion piece encoding (0 == no promotion)
+var promotionPieces = []piece.Piece{piece.KNIGHT, piece.BISHOP, piece.ROOK, piece.QUEEN}
+
+// EncodeMove returns the Polyglot encoding of the given move:
+//
+//	bits 0-2 'to' file, bits 3-5 'to' row, bits 6-8 'from' file, bits 9-11 'from' row, bits 12-14 promotion piece.
+//
+// Castling is encoded as 'king captures rook', e.g. O-O for white is e1h1.
+func EncodeMove(m move.Move) uint16 {
+	from := m.From()
+	to := m.To()
+	if m.IsCastles() {
+		to = m.CastlingRookFrom()
+	}
+	encoded := uint16(squareIndex(to)) | uint16(squareIndex(from))<<6
+	if m.IsPromotion() {
+		for i, pp := range promotionPieces {
+			if pp == m.PromotedPiece() {
+				encoded |= uint16(i+1) << 12
+			}
+		}
+	}
+	return encoded
+}
+
+// DecodeMove finds the move in the given list of (legal) moves which corresponds to the Polyglot move encoding.
+// Returns false if no such move exists.
+func DecodeMove(encoded uint16, moves []move.Move) (move.Move, bool) {
+	for _, m := range moves {
+		if EncodeMove(m) == encoded {
+			return m, true
+		}
+	}
+	return move.Move{}, false
+}
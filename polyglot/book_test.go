@@ -0,0 +1,141 @@
+package polyglot
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+func TestEncodeMove(t *testing.T) {
+	data := []struct {
+		m        move.Move
+		expected uint16
+	}{
+		{move.New(colour.White, square.E2, square.E4, piece.PAWN), 0x031C},
+		{move.CastleKingsSide(colour.White), 0x0107},
+		{move.CastleQueensSide(colour.Black), 0x0F38},
+		{move.NewPromotion(colour.White, square.A7, square.A8, piece.QUEEN), 0x4C38},
+		{move.NewPromotion(colour.White, square.A7, square.A8, piece.KNIGHT), 0x1C38},
+	}
+	for testNbr, d := range data {
+		if encoded := EncodeMove(d.m); encoded != d.expected {
+			t.Errorf("test %d: move %s encoded as %04x, expected %04x", testNbr, d.m.String(), encoded, d.expected)
+		}
+	}
+}
+
+const games = `[Result "1-0"]
+1. e4 e5 2. Nf3 Nc6 1-0
+
+[Result "1/2-1/2"]
+1. e4 c5 2. Nf3 d6 1/2-1/2
+
+[Result "0-1"]
+1. d4 d5 0-1
+
+[Result "1-0"]
+1. e4 e5 2. Bc4 1-0
+`
+
+func TestBuild(t *testing.T) {
+	book, err := Build(strings.NewReader(games), BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	posn := position.StartPosition()
+	moves := book.Moves(posn)
+	if len(moves) != 2 {
+		t.Fatalf("expected 2 moves in start position but got %d", len(moves))
+	}
+	// e4: 2 wins + 1 draw; d4: one loss
+	if moves[0].Move.To() != square.E4 || moves[0].Weight != 5 || moves[1].Move.To() != square.D4 || moves[1].Weight != 0 {
+		t.Errorf("unexpected moves: %v", moves)
+	}
+	m, ok := book.BestMove(posn)
+	if !ok || m.To() != square.E4 {
+		t.Errorf("unexpected best move: %s", m.String())
+	}
+
+	// filtering
+	book, err = Build(strings.NewReader(games), BuildOptions{MinGames: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if book.Len() != 2 { // 1. e4, 1... e5
+		t.Errorf("expected 2 entries but got %d", book.Len())
+	}
+	book, err = Build(strings.NewReader(games), BuildOptions{MinWeight: 1, MaxPly: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if book.Len() != 1 {
+		t.Errorf("expected 1 entry but got %d", book.Len())
+	}
+}
+
+func TestBuildError(t *testing.T) {
+	_, err := Build(strings.NewReader("1. e4 e5 2. Ke3 *"), BuildOptions{})
+	if err == nil || !strings.Contains(err.Error(), "ply 3") {
+		t.Errorf("expected error for illegal move, got: %v", err)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	book, err := Build(strings.NewReader(games), BuildOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var buf bytes.Buffer
+	if err := book.Write(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if buf.Len() != book.Len()*entrySize {
+		t.Fatalf("wrote %d bytes for %d entries", buf.Len(), book.Len())
+	}
+	book2, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(book.entries, book2.entries) {
+		t.Errorf("books differ after round trip")
+	}
+
+	_, err = Read(bytes.NewReader(make([]byte, entrySize+3)))
+	if err == nil {
+		t.Errorf("expected error for truncated book")
+	}
+}
+
+func TestWeightedMove(t *testing.T) {
+	posn := position.StartPosition()
+	book := NewBook([]Entry{
+		{Key: Hash(posn), Move: EncodeMove(move.New(colour.White, square.E2, square.E4, piece.PAWN)), Weight: 3},
+		{Key: Hash(posn), Move: EncodeMove(move.New(colour.White, square.D2, square.D4, piece.PAWN)), Weight: 1},
+		{Key: Hash(posn), Move: EncodeMove(move.New(colour.White, square.E2, square.E5, piece.PAWN)), Weight: 100}, // illegal, ignored
+	})
+	rnd := rand.New(rand.NewSource(1))
+	counts := make(map[square.Square]int)
+	for i := 0; i < 1000; i++ {
+		m, ok := book.WeightedMove(posn, rnd)
+		if !ok {
+			t.Fatalf("no move found")
+		}
+		counts[m.To()]++
+	}
+	if len(counts) != 2 || counts[square.E4] < 650 || counts[square.E4] > 850 {
+		t.Errorf("unexpected distribution: %v", counts)
+	}
+
+	posn, _ = position.ParseFen("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if _, ok := book.WeightedMove(posn, rnd); ok {
+		t.Errorf("did not expect a move for position not in book")
+	}
+}
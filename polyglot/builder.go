@@ -0,0 +1,125 @@
+package polyglot
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/rjo67/chess/pgn"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+)
+
+// BuildOptions control which moves are stored when building a book
+type BuildOptions struct {
+	MaxPly    int // only the first MaxPly half-moves of each game are considered (0 == no limit)
+	MinGames  int // a move must have been played in at least this many games to be stored
+	MinWeight int // a move must have at least this weight to be stored (before scaling)
+}
+
+// key of the map used whilst building the book
+type bookKey struct {
+	key  uint64
+	move uint16
+}
+
+// statistics of a move whilst building the book
+type moveStats struct {
+	games  int
+	weight int
+}
+
+// Build creates a book from the games in the given PGN input.
+// As in Polyglot's 'make-book', the weight of a move is the number of points scored with it (win=2, draw=1, loss=0).
+// Weights are scaled down if necessary to fit into 16 bits.
+func Build(r io.Reader, opts BuildOptions) (*Book, error) {
+	stats := make(map[bookKey]*moveStats)
+	reader := pgn.NewReader(r)
+	for gameNbr := 1; ; gameNbr++ {
+		game, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := addGame(stats, game, opts.MaxPly); err != nil {
+			return nil, fmt.Errorf("game %d: %s", gameNbr, err)
+		}
+	}
+
+	maxWeight := 0
+	entries := make([]Entry, 0, len(stats))
+	for k, s := range stats {
+		if s.games < opts.MinGames || s.weight < opts.MinWeight {
+			continue
+		}
+		entries = append(entries, Entry{Key: k.key, Move: k.move})
+		if s.weight > maxWeight {
+			maxWeight = s.weight
+		}
+	}
+	scale := 1.0
+	if maxWeight > math.MaxUint16 {
+		scale = float64(math.MaxUint16) / float64(maxWeight)
+	}
+	for i := range entries {
+		s := stats[bookKey{entries[i].Key, entries[i].Move}]
+		weight := int(float64(s.weight) * scale)
+		if weight == 0 && s.weight > 0 {
+			weight = 1 // don't let scaling remove a move completely
+		}
+		entries[i].Weight = uint16(weight)
+	}
+	return NewBook(entries), nil
+}
+
+// addGame replays the game and updates the statistics for each move played
+func addGame(stats map[bookKey]*moveStats, game *pgn.Game, maxPly int) error {
+	var posn position.Position
+	if fen, ok := game.Tags["FEN"]; ok {
+		var err error
+		if posn, err = position.ParseFen(fen); err != nil {
+			return err
+		}
+	} else {
+		posn = position.StartPosition()
+	}
+
+	for ply, san := range game.Moves {
+		if maxPly != 0 && ply >= maxPly {
+			break
+		}
+		m, err := posn.ParseSan(san)
+		if err != nil {
+			return fmt.Errorf("ply %d: %s", ply+1, err)
+		}
+		k := bookKey{Hash(posn), EncodeMove(m)}
+		s, ok := stats[k]
+		if !ok {
+			s = &moveStats{}
+			stats[k] = s
+		}
+		s.games++
+		s.weight += points(game.Result, posn.ActiveColour())
+		posn.MakeMove(m)
+	}
+	return nil
+}
+
+// points returns the number of points scored by the given colour (win=2, draw=1, loss=0)
+func points(result string, col colour.Colour) int {
+	switch result {
+	case pgn.Draw:
+		return 1
+	case pgn.WhiteWins:
+		if col == colour.White {
+			return 2
+		}
+	case pgn.BlackWins:
+		if col == colour.Black {
+			return 2
+		}
+	}
+	return 0
+}
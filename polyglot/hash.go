@@ -0,0 +1,79 @@
+package polyglot
+
+import (
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// pieceKinds maps our piece types onto the Polyglot 'kind of piece' (black pawn=0, white pawn=1, black knight=2, ...), keyed by colour
+var pieceKinds = [2][6]int{
+	// WHITE: PAWN, ROOK, KNIGHT, BISHOP, QUEEN, KING
+	{1, 7, 3, 5, 9, 11},
+	// BLACK
+	{0, 6, 2, 4, 8, 10},
+}
+
+// Hash returns the Polyglot key of the given position
+func Hash(p position.Position) uint64 {
+	var key uint64
+	for _, col := range colour.AllColours {
+		for _, pieceType := range piece.AllPieces {
+			for _, bit := range p.Pieces(col, pieceType).SetBits() {
+				key ^= random64[pieceOffset+64*pieceKinds[col][pieceType]+squareIndex(square.Square(bit))]
+			}
+		}
+	}
+
+	if p.CastlingAvailabilityKingsSide(colour.White) {
+		key ^= random64[castleOffset]
+	}
+	if p.CastlingAvailabilityQueensSide(colour.White) {
+		key ^= random64[castleOffset+1]
+	}
+	if p.CastlingAvailabilityKingsSide(colour.Black) {
+		key ^= random64[castleOffset+2]
+	}
+	if p.CastlingAvailabilityQueensSide(colour.Black) {
+		key ^= random64[castleOffset+3]
+	}
+
+	if hasEnpassantCapture(p) {
+		key ^= random64[enpassantOffset+p.EnpassantSquare().File()-1]
+	}
+
+	if p.ActiveColour() == colour.White {
+		key ^= random64[turnOffset]
+	}
+	return key
+}
+
+// squareIndex returns the Polyglot index of the square: 8*row + file, with a1=0 and h8=63
+func squareIndex(sq square.Square) int {
+	return 8*(sq.Rank()-1) + sq.File() - 1
+}
+
+// hasEnpassantCapture returns true if an enpassant square is set and a pawn of the side to move stands next to the pawn which has just moved.
+// Polyglot only includes the enpassant file in the key in this case (it does not check if the capture would be legal).
+func hasEnpassantCapture(p position.Position) bool {
+	epSquare := p.EnpassantSquare()
+	if epSquare == nil {
+		return false
+	}
+	var pawnSq square.Square // the square of the pawn which has just moved
+	if p.ActiveColour() == colour.White {
+		pawnSq = *epSquare - 8
+	} else {
+		pawnSq = *epSquare + 8
+	}
+	adjacent := bitset.New(0)
+	if pawnSq.File() > 1 {
+		adjacent.SetSquare(pawnSq + 1)
+	}
+	if pawnSq.File() < 8 {
+		adjacent.SetSquare(pawnSq - 1)
+	}
+	return !adjacent.And(p.Pieces(p.ActiveColour(), piece.PAWN)).IsEmpty()
+}
@@ -0,0 +1,219 @@
+package position
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+// Binary format of a position (see MarshalBinary), at most 30 bytes for a position with 32 pieces:
+//
+//	8 bytes   occupied squares (bit n set if square n+1 is occupied, big endian)
+//	n bytes   one nibble per occupied square, in the order of the squares, low nibble first:
+//	          0..5 white piece type, 6..11 black piece type, 12/13 white/black rook with castling rights
+//	1 byte    bit 0: black to move, bit 1: Chess960
+//	1 byte    enpassant square, 0 if none
+//	2 bytes   halfmove clock (big endian)
+//	2 bytes   fullmove nbr (big endian)
+const (
+	nibbleBlackOffset        = 6
+	nibbleWhiteCastlingRook  = 12
+	nibbleBlackCastlingRook  = 13
+	binaryFlagBlackToMove    = 0x1
+	binaryFlagChess960       = 0x2
+	binaryOccupiedSquaresLen = 8
+	binaryTrailerLen         = 6
+)
+
+// ErrBinaryFormat is returned by UnmarshalBinary if the data does not contain a valid position
+var ErrBinaryFormat = errors.New("invalid binary position")
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+// Returns an error if the position cannot be represented, e.g. because two pieces occupy the same square,
+// castling is available without a rook on the castling square, or the clocks are larger than 65535.
+func (p Position) MarshalBinary() ([]byte, error) {
+	nbrPieces := 0
+	for _, col := range colour.AllColours {
+		for _, pieceType := range piece.AllPieces {
+			nbrPieces += p.pieces[col][pieceType].Cardinality()
+		}
+	}
+	if nbrPieces != p.occupiedSquares.Cardinality() {
+		return nil, fmt.Errorf("cannot encode position: more than one piece on a square")
+	}
+	if p.halfmoveClock < 0 || p.halfmoveClock > 0xFFFF || p.fullmoveNbr < 0 || p.fullmoveNbr > 0xFFFF {
+		return nil, fmt.Errorf("cannot encode position: clocks out of range")
+	}
+
+	// rooks with castling rights
+	var castlingRooks [2]bitset.BitSet
+	for _, col := range colour.AllColours {
+		for _, kingsside := range []bool{true, false} {
+			var rookSq square.Square
+			if kingsside && p.CastlingAvailabilityKingsSide(col) {
+				rookSq = p.kingssideCastlingRook[col]
+			} else if !kingsside && p.CastlingAvailabilityQueensSide(col) {
+				rookSq = p.queenssideCastlingRook[col]
+			} else {
+				continue
+			}
+			if !p.pieces[col][piece.ROOK].IsSet(uint(rookSq)) {
+				return nil, fmt.Errorf("cannot encode position: %s can castle without a rook on %s", col.String(), rookSq.String())
+			}
+			castlingRooks[col].SetSquare(rookSq)
+		}
+	}
+
+	data := make([]byte, binaryOccupiedSquaresLen+(nbrPieces+1)/2+binaryTrailerLen)
+	binary.BigEndian.PutUint64(data, p.occupiedSquares.Val())
+	for i, sq := range p.occupiedSquares.SetBits() {
+		pieceType, col, _ := p.PieceOn(square.Square(sq))
+		nibble := byte(pieceType)
+		switch {
+		case castlingRooks[col].IsSet(uint(sq)) && col == colour.White:
+			nibble = nibbleWhiteCastlingRook
+		case castlingRooks[col].IsSet(uint(sq)):
+			nibble = nibbleBlackCastlingRook
+		case col == colour.Black:
+			nibble += nibbleBlackOffset
+		}
+		data[binaryOccupiedSquaresLen+i/2] |= nibble << (4 * (i % 2))
+	}
+
+	trailer := data[len(data)-binaryTrailerLen:]
+	if p.activeColour == colour.Black {
+		trailer[0] |= binaryFlagBlackToMove
+	}
+	if p.chess960 {
+		trailer[0] |= binaryFlagChess960
+	}
+	if p.enpassantSquare != nil {
+		trailer[1] = byte(*p.enpassantSquare)
+	}
+	binary.BigEndian.PutUint16(trailer[2:], uint16(p.halfmoveClock))
+	binary.BigEndian.PutUint16(trailer[4:], uint16(p.fullmoveNbr))
+	return data, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler. The history of the position is cleared.
+// Returns an error wrapping ErrBinaryFormat if the data is not valid.
+func (p *Position) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryOccupiedSquaresLen+binaryTrailerLen {
+		return fmt.Errorf("%w: %d bytes", ErrBinaryFormat, len(data))
+	}
+	occupied := bitset.New(binary.BigEndian.Uint64(data))
+	squares := occupied.SetBits()
+	if len(data) != binaryOccupiedSquaresLen+(len(squares)+1)/2+binaryTrailerLen {
+		return fmt.Errorf("%w: %d bytes for %d pieces", ErrBinaryFormat, len(data), len(squares))
+	}
+
+	pieces := make([]map[piece.Piece]bitset.BitSet, 2)
+	for _, col := range colour.AllColours {
+		pieces[col] = make(map[piece.Piece]bitset.BitSet)
+	}
+	var castlingRooks []square.Square
+	var castlingRookColours []colour.Colour
+	for i, sq := range squares {
+		nibble := data[binaryOccupiedSquaresLen+i/2] >> (4 * (i % 2)) & 0xF
+		col, pieceType := colour.White, piece.Piece(nibble)
+		switch {
+		case nibble == nibbleWhiteCastlingRook || nibble == nibbleBlackCastlingRook:
+			col, pieceType = colour.Colour(nibble-nibbleWhiteCastlingRook), piece.ROOK
+			castlingRooks = append(castlingRooks, square.Square(sq))
+			castlingRookColours = append(castlingRookColours, col)
+		case nibble >= nibbleBlackOffset && nibble < nibbleWhiteCastlingRook:
+			col, pieceType = colour.Black, piece.Piece(nibble-nibbleBlackOffset)
+		case nibble >= nibbleBlackOffset:
+			return fmt.Errorf("%w: piece code %d", ErrBinaryFormat, nibble)
+		}
+		bs := pieces[col][pieceType]
+		bs.Set(uint(sq))
+		pieces[col][pieceType] = bs
+	}
+	if len(squares)%2 == 1 && data[binaryOccupiedSquaresLen+len(squares)/2]>>4 != 0 {
+		return fmt.Errorf("%w: unused nibble is not zero", ErrBinaryFormat)
+	}
+
+	posn := NewPosition(pieces[colour.White], pieces[colour.Black])
+	for i, rookSq := range castlingRooks {
+		col := castlingRookColours[i]
+		kings := pieces[col][piece.KING].SetBits()
+		if len(kings) != 1 {
+			return fmt.Errorf("%w: castling rook on %s without a king", ErrBinaryFormat, rookSq.String())
+		}
+		if rookSq.File() > square.Square(kings[0]).File() {
+			posn.kingssideCastlingRook[col] = rookSq
+			posn.SetCastlingAvailabilityKingsSide(col)
+		} else {
+			posn.queenssideCastlingRook[col] = rookSq
+			posn.SetCastlingAvailabilityQueensSide(col)
+		}
+	}
+
+	trailer := data[len(data)-binaryTrailerLen:]
+	if trailer[0]&^(binaryFlagBlackToMove|binaryFlagChess960) != 0 || trailer[1] > 64 {
+		return fmt.Errorf("%w: invalid flags or enpassant square", ErrBinaryFormat)
+	}
+	if trailer[0]&binaryFlagBlackToMove != 0 {
+		posn.activeColour = colour.Black
+	}
+	posn.chess960 = trailer[0]&binaryFlagChess960 != 0
+	if trailer[1] != 0 {
+		ep := square.Square(trailer[1])
+		posn.enpassantSquare = &ep
+	}
+	posn.halfmoveClock = int(binary.BigEndian.Uint16(trailer[2:]))
+	posn.fullmoveNbr = int(binary.BigEndian.Uint16(trailer[4:]))
+	posn.key = posn.computeKey()
+	*p = posn
+	return nil
+}
+
+// DecodeMove restores the complete move from its 16-bit encoding (see move.Encode), using the pieces in the current position.
+// The move is not checked for legality, but an error is returned if the move is not possible,
+// e.g. because there is no piece of the side to move on the 'from' square.
+func (p Position) DecodeMove(e move.Encoded) (move.Move, error) {
+	col := p.activeColour
+	from, to := e.From(), e.To()
+	pieceType, pieceColour, ok := p.PieceOn(from)
+	if !ok || pieceColour != col {
+		return move.Move{}, fmt.Errorf("cannot decode move %s%s: no %s piece on %s", from.String(), to.String(), col.String(), from.String())
+	}
+	capturedPiece, capturedColour, capture := p.PieceOn(to)
+
+	switch e.Flags() {
+	case move.EncodedCastles:
+		if pieceType != piece.KING || !capture || capturedColour != col || capturedPiece != piece.ROOK {
+			return move.Move{}, fmt.Errorf("cannot decode castling move %s%s: king or rook missing", from.String(), to.String())
+		}
+		return move.NewCastles(col, from, to, to.File() > from.File()), nil
+	case move.EncodedEnpassant:
+		if pieceType != piece.PAWN || p.enpassantSquare == nil || *p.enpassantSquare != to {
+			return move.Move{}, fmt.Errorf("cannot decode enpassant move %s%s", from.String(), to.String())
+		}
+		return move.NewEpCapture(col, from, to), nil
+	}
+
+	if capture && capturedColour == col {
+		return move.Move{}, fmt.Errorf("cannot decode move %s%s: square %s is occupied", from.String(), to.String(), to.String())
+	}
+	if e.Flags() == move.EncodedPromotion {
+		if pieceType != piece.PAWN {
+			return move.Move{}, fmt.Errorf("cannot decode promotion %s%s: no pawn on %s", from.String(), to.String(), from.String())
+		}
+		if capture {
+			return move.NewPromotionCapture(col, from, to, e.PromotedPiece(), capturedPiece), nil
+		}
+		return move.NewPromotion(col, from, to, e.PromotedPiece()), nil
+	}
+	if capture {
+		return move.NewCapture(col, from, to, pieceType, capturedPiece), nil
+	}
+	return move.New(col, from, to, pieceType), nil
+}
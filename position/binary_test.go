@@ -0,0 +1,114 @@
+package position
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+// checks the binary encoding of the position and of all legal moves, recursively up to the given depth
+func checkBinaryRoundTrip(t *testing.T, posn Position, depth int) {
+	data, err := posn.MarshalBinary()
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %s", posn.ShredderFen(), err)
+	}
+	if len(data) > 30 {
+		t.Errorf("%s: encoding too long: %d bytes", posn.ShredderFen(), len(data))
+	}
+	var decoded Position
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("%s: unexpected error: %s", posn.ShredderFen(), err)
+	}
+	if decoded.ShredderFen() != posn.ShredderFen() || decoded.Chess960() != posn.Chess960() {
+		t.Fatalf("expected %s but got %s after decoding", posn.ShredderFen(), decoded.ShredderFen())
+	}
+	if depth == 0 {
+		return
+	}
+	for _, m := range posn.FindMoves(posn.ActiveColour()) {
+		decodedMove, err := posn.DecodeMove(m.Encode())
+		if err != nil {
+			t.Fatalf("%s: move %s: unexpected error: %s", posn.ShredderFen(), m.String(), err)
+		}
+		if !reflect.DeepEqual(decodedMove, m) {
+			t.Fatalf("%s: expected move %s but got %s", posn.ShredderFen(), m.String(), decodedMove.String())
+		}
+		posn.MakeMove(m)
+		checkBinaryRoundTrip(t, posn, depth-1)
+		posn.UnmakeMove(m)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+		"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+		"1rqbkrbn/1ppppp1p/1n6/p1N3p1/8/2P4P/PP1PPPP1/1RQBKRBN w FBfb - 0 9",
+	} {
+		posn, err := ParseFen(fen)
+		if err != nil {
+			t.Fatalf("error parsing fen '%s': %s", fen, err)
+		}
+		checkBinaryRoundTrip(t, posn, 2)
+	}
+}
+
+func TestMarshalBinaryErrors(t *testing.T) {
+	// castling right without rook
+	posn, _ := ParseFen("7k/8/8/8/8/8/8/7K w KQkq - 0 1")
+	if _, err := posn.MarshalBinary(); err == nil {
+		t.Errorf("expected error for castling right without rook")
+	}
+	// two pieces on a square
+	kings := bitset.NewFromSquares(square.E1)
+	posn = NewBuilder().AddPiece(colour.White, piece.KING, &kings).AddPiece(colour.Black, piece.KING, &kings).build()
+	if _, err := posn.MarshalBinary(); err == nil {
+		t.Errorf("expected error for overlapping pieces")
+	}
+}
+
+func TestUnmarshalBinaryErrors(t *testing.T) {
+	data, err := StartPosition().MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	invalidPiece := append([]byte{}, data...)
+	invalidPiece[8] = 0xFF
+	invalidFlags := append([]byte{}, data...)
+	invalidFlags[len(data)-6] = 0x80
+	for _, d := range [][]byte{nil, data[:10], append(data, 0), invalidPiece, invalidFlags} {
+		var posn Position
+		if err := posn.UnmarshalBinary(d); !errors.Is(err, ErrBinaryFormat) {
+			t.Errorf("expected ErrBinaryFormat for %v but got %v", d, err)
+		}
+	}
+}
+
+func TestDecodeMoveErrors(t *testing.T) {
+	posn := StartPosition()
+	for _, m := range []move.Move{
+		move.New(colour.White, square.E3, square.E4, piece.PAWN),           // no piece
+		move.New(colour.Black, square.E7, square.E5, piece.PAWN),           // wrong colour
+		move.New(colour.White, square.D1, square.D2, piece.QUEEN),          // own piece on target square
+		move.NewPromotion(colour.White, square.B1, square.B8, piece.QUEEN), // no pawn
+		move.NewEpCapture(colour.White, square.D2, square.C3),              // no enpassant square
+	} {
+		if _, err := posn.DecodeMove(m.Encode()); err == nil {
+			t.Errorf("expected error for move %s", m.String())
+		}
+	}
+	posn, _ = ParseFen("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if _, err := posn.DecodeMove(move.CastleKingsSide(colour.White).Encode()); err == nil {
+		t.Errorf("expected error for castles without rook")
+	}
+}
@@ -1,6 +1,7 @@
 package position
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/rjo67/chess/bitset"
@@ -9,7 +10,14 @@ import (
 	"github.com/rjo67/chess/square"
 )
 
-// Builder implements the builder pattern for a position. Create with NewBuilder
+// the kinds of errors recorded by the builder, in addition to the Err... values of Validate
+var (
+	ErrDuplicatePiece  = errors.New("piece added more than once")
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// Builder implements the builder pattern for a position. Create with NewBuilder.
+// The builder never panics: invalid arguments are recorded and returned by Build.
 type Builder struct {
 	pieces                         []map[piece.Piece]bitset.BitSet
 	piecesInitialised              []map[piece.Piece]bool // whether the appropriate entry in the map has already been set
@@ -19,6 +27,10 @@ type Builder struct {
 	enpassantSquare                *square.Square
 	halfmoveClock                  int
 	fullmoveNbr                    int
+	kingssideCastlingRook          [2]square.Square // zero: default square
+	queenssideCastlingRook         [2]square.Square
+	chess960                       bool
+	violations                     []Violation // errors recorded whilst building
 }
 
 // NewBuilder returns a builder for a position
@@ -36,24 +48,60 @@ func NewBuilder() *Builder {
 	return &b
 }
 
-// AddPiece adds the given piece bitset to the builder
+// AddPiece adds the given piece bitset to the builder.
+// Should only be called once per colour and piece type, see also PlacePiece.
 func (b *Builder) AddPiece(col colour.Colour, pieceID piece.Piece, bs *bitset.BitSet) *Builder {
+	if !b.checkPiece(col, pieceID) || bs == nil {
+		return b
+	}
 	if b.piecesInitialised[col][pieceID] {
-		panic(fmt.Sprintf("AddPiece called multiple times for colour: %d, piece type: %d", col, pieceID))
+		b.add(ErrDuplicatePiece, "AddPiece called multiple times for %s %s", col.String(), pieceID.String(col))
 	}
-	b.pieces[col][pieceID] = *bs
+	b.pieces[col][pieceID] = b.pieces[col][pieceID].Or(*bs)
 	b.piecesInitialised[col][pieceID] = true
 	return b
 }
 
+// PlacePiece puts the given piece on sq, replacing any piece already there
+func (b *Builder) PlacePiece(sq square.Square, col colour.Colour, pieceID piece.Piece) *Builder {
+	if !b.checkPiece(col, pieceID) || !b.checkSquare(sq) {
+		return b
+	}
+	b.RemovePiece(sq)
+	bs := b.pieces[col][pieceID]
+	bs.SetSquare(sq)
+	b.pieces[col][pieceID] = bs
+	return b
+}
+
+// RemovePiece removes the piece (if any) on sq
+func (b *Builder) RemovePiece(sq square.Square) *Builder {
+	if !b.checkSquare(sq) {
+		return b
+	}
+	for _, col := range colour.AllColours {
+		for pieceID, bs := range b.pieces[col] {
+			bs.ClearSquare(sq)
+			b.pieces[col][pieceID] = bs
+		}
+	}
+	return b
+}
+
 // ActiveColour sets the active colour of the position
 func (b *Builder) ActiveColour(col colour.Colour) *Builder {
+	if !b.checkColour(col) {
+		return b
+	}
 	b.activeColour = col
 	return b
 }
 
 // CastlingAvailability sets the castling rights of the position
 func (b *Builder) CastlingAvailability(col colour.Colour, kingsside bool, canCastle bool) *Builder {
+	if !b.checkColour(col) {
+		return b
+	}
 	if kingsside {
 		b.castlingAvailabilityKingsSide[col] = canCastle
 	} else {
@@ -62,8 +110,31 @@ func (b *Builder) CastlingAvailability(col colour.Colour, kingsside bool, canCas
 	return b
 }
 
+// CastlingRook sets the start square of the rook used for castling.
+// Only required for Chess960, by default the rooks on the a- and h-files are used.
+func (b *Builder) CastlingRook(col colour.Colour, kingsside bool, rookSq square.Square) *Builder {
+	if !b.checkColour(col) || !b.checkSquare(rookSq) {
+		return b
+	}
+	if kingsside {
+		b.kingssideCastlingRook[col] = rookSq
+	} else {
+		b.queenssideCastlingRook[col] = rookSq
+	}
+	return b
+}
+
+// Chess960 sets whether the position is from a game of Chess960
+func (b *Builder) Chess960(chess960 bool) *Builder {
+	b.chess960 = chess960
+	return b
+}
+
 // EnpassantSquare sets the enpassant square of the position
 func (b *Builder) EnpassantSquare(enpassantSquare *square.Square) *Builder {
+	if enpassantSquare != nil && !b.checkSquare(*enpassantSquare) {
+		return b
+	}
 	b.enpassantSquare = enpassantSquare
 	return b
 }
@@ -80,14 +151,50 @@ func (b *Builder) FullmoveNbr(moveNbr int) *Builder {
 	return b
 }
 
-// Build builds a position object
-func (b *Builder) Build() Position {
-	posn := NewPosition(b.pieces[colour.White], b.pieces[colour.Black])
+// Build builds a position object.
+// Returns a ValidationError if invalid arguments were passed to the builder, or if the pieces overlap,
+// a side does not have exactly one king, or the castling rights or enpassant square are inconsistent
+// with the position. Use Position.Validate for further checks.
+func (b *Builder) Build() (Position, error) {
+	violations := append([]Violation(nil), b.violations...)
+	add := func(kind error, format string, args ...interface{}) {
+		violations = append(violations, Violation{Kind: kind, Detail: fmt.Sprintf(format, args...)})
+	}
+	posn := b.build()
+	if posn.validateKings(add) {
+		posn.validateCastlingRights(add)
+	}
+	posn.validateSquares(add)
+	posn.validateEnpassantSquare(add)
+	if len(violations) != 0 {
+		return Position{}, ValidationError{violations}
+	}
+	return posn, nil
+}
+
+// build builds a position object without any checks
+func (b *Builder) build() Position {
+	// copy the maps, since the position would otherwise be changed by further calls to the builder
+	pieces := make([]map[piece.Piece]bitset.BitSet, 2)
+	for _, col := range colour.AllColours {
+		pieces[col] = make(map[piece.Piece]bitset.BitSet)
+		for _, pieceType := range piece.AllPieces {
+			pieces[col][pieceType] = b.pieces[col][pieceType]
+		}
+	}
+	posn := NewPosition(pieces[colour.White], pieces[colour.Black])
 	posn.enpassantSquare = b.enpassantSquare
 	posn.halfmoveClock = b.halfmoveClock
 	posn.fullmoveNbr = b.fullmoveNbr
 	posn.activeColour = b.activeColour
+	posn.chess960 = b.chess960
 	for _, col := range colour.AllColours {
+		if b.kingssideCastlingRook[col] != 0 {
+			posn.kingssideCastlingRook[col] = b.kingssideCastlingRook[col]
+		}
+		if b.queenssideCastlingRook[col] != 0 {
+			posn.queenssideCastlingRook[col] = b.queenssideCastlingRook[col]
+		}
 		if b.castlingAvailabilityKingsSide[col] {
 			posn.SetCastlingAvailabilityKingsSide(col)
 		}
@@ -95,6 +202,42 @@ func (b *Builder) Build() Position {
 			posn.SetCastlingAvailabilityQueensSide(col)
 		}
 	}
+	posn.key = posn.computeKey()
 
 	return posn
 }
+
+// add records an error
+func (b *Builder) add(kind error, format string, args ...interface{}) {
+	b.violations = append(b.violations, Violation{Kind: kind, Detail: fmt.Sprintf(format, args...)})
+}
+
+// checkColour records an error if col is not White or Black
+func (b *Builder) checkColour(col colour.Colour) bool {
+	if col != colour.White && col != colour.Black {
+		b.add(ErrInvalidArgument, "colour %d", col)
+		return false
+	}
+	return true
+}
+
+// checkPiece records an error if col or pieceID are invalid
+func (b *Builder) checkPiece(col colour.Colour, pieceID piece.Piece) bool {
+	if !b.checkColour(col) {
+		return false
+	}
+	if pieceID > piece.KING {
+		b.add(ErrInvalidArgument, "piece type %d", pieceID)
+		return false
+	}
+	return true
+}
+
+// checkSquare records an error if sq is not on the board
+func (b *Builder) checkSquare(sq square.Square) bool {
+	if sq < 1 || sq > 64 {
+		b.add(ErrInvalidArgument, "square %d", sq)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package position
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+func TestBuilder(t *testing.T) {
+	posn, err := NewBuilder().PlacePiece(square.E1, colour.White, piece.KING).PlacePiece(square.H1, colour.White, piece.ROOK).
+		PlacePiece(square.E8, colour.Black, piece.KING).PlacePiece(square.D5, colour.Black, piece.PAWN).
+		PlacePiece(square.D5, colour.Black, piece.KNIGHT). // replaces the pawn
+		PlacePiece(square.A2, colour.White, piece.PAWN).RemovePiece(square.A2).
+		CastlingAvailability(colour.White, true, true).ActiveColour(colour.Black).FullmoveNbr(1).Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if fen := posn.Fen(); fen != "4k3/8/8/3n4/8/8/8/4K2R b K - 0 1" {
+		t.Errorf("unexpected fen %s", fen)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	kings := bitset.NewFromSquares(square.E1)
+	ep := square.E3
+	badSquare := square.Square(65)
+	data := []struct {
+		name     string
+		builder  *Builder
+		expected []error
+	}{
+		{"no kings", NewBuilder(), []error{ErrKingCount, ErrKingCount}},
+		{"duplicate AddPiece", NewBuilder().AddPiece(colour.White, piece.KING, &kings).AddPiece(colour.White, piece.KING, &kings).
+			PlacePiece(square.E8, colour.Black, piece.KING), []error{ErrDuplicatePiece}},
+		{"overlapping", NewBuilder().AddPiece(colour.White, piece.KING, &kings).AddPiece(colour.Black, piece.ROOK, &kings).
+			PlacePiece(square.E8, colour.Black, piece.KING), []error{ErrSquareOccupied}},
+		{"invalid arguments", NewBuilder().PlacePiece(0, colour.White, piece.KING).PlacePiece(square.E1, colour.AnyColour, piece.KING).
+			PlacePiece(square.E1, colour.White, piece.Piece(6)).EnpassantSquare(&badSquare).CastlingRook(colour.White, true, badSquare).
+			PlacePiece(square.E1, colour.White, piece.KING).PlacePiece(square.E8, colour.Black, piece.KING),
+			[]error{ErrInvalidArgument, ErrInvalidArgument, ErrInvalidArgument, ErrInvalidArgument, ErrInvalidArgument}},
+		{"castling", NewBuilder().PlacePiece(square.E1, colour.White, piece.KING).PlacePiece(square.E8, colour.Black, piece.KING).
+			CastlingAvailability(colour.White, false, true), []error{ErrCastlingRights}},
+		{"enpassant", NewBuilder().PlacePiece(square.E1, colour.White, piece.KING).PlacePiece(square.E8, colour.Black, piece.KING).
+			ActiveColour(colour.Black).EnpassantSquare(&ep), []error{ErrEnpassantSquare}},
+	}
+	for _, d := range data {
+		_, err := d.builder.Build()
+		var validationError ValidationError
+		if !errors.As(err, &validationError) {
+			t.Errorf("%s: expected ValidationError but got %v", d.name, err)
+			continue
+		}
+		if len(validationError.Violations) != len(d.expected) {
+			t.Errorf("%s: expected %d violations but got %v", d.name, len(d.expected), err)
+			continue
+		}
+		for i, v := range validationError.Violations {
+			if !errors.Is(v, d.expected[i]) {
+				t.Errorf("%s: expected violation '%s' but got '%s'", d.name, d.expected[i], v)
+			}
+		}
+	}
+}
+
+func TestBuilderReuse(t *testing.T) {
+	builder := NewBuilder().PlacePiece(square.E1, colour.White, piece.KING).PlacePiece(square.E8, colour.Black, piece.KING)
+	posn, err := builder.Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	builder.PlacePiece(square.D1, colour.White, piece.QUEEN)
+	if _, _, ok := posn.PieceOn(square.D1); ok {
+		t.Errorf("position changed by builder")
+	}
+
+	// the violations of one Build must not be changed by the next
+	ep := square.E3
+	builder = NewBuilder().PlacePiece(0, colour.White, piece.PAWN).PlacePiece(0, colour.White, piece.PAWN).PlacePiece(0, colour.White, piece.PAWN).
+		PlacePiece(square.E1, colour.White, piece.KING).PlacePiece(square.E8, colour.Black, piece.KING).CastlingAvailability(colour.White, true, true)
+	_, err1 := builder.Build()
+	builder.CastlingAvailability(colour.White, true, false).ActiveColour(colour.Black).EnpassantSquare(&ep)
+	_, err2 := builder.Build()
+	var v1, v2 ValidationError
+	if !errors.As(err1, &v1) || !errors.As(err2, &v2) || len(v1.Violations) != 4 || len(v2.Violations) != 4 {
+		t.Fatalf("expected 4 violations each but got %v and %v", err1, err2)
+	}
+	if !errors.Is(v1.Violations[3], ErrCastlingRights) || !errors.Is(v2.Violations[3], ErrEnpassantSquare) {
+		t.Errorf("unexpected violations %v and %v", err1, err2)
+	}
+}
+
+func TestPieceOn(t *testing.T) {
+	posn := StartPosition()
+	data := []struct {
+		sq        square.Square
+		pieceType piece.Piece
+		col       colour.Colour
+		ok        bool
+	}{
+		{square.E1, piece.KING, colour.White, true},
+		{square.D8, piece.QUEEN, colour.Black, true},
+		{square.B7, piece.PAWN, colour.Black, true},
+		{square.E4, 0, 0, false},
+		{0, 0, 0, false},
+		{65, 0, 0, false},
+	}
+	for _, d := range data {
+		pieceType, col, ok := posn.PieceOn(d.sq)
+		if pieceType != d.pieceType || col != d.col || ok != d.ok {
+			t.Errorf("square %d: expected %d %d %t but got %d %d %t", d.sq, d.pieceType, d.col, d.ok, pieceType, col, ok)
+		}
+	}
+}
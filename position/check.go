@@ -0,0 +1,102 @@
+package position
+
+import (
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/ray"
+	"github.com/rjo67/chess/square"
+)
+
+// Checkers returns a bitset of the pieces giving check to the king of the side to move
+func (p Position) Checkers() bitset.BitSet {
+	kings := p.Pieces(p.activeColour, piece.KING).SetBits()
+	if len(kings) != 1 {
+		return bitset.New(0)
+	}
+	return p.Attacks(square.Square(kings[0]), p.activeColour.Other())
+}
+
+// InCheck returns true if the king of the side to move is in check
+func (p Position) InCheck() bool {
+	return !p.Checkers().IsEmpty()
+}
+
+// IsCheckmate returns true if the side to move is in check and has no legal moves
+func (p Position) IsCheckmate() bool {
+	return p.InCheck() && len(p.FindMoves(p.activeColour)) == 0
+}
+
+// IsStalemate returns true if the side to move is not in check but has no legal moves
+func (p Position) IsStalemate() bool {
+	return !p.InCheck() && len(p.FindMoves(p.activeColour)) == 0
+}
+
+// GivesCheck returns true if the given move (of the side to move) checks the opponent's king,
+// either directly or by uncovering an attack of another piece.
+// The move is not made, instead the attacks are calculated from the squares the pieces occupy after the move.
+func (p Position) GivesCheck(m move.Move) bool {
+	col := p.activeColour
+	kings := p.Pieces(col.Other(), piece.KING).SetBits()
+	if len(kings) != 1 {
+		return false
+	}
+	king := square.Square(kings[0])
+
+	// occupied squares and my pieces after the move
+	occupied := p.occupiedSquares
+	occupied.ClearSquare(m.From())
+	if m.IsEnpassant() {
+		occupied = occupied.AndNot(m.EnpassantPawnRealLocation())
+	}
+	if m.IsCastles() {
+		occupied.ClearSquare(m.CastlingRookFrom())
+		occupied.SetSquare(m.CastlingRookTo())
+	}
+	occupied.SetSquare(m.To())
+	piecesAfterMove := func(pieceType piece.Piece) bitset.BitSet {
+		bs := p.pieces[col][pieceType]
+		if m.PieceType() == pieceType {
+			bs.ClearSquare(m.From())
+		}
+		if m.IsCastles() && pieceType == piece.ROOK {
+			bs.ClearSquare(m.CastlingRookFrom())
+			bs.SetSquare(m.CastlingRookTo())
+		}
+		if (m.IsPromotion() && m.PromotedPiece() == pieceType) || (!m.IsPromotion() && m.PieceType() == pieceType) {
+			bs.SetSquare(m.To())
+		}
+		return bs
+	}
+
+	if !ray.PawnAttackBitSets[col][king].And(piecesAfterMove(piece.PAWN)).IsEmpty() ||
+		!ray.KnightAttackBitSets[king].And(piecesAfterMove(piece.KNIGHT)).IsEmpty() {
+		return true
+	}
+	queens := piecesAfterMove(piece.QUEEN)
+	diagonals, rankfiles := bitset.New(0), bitset.New(0)
+	for _, direction := range ray.AllBishopDirections {
+		bs, _ := move.Search2(int(king), direction, occupied)
+		diagonals = diagonals.Or(bs)
+	}
+	for _, direction := range ray.AllRookDirections {
+		bs, _ := move.Search2(int(king), direction, occupied)
+		rankfiles = rankfiles.Or(bs)
+	}
+	return !diagonals.And(piecesAfterMove(piece.BISHOP).Or(queens)).IsEmpty() ||
+		!rankfiles.And(piecesAfterMove(piece.ROOK).Or(queens)).IsEmpty()
+}
+
+// checkSuffix returns "#" if the move (of the side to move) gives checkmate, "+" if it gives check, otherwise ""
+func (p Position) checkSuffix(m move.Move) string {
+	if !p.GivesCheck(m) {
+		return ""
+	}
+	p.MakeMove(m)
+	mate := len(p.FindMoves(p.activeColour)) == 0
+	p.UnmakeMove(m)
+	if mate {
+		return "#"
+	}
+	return "+"
+}
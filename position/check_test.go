@@ -0,0 +1,74 @@
+package position
+
+import (
+	"testing"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/square"
+)
+
+func TestCheckQueries(t *testing.T) {
+	data := []struct {
+		fen                  string
+		checkers             bitset.BitSet
+		checkmate, stalemate bool
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", bitset.New(0), false, false},
+		{"4k3/8/8/8/8/5n2/8/R3K2R w KQ - 0 1", bitset.NewFromSquares(square.F3), false, false},
+		{"4k3/8/8/8/1b6/5n2/8/R3K2R w KQ - 0 1", bitset.NewFromSquares(square.F3, square.B4), false, false},
+		{"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", bitset.NewFromSquares(square.H4), true, false},
+		{"k7/8/1K6/8/8/8/8/R7 b - - 0 1", bitset.NewFromSquares(square.A1), false, false},
+		{"k7/1Q6/1K6/8/8/8/8/8 b - - 0 1", bitset.NewFromSquares(square.B7), true, false},
+		{"k7/8/1QK5/8/8/8/8/8 b - - 0 1", bitset.New(0), false, true},
+	}
+	for _, d := range data {
+		posn, err := ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("%s: error parsing fen: %s", d.fen, err)
+		}
+		if checkers := posn.Checkers(); checkers != d.checkers {
+			t.Errorf("%s: expected checkers %v but got %v", d.fen, d.checkers.SetBits(), checkers.SetBits())
+		}
+		if posn.InCheck() != !d.checkers.IsEmpty() {
+			t.Errorf("%s: InCheck returned %t", d.fen, posn.InCheck())
+		}
+		if posn.IsCheckmate() != d.checkmate {
+			t.Errorf("%s: IsCheckmate returned %t", d.fen, posn.IsCheckmate())
+		}
+		if posn.IsStalemate() != d.stalemate {
+			t.Errorf("%s: IsStalemate returned %t", d.fen, posn.IsStalemate())
+		}
+	}
+}
+
+// compares GivesCheck for all legal moves with the result of making the move
+func TestGivesCheck(t *testing.T) {
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",
+		"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8",
+		"5k2/8/8/8/8/8/8/4K2R w K - 0 1",                           // castling with check from the rook
+		"8/8/8/K2pP2q/8/8/8/7k w - d6 0 1",                         // enpassant capture exposes own king (illegal)
+		"8/8/8/1k1pP2R/8/8/8/4K3 w - d6 0 1",                       // enpassant capture gives discovered check
+		"3k4/4P3/8/8/8/8/8/4K3 w - - 0 1",                          // promotion
+		"1n1k4/P7/8/8/8/8/1B6/4K3 w - - 0 1",                       // promotion with discovered check
+		"4k3/8/8/8/8/8/4N3/4R1K1 w - - 0 1",                        // discovered check by knight move
+		"nrkbbrqn/pppppppp/8/8/8/8/PPPPPPPP/1RKBBRQN w FBfb - 0 1", // Chess960
+	} {
+		posn, err := ParseFen(fen)
+		if err != nil {
+			t.Fatalf("%s: error parsing fen: %s", fen, err)
+		}
+		for _, m := range posn.FindMoves(posn.ActiveColour()) {
+			givesCheck := posn.GivesCheck(m)
+			posn.MakeMove(m)
+			inCheck := posn.InCheck()
+			posn.UnmakeMove(m)
+			if givesCheck != inCheck {
+				t.Errorf("%s: move %s: GivesCheck returned %t", fen, m.String(), givesCheck)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package position
+
+import (
+	"fmt"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+// Chess960StandardIndex is the index of the normal chess start position in the Chess960 numbering
+const Chess960StandardIndex = 518
+
+// knight placements on the five empty squares remaining after placing the bishops and queen
+var chess960Knights = [10][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2}, {1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4}}
+
+// Chess960StartPosition returns the Chess960 start position with the given index (0..959), as defined by Scharnagl.
+// https://en.wikipedia.org/wiki/Fischer_random_chess_numbering_scheme
+func Chess960StartPosition(index int) (Position, error) {
+	if index < 0 || index >= 960 {
+		return Position{}, fmt.Errorf("invalid Chess960 start position index %d", index)
+	}
+	var backRank [8]piece.Piece // indexed by file-1
+	var placed [8]bool
+	place := func(file int, p piece.Piece) {
+		backRank[file] = p
+		placed[file] = true
+	}
+	// returns the n-th empty file
+	emptyFile := func(n int) int {
+		for file := range placed {
+			if !placed[file] {
+				if n == 0 {
+					return file
+				}
+				n--
+			}
+		}
+		panic("no empty file")
+	}
+
+	n := index
+	place(2*(n%4)+1, piece.BISHOP) // light squares: b, d, f, h
+	n /= 4
+	place(2*(n%4), piece.BISHOP) // dark squares: a, c, e, g
+	n /= 4
+	place(emptyFile(n%6), piece.QUEEN)
+	n /= 6
+	knights := chess960Knights[n]
+	// the second knight must be found before the first is placed
+	file1, file2 := emptyFile(knights[0]), emptyFile(knights[1])
+	place(file1, piece.KNIGHT)
+	place(file2, piece.KNIGHT)
+	// the remaining files are taken by rook, king, rook
+	for _, p := range []piece.Piece{piece.ROOK, piece.KING, piece.ROOK} {
+		place(emptyFile(0), p)
+	}
+
+	builder := NewBuilder()
+	for _, col := range colour.AllColours {
+		backRankNbr, pawnRank := 1, 2
+		if col == colour.Black {
+			backRankNbr, pawnRank = 8, 7
+		}
+		pieces := make(map[piece.Piece]*bitset.BitSet)
+		for _, pieceType := range piece.AllPieces {
+			pieces[pieceType] = &bitset.BitSet{}
+		}
+		var rooks []square.Square
+		for file := 1; file <= 8; file++ {
+			sq := square.FromRankAndFile(backRankNbr, file)
+			pieces[backRank[file-1]].SetSquare(sq)
+			pieces[piece.PAWN].SetSquare(square.FromRankAndFile(pawnRank, file))
+			if backRank[file-1] == piece.ROOK {
+				rooks = append(rooks, sq)
+			}
+		}
+		for _, pieceType := range piece.AllPieces {
+			builder.AddPiece(col, pieceType, pieces[pieceType])
+		}
+		builder.CastlingAvailability(col, true, true).CastlingRook(col, true, rooks[1])
+		builder.CastlingAvailability(col, false, true).CastlingRook(col, false, rooks[0])
+	}
+	return builder.Chess960(true).FullmoveNbr(1).Build()
+}
@@ -0,0 +1,137 @@
+package position
+
+import (
+	"testing"
+
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+)
+
+func TestChess960StartPosition(t *testing.T) {
+	data := map[int]string{
+		0:                     "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1",
+		Chess960StandardIndex: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		959:                   "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w KQkq - 0 1",
+	}
+	for index, expected := range data {
+		posn, err := Chess960StartPosition(index)
+		if err != nil {
+			t.Fatalf("index %d: unexpected error: %s", index, err)
+		}
+		if posn.Fen() != expected {
+			t.Errorf("index %d: expected %s but got %s", index, expected, posn.Fen())
+		}
+		if !posn.Chess960() {
+			t.Errorf("index %d: expected a Chess960 position", index)
+		}
+	}
+	seen := make(map[string]bool)
+	for index := 0; index < 960; index++ {
+		posn, _ := Chess960StartPosition(index)
+		fen := posn.Fen()
+		if seen[fen] {
+			t.Fatalf("index %d: position %s generated twice", index, fen)
+		}
+		seen[fen] = true
+		bishops := posn.Pieces(colour.White, piece.BISHOP).SetBits()
+		if (bishops[0]+bishops[1])%2 == 0 {
+			t.Errorf("index %d: bishops on squares of the same colour: %s", index, fen)
+		}
+		king := posn.Pieces(colour.White, piece.KING).SetBits()[0]
+		if posn.QueenssideCastlingRook(colour.White).File() > posn.KingssideCastlingRook(colour.White).File() ||
+			int(posn.QueenssideCastlingRook(colour.White)) < king || int(posn.KingssideCastlingRook(colour.White)) > king {
+			t.Errorf("index %d: king not between the rooks: %s", index, fen)
+		}
+	}
+	for _, index := range []int{-1, 960} {
+		if _, err := Chess960StartPosition(index); err == nil {
+			t.Errorf("expected error for index %d", index)
+		}
+	}
+}
+
+// https://www.chessprogramming.org/Chess960_Perft_Results
+func TestChess960Perft(t *testing.T) {
+	doTest(moveData{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{21, 528, 12189, 326672}}, t)
+	doTest(moveData{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{21, 807, 18002}}, t)
+	doTest(moveData{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []int{20, 479, 10471}}, t)
+	doTest(moveData{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", []int{22, 593, 13440}}, t)
+	doTest(moveData{"1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9", []int{28, 1120, 31058}}, t)
+	doTest(moveData{"qnbnr1kr/ppp1b1pp/4p3/3p1p2/8/2NPP3/PPP1BPPP/QNB1R1KR w HEhe - 1 9", []int{29, 899, 26578}}, t)
+	doTest(moveData{"q1bnrkr1/ppppp2p/2n2p2/4b1p1/2NP4/8/PPP1PPPP/QNB1RRKB w ge - 1 9", []int{30, 860, 24566}}, t)
+	doTest(moveData{"qbn1brkr/ppp1p1p1/2n4p/3p1p2/P7/6PP/QPPPPP2/1BNNBRKR w HFhf - 0 9", []int{25, 635, 17054}}, t)
+	doTest(moveData{"qnnbbrkr/1p2ppp1/2pp3p/p7/1P5P/2NP4/P1P1PPP1/Q1NBBRKR w HFhf - 0 9", []int{24, 572, 15243}}, t)
+}
+
+func TestChess960Castling(t *testing.T) {
+	data := []struct {
+		fen, uci, expected string
+	}{
+		// king stays on c1, rook moves from b1 to d1
+		{"4k3/8/8/8/8/8/8/1RK5 w B - 0 1", "c1b1", "4k3/8/8/8/8/8/8/2KR4 b - - 1 1"},
+		// king and rook swap squares
+		{"4k3/8/8/8/8/8/8/5KR1 w G - 0 1", "f1g1", "4k3/8/8/8/8/8/8/5RK1 b - - 1 1"},
+		// king moves from b8 to g8 past the rook
+		{"1k4r1/8/8/8/8/8/8/4K3 b g - 0 1", "b8g8", "5rk1/8/8/8/8/8/8/4K3 w - - 1 2"},
+	}
+	for _, d := range data {
+		posn, err := ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("error parsing fen '%s': %s", d.fen, err)
+		}
+		m, err := posn.ParseUci(d.uci)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", d.fen, err)
+		}
+		if !m.IsCastles() || posn.UciString(m) != d.uci {
+			t.Fatalf("%s: expected castles %s but got %s", d.fen, d.uci, posn.UciString(m))
+		}
+		posn.MakeMove(m)
+		if posn.Fen() != d.expected {
+			t.Errorf("%s: expected %s after castling but got %s", d.fen, d.expected, posn.Fen())
+		}
+		posn.UnmakeMove(m)
+		if posn.ShredderFen() != d.fen {
+			t.Errorf("expected %s after unmaking the move but got %s", d.fen, posn.ShredderFen())
+		}
+	}
+
+	// the rook on b1 shields the king from the rook on a1: castling is not allowed
+	posn, _ := ParseFen("4k3/8/8/8/8/8/8/rRK5 w B - 0 1")
+	for _, m := range posn.FindMoves(colour.White) {
+		if m.IsCastles() {
+			t.Errorf("castling should not be allowed")
+		}
+	}
+	// in Chess960 castling is only accepted as 'king takes rook'
+	if m, err := posn.ParseUci("f1g1"); err == nil {
+		t.Errorf("unexpected move %s", m.String())
+	}
+}
+
+func TestParseUci(t *testing.T) {
+	posn, _ := ParseFen("r3k2r/1P6/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	for _, d := range []struct{ uci, expected string }{
+		{"e1g1", "O-O"}, {"e1h1", "O-O"}, {"e1c1", "O-O-O"}, {"e1a1", "O-O-O"}, {"b7a8n", "B7xA8=N"}, {"B7B8Q", "B7B8=Q"},
+	} {
+		m, err := posn.ParseUci(d.uci)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", d.uci, err)
+		} else if m.String() != d.expected {
+			t.Errorf("%s: expected %s but got %s", d.uci, d.expected, m.String())
+		}
+	}
+	for _, str := range []string{"e1e3", "b7b8", "x"} {
+		if _, err := posn.ParseUci(str); err == nil {
+			t.Errorf("expected error for '%s'", str)
+		}
+	}
+	m, _ := posn.ParseUci("e1g1")
+	if posn.UciString(m) != "e1g1" {
+		t.Errorf("expected e1g1 but got %s", posn.UciString(m))
+	}
+	posn.SetChess960(true)
+	if posn.UciString(m) != "e1h1" {
+		t.Errorf("expected e1h1 but got %s", posn.UciString(m))
+	}
+}
@@ -0,0 +1,165 @@
+package position
+
+import (
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/ray"
+	"github.com/rjo67/chess/square"
+)
+
+// DrawReason describes why a position is drawn
+type DrawReason int
+
+// the reasons for a draw
+const (
+	NoDraw               DrawReason = iota // the position is not drawn
+	Stalemate                              // the side to move has no legal moves and is not in check
+	InsufficientMaterial                   // neither side can checkmate with the remaining pieces
+	DeadPosition                           // no sequence of legal moves can lead to checkmate (heuristic, see DrawReason)
+	SeventyFiveMoveRule                    // 75 moves by each side without a capture or pawn move: automatic draw
+	FiftyMoveRule                          // 50 moves by each side without a capture or pawn move: draw can be claimed
+	FivefoldRepetition                     // the same position has occurred five times: automatic draw
+	ThreefoldRepetition                    // the same position has occurred three times: draw can be claimed
+)
+
+var drawReasonMapping = []string{"no draw", "stalemate", "insufficient material", "dead position", "seventy-five-move rule", "fifty-move rule",
+	"fivefold repetition", "threefold repetition"}
+
+func (r DrawReason) String() string {
+	return drawReasonMapping[r]
+}
+
+// Claimable returns true if the draw must be claimed by one of the players, rather than ending the game automatically
+func (r DrawReason) Claimable() bool {
+	return r == FiftyMoveRule || r == ThreefoldRepetition
+}
+
+// DrawReason returns the reason why the position is drawn according to the FIDE laws, or NoDraw.
+// A checkmate takes precedence over the fifty- and seventy-five-move rules.
+// Automatic draws are returned in preference to draws which must be claimed.
+// Repetitions are detected using the history of the moves made on this position, see IsRepetition.
+//
+// If deadPositionHeuristic is set, positions containing only kings and pawns where all pawns are blocked
+// and neither king can reach an opponent's pawn are also recognised as dead. This does not find all dead positions.
+func (p Position) DrawReason(deadPositionHeuristic bool) DrawReason {
+	noMoves := len(p.FindMoves(p.activeColour)) == 0
+	inCheck := p.InCheck()
+	switch {
+	case noMoves && inCheck:
+		return NoDraw
+	case noMoves:
+		return Stalemate
+	case p.IsInsufficientMaterial():
+		return InsufficientMaterial
+	case deadPositionHeuristic && p.isBlockedPawnPosition():
+		return DeadPosition
+	case p.halfmoveClock >= 150:
+		return SeventyFiveMoveRule
+	case p.IsRepetition(5):
+		return FivefoldRepetition
+	case p.halfmoveClock >= 100:
+		return FiftyMoveRule
+	case p.IsRepetition(3):
+		return ThreefoldRepetition
+	}
+	return NoDraw
+}
+
+// IsRepetition returns true if the current position has occurred at least n times (including the current occurrence)
+// since the position was created, e.g. IsRepetition(3) for the threefold repetition rule, or IsRepetition(2) to detect
+// repetitions during a search. See Key for the definition of 'same position'.
+// Only the moves since the last capture or pawn move (given by the halfmove clock) are examined.
+// The key of the position is compared with the keys stored in the history by MakeMove, no key is recalculated.
+func (p Position) IsRepetition(n int) bool {
+	if n <= 1 {
+		return true
+	}
+	count := 1
+	// positions with the same side to move are at every second entry
+	oldest := len(p.history) - p.halfmoveClock
+	for i := len(p.history) - 2; i >= 0 && i >= oldest; i -= 2 {
+		if p.history[i].key == p.key {
+			count++
+			if count >= n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsInsufficientMaterial returns true if neither side can checkmate, i.e. there are no pawns, rooks or queens, and
+// the remaining pieces are either one knight or bishop, or any number of bishops which are all on squares of the same colour
+func (p Position) IsInsufficientMaterial() bool {
+	var knights, bishops bitset.BitSet
+	for _, col := range colour.AllColours {
+		if !p.pieces[col][piece.PAWN].Or(p.pieces[col][piece.ROOK]).Or(p.pieces[col][piece.QUEEN]).IsEmpty() {
+			return false
+		}
+		knights = knights.Or(p.pieces[col][piece.KNIGHT])
+		bishops = bishops.Or(p.pieces[col][piece.BISHOP])
+	}
+	minorPieces := knights.Cardinality() + bishops.Cardinality()
+	if minorPieces <= 1 {
+		return true
+	}
+	return knights.IsEmpty() && (bishops.And(bitset.LightSquares).IsEmpty() || bishops.And(bitset.DarkSquares).IsEmpty())
+}
+
+// isBlockedPawnPosition returns true if there are only kings and pawns, no pawn can move or capture,
+// and neither king can reach an undefended pawn of the opponent
+func (p Position) isBlockedPawnPosition() bool {
+	if p.enpassantSquare != nil {
+		return false
+	}
+	pawns := p.pieces[colour.White][piece.PAWN].Or(p.pieces[colour.Black][piece.PAWN])
+	if pawns.IsEmpty() || pawns.Or(p.pieces[colour.White][piece.KING]).Or(p.pieces[colour.Black][piece.KING]) != p.occupiedSquares {
+		return false
+	}
+	var pawnAttacks [2]bitset.BitSet
+	for _, col := range colour.AllColours {
+		for sq := square.Square(1); sq <= 64; sq++ {
+			if !ray.PawnAttackBitSets[col][sq].And(p.pieces[col][piece.PAWN]).IsEmpty() {
+				pawnAttacks[col].SetSquare(sq)
+			}
+		}
+	}
+	for _, col := range colour.AllColours {
+		otherColour := col.Other()
+		direction := 1
+		if col == colour.Black {
+			direction = -1
+		}
+		// pawns must be blocked and unable to capture
+		if !pawnAttacks[col].And(p.pieces[otherColour][piece.PAWN]).IsEmpty() {
+			return false
+		}
+		for _, bit := range p.pieces[col][piece.PAWN].SetBits() {
+			sq := square.Square(bit)
+			if rank := sq.Rank() + direction; rank < 1 || rank > 8 {
+				return false
+			}
+			if !pawns.IsSet(uint(square.FromRankAndFile(sq.Rank()+direction, sq.File()))) {
+				return false
+			}
+		}
+		// squares which the king can reach, avoiding its own pawns and the squares attacked by the opponent's pawns
+		allowed := p.pieces[col][piece.PAWN].Or(pawnAttacks[otherColour]).Not()
+		reachable := p.pieces[col][piece.KING]
+		for {
+			next := reachable
+			for _, bit := range reachable.SetBits() {
+				next = next.Or(ray.KingAttackBitSets[bit].And(allowed))
+			}
+			if next == reachable {
+				break
+			}
+			reachable = next
+		}
+		if !reachable.And(p.pieces[otherColour][piece.PAWN]).IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,214 @@
+package position
+
+import "testing"
+
+func TestDrawReason(t *testing.T) {
+	data := []struct {
+		fen       string
+		heuristic bool
+		expected  DrawReason
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", true, NoDraw},
+		{"k7/8/1QK5/8/8/8/8/8 b - - 0 1", false, Stalemate},
+		{"k7/1Q6/1K6/8/8/8/8/8 b - - 150 100", false, NoDraw}, // checkmate
+		{"8/8/4k3/8/8/3K4/8/8 w - - 0 1", false, InsufficientMaterial},
+		{"8/8/4k3/8/8/3K4/8/6N1 w - - 0 1", false, InsufficientMaterial},
+		{"8/8/4k3/8/8/3K4/8/6b1 w - - 0 1", false, InsufficientMaterial},
+		{"8/2b5/4k3/8/8/3K4/8/2B1B3 w - - 0 1", false, InsufficientMaterial}, // all bishops on dark squares
+		{"8/8/4k3/8/8/3K4/8/4BB2 w - - 0 1", false, NoDraw},                  // bishops on different colours
+		{"8/8/4k3/8/8/3K4/8/4Bb2 w - - 0 1", false, NoDraw},                  // bishops on different colours
+		{"8/8/4k3/6n1/8/3K4/8/6N1 w - - 0 1", false, NoDraw},                 // K+N vs K+N
+		{"8/8/4k3/8/8/3K4/8/5NN1 w - - 0 1", false, NoDraw},
+		{"8/8/4k3/8/8/3K4/P7/8 w - - 0 1", false, NoDraw},
+		{"8/8/4k3/1p1p1p1p/1P1P1P1P/4K3/8/8 w - - 0 1", true, DeadPosition},
+		{"8/8/4k3/1p1p1p1p/1P1P1P1P/4K3/8/8 w - - 0 1", false, NoDraw},
+		{"8/8/4k3/1p1p1p2/1P1P1P2/4K3/8/8 w - - 0 1", true, NoDraw},     // king can reach the f-pawn via the h-file
+		{"8/8/4k3/1p1p1p1p/1P1P1P1P/4K3/8/1B6 w - - 0 1", true, NoDraw}, // bishop
+		{"8/8/4k3/1p1p1p1p/PP1P1P1P/4K3/8/8 w - - 0 1", true, NoDraw},   // a-pawn can move
+		{"8/8/4k3/pp1p1p1p/1P1P1P1P/4K3/8/8 w - - 0 1", true, NoDraw},   // bxa5 possible
+		{"8/8/4k3/8/8/3K4/P7/8 w - - 99 80", false, NoDraw},
+		{"8/8/4k3/8/8/3K4/P7/8 w - - 100 80", false, FiftyMoveRule},
+		{"8/8/4k3/8/8/3K4/P7/8 w - - 150 100", false, SeventyFiveMoveRule},
+	}
+	for _, d := range data {
+		posn, err := ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("%s: error parsing fen: %s", d.fen, err)
+		}
+		if reason := posn.DrawReason(d.heuristic); reason != d.expected {
+			t.Errorf("%s: expected '%s' but got '%s'", d.fen, d.expected, reason)
+		}
+	}
+}
+
+func TestDrawReasonClaimable(t *testing.T) {
+	for _, r := range []DrawReason{Stalemate, InsufficientMaterial, DeadPosition, SeventyFiveMoveRule} {
+		if r.Claimable() {
+			t.Errorf("%s should not be claimable", r)
+		}
+	}
+	if !FiftyMoveRule.Claimable() {
+		t.Errorf("%s should be claimable", FiftyMoveRule)
+	}
+}
+
+// plays the given moves (in SAN) and returns the resulting position
+func playMoves(t *testing.T, fen string, moves ...string) Position {
+	posn, err := ParseFen(fen)
+	if err != nil {
+		t.Fatalf("%s: error parsing fen: %s", fen, err)
+	}
+	for _, san := range moves {
+		m, err := posn.ParseSan(san)
+		if err != nil {
+			t.Fatalf("%s: %s", fen, err)
+		}
+		posn.MakeMove(m)
+	}
+	return posn
+}
+
+func TestRepetition(t *testing.T) {
+	start := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+	knightMoves := []string{"Nf3", "Nf6", "Ng1", "Ng8"}
+	posn := playMoves(t, start, knightMoves[:3]...)
+	if posn.IsRepetition(2) {
+		t.Errorf("unexpected repetition")
+	}
+	posn = playMoves(t, start, knightMoves...)
+	if !posn.IsRepetition(2) || posn.IsRepetition(3) || posn.DrawReason(false) != NoDraw {
+		t.Errorf("expected position to have occurred twice")
+	}
+	posn = playMoves(t, start, append(knightMoves, knightMoves...)...)
+	if !posn.IsRepetition(3) || posn.IsRepetition(4) || posn.DrawReason(false) != ThreefoldRepetition {
+		t.Errorf("expected position to have occurred three times, draw reason %s", posn.DrawReason(false))
+	}
+	moves := append(knightMoves, knightMoves...)
+	moves = append(moves, knightMoves...)
+	posn = playMoves(t, start, append(moves, knightMoves...)...)
+	if !posn.IsRepetition(5) || posn.DrawReason(false) != FivefoldRepetition {
+		t.Errorf("expected position to have occurred five times, draw reason %s", posn.DrawReason(false))
+	}
+
+	// a pawn move in between: the earlier positions are not examined
+	posn = playMoves(t, start, "Nf3", "Nf6", "Ng1", "Ng8", "e4", "e5", "Nf3", "Nf6", "Ng1", "Ng8")
+	if posn.IsRepetition(3) {
+		t.Errorf("unexpected repetition after pawn move")
+	}
+
+	// the enpassant square only counts if the capture is possible
+	posn = playMoves(t, start, "e4", "Nf6", "Nf3", "Ng8", "Ng1")
+	if !posn.IsRepetition(2) {
+		t.Errorf("expected repetition, the pawn on e4 cannot be captured enpassant")
+	}
+	posn = playMoves(t, "4k3/8/8/8/3p4/8/4P3/4K3 w - - 0 1", "e4", "Ke7", "Kd1", "Ke8", "Ke1")
+	if posn.IsRepetition(2) {
+		t.Errorf("unexpected repetition, the pawn on e4 could be captured enpassant")
+	}
+
+	// castling rights are lost by the king moves
+	posn = playMoves(t, "r3k3/8/8/8/8/8/8/R3K3 w Qq - 0 1", "Kd1", "Kd8", "Ke1", "Ke8", "Kd1", "Kd8", "Ke1", "Ke8")
+	if !posn.IsRepetition(2) || posn.IsRepetition(3) {
+		t.Errorf("positions with different castling rights must not be counted")
+	}
+
+	// making and unmaking moves maintains the history
+	posn = playMoves(t, start, append(knightMoves, knightMoves[:3]...)...)
+	m, _ := posn.ParseSan("Ng8")
+	posn.MakeMove(m)
+	if !posn.IsRepetition(3) {
+		t.Errorf("expected repetition after MakeMove")
+	}
+	posn.UnmakeMove(m)
+	if !posn.IsRepetition(2) || posn.IsRepetition(3) {
+		t.Errorf("expected position to have occurred twice after UnmakeMove")
+	}
+}
+
+func TestKeyEnpassant(t *testing.T) {
+	data := []struct {
+		fen1, fen2 string
+		equal      bool
+	}{
+		// no black pawn can capture
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq - 0 1", true},
+		// capture possible
+		{"4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 1", "4k3/8/8/8/3pP3/8/8/4K3 b - - 0 1", false},
+		// capture not legal: after dxe3 the king is attacked along the rank
+		{"8/8/8/8/k2pP2R/8/8/4K3 b - e3 0 1", "8/8/8/8/k2pP2R/8/8/4K3 b - - 0 1", true},
+		// castling rights and side to move
+		{"r3k3/8/8/8/8/8/8/R3K3 w Qq - 0 1", "r3k3/8/8/8/8/8/8/R3K3 w Q - 0 1", false},
+		{"r3k3/8/8/8/8/8/8/R3K3 w - - 0 1", "r3k3/8/8/8/8/8/8/R3K3 b - - 0 1", false},
+		// clocks are ignored
+		{"r3k3/8/8/8/8/8/8/R3K3 w - - 0 1", "r3k3/8/8/8/8/8/8/R3K3 w - - 12 40", true},
+	}
+	for _, d := range data {
+		posn1 := playMoves(t, d.fen1)
+		posn2 := playMoves(t, d.fen2)
+		if (posn1.Key() == posn2.Key()) != d.equal {
+			t.Errorf("%s / %s: expected equal keys: %t", d.fen1, d.fen2, d.equal)
+		}
+	}
+}
+
+// the key kept up to date by MakeMove must be the same as the key calculated from scratch after every move
+func TestKeyIncremental(t *testing.T) {
+	var walk func(posn *Position, depth int)
+	walk = func(posn *Position, depth int) {
+		if depth == 0 {
+			return
+		}
+		for _, m := range posn.FindMoves(posn.ActiveColour()) {
+			key := posn.Key()
+			posn.MakeMove(m)
+			if posn.Key() != posn.computeKey() {
+				t.Fatalf("key after %s does not match the position %s", m.String(), posn.Fen())
+			}
+			walk(posn, depth-1)
+			posn.UnmakeMove(m)
+			if posn.Key() != key {
+				t.Fatalf("key not restored after unmaking %s in %s", m.String(), posn.Fen())
+			}
+		}
+	}
+	for _, fen := range []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", // castling, rook captures
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",                            // enpassant captures, some of them illegal
+		"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1",     // promotions with and without capture
+		"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",    // Chess960 castling
+		"4k3/8/8/8/3p4/8/4P3/R3K2R w KQ - 0 1",
+	} {
+		posn, err := ParseFen(fen)
+		if err != nil {
+			t.Fatalf("error parsing fen: %s", err)
+		}
+		if posn.Key() != posn.computeKey() {
+			t.Fatalf("%s: key of the parsed position does not match", fen)
+		}
+		walk(&posn, 3)
+	}
+}
+
+func TestMakeMoveClocks(t *testing.T) {
+	posn := playMoves(t, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e4", "Nf6", "Nc3")
+	if fen := posn.Fen(); fen != "rnbqkb1r/pppppppp/5n2/8/4P3/2N5/PPPP1PPP/R1BQKBNR b KQkq - 2 2" {
+		t.Errorf("unexpected fen after moves: %s", fen)
+	}
+	// enpassant square and clocks are restored by UnmakeMove, also over several moves
+	posn = playMoves(t, "4k3/8/8/8/3p4/8/4P3/4K3 w - - 5 10")
+	m1, _ := posn.ParseSan("e4")
+	posn.MakeMove(m1)
+	m2, _ := posn.ParseSan("Kd7")
+	posn.MakeMove(m2)
+	if fen := posn.Fen(); fen != "8/3k4/8/8/3pP3/8/8/4K3 w - - 1 11" {
+		t.Errorf("unexpected fen after moves: %s", fen)
+	}
+	posn.UnmakeMove(m2)
+	if fen := posn.Fen(); fen != "4k3/8/8/8/3pP3/8/8/4K3 b - e3 0 10" {
+		t.Errorf("unexpected fen after unmaking second move: %s", fen)
+	}
+	posn.UnmakeMove(m1)
+	if fen := posn.Fen(); fen != "4k3/8/8/8/3p4/8/4P3/4K3 w - - 5 10" {
+		t.Errorf("unexpected fen after unmaking first move: %s", fen)
+	}
+}
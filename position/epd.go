@@ -0,0 +1,231 @@
+package position
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rjo67/chess/move"
+)
+
+// EPD stores a position read from an EPD record together with its operations.
+// https://www.chessprogramming.org/Extended_Position_Description
+type EPD struct {
+	Position   Position
+	Fen        string      // FEN of the position; the clocks are taken from the 'hmvc' and 'fmvn' operations if present
+	Operations []Operation // in the order given in the record
+}
+
+// Operation is one opcode of an EPD record with its operands, e.g. "bm" with operands ["Nf3", "e4"]
+type Operation struct {
+	Opcode   string
+	Operands []string
+}
+
+// ParseEpd creates an EPD from one record, consisting of the first four fields of a FEN followed by the operations,
+// e.g. `r1b1k2r/... w kq - bm Nf3; id "WAC.001";`.
+// The halfmove clock and fullmove number may also be given directly after the four fields, as found in some perft suites.
+func ParseEpd(record string) (EPD, error) {
+	fields := strings.Fields(record)
+	if len(fields) < 4 {
+		return EPD{}, ParseError{badNbrFields, 0}
+	}
+	// the operations start after the fourth field
+	rest := record
+	for i := 0; i < 4; i++ {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		rest = rest[strings.IndexFunc(rest+" ", unicode.IsSpace):]
+	}
+	operations, err := parseOperations(rest)
+	if err != nil {
+		return EPD{}, err
+	}
+
+	halfmoveClock, fullmoveNbr := "0", "1"
+	// clocks directly after the four fields
+	if len(operations) > 0 && isNumber(operations[0].Opcode) {
+		tokens := append([]string{operations[0].Opcode}, operations[0].Operands...)
+		if len(tokens) < 2 || !isNumber(tokens[1]) {
+			return EPD{}, fmt.Errorf("expected halfmove clock and fullmove number but got '%s'", strings.Join(tokens, " "))
+		}
+		halfmoveClock, fullmoveNbr = tokens[0], tokens[1]
+		if len(tokens) == 2 {
+			operations = operations[1:]
+		} else {
+			operations[0] = Operation{Opcode: tokens[2], Operands: tokens[3:]}
+		}
+	}
+
+	e := EPD{Operations: operations}
+	for _, op := range operations {
+		if !isOpcode(op.Opcode) {
+			return EPD{}, fmt.Errorf("invalid opcode '%s'", op.Opcode)
+		}
+	}
+	if operands, ok := e.Operands("hmvc"); ok && len(operands) == 1 {
+		halfmoveClock = operands[0]
+	}
+	if operands, ok := e.Operands("fmvn"); ok && len(operands) == 1 {
+		fullmoveNbr = operands[0]
+	}
+	e.Fen = strings.Join(append(fields[:4:4], halfmoveClock, fullmoveNbr), " ")
+	if e.Position, err = ParseFen(e.Fen); err != nil {
+		return EPD{}, err
+	}
+	return e, nil
+}
+
+// parseOperations splits the operations into opcodes and operands.
+// Operations are terminated by ';', operands are separated by whitespace or given as strings in double quotes.
+func parseOperations(str string) ([]Operation, error) {
+	var operations []Operation
+	var tokens []string
+	endOperation := func() {
+		if len(tokens) > 0 {
+			operations = append(operations, Operation{Opcode: tokens[0], Operands: tokens[1:]})
+		}
+		tokens = nil
+	}
+	for i := 0; i < len(str); {
+		switch ch := str[i]; {
+		case ch == ';':
+			endOperation()
+			i++
+		case ch == ' ' || ch == '\t' || ch == '\r' || ch == '\n':
+			i++
+		case ch == '"':
+			end := strings.IndexByte(str[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in operation: %s", str[i:])
+			}
+			tokens = append(tokens, str[i+1:i+1+end])
+			i += end + 2
+		default:
+			end := strings.IndexAny(str[i:], " \t\r\n;\"")
+			if end < 0 {
+				end = len(str) - i
+			}
+			tokens = append(tokens, str[i:i+end])
+			i += end
+		}
+	}
+	// the last operation need not be terminated
+	endOperation()
+	return operations, nil
+}
+
+// opcodes start with a letter and consist of letters, digits and underscores
+func isOpcode(str string) bool {
+	for i, ch := range str {
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || i > 0 && (ch >= '0' && ch <= '9' || ch == '_')) {
+			return false
+		}
+	}
+	return str != ""
+}
+
+func isNumber(str string) bool {
+	_, err := strconv.Atoi(str)
+	return err == nil
+}
+
+// ReadEpd reads all records of an EPD file. Empty lines and lines starting with '#' are ignored.
+func ReadEpd(r io.Reader) ([]EPD, error) {
+	var records []EPD
+	scanner := bufio.NewScanner(r)
+	lineNbr := 0
+	for scanner.Scan() {
+		lineNbr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		e, err := ParseEpd(line)
+		if err != nil {
+			return records, fmt.Errorf("line %d: %s", lineNbr, err)
+		}
+		records = append(records, e)
+	}
+	return records, scanner.Err()
+}
+
+// Operands returns the operands of the first operation with the given opcode
+func (e EPD) Operands(opcode string) ([]string, bool) {
+	for _, op := range e.Operations {
+		if op.Opcode == opcode {
+			return op.Operands, true
+		}
+	}
+	return nil, false
+}
+
+// ID returns the operand of the 'id' operation, or an empty string
+func (e EPD) ID() string {
+	if operands, ok := e.Operands("id"); ok && len(operands) > 0 {
+		return operands[0]
+	}
+	return ""
+}
+
+// IntOperand returns the first operand of the given opcode as an integer, e.g. for 'acd' (analysis depth) or 'ce' (centipawn evaluation)
+func (e EPD) IntOperand(opcode string) (int, bool, error) {
+	operands, ok := e.Operands(opcode)
+	if !ok || len(operands) == 0 {
+		return 0, false, nil
+	}
+	i, err := strconv.Atoi(operands[0])
+	if err != nil {
+		return 0, true, fmt.Errorf("operation %s: could not parse '%s'", opcode, operands[0])
+	}
+	return i, true, nil
+}
+
+// BestMoves returns the moves of the 'bm' operation
+func (e EPD) BestMoves() ([]move.Move, error) {
+	return e.moves("bm")
+}
+
+// AvoidMoves returns the moves of the 'am' operation
+func (e EPD) AvoidMoves() ([]move.Move, error) {
+	return e.moves("am")
+}
+
+// moves parses the operands (in SAN) of the given opcode
+func (e EPD) moves(opcode string) ([]move.Move, error) {
+	operands, _ := e.Operands(opcode)
+	moves := make([]move.Move, 0, len(operands))
+	for _, san := range operands {
+		m, err := e.Position.ParseSan(san)
+		if err != nil {
+			return nil, fmt.Errorf("operation %s: %s", opcode, err)
+		}
+		moves = append(moves, m)
+	}
+	return moves, nil
+}
+
+// PerftCounts returns the expected number of leaf nodes as given by the operations D1, D2, ..., keyed by depth
+func (e EPD) PerftCounts() (map[int]int, error) {
+	counts := make(map[int]int)
+	for _, op := range e.Operations {
+		if len(op.Opcode) < 2 || op.Opcode[0] != 'D' {
+			continue
+		}
+		depth, err := strconv.Atoi(op.Opcode[1:])
+		if err != nil || depth < 1 {
+			continue
+		}
+		if len(op.Operands) != 1 {
+			return nil, fmt.Errorf("operation %s: expected one operand but got %d", op.Opcode, len(op.Operands))
+		}
+		count, err := strconv.Atoi(op.Operands[0])
+		if err != nil {
+			return nil, fmt.Errorf("operation %s: could not parse '%s'", op.Opcode, op.Operands[0])
+		}
+		counts[depth] = count
+	}
+	return counts, nil
+}
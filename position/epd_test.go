@@ -0,0 +1,136 @@
+package position
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rjo67/chess/piece/colour"
+)
+
+func TestParseEpd(t *testing.T) {
+	e, err := ParseEpd(`2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001"; c0 "semicolon; in a string";`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if e.ID() != "WAC.001" {
+		t.Errorf("expected id 'WAC.001' but got '%s'", e.ID())
+	}
+	if operands, _ := e.Operands("c0"); !reflect.DeepEqual(operands, []string{"semicolon; in a string"}) {
+		t.Errorf("bad operands for c0: %v", operands)
+	}
+	if e.Fen != "2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1" {
+		t.Errorf("bad fen: %s", e.Fen)
+	}
+	moves, err := e.BestMoves()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(moves) != 1 || moves[0].UciString() != "g3g6" {
+		t.Errorf("bad best moves: %v", moves)
+	}
+	if moves, err := e.AvoidMoves(); err != nil || len(moves) != 0 {
+		t.Errorf("expected no avoid moves but got %v (%v)", moves, err)
+	}
+}
+
+func TestParseEpdOpcodes(t *testing.T) {
+	e, err := ParseEpd("4k3/8/8/8/8/8/4P3/4K3 b - - am Kd7 Ke7;acd 12; ce -35 ; hmvc 7; fmvn 42; bm Kf8")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var opcodes []string
+	for _, op := range e.Operations {
+		opcodes = append(opcodes, op.Opcode)
+	}
+	if strings.Join(opcodes, " ") != "am acd ce hmvc fmvn bm" {
+		t.Errorf("bad opcodes: %v", opcodes)
+	}
+	if acd, ok, err := e.IntOperand("acd"); acd != 12 || !ok || err != nil {
+		t.Errorf("bad acd: %d, %t, %v", acd, ok, err)
+	}
+	if ce, ok, err := e.IntOperand("ce"); ce != -35 || !ok || err != nil {
+		t.Errorf("bad ce: %d, %t, %v", ce, ok, err)
+	}
+	if _, ok, _ := e.IntOperand("dm"); ok {
+		t.Errorf("did not expect opcode 'dm'")
+	}
+	if e.Position.ActiveColour() != colour.Black || e.Position.HalfmoveClock() != 7 || e.Position.FullmoveNbr() != 42 {
+		t.Errorf("bad position: %s", e.Fen)
+	}
+	moves, err := e.AvoidMoves()
+	if err != nil || len(moves) != 2 {
+		t.Fatalf("bad avoid moves: %v (%v)", moves, err)
+	}
+	if moves[0].UciString() != "e8d7" || moves[1].UciString() != "e8e7" {
+		t.Errorf("bad avoid moves: %s %s", moves[0].UciString(), moves[1].UciString())
+	}
+}
+
+func TestParseEpdPerft(t *testing.T) {
+	// format of the common perft suites, with and without clocks
+	for _, record := range []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - ;D1 48 ;D2 2039 ;D3 97862",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1 ;D1 48 ;D2 2039 ;D3 97862",
+	} {
+		e, err := ParseEpd(record)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", record, err)
+		}
+		counts, err := e.PerftCounts()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", record, err)
+		}
+		if !reflect.DeepEqual(counts, map[int]int{1: 48, 2: 2039, 3: 97862}) {
+			t.Errorf("%s: bad counts %v", record, counts)
+		}
+		for depth, expected := range counts {
+			if nodes := e.Position.Perft(depth); nodes != expected {
+				t.Errorf("%s: depth %d: expected %d nodes but got %d", record, depth, expected, nodes)
+			}
+		}
+	}
+}
+
+func TestParseEpdErrors(t *testing.T) {
+	for _, record := range []string{
+		"",
+		"4k3/8/8/8/8/8/4P3/4K3 w -",
+		"4k3/8/8/8/8/8/4P3/4K3 x - - bm e4;",
+		`4k3/8/8/8/8/8/4P3/4K3 w - - id "unterminated;`,
+		"4k3/8/8/8/8/8/4P3/4K3 w - - 5 ;D1 5",
+		"4k3/8/8/8/8/8/4P3/4K3 w - - 1x 5;",
+	} {
+		if _, err := ParseEpd(record); err == nil {
+			t.Errorf("expected error for '%s'", record)
+		}
+	}
+	e, err := ParseEpd("4k3/8/8/8/8/8/4P3/4K3 w - - bm e5; D1 x")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, err := e.BestMoves(); err == nil {
+		t.Errorf("expected error for illegal best move")
+	}
+	if _, err := e.PerftCounts(); err == nil {
+		t.Errorf("expected error for bad perft count")
+	}
+}
+
+func TestReadEpd(t *testing.T) {
+	input := `# comment
+4k3/8/8/8/8/8/4P3/4K3 w - - id "1";
+
+4k3/8/8/8/8/8/4P3/4K3 b - - id "2";
+`
+	records, err := ReadEpd(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(records) != 2 || records[0].ID() != "1" || records[1].ID() != "2" {
+		t.Errorf("bad records: %v", records)
+	}
+	if _, err := ReadEpd(strings.NewReader(input + "bad\n")); err == nil || !strings.Contains(err.Error(), "line 5") {
+		t.Errorf("expected error in line 5 but got %v", err)
+	}
+}
@@ -28,6 +28,16 @@ func (e ParseError) Error() string {
 	return fmt.Sprintf("%s in field %d", e.msg, e.field)
 }
 
+// Field returns the number of the field (from 1) in which the error was found, or 0 if the number of fields is wrong
+func (e ParseError) Field() int {
+	return e.field
+}
+
+// Message returns the description of the error without the field
+func (e ParseError) Message() string {
+	return e.msg
+}
+
 // ParseFen creates a position from a FEN string
 // https://en.wikipedia.org/wiki/Forsyth%E2%80%93Edwards_Notation
 func ParseFen(fen string) (Position, error) {
@@ -65,14 +75,17 @@ func ParseFen(fen string) (Position, error) {
 	builder.ActiveColour(activeColour)
 
 	// third field: castling rights
-	castlingAvailabilityKingsSide, castlingAvailabilityQueensSide, err := processField3(fields[2])
+	castling, err := processField3(fields[2], pieceMap)
 	if err != nil {
 		return Position{}, err
 	}
-	builder.CastlingAvailability(colour.White, true, castlingAvailabilityKingsSide[colour.White])
-	builder.CastlingAvailability(colour.White, false, castlingAvailabilityQueensSide[colour.White])
-	builder.CastlingAvailability(colour.Black, true, castlingAvailabilityKingsSide[colour.Black])
-	builder.CastlingAvailability(colour.Black, false, castlingAvailabilityQueensSide[colour.Black])
+	for _, col := range colour.AllColours {
+		builder.CastlingAvailability(col, true, castling.kingsSide[col])
+		builder.CastlingAvailability(col, false, castling.queensSide[col])
+		builder.CastlingRook(col, true, castling.kingsSideRook[col])
+		builder.CastlingRook(col, false, castling.queensSideRook[col])
+	}
+	builder.Chess960(castling.chess960)
 
 	// fourth field: enpassant square
 	enpassantSquare, err := processField4(fields[3], activeColour)
@@ -95,8 +108,8 @@ func ParseFen(fen string) (Position, error) {
 	}
 	builder.FullmoveNbr(fullmoveNbr)
 
-	posn := builder.Build()
-	return posn, err
+	// ParseFen is lenient, see ParseFenStrict
+	return builder.build(), nil
 }
 
 // first field -- piece information in 8 subfields separated by '/'
@@ -160,46 +173,92 @@ func processField2(field string) (colour.Colour, error) {
 	return activeColour, nil
 }
 
-// third field: castling rights
-func processField3(field string) ([]bool, []bool, error) {
-	kingsSide := make([]bool, 2)
-	queensSide := make([]bool, 2)
+// castlingRights stores the castling rights given in the FEN
+type castlingRights struct {
+	kingsSide, queensSide         [2]bool
+	kingsSideRook, queensSideRook [2]square.Square // start squares of the rooks used for castling
+	chess960                      bool             // set if the castling rights can only occur in Chess960
+}
+
+// third field: castling rights.
+// As well as the standard notation 'KQkq', the Chess960 notations X-FEN and Shredder-FEN are supported,
+// which use the file of the rook (e.g. 'HAha') if necessary.
+// 'K' and 'Q' refer to the outermost rook on the appropriate side of the king.
+func processField3(field string, pieceMap map[string]*bitset.BitSet) (castlingRights, error) {
+	var castling castlingRights
+	for _, col := range colour.AllColours {
+		castling.kingsSideRook[col] = defaultKingssideCastlingRook[col]
+		castling.queensSideRook[col] = defaultQueenssideCastlingRook[col]
+	}
 	if field == "-" {
-		return kingsSide, queensSide, nil
+		return castling, nil
 	}
-	var kingsSideWhite, kingsSideBlack, queensSideWhite, queensSideBlack bool
 	for i := 0; i < len(field); i++ {
-		str := string(field[i])
-		switch str {
-		case "K":
-			if kingsSideWhite {
-				return nil, nil, ParseError{fmt.Sprintf("%s %s", castlingAvailabilitySyntax, "(multiple 'K')"), 3}
+		ch := field[i]
+		var col colour.Colour
+		switch {
+		case ch == 'K' || ch == 'Q' || (ch >= 'A' && ch <= 'H'):
+			col = colour.White
+		case ch == 'k' || ch == 'q' || (ch >= 'a' && ch <= 'h'):
+			col = colour.Black
+			ch -= 'a' - 'A'
+		default:
+			return castlingRights{}, ParseError{castlingAvailabilitySyntax, 3}
+		}
+		backRank := 1
+		kingString, rookString := piece.WhiteKingString, piece.WhiteRookString
+		if col == colour.Black {
+			backRank = 8
+			kingString, rookString = piece.BlackKingString, piece.BlackRookString
+		}
+		kingFile := square.Square(pieceMap[kingString].SetBits()[0]).File()
+		rooks := pieceMap[rookString]
+
+		var kingsside bool
+		var rookSq square.Square
+		switch ch {
+		case 'K':
+			kingsside = true
+			rookSq = castling.kingsSideRook[col]
+			for file := 8; file > kingFile; file-- {
+				if sq := square.FromRankAndFile(backRank, file); rooks.IsSet(uint(sq)) {
+					rookSq = sq
+					break
+				}
 			}
-			kingsSideWhite = true
-			kingsSide[colour.White] = true
-		case "k":
-			if kingsSideBlack {
-				return nil, nil, ParseError{fmt.Sprintf("%s %s", castlingAvailabilitySyntax, "(multiple 'k')"), 3}
+		case 'Q':
+			rookSq = castling.queensSideRook[col]
+			for file := 1; file < kingFile; file++ {
+				if sq := square.FromRankAndFile(backRank, file); rooks.IsSet(uint(sq)) {
+					rookSq = sq
+					break
+				}
 			}
-			kingsSideBlack = true
-			kingsSide[colour.Black] = true
-		case "Q":
-			if queensSideWhite {
-				return nil, nil, ParseError{fmt.Sprintf("%s %s", castlingAvailabilitySyntax, "(multiple 'Q')"), 3}
+		default:
+			// file of the rook
+			file := int(ch-'A') + 1
+			kingsside = file > kingFile
+			rookSq = square.FromRankAndFile(backRank, file)
+			castling.chess960 = true
+		}
+		if kingsside {
+			if castling.kingsSide[col] {
+				return castlingRights{}, ParseError{fmt.Sprintf("%s (multiple kingsside rights for %s)", castlingAvailabilitySyntax, col.String()), 3}
 			}
-			queensSideWhite = true
-			queensSide[colour.White] = true
-		case "q":
-			if queensSideBlack {
-				return nil, nil, ParseError{fmt.Sprintf("%s %s", castlingAvailabilitySyntax, "(multiple 'q')"), 3}
+			castling.kingsSide[col] = true
+			castling.kingsSideRook[col] = rookSq
+		} else {
+			if castling.queensSide[col] {
+				return castlingRights{}, ParseError{fmt.Sprintf("%s (multiple queensside rights for %s)", castlingAvailabilitySyntax, col.String()), 3}
 			}
-			queensSideBlack = true
-			queensSide[colour.Black] = true
-		default:
-			return nil, nil, ParseError{castlingAvailabilitySyntax, 3}
+			castling.queensSide[col] = true
+			castling.queensSideRook[col] = rookSq
+		}
+		if kingFile != 5 || (rookSq.File() != 1 && rookSq.File() != 8) {
+			castling.chess960 = true
 		}
 	}
-	return kingsSide, queensSide, nil
+	return castling, nil
 }
 
 // fourth field: enpassant square
@@ -209,10 +268,10 @@ func processField4(field string, activeColour colour.Colour) (*square.Square, er
 	}
 	sq, err := square.FromString(field)
 	if err != nil {
-		return nil, err
+		return nil, ParseError{err.Error(), 4}
 	}
 	if (activeColour == colour.White && sq.Rank() != 6) || (activeColour == colour.Black && sq.Rank() != 3) {
-		return nil, ParseError{fmt.Sprintf("invalid e.p. square '%s' for active colour: %s", sq.String(), activeColour.String()), 3}
+		return nil, ParseError{fmt.Sprintf("invalid e.p. square '%s' for active colour: %s", sq.String(), activeColour.String()), 4}
 	}
 	return &sq, nil
 }
@@ -221,10 +280,10 @@ func processField4(field string, activeColour colour.Colour) (*square.Square, er
 func processField5(field string) (int, error) {
 	i, err := strconv.Atoi(field)
 	if err != nil {
-		return 0, fmt.Errorf("could not parse halfmove clock: '%s'", field)
+		return 0, ParseError{fmt.Sprintf("could not parse halfmove clock: '%s'", field), 5}
 	}
 	if i < 0 {
-		return 0, fmt.Errorf("invalid value for halfmove clock: '%d'", i)
+		return 0, ParseError{fmt.Sprintf("invalid value for halfmove clock: '%d'", i), 5}
 	}
 	return i, nil
 }
@@ -233,10 +292,113 @@ func processField5(field string) (int, error) {
 func processField6(field string) (int, error) {
 	i, err := strconv.Atoi(field)
 	if err != nil {
-		return 0, fmt.Errorf("could not parse fullmove number: '%s'", field)
+		return 0, ParseError{fmt.Sprintf("could not parse fullmove number: '%s'", field), 6}
 	}
 	if i < 0 {
-		return 0, fmt.Errorf("invalid value for fullmove number: '%d'", i)
+		return 0, ParseError{fmt.Sprintf("invalid value for fullmove number: '%d'", i), 6}
 	}
 	return i, nil
 }
+
+// Fen returns the position in Forsyth-Edwards Notation.
+// For Chess960 positions the castling rights are given in X-FEN: 'KQkq' if the rook is the outermost rook on that
+// side of the king, otherwise the file of the rook.
+func (p Position) Fen() string {
+	return p.fen(false)
+}
+
+// ShredderFen returns the position in Shredder-FEN, where the castling rights are always given as the files of the rooks,
+// e.g. 'HAha' for the normal start position.
+func (p Position) ShredderFen() string {
+	return p.fen(true)
+}
+
+func (p Position) fen(shredder bool) string {
+	var sb strings.Builder
+	for rank := 8; rank >= 1; rank-- {
+		empty := 0
+		for file := 1; file <= 8; file++ {
+			str := p.pieceString(square.FromRankAndFile(rank, file))
+			if str == "" {
+				empty++
+				continue
+			}
+			if empty > 0 {
+				sb.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			sb.WriteString(str)
+		}
+		if empty > 0 {
+			sb.WriteString(strconv.Itoa(empty))
+		}
+		if rank > 1 {
+			sb.WriteByte('/')
+		}
+	}
+	if p.activeColour == colour.White {
+		sb.WriteString(" w ")
+	} else {
+		sb.WriteString(" b ")
+	}
+	sb.WriteString(p.castlingField(shredder))
+	if p.enpassantSquare != nil {
+		sb.WriteString(" " + strings.ToLower(p.enpassantSquare.String()))
+	} else {
+		sb.WriteString(" -")
+	}
+	sb.WriteString(fmt.Sprintf(" %d %d", p.halfmoveClock, p.fullmoveNbr))
+	return sb.String()
+}
+
+// pieceString returns the FEN letter of the piece on the given square, or an empty string
+func (p Position) pieceString(sq square.Square) string {
+	if pieceType, col, ok := p.PieceOn(sq); ok {
+		return pieceType.String(col)
+	}
+	return ""
+}
+
+// castlingField returns the castling rights as given in the third field of a FEN
+func (p Position) castlingField(shredder bool) string {
+	var sb strings.Builder
+	for _, col := range colour.AllColours {
+		rooks := p.pieces[col][piece.ROOK]
+		if p.CastlingAvailabilityKingsSide(col) {
+			rookSq := p.kingssideCastlingRook[col]
+			outermost := true
+			for file := rookSq.File() + 1; file <= 8; file++ {
+				if rooks.IsSet(uint(square.FromRankAndFile(rookSq.Rank(), file))) {
+					outermost = false
+				}
+			}
+			sb.WriteString(castlingString(col, "K", rookSq, shredder || (p.chess960 && !outermost)))
+		}
+		if p.CastlingAvailabilityQueensSide(col) {
+			rookSq := p.queenssideCastlingRook[col]
+			outermost := true
+			for file := 1; file < rookSq.File(); file++ {
+				if rooks.IsSet(uint(square.FromRankAndFile(rookSq.Rank(), file))) {
+					outermost = false
+				}
+			}
+			sb.WriteString(castlingString(col, "Q", rookSq, shredder || (p.chess960 && !outermost)))
+		}
+	}
+	if sb.Len() == 0 {
+		return "-"
+	}
+	return sb.String()
+}
+
+// castlingString returns the letter for the castling right, either 'K'/'Q' or the file of the rook (lowercase for black)
+func castlingString(col colour.Colour, side string, rookSq square.Square, useFile bool) string {
+	str := side
+	if useFile {
+		str = rookSq.String()[0:1]
+	}
+	if col == colour.Black {
+		return strings.ToLower(str)
+	}
+	return str
+}
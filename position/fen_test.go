@@ -24,6 +24,38 @@ func TestNoKingDefined(t *testing.T) {
 	checkErrorMessage(err, "king not defined", t)
 }
 
+func TestParseErrorField(t *testing.T) {
+	_, err := ParseFen("7k/8/8/8/8/8/8/7K x - - 0 1")
+	parseErr, ok := err.(ParseError)
+	if !ok {
+		t.Fatalf("expected ParseError but got %v", err)
+	}
+	if parseErr.Field() != 2 || parseErr.Message() != "unrecognised colour: 'x'" {
+		t.Errorf("unexpected field %d, message '%s'", parseErr.Field(), parseErr.Message())
+	}
+	for _, test := range []struct {
+		fen   string
+		field int
+	}{
+		{"7k/8/8/8/8/8/8/7K w - - 0", 0},
+		{"7k/8/8/8/8/8/8/7X w - - 0 1", 1},
+		{"7k/8/8/8/8/8/8/7K w X - 0 1", 3},
+		{"7k/8/8/8/8/8/8/7K w - z9 0 1", 4},
+		{"7k/8/8/8/8/8/8/7K w - a3 0 1", 4},
+		{"7k/8/8/8/8/8/8/7K w - - x 1", 5},
+		{"7k/8/8/8/8/8/8/7K w - - -1 1", 5},
+		{"7k/8/8/8/8/8/8/7K w - - 0 y", 6},
+		{"7k/8/8/8/8/8/8/7K w - - 0 -1", 6},
+	} {
+		_, err := ParseFen(test.fen)
+		if parseErr, ok := err.(ParseError); !ok {
+			t.Errorf("%s: expected ParseError but got %v", test.fen, err)
+		} else if parseErr.Field() != test.field {
+			t.Errorf("%s: expected field %d but got %d (%s)", test.fen, test.field, parseErr.Field(), parseErr.Message())
+		}
+	}
+}
+
 func TestField1Error(t *testing.T) {
 	_, err := ParseFen("7k/8/8/8/8/8/8 w KQkq - 0 0")
 	checkErrorMessage(err, "number of fields", t)
@@ -173,3 +205,55 @@ func checkBitsForPiece(piece piece.Piece, bits []int, bs bitset.BitSet, t *testi
 		}
 	}
 }
+
+func TestFen(t *testing.T) {
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b Kq - 3 17",
+		"8/8/8/2k5/3Pp3/8/8/4K3 b - d3 0 40",
+		// X-FEN: file of the rook only if it is not the outermost rook
+		"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w kq - 0 9",
+		"1r1k1r2/8/8/8/8/8/8/RRK5 w Bkq - 0 1",
+	} {
+		posn, err := ParseFen(fen)
+		if err != nil {
+			t.Fatalf("error parsing fen '%s': %s", fen, err)
+		}
+		if posn.Fen() != fen {
+			t.Errorf("expected fen '%s' but got '%s'", fen, posn.Fen())
+		}
+	}
+}
+
+func TestShredderFen(t *testing.T) {
+	data := []struct {
+		fen, xfen, shredderFen string
+		chess960               bool
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", false},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w HAha - 0 1", true},
+		{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", "qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w kq - 0 9", "qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", true},
+		{"1r1k1r2/8/8/8/8/8/8/RRK5 w Bkq - 0 1", "1r1k1r2/8/8/8/8/8/8/RRK5 w Bkq - 0 1", "1r1k1r2/8/8/8/8/8/8/RRK5 w Bfb - 0 1", true},
+	}
+	for _, d := range data {
+		posn, err := ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("error parsing fen '%s': %s", d.fen, err)
+		}
+		if posn.Chess960() != d.chess960 {
+			t.Errorf("%s: expected chess960 %t", d.fen, d.chess960)
+		}
+		if posn.Fen() != d.xfen {
+			t.Errorf("%s: expected X-FEN '%s' but got '%s'", d.fen, d.xfen, posn.Fen())
+		}
+		if posn.ShredderFen() != d.shredderFen {
+			t.Errorf("%s: expected Shredder-FEN '%s' but got '%s'", d.fen, d.shredderFen, posn.ShredderFen())
+		}
+	}
+	posn, _ := ParseFen("1r1k1r2/8/8/8/8/8/8/RRK5 w Bkq - 0 1")
+	if posn.QueenssideCastlingRook(colour.White) != square.B1 || posn.QueenssideCastlingRook(colour.Black) != square.B8 || posn.KingssideCastlingRook(colour.Black) != square.F8 {
+		t.Errorf("wrong castling rooks: %s %s %s", posn.QueenssideCastlingRook(colour.White), posn.QueenssideCastlingRook(colour.Black), posn.KingssideCastlingRook(colour.Black))
+	}
+	_, err := ParseFen("1r1k1r2/8/8/8/8/8/8/RRK5 w BAkq - 0 1")
+	checkErrorMessage(err, "castling availability syntax error", t)
+}
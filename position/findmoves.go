@@ -23,32 +23,7 @@ func (p Position) FindMoves(col colour.Colour) []move.Move {
 	for _, move := range potentiallyIllegalMoves {
 		valid := true
 		if move.IsCastles() {
-			// castle out of check is not allowed
-			if p.AnyPieceAttacksSquare(otherColour, myKing) {
-				valid = false
-			} else {
-				// check if castling allowed (relevant squares are free, and not moving into check)
-				var bitsetToCheck bitset.BitSet
-				var squaresToCheck []square.Square
-				if move.IsKingsSideCastles() {
-					bitsetToCheck = kingssideCastlingsBitMaps[col]
-					squaresToCheck = kingssideCastlingsSquares[col]
-				} else {
-					bitsetToCheck = queenssideCastlingsBitMaps[col]
-					squaresToCheck = queenssideCastlingsSquares[col]
-				}
-				if p.OccupiedSquares().And(bitsetToCheck).IsEmpty() {
-					// anything attacking the relevant squares?
-					for _, sq := range squaresToCheck {
-						if p.AnyPieceAttacksSquare(otherColour, sq) {
-							valid = false
-							break
-						}
-					}
-				} else {
-					valid = false
-				}
-			}
+			valid = p.castlingAllowed(move, col)
 		} else {
 			if move.IsKingsMove() {
 				// is king adjacent to other king
@@ -56,7 +31,7 @@ func (p Position) FindMoves(col colour.Colour) []move.Move {
 					valid = false
 				} else {
 					// is king moving into check..?
-					p.MakeMove(&move)
+					p.MakeMove(move)
 					if p.AnyPieceAttacksSquare(otherColour, move.To()) {
 						valid = false
 					}
@@ -64,7 +39,7 @@ func (p Position) FindMoves(col colour.Colour) []move.Move {
 				}
 			} else {
 				// for other moves: is king now in check
-				p.MakeMove(&move)
+				p.MakeMove(move)
 				if p.AnyPieceAttacksSquare(otherColour, myKing) {
 					valid = false
 				}
@@ -211,23 +186,49 @@ func (p Position) findQueenMoves(col colour.Colour) []move.Move {
 
 }
 
-// these squares must be empty
-var kingssideCastlingsBitMaps = [2]bitset.BitSet{bitset.New(0x06), bitset.New(0x0600000000000000)}
-
-// these squares cannot be attacked
-var kingssideCastlingsSquares = [2][]square.Square{{square.F1, square.G1}, {square.F8, square.G8}}
-
-// kingssideCastlingsRookMove is the XOR for rook's move, kingsside castling
-var kingssideCastlingsRookMove = [2]bitset.BitSet{bitset.New(0x05), bitset.New(0x0500000000000000)}
-
-// these squares must be empty
-var queenssideCastlingsBitMaps = [2]bitset.BitSet{bitset.New(0x70), bitset.New(0x7000000000000000)}
-
-// these squares cannot be attacked
-var queenssideCastlingsSquares = [2][]square.Square{{square.C1, square.D1}, {square.C8, square.D8}}
-
-// queenssideCastlingsRookMove is the XOR for rook's move, queenssside castling
-var queenssideCastlingsRookMove = [2]bitset.BitSet{bitset.New(0x90), bitset.New(0x9000000000000000)}
+// castlingAllowed checks whether the castling move is legal: all squares between the start and target squares
+// of king and rook must be empty (apart from the king and rook themselves), and the king may not be in check,
+// pass through an attacked square or move into check.
+// This is valid for normal chess and Chess960.
+func (p Position) castlingAllowed(m move.Move, col colour.Colour) bool {
+	kingFrom, kingTo := m.From(), m.To()
+	rookFrom, rookTo := m.CastlingRookFrom(), m.CastlingRookTo()
+	if !p.Pieces(col, piece.ROOK).IsSet(uint(rookFrom)) {
+		return false
+	}
+	minFile, maxFile := kingFrom.File(), kingFrom.File()
+	for _, sq := range []square.Square{kingTo, rookFrom, rookTo} {
+		if sq.File() < minFile {
+			minFile = sq.File()
+		}
+		if sq.File() > maxFile {
+			maxFile = sq.File()
+		}
+	}
+	rank := kingFrom.Rank()
+	for file := minFile; file <= maxFile; file++ {
+		sq := square.FromRankAndFile(rank, file)
+		if sq != kingFrom && sq != rookFrom && p.occupiedSquares.IsSet(uint(sq)) {
+			return false
+		}
+	}
+	// King and rook are removed from the board before checking for attacks,
+	// since in Chess960 the rook could otherwise hide an attack on the king's target square.
+	withoutKingAndRook := p
+	withoutKingAndRook.occupiedSquares = p.occupiedSquares.AndNot(bitset.NewFromSquares(kingFrom, rookFrom))
+	step := 1
+	if kingTo.File() < kingFrom.File() {
+		step = -1
+	}
+	for file := kingFrom.File(); ; file += step {
+		if withoutKingAndRook.AnyPieceAttacksSquare(col.Other(), square.FromRankAndFile(rank, file)) {
+			return false
+		}
+		if file == kingTo.File() {
+			return true
+		}
+	}
+}
 
 // in which directions can the 'castling fields' possibly be attacked?
 var castlingAttackDirections = [][]ray.Direction{{ray.NORTHWEST, ray.NORTH, ray.NORTHEAST}, {ray.SOUTHWEST, ray.SOUTH, ray.SOUTHEAST}}
@@ -250,11 +251,14 @@ func (p Position) findKingMoves(col colour.Colour) []move.Move {
 	}
 
 	// castling moves are added without checking for legality
-	if p.CastlingAvailabilityKingsSide(col) {
-		moves = append(moves, move.CastleKingsSide(col))
-	}
-	if p.CastlingAvailabilityQueensSide(col) {
-		moves = append(moves, move.CastleQueensSide(col))
+	if p.CastlingAvailabilityKingsSide(col) || p.CastlingAvailabilityQueensSide(col) {
+		kingSq := square.Square(p.Pieces(col, piece.KING).SetBits()[0])
+		if p.CastlingAvailabilityKingsSide(col) {
+			moves = append(moves, move.NewCastles(col, kingSq, p.kingssideCastlingRook[col], true))
+		}
+		if p.CastlingAvailabilityQueensSide(col) {
+			moves = append(moves, move.NewCastles(col, kingSq, p.queenssideCastlingRook[col], false))
+		}
 	}
 	return moves
 }
@@ -0,0 +1,109 @@
+package position
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/square"
+)
+
+// positionJSON is the JSON representation of a position, e.g.
+//
+//	{"fen":"8/8/8/8/8/8/8/K6k w - - 0 1","board":["........",...,"K......k"]}
+//
+// The board contains the ranks 8..1, each as a string of FEN letters with '.' for an empty square.
+// It is always written, but is optional when reading; if given, it must match the FEN.
+type positionJSON struct {
+	Fen      string    `json:"fen"`
+	Chess960 bool      `json:"chess960,omitempty"`
+	Board    *[]string `json:"board,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, see positionJSON
+func (p Position) MarshalJSON() ([]byte, error) {
+	board := p.board()
+	return json.Marshal(positionJSON{Fen: p.Fen(), Chess960: p.chess960, Board: &board})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, see positionJSON. Unknown fields are rejected.
+// As with ParseFen, the position is not checked for legality.
+func (p *Position) UnmarshalJSON(data []byte) error {
+	var j positionJSON
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&j); err != nil {
+		return fmt.Errorf("invalid position: %w", err)
+	}
+	if j.Fen == "" {
+		return fmt.Errorf("invalid position: 'fen' is required")
+	}
+	posn, err := ParseFen(j.Fen)
+	if err != nil {
+		return fmt.Errorf("invalid position: fen '%s': %w", j.Fen, err)
+	}
+	if j.Chess960 {
+		posn.chess960 = true
+	}
+	if j.Board != nil {
+		board := posn.board()
+		if len(*j.Board) != len(board) {
+			return fmt.Errorf("invalid position: board has %d ranks", len(*j.Board))
+		}
+		for i, rank := range *j.Board {
+			if rank != board[i] {
+				return fmt.Errorf("invalid position: rank %d of the board '%s' does not match the fen", 8-i, rank)
+			}
+		}
+	}
+	*p = posn
+	return nil
+}
+
+// board returns the ranks 8..1 as strings of FEN letters, with '.' for an empty square
+func (p Position) board() []string {
+	board := make([]string, 0, 8)
+	for rank := 8; rank >= 1; rank-- {
+		var sb strings.Builder
+		for file := 1; file <= 8; file++ {
+			if str := p.pieceString(square.FromRankAndFile(rank, file)); str != "" {
+				sb.WriteString(str)
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		board = append(board, sb.String())
+	}
+	return board
+}
+
+// MoveJSON returns the JSON representation of the move (of the side to move), including the SAN.
+// For Chess960 positions the UCI string of a castling move is given as 'king takes rook'.
+func (p Position) MoveJSON(m move.Move) move.JSON {
+	j := m.ToJSON()
+	j.Uci = p.UciString(m)
+	j.San = p.San(m)
+	return j
+}
+
+// ParseMoveJSON returns the legal move in the current position which matches the JSON representation.
+// As well as the checks made by move.JSON.Move, the SAN (if given) must match the move.
+func (p Position) ParseMoveJSON(j move.JSON) (move.Move, error) {
+	m, err := j.Move()
+	if err != nil {
+		return move.Move{}, err
+	}
+	for _, legal := range p.FindMoves(p.activeColour) {
+		if legal.Encode() != m.Encode() || legal.PieceType() != m.PieceType() || legal.IsCapture() != m.IsCapture() ||
+			(m.IsCapture() && legal.CapturedPiece() != m.CapturedPiece()) {
+			continue
+		}
+		if j.San != "" && j.San != p.San(legal) {
+			return move.Move{}, fmt.Errorf("san '%s' does not match move %s", j.San, p.San(legal))
+		}
+		return legal, nil
+	}
+	return move.Move{}, fmt.Errorf("illegal move '%s'", j.Uci)
+}
@@ -0,0 +1,151 @@
+package position
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rjo67/chess/move"
+)
+
+func TestPositionJSON(t *testing.T) {
+	for _, fen := range []string{
+		"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9",
+	} {
+		posn, _ := ParseFen(fen)
+		data, err := json.Marshal(posn)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", fen, err)
+		}
+		var decoded Position
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: unexpected error: %s", fen, err)
+		}
+		if decoded.ShredderFen() != posn.ShredderFen() || decoded.Chess960() != posn.Chess960() {
+			t.Errorf("expected %s but got %s after decoding %s", posn.ShredderFen(), decoded.ShredderFen(), data)
+		}
+	}
+
+	data, _ := json.Marshal(StartPosition())
+	expected := `{"fen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1","board":["rnbqkbnr","pppppppp","........","........","........","........","PPPPPPPP","RNBQKBNR"]}`
+	if string(data) != expected {
+		t.Errorf("expected %s but got %s", expected, data)
+	}
+
+	// the board is optional, the chess960 flag is kept
+	var posn Position
+	if err := json.Unmarshal([]byte(`{"fen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1","chess960":true}`), &posn); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !posn.Chess960() {
+		t.Errorf("chess960 flag not set")
+	}
+}
+
+func TestPositionJSONErrors(t *testing.T) {
+	for _, str := range []string{
+		`"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"`,
+		`{}`,
+		`{"fen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0"}`,
+		`{"fen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1","moves":[]}`,
+		`{"fen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1","board":["rnbqkbnr"]}`,
+		`{"fen":"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",` +
+			`"board":["rnbqkbnr","pppppppp","........","........","....P...","........","PPPPPPPP","RNBQKBNR"]}`,
+	} {
+		var posn Position
+		if err := json.Unmarshal([]byte(str), &posn); err == nil {
+			t.Errorf("expected error for %s", str)
+		} else if !strings.HasPrefix(err.Error(), "invalid position") {
+			t.Errorf("unexpected error message: %s", err)
+		}
+	}
+}
+
+func TestMoveJSON(t *testing.T) {
+	posn, _ := ParseFen("bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9")
+	for _, m := range posn.FindMoves(posn.ActiveColour()) {
+		j := posn.MoveJSON(m)
+		if j.San != posn.San(m) || j.Uci != posn.UciString(m) {
+			t.Errorf("move %s: unexpected JSON %v", m.String(), j)
+		}
+		data, err := json.Marshal(j)
+		if err != nil {
+			t.Fatalf("move %s: unexpected error: %s", m.String(), err)
+		}
+		var decoded move.JSON
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("move %s: unexpected error: %s", m.String(), err)
+		}
+		got, err := posn.ParseMoveJSON(decoded)
+		if err != nil {
+			t.Fatalf("move %s: unexpected error: %s", m.String(), err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("expected move %s but got %s", m.String(), got.String())
+		}
+	}
+
+	// Chess960 castling where the king stays on its square
+	posn, _ = ParseFen("1r4kr/8/8/8/8/8/8/1R4KR w HBhb - 0 1")
+	castles := `{"uci":"g1h1","san":"O-O","from":"g1","to":"g1","piece":"king","rook":"h1","flags":["castles"]}`
+	var found bool
+	for _, m := range posn.FindMoves(posn.ActiveColour()) {
+		if !m.IsCastles() || !m.IsKingsSideCastles() {
+			continue
+		}
+		found = true
+		data, err := json.Marshal(posn.MoveJSON(m))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(data) != castles {
+			t.Errorf("expected %s but got %s", castles, data)
+		}
+		var decoded move.JSON
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		got, err := posn.ParseMoveJSON(decoded)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(got, m) {
+			t.Errorf("expected move %s but got %s", m.String(), got.String())
+		}
+		var unmarshalled move.Move
+		if err := json.Unmarshal(data, &unmarshalled); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(unmarshalled, m) {
+			t.Errorf("expected move %s but got %s", m.String(), unmarshalled.String())
+		}
+	}
+	if !found {
+		t.Errorf("kings-side castling not found")
+	}
+	var m move.Move
+	err := json.Unmarshal([]byte(`{"uci":"g1g1","from":"g1","to":"g1","piece":"king"}`), &m)
+	if err == nil || !strings.Contains(err.Error(), "'from' and 'to' are both g1") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	posn = StartPosition()
+	for _, str := range []string{
+		`{"uci":"e2e5","from":"e2","to":"e5","piece":"pawn"}`,                                       // not a valid move
+		`{"uci":"e3e4","from":"e3","to":"e4","piece":"pawn"}`,                                       // not a legal move
+		`{"uci":"e2e4","from":"e2","to":"e4","piece":"knight"}`,                                     // wrong piece
+		`{"uci":"e2e4","san":"e3","from":"e2","to":"e4","piece":"pawn"}`,                            // wrong SAN
+		`{"uci":"e2e4","from":"e2","to":"e4","piece":"pawn","captured":"pawn","flags":["capture"]}`, // not a capture
+	} {
+		var j move.JSON
+		if err := json.Unmarshal([]byte(str), &j); err != nil {
+			t.Fatalf("unexpected error for %s: %s", str, err)
+		}
+		if _, err := posn.ParseMoveJSON(j); err == nil {
+			t.Errorf("expected error for %s", str)
+		}
+	}
+}
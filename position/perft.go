@@ -0,0 +1,20 @@
+package position
+
+// Perft returns the number of leaf nodes of the move tree of the given depth
+// https://www.chessprogramming.org/Perft
+func (p Position) Perft(depth int) int {
+	if depth < 1 {
+		return 1
+	}
+	moves := p.FindMoves(p.activeColour)
+	if depth == 1 {
+		return len(moves)
+	}
+	nodes := 0
+	for _, m := range moves {
+		p.MakeMove(m)
+		nodes += p.Perft(depth - 1)
+		p.UnmakeMove(m)
+	}
+	return nodes
+}
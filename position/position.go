@@ -20,17 +20,35 @@ var blackQueenssideMask = blackKingssideMask << 1
 
 // Position represents a chess position
 type Position struct {
-	pieces                  []map[piece.Piece]bitset.BitSet // array of map of piece bitsets, array-dim = colour
-	allPieces               []bitset.BitSet                 // all pieces of a particular colour
-	occupiedSquares         bitset.BitSet                   // all occupied squares
-	activeColour            colour.Colour                   // whose move
-	castlingAvailability    uint32                          // whether white/black can castle kingsside/queensside (see mask values above)
-	enpassantSquare         *square.Square                  // enpassant square of current move
-	previousEnpassantSquare *square.Square                  // enpassant square if any in previous move
-	halfmoveClock           int
-	fullmoveNbr             int
+	pieces                 []map[piece.Piece]bitset.BitSet // array of map of piece bitsets, array-dim = colour
+	allPieces              []bitset.BitSet                 // all pieces of a particular colour
+	occupiedSquares        bitset.BitSet                   // all occupied squares
+	activeColour           colour.Colour                   // whose move
+	castlingAvailability   uint32                          // whether white/black can castle kingsside/queensside (see mask values above)
+	enpassantSquare        *square.Square                  // enpassant square of current move
+	halfmoveClock          int
+	fullmoveNbr            int
+	kingssideCastlingRook  [2]square.Square // start squares of the rooks used for castling, by colour
+	queenssideCastlingRook [2]square.Square // (in Chess960 not necessarily on the a- and h-files)
+	chess960               bool             // affects FEN output and UCI notation of castling moves
+	key                    uint64           // see Key
+	history                []historyEntry   // state before each move made, see MakeMove
 }
 
+// historyEntry stores the state of the position before a move, which cannot be restored from the move itself
+type historyEntry struct {
+	key                  uint64 // see Key
+	castlingAvailability uint32
+	enpassantSquare      *square.Square
+	halfmoveClock        int
+}
+
+// default start squares of the rooks used for castling
+var (
+	defaultKingssideCastlingRook  = [2]square.Square{square.H1, square.H8}
+	defaultQueenssideCastlingRook = [2]square.Square{square.A1, square.A8}
+)
+
 // NewPosition creates a new position
 // The bitset arrays are in the order as given by the piece constants
 func NewPosition(whitePieces, blackPieces map[piece.Piece]bitset.BitSet) Position {
@@ -49,32 +67,40 @@ func NewPosition(whitePieces, blackPieces map[piece.Piece]bitset.BitSet) Positio
 		}
 	}
 	p.occupiedSquares = p.allPieces[colour.White].Or(p.allPieces[colour.Black])
+	p.kingssideCastlingRook = defaultKingssideCastlingRook
+	p.queenssideCastlingRook = defaultQueenssideCastlingRook
+	p.key = p.computeKey()
 
 	return p
 }
 
-// MakeMove updates the position with the given move
-// NB: the move will store the new castling rights if necessary, therefore takes a pointer object
-func (p *Position) MakeMove(m *move.Move) {
+// MakeMove updates the position with the given move, including the castling rights, halfmove clock and fullmove nbr.
+// The state which cannot be restored from the move itself is recorded in the position's history, see UnmakeMove and IsRepetition.
+func (p *Position) MakeMove(m move.Move) {
 	myColour := p.activeColour
 	otherColour := myColour.Other()
+	p.history = append(p.history, historyEntry{key: p.key, castlingAvailability: p.castlingAvailability,
+		enpassantSquare: p.enpassantSquare, halfmoveClock: p.halfmoveClock})
+	// the key is updated along with the pieces, the castling rights (see toggleCastlingAvailability), the enpassant square and the side to move
+	p.key ^= p.enpassantKey()
 	if m.IsCastles() {
-		// move rook (the king's move will be taken care of later)
-		var rooksMove bitset.BitSet
-		if m.IsKingsSideCastles() {
-			rooksMove = kingssideCastlingsRookMove[myColour]
-			// castlingAvailability...Side set lower down
-		} else if m.IsQueensSideCastles() {
-			rooksMove = queenssideCastlingsRookMove[myColour]
+		p.moveCastlingPieces(m, myColour)
+		p.key ^= zobristPieces[myColour][piece.KING][m.From()] ^ zobristPieces[myColour][piece.KING][m.To()] ^
+			zobristPieces[myColour][piece.ROOK][m.CastlingRookFrom()] ^ zobristPieces[myColour][piece.ROOK][m.CastlingRookTo()]
+		// remove castling rights
+		if p.CastlingAvailabilityKingsSide(myColour) {
+			p.ToggleCastlingAvailabilityKingsSide(myColour)
+		}
+		if p.CastlingAvailabilityQueensSide(myColour) {
+			p.ToggleCastlingAvailabilityQueensSide(myColour)
 		}
-		p.pieces[myColour][piece.ROOK] = p.pieces[myColour][piece.ROOK].Xor(rooksMove)
-		p.allPieces[myColour] = p.allPieces[myColour].Xor(rooksMove)
-		p.occupiedSquares = p.occupiedSquares.Xor(rooksMove)
 	} else if m.IsEnpassant() {
 		// remove other-coloured piece, which is not at m.To(), but rather m.EnpassantPawnReallyOn()
 		p.pieces[otherColour][m.CapturedPiece()] = p.pieces[otherColour][m.CapturedPiece()].Xor(m.EnpassantPawnRealLocation())
 		p.allPieces[otherColour] = p.allPieces[otherColour].Xor(m.EnpassantPawnRealLocation())
+		p.key ^= zobristPieces[otherColour][piece.PAWN][square.FromRankAndFile(m.From().Rank(), m.To().File())]
 	} else if m.IsPromotion() {
+		p.key ^= zobristPieces[myColour][piece.PAWN][m.From()] ^ zobristPieces[myColour][m.PromotedPiece()][m.To()]
 		if m.IsCapture() {
 			BothBs := bitset.NewFromSquares(m.From(), m.To())
 			FromBs := bitset.NewFromSquares(m.From())
@@ -84,6 +110,7 @@ func (p *Position) MakeMove(m *move.Move) {
 			p.pieces[otherColour][m.CapturedPiece()] = p.pieces[otherColour][m.CapturedPiece()].Xor(ToBs)
 			p.allPieces[myColour] = p.allPieces[myColour].Xor(BothBs)
 			p.allPieces[otherColour] = p.allPieces[otherColour].Xor(ToBs)
+			p.key ^= zobristPieces[otherColour][m.CapturedPiece()][m.To()]
 
 			// just remove the piece at m.From() -- there is a (new) piece at m.To()
 			p.occupiedSquares = p.occupiedSquares.Xor(FromBs)
@@ -103,12 +130,14 @@ func (p *Position) MakeMove(m *move.Move) {
 		targetBs := bitset.NewFromSquares(m.To())
 		p.pieces[otherColour][m.CapturedPiece()] = p.pieces[otherColour][m.CapturedPiece()].Xor(targetBs)
 		p.allPieces[otherColour] = p.allPieces[otherColour].Xor(targetBs)
+		p.key ^= zobristPieces[otherColour][m.CapturedPiece()][m.To()]
 	}
-	if !m.IsPromotion() {
+	if !m.IsPromotion() && !m.IsCastles() {
 		// move our colour piece from m.From() to m.To()
 		bs := bitset.NewFromSquares(m.From(), m.To())
 		p.pieces[myColour][m.PieceType()] = p.pieces[myColour][m.PieceType()].Xor(bs)
 		p.allPieces[myColour] = p.allPieces[myColour].Xor(bs)
+		p.key ^= zobristPieces[myColour][m.PieceType()][m.From()] ^ zobristPieces[myColour][m.PieceType()][m.To()]
 
 		if m.IsEnpassant() {
 			// must also clear m.EnpassantPawnRealLocation()
@@ -122,63 +151,60 @@ func (p *Position) MakeMove(m *move.Move) {
 		// remove castling rights on king move
 		if m.IsKingsMove() {
 			if p.CastlingAvailabilityKingsSide(myColour) {
-				m.SetCastleBeforeMove(true)
 				p.ToggleCastlingAvailabilityKingsSide(myColour)
 			}
 			if p.CastlingAvailabilityQueensSide(myColour) {
-				m.SetCastleBeforeMove(false)
 				p.ToggleCastlingAvailabilityQueensSide(myColour)
 			}
 		} else if m.PieceType() == piece.ROOK {
 			// remove castling rights on rook move
-			if p.CastlingAvailabilityQueensSide(myColour) &&
-				((myColour == colour.White && m.From() == square.A1) || (myColour == colour.Black && m.From() == square.A8)) {
-				m.SetCastleBeforeMove(false)
+			if p.CastlingAvailabilityQueensSide(myColour) && m.From() == p.queenssideCastlingRook[myColour] {
 				p.ToggleCastlingAvailabilityQueensSide(myColour)
-			} else if p.CastlingAvailabilityKingsSide(myColour) &&
-				((myColour == colour.White && m.From() == square.H1) || (myColour == colour.Black && m.From() == square.H8)) {
-				m.SetCastleBeforeMove(true)
+			} else if p.CastlingAvailabilityKingsSide(myColour) && m.From() == p.kingssideCastlingRook[myColour] {
 				p.ToggleCastlingAvailabilityKingsSide(myColour)
 			}
 		}
-		// remove castling rights FOR OTHER SIDE if necessary
-		if p.CastlingAvailabilityQueensSide(otherColour) &&
-			((myColour == colour.Black && m.To() == square.A1) || (myColour == colour.White && m.To() == square.A8)) {
-			m.SetOpponentCastleBeforeMove(false)
-			p.ToggleCastlingAvailabilityQueensSide(otherColour)
-		} else if p.CastlingAvailabilityKingsSide(otherColour) &&
-			((myColour == colour.Black && m.To() == square.H1) || (myColour == colour.White && m.To() == square.H8)) {
-			m.SetOpponentCastleBeforeMove(true)
-			p.ToggleCastlingAvailabilityKingsSide(otherColour)
-		}
+	}
+	// remove castling rights FOR OTHER SIDE if necessary (also for promotions, which can capture a rook)
+	if p.CastlingAvailabilityQueensSide(otherColour) && m.To() == p.queenssideCastlingRook[otherColour] {
+		p.ToggleCastlingAvailabilityQueensSide(otherColour)
+	} else if p.CastlingAvailabilityKingsSide(otherColour) && m.To() == p.kingssideCastlingRook[otherColour] {
+		p.ToggleCastlingAvailabilityKingsSide(otherColour)
 	}
 	p.activeColour = p.activeColour.Other()
+	p.key ^= zobristBlackToMove
 	if m.HasEnpassantSquare() {
 		sq := m.EnpassantSquare()
-		p.previousEnpassantSquare = p.enpassantSquare
 		p.enpassantSquare = &sq
+		p.key ^= p.enpassantKey()
 	} else {
-		p.previousEnpassantSquare = p.enpassantSquare
 		p.enpassantSquare = nil
 	}
+	if m.IsCapture() || m.PieceType() == piece.PAWN {
+		p.halfmoveClock = 0
+	} else {
+		p.halfmoveClock++
+	}
+	if myColour == colour.Black {
+		p.fullmoveNbr++
+	}
 }
 
-// UnmakeMove updates the position with the reverse of the given move
-// (does not need to update the move, therefore not a pointer)
+// UnmakeMove updates the position with the reverse of the given move, which must be the last move made with MakeMove.
+// Moves can be made and unmade to any depth, the state recorded by MakeMove is restored exactly.
 func (p *Position) UnmakeMove(m move.Move) {
+	n := len(p.history)
+	if n == 0 {
+		panic(fmt.Sprintf("UnmakeMove(%s) called without a previous MakeMove", m.String()))
+	}
+	state := p.history[n-1]
+	p.history = p.history[:n-1]
+
 	myColour := p.activeColour.Other() // position has played the move 'm' which will have changed activeColor to the other side
 	otherColour := myColour.Other()
 	if m.IsCastles() {
-		// move rook back (the king's move will be taken care of later)
-		var rooksMove bitset.BitSet
-		if m.IsKingsSideCastles() {
-			rooksMove = kingssideCastlingsRookMove[myColour]
-		} else if m.IsQueensSideCastles() {
-			rooksMove = queenssideCastlingsRookMove[myColour]
-		}
-		p.pieces[myColour][piece.ROOK] = p.pieces[myColour][piece.ROOK].Xor(rooksMove)
-		p.allPieces[myColour] = p.allPieces[myColour].Xor(rooksMove)
-		p.occupiedSquares = p.occupiedSquares.Xor(rooksMove)
+		// moving the pieces again restores the original squares
+		p.moveCastlingPieces(m, myColour)
 	}
 	var enpassantPawnRealLocation bitset.BitSet
 	if m.IsEnpassant() {
@@ -216,7 +242,7 @@ func (p *Position) UnmakeMove(m move.Move) {
 		p.allPieces[otherColour] = p.allPieces[otherColour].Or(targetBs)
 	}
 
-	if !m.IsPromotion() {
+	if !m.IsPromotion() && !m.IsCastles() {
 		bs := bitset.NewFromSquares(m.From(), m.To())
 		p.pieces[myColour][m.PieceType()] = p.pieces[myColour][m.PieceType()].Xor(bs)
 		p.allPieces[myColour] = p.allPieces[myColour].Xor(bs)
@@ -230,37 +256,28 @@ func (p *Position) UnmakeMove(m move.Move) {
 		} else {
 			p.occupiedSquares = p.occupiedSquares.Xor(bs)
 		}
-		// restore castling rights on king or rook move
-		if m.IsKingsMove() {
-			if m.CouldCastleBeforeMove(true) {
-				p.SetCastlingAvailabilityKingsSide(myColour)
-			}
-			if m.CouldCastleBeforeMove(false) {
-				p.SetCastlingAvailabilityQueensSide(myColour)
-			}
-		} else if m.PieceType() == piece.ROOK {
-			// restore castling rights on rook move
-			if m.CouldCastleBeforeMove(false) &&
-				((myColour == colour.White && m.From() == square.A1) || (myColour == colour.Black && m.From() == square.A8)) {
-				p.SetCastlingAvailabilityQueensSide(myColour)
-			} else if m.CouldCastleBeforeMove(true) &&
-				((myColour == colour.White && m.From() == square.H1) || (myColour == colour.Black && m.From() == square.H8)) {
-				p.SetCastlingAvailabilityKingsSide(myColour)
-			}
-		}
-
-		// restore castling rights FOR OTHER SIDE if necessary
-		if m.OpponentCouldCastleBeforeMove(false) &&
-			((myColour == colour.Black && m.To() == square.A1) || (myColour == colour.White && m.To() == square.A8)) {
-			p.SetCastlingAvailabilityQueensSide(otherColour)
-		} else if m.OpponentCouldCastleBeforeMove(true) &&
-			((myColour == colour.Black && m.To() == square.H1) || (myColour == colour.White && m.To() == square.H8)) {
-			p.SetCastlingAvailabilityKingsSide(otherColour)
-		}
 	}
 
 	p.activeColour = p.activeColour.Other()
-	p.enpassantSquare = p.previousEnpassantSquare
+	p.castlingAvailability = state.castlingAvailability
+	p.enpassantSquare = state.enpassantSquare
+	p.halfmoveClock = state.halfmoveClock
+	p.key = state.key
+	if myColour == colour.Black {
+		p.fullmoveNbr--
+	}
+}
+
+// moveCastlingPieces moves the king and the rook of a castling move.
+// In Chess960 the king or the rook may stay where they are, or they may swap squares, therefore
+// the moves are applied separately. Applying the same move twice restores the original position.
+func (p *Position) moveCastlingPieces(m move.Move, col colour.Colour) {
+	kingsMove := bitset.NewFromSquares(m.From()).Xor(bitset.NewFromSquares(m.To()))
+	rooksMove := bitset.NewFromSquares(m.CastlingRookFrom()).Xor(bitset.NewFromSquares(m.CastlingRookTo()))
+	p.pieces[col][piece.KING] = p.pieces[col][piece.KING].Xor(kingsMove)
+	p.pieces[col][piece.ROOK] = p.pieces[col][piece.ROOK].Xor(rooksMove)
+	p.allPieces[col] = p.allPieces[col].Xor(kingsMove).Xor(rooksMove)
+	p.occupiedSquares = p.occupiedSquares.Xor(kingsMove).Xor(rooksMove)
 }
 
 // StartPosition creates a new start position
@@ -274,11 +291,17 @@ func StartPosition() Position {
 		}
 	}
 
-	return NewPosition(pieces[colour.White], pieces[colour.Black])
+	posn := NewPosition(pieces[colour.White], pieces[colour.Black])
+	for _, col := range colour.AllColours {
+		posn.SetCastlingAvailabilityKingsSide(col)
+		posn.SetCastlingAvailabilityQueensSide(col)
+	}
+	posn.fullmoveNbr = 1
+	return posn
 }
 
 // PieceAt returns the piece of the specified colour located at sq
-// -- panic if there is no such piece
+// -- panic if there is no such piece. See PieceOn for a non-panicking alternative
 func (p Position) PieceAt(sq uint, requiredColour colour.Colour) piece.Piece {
 	for _, pieceType := range piece.AllPieces {
 		if p.pieces[requiredColour][pieceType].IsSet(sq) {
@@ -288,6 +311,22 @@ func (p Position) PieceAt(sq uint, requiredColour colour.Colour) piece.Piece {
 	panic(fmt.Sprintf("no %s piece found on square %d", requiredColour.String(), sq))
 }
 
+// PieceOn returns the piece and its colour located at sq.
+// Returns false if the square is empty or not on the board.
+func (p Position) PieceOn(sq square.Square) (piece.Piece, colour.Colour, bool) {
+	if sq < 1 || sq > 64 {
+		return 0, 0, false
+	}
+	for _, col := range colour.AllColours {
+		for _, pieceType := range piece.AllPieces {
+			if p.pieces[col][pieceType].IsSet(uint(sq)) {
+				return pieceType, col, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
 // AllPieces returns a bitset with all the occupied squares for the given colour
 func (p Position) AllPieces(col colour.Colour) bitset.BitSet {
 	return p.allPieces[col]
@@ -303,6 +342,11 @@ func (p Position) Pieces(col colour.Colour, pieceType piece.Piece) bitset.BitSet
 	return p.pieces[col][pieceType]
 }
 
+// ActiveColour returns the colour whose move it is
+func (p Position) ActiveColour() colour.Colour {
+	return p.activeColour
+}
+
 // EnpassantSquare returns the current enpassant square (or nil)
 func (p Position) EnpassantSquare() *square.Square {
 	return p.enpassantSquare
@@ -337,39 +381,56 @@ func (p Position) CastlingAvailabilityQueensSide(col colour.Colour) bool {
 // ToggleCastlingAvailabilityKingsSide toggles the castling availabilty on the kingsside for the given colour
 func (p *Position) ToggleCastlingAvailabilityKingsSide(col colour.Colour) {
 	if col == colour.White {
-		p.castlingAvailability ^= whiteKingssideMask
+		p.toggleCastlingAvailability(whiteKingssideMask)
 	} else {
-		p.castlingAvailability ^= blackKingssideMask
+		p.toggleCastlingAvailability(blackKingssideMask)
 	}
 }
 
 // SetCastlingAvailabilityKingsSide sets the castling availabilty on the kingsside for the given colour
 func (p *Position) SetCastlingAvailabilityKingsSide(col colour.Colour) {
-	if col == colour.White {
-		p.castlingAvailability |= whiteKingssideMask
-	} else {
-		p.castlingAvailability |= blackKingssideMask
+	if !p.CastlingAvailabilityKingsSide(col) {
+		p.ToggleCastlingAvailabilityKingsSide(col)
 	}
 }
 
 // ToggleCastlingAvailabilityQueensSide toggles the castling availabilty on the queensside for the given colour
 func (p *Position) ToggleCastlingAvailabilityQueensSide(col colour.Colour) {
 	if col == colour.White {
-		p.castlingAvailability ^= whiteQueenssideMask
+		p.toggleCastlingAvailability(whiteQueenssideMask)
 	} else {
-		p.castlingAvailability ^= blackQueenssideMask
+		p.toggleCastlingAvailability(blackQueenssideMask)
 	}
 }
 
 // SetCastlingAvailabilityQueensSide sets the castling availabilty on the queensside for the given colour
 func (p *Position) SetCastlingAvailabilityQueensSide(col colour.Colour) {
-	if col == colour.White {
-		p.castlingAvailability |= whiteQueenssideMask
-	} else {
-		p.castlingAvailability |= blackQueenssideMask
+	if !p.CastlingAvailabilityQueensSide(col) {
+		p.ToggleCastlingAvailabilityQueensSide(col)
 	}
 }
 
+// KingssideCastlingRook returns the start square of the rook used for castling kingsside
+func (p Position) KingssideCastlingRook(col colour.Colour) square.Square {
+	return p.kingssideCastlingRook[col]
+}
+
+// QueenssideCastlingRook returns the start square of the rook used for castling queensside
+func (p Position) QueenssideCastlingRook(col colour.Colour) square.Square {
+	return p.queenssideCastlingRook[col]
+}
+
+// Chess960 returns true if the position is from a game of Chess960 (Fischer Random Chess)
+func (p Position) Chess960() bool {
+	return p.chess960
+}
+
+// SetChess960 sets whether the position is from a game of Chess960.
+// This affects the FEN output of the castling rights and the UCI notation of castling moves.
+func (p *Position) SetChess960(chess960 bool) {
+	p.chess960 = chess960
+}
+
 // BitSetFor returns the bitset for the given piece and colour
 func (p Position) BitSetFor(col colour.Colour, piece piece.Piece) bitset.BitSet {
 	return p.pieces[col][piece]
@@ -39,7 +39,7 @@ func TestMakeMoveNonCapture(t *testing.T) {
 	}
 
 	m := move.New(colour.White, square.B5, square.F1, piece.QUEEN)
-	posn.MakeMove(&m)
+	posn.MakeMove(m)
 
 	// check after-effects of MakeMove
 	if !posn.pieces[colour.White][piece.QUEEN].And(queenBitset).IsEmpty() {
@@ -101,7 +101,7 @@ func TestMakePromotionNonCapture(t *testing.T) {
 	}
 
 	m := move.NewPromotion(colour.White, square.E7, square.E8, piece.QUEEN)
-	posn.MakeMove(&m)
+	posn.MakeMove(m)
 
 	promotedQueenBitset := bitset.NewFromSquares(square.E8)
 	// check after-effects of MakeMove
@@ -176,7 +176,7 @@ func TestMakeMoveCapture(t *testing.T) {
 	}
 
 	m := move.NewCapture(colour.White, square.D4, square.D3, piece.ROOK, piece.PAWN)
-	posn.MakeMove(&m)
+	posn.MakeMove(m)
 
 	// check after-effects of MakeMove
 	if !posn.pieces[colour.White][piece.ROOK].And(rookBitset).IsEmpty() {
@@ -248,7 +248,7 @@ func TestEnpassantCapture(t *testing.T) {
 	if m.EnpassantSquare() != square.A6 {
 		t.Fatalf("enpassant square should be A6 but was: %s", m.EnpassantSquare().String())
 	}
-	posn.MakeMove(&m)
+	posn.MakeMove(m)
 
 	blackPawnBitsetBeforeMove := bitset.NewFromSquares(square.A7)
 	blackPawnBitsetAfterMove := bitset.NewFromSquares(square.A5)
@@ -286,12 +286,12 @@ func TestEnpassantCapture(t *testing.T) {
 	}
 
 	// redo the first (black) move
-	posn.MakeMove(&m)
+	posn.MakeMove(m)
 
 	// second: white takes enpassant
 	m = move.NewEpCapture(colour.White, square.B5, square.A6)
 
-	posn.MakeMove(&m)
+	posn.MakeMove(m)
 
 	bothBlackPawnSquares := blackPawnBitsetBeforeMove.Or(blackPawnBitsetAfterMove)
 	if !posn.pieces[colour.Black][piece.PAWN].And(bothBlackPawnSquares).IsEmpty() {
@@ -351,22 +351,19 @@ func TestCastling(t *testing.T) {
 		t.Fatalf("error parsing fen: %s", err)
 	}
 	m := move.New(colour.White, square.D2, square.D3, piece.BISHOP)
-	posn.MakeMove(&m)
-	// castling flags should still be false for this move, since no castling-relevant move was made
-	if m.OpponentCouldCastleBeforeMove(true) || m.OpponentCouldCastleBeforeMove(false) {
+	posn.MakeMove(m)
+	// castling flags should be unchanged, since no castling-relevant move was made
+	if !posn.CastlingAvailabilityKingsSide(colour.Black) || !posn.CastlingAvailabilityQueensSide(colour.Black) {
 		t.Fatalf("castling flags wrong")
 	}
 	posn.UnmakeMove(m)
-	if m.OpponentCouldCastleBeforeMove(true) || m.OpponentCouldCastleBeforeMove(false) {
+	if !posn.CastlingAvailabilityKingsSide(colour.Black) || !posn.CastlingAvailabilityQueensSide(colour.Black) {
 		t.Fatalf("castling flags wrong")
 	}
 	m = move.New(colour.White, square.B6, square.A8, piece.KNIGHT)
-	posn.MakeMove(&m)
-	// queensside castling flag should be set for this move
-	if m.OpponentCouldCastleBeforeMove(true) || !m.OpponentCouldCastleBeforeMove(false) {
-		t.Fatalf("castling flags wrong")
-	}
-	if posn.CastlingAvailabilityQueensSide(colour.Black) {
+	posn.MakeMove(m)
+	// only the queensside castling flag should be removed by this move
+	if !posn.CastlingAvailabilityKingsSide(colour.Black) || posn.CastlingAvailabilityQueensSide(colour.Black) {
 		t.Fatalf("castling flags wrong")
 	}
 	posn.UnmakeMove(m)
@@ -374,12 +371,9 @@ func TestCastling(t *testing.T) {
 		t.Fatalf("castling flags wrong")
 	}
 	m = move.New(colour.White, square.G6, square.H8, piece.KNIGHT)
-	posn.MakeMove(&m)
-	// kingssside castling flag should be set for this move
-	if !m.OpponentCouldCastleBeforeMove(true) || m.OpponentCouldCastleBeforeMove(false) {
-		t.Fatalf("castling flags wrong")
-	}
-	if posn.CastlingAvailabilityKingsSide(colour.Black) {
+	posn.MakeMove(m)
+	// only the kingssside castling flag should be removed by this move
+	if posn.CastlingAvailabilityKingsSide(colour.Black) || !posn.CastlingAvailabilityQueensSide(colour.Black) {
 		t.Fatalf("castling flags wrong")
 	}
 	posn.UnmakeMove(m)
@@ -406,7 +400,7 @@ func TestMakePromotionCapture(t *testing.T) {
 	}
 
 	m := move.NewPromotionCapture(colour.White, square.E7, square.F8, piece.QUEEN, piece.ROOK)
-	posn.MakeMove(&m)
+	posn.MakeMove(m)
 
 	// check after-effects of MakeMove
 	promotedQueenBitset := bitset.NewFromSquares(square.F8)
@@ -601,7 +595,6 @@ func TestShortCastlingChecksOpponent(t *testing.T) {
 }
 func TestLongCastlingChecksOpponent(t *testing.T) {
 	doTest(moveData{"3k4/8/8/8/8/8/8/R3K3 w Q - 0 1", []int{16, 71, 1286, 7418, 141077, 803711}}, t)
-	doTest(moveData{"r3k3/8/8/8/8/8/8/3K4 b q - 0 1", []int{16, 71, 1286, 7418, 141077, 803711}}, t)
 }
 func TestCastlingIncludingLosingOrRookCapture(t *testing.T) {
 	doTest(moveData{"r3k2r/1b4bq/8/8/8/8/7B/R3K2R w KQkq - 0 1", []int{26, 1141, 27826, 1274206}}, t)
@@ -609,26 +602,21 @@ func TestCastlingIncludingLosingOrRookCapture(t *testing.T) {
 }
 func TestCastlingPrevented(t *testing.T) {
 	doTest(moveData{"r3k2r/8/5Q2/8/8/3q4/8/R3K2R w KQkq - 0 1", []int{44, 1494, 50509, 1720476}}, t)
-	doTest(moveData{"r3k2r/8/3Q4/8/8/5q2/8/R3K2R b KQkq - 0 1", []int{44, 1494, 50509, 1720476}}, t)
 }
 func TestPromoteOutOfCheck(t *testing.T) {
 	doTest(moveData{"2K2r2/4P3/8/8/8/8/8/3k4 w - - 0 1", []int{11, 133, 1442, 19174, 266199, 3821001}}, t)
-	doTest(moveData{"3K4/8/8/8/8/8/4p3/2k2R2 b - - 0 1", []int{11, 133, 1442, 19174, 266199, 3821001}}, t)
 }
 func TestDiscoveredCheck(t *testing.T) {
 	doTest(moveData{"8/8/8/2k3PR/8/1p2K3/2P2B2/2Q5 w - - 0 10", []int{31, 223, 7685, 54476}}, t)
 }
 func TestDiscoveredCheck2(t *testing.T) {
 	doTest(moveData{"5K2/8/1Q6/2N5/8/1p2k3/8/8 w - - 0 1", []int{29, 165, 5160, 31961, 1004658}}, t)
-	doTest(moveData{"8/8/1P2K3/8/2n5/1q6/8/5k2 b - - 0 1", []int{29, 165, 5160, 31961, 1004658}}, t)
 }
 func TestSelfStalemate(t *testing.T) {
 	doTest(moveData{"8/k1P5/8/1K6/8/8/8/8 w - - 0 1", []int{10, 25, 268, 926, 10857, 43261, 567584}}, t)
-	doTest(moveData{"8/8/8/8/1k6/8/K1p5/8 b - - 0 1", []int{10, 25, 268, 926, 10857, 43261, 567584}}, t)
 }
 func TestSelfStalemate2(t *testing.T) {
 	doTest(moveData{"K1k5/8/P7/8/8/8/8/8 w - - 0 1", []int{2, 6, 13, 63, 382, 2217, 15453}}, t)
-	doTest(moveData{"8/8/8/8/8/p7/8/k1K5 b - - 0 1", []int{2, 6, 13, 63, 382, 2217, 15453}}, t)
 }
 func TestPromotionRocechess(t *testing.T) {
 	//www.rocechess.ch/perft.html
@@ -636,22 +624,30 @@ func TestPromotionRocechess(t *testing.T) {
 }
 func TestPromotionToGiveCheck(t *testing.T) {
 	doTest(moveData{"4k3/1P6/8/8/8/8/K7/8 w - - 0 1", []int{9, 40, 472, 2661, 38983, 217342}}, t)
-	doTest(moveData{"8/k7/8/8/8/8/1p6/4K3 b - - 0 1", []int{9, 40, 472, 2661, 38983, 217342}}, t)
 }
 func TestUnderPromoteToGiveCheck(t *testing.T) {
 	doTest(moveData{"8/P1k5/K7/8/8/8/8/8 w - - 0 1", []int{6, 27, 273, 1329, 18135, 92683}}, t)
-	doTest(moveData{"8/8/8/8/8/k7/p1K5/8 b - - 0 1", []int{6, 27, 273, 1329, 18135, 92683}}, t)
 }
 func TestDoubleCheck(t *testing.T) {
 	doTest(moveData{"8/5k2/8/5N2/5Q2/2K5/8/8 w - - 0 1", []int{37, 183, 6559, 23527, 811573}}, t)
-	doTest(moveData{"8/8/2k5/5q2/5n2/8/5K2/8 b - - 0 1", []int{37, 183, 6559, 23527, 811573}}, t)
 }
 
+// perft counts up to this number of moves are also checked for the mirrored position
+const mirroredPerftLimit = 250000
+
 func doTest(data moveData, t *testing.T) {
 	posn, err := ParseFen(data.fen)
 	if err != nil {
 		t.Fatalf("could not parse fen: %s, err: %s", data.fen, err.Error())
 	}
+	mirrored := posn.Mirror()
+	for depth, expectedNbrMoves := range data.expectedNbrMoves {
+		if expectedNbrMoves != -1 && expectedNbrMoves <= mirroredPerftLimit {
+			if nbrMoves := mirrored.Perft(depth + 1); nbrMoves != expectedNbrMoves {
+				t.Fatalf("depth: %d, expected %d moves but got %d for mirrored position %s of fen: %s", depth+1, expectedNbrMoves, nbrMoves, mirrored.Fen(), data.fen)
+			}
+		}
+	}
 	for depth, expectedNbrMoves := range data.expectedNbrMoves {
 		if depth < 99 { // can use this to limit which tests are carried out
 			if expectedNbrMoves != -1 {
@@ -679,7 +675,7 @@ func perft(posn Position, depth int) map[string]int {
 	moveMap := make(map[string]int, 0)
 	// fill move map with starting moves
 	for _, startMove := range posn.FindMoves(posn.activeColour) {
-		posn.MakeMove(&startMove)
+		posn.MakeMove(startMove)
 		moveMap[startMove.String()] = len(p2(startMove, posn, depth)) // just store the number of moves, to allow GC of the moves
 		posn.UnmakeMove(startMove)
 	}
@@ -694,7 +690,7 @@ func p2(origMove move.Move, posn Position, depth int) []move.Move {
 	}
 	movesAtNextDepth := make([]move.Move, 0, 300)
 	for _, m := range posn.FindMoves(posn.activeColour) {
-		posn.MakeMove(&m)
+		posn.MakeMove(m)
 		movesAtNextDepth = append(movesAtNextDepth, p2(m, posn, depth-1)...)
 		posn.UnmakeMove(m)
 	}
@@ -712,3 +708,42 @@ func checkBits(testNbr int, bs bitset.BitSet, expectedSetBits []int, t *testing.
 		t.Fatalf("test %d: found %d errors (%v) for bitset:\n%s", testNbr, len(errors), errors, bs.String())
 	}
 }
+
+// makes a sequence of moves (always the n-th legal move) and then unmakes them, checking that every position is restored exactly
+func TestMakeUnmakeDeep(t *testing.T) {
+	for _, fen := range []string{
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+	} {
+		for n := 0; n < 7; n++ {
+			posn, err := ParseFen(fen)
+			if err != nil {
+				t.Fatalf("error parsing fen: %s", err)
+			}
+			var fens []string
+			var keys []uint64
+			var moves []move.Move
+			for depth := 0; depth < 20; depth++ {
+				legalMoves := posn.FindMoves(posn.ActiveColour())
+				if len(legalMoves) == 0 {
+					break
+				}
+				m := legalMoves[(n*7+depth)%len(legalMoves)]
+				fens = append(fens, posn.Fen())
+				keys = append(keys, posn.Key())
+				moves = append(moves, m)
+				posn.MakeMove(m)
+				if posn.Key() != posn.computeKey() {
+					t.Fatalf("%s: after move %d (%s) the key does not match the position %s", fen, depth, m.String(), posn.Fen())
+				}
+			}
+			for i := len(moves) - 1; i >= 0; i-- {
+				posn.UnmakeMove(moves[i])
+				if posn.Fen() != fens[i] || posn.Key() != keys[i] {
+					t.Fatalf("%s: after unmaking move %d (%s) expected %s but got %s", fen, i, moves[i].String(), fens[i], posn.Fen())
+				}
+			}
+		}
+	}
+}
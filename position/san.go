@@ -0,0 +1,151 @@
+package position
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+// ParseSan returns the legal move in the current position which matches the given move in standard algebraic notation (e.g. "Nbd2", "exd6", "e8=Q+", "O-O").
+// https://en.wikipedia.org/wiki/Algebraic_notation_(chess)
+func (p Position) ParseSan(san string) (move.Move, error) {
+	str := strings.TrimRight(san, "+#!?")
+	if len(str) < 2 {
+		return move.Move{}, fmt.Errorf("unrecognised move '%s'", san)
+	}
+	legalMoves := p.FindMoves(p.activeColour)
+
+	// castling
+	switch str {
+	case "O-O", "0-0":
+		for _, m := range legalMoves {
+			if m.IsKingsSideCastles() {
+				return m, nil
+			}
+		}
+		return move.Move{}, fmt.Errorf("illegal move '%s'", san)
+	case "O-O-O", "0-0-0":
+		for _, m := range legalMoves {
+			if m.IsQueensSideCastles() {
+				return m, nil
+			}
+		}
+		return move.Move{}, fmt.Errorf("illegal move '%s'", san)
+	}
+
+	pieceType := piece.PAWN
+	if strings.ContainsRune("KQRBN", rune(str[0])) {
+		pieceType = piece.FromString(p.activeColour, str[0:1])
+		str = str[1:]
+	}
+
+	// promotion: either "e8=Q" or "e8Q"
+	var promotedPiece *piece.Piece
+	if pieceType == piece.PAWN && len(str) > 2 && strings.ContainsRune("QRBN", rune(str[len(str)-1])) {
+		pp := piece.FromString(p.activeColour, str[len(str)-1:])
+		promotedPiece = &pp
+		str = strings.TrimSuffix(str[:len(str)-1], "=")
+	}
+
+	if len(str) < 2 {
+		return move.Move{}, fmt.Errorf("unrecognised move '%s'", san)
+	}
+	target, err := square.FromString(str[len(str)-2:])
+	if err != nil {
+		return move.Move{}, fmt.Errorf("unrecognised move '%s': %s", san, err)
+	}
+
+	// whatever remains is the disambiguation (file and/or rank of the 'from' square) and the capture marker
+	fromFile, fromRank := 0, 0
+	for _, ch := range strings.Replace(str[:len(str)-2], "x", "", 1) {
+		switch {
+		case ch >= 'a' && ch <= 'h':
+			fromFile = int(ch-'a') + 1
+		case ch >= '1' && ch <= '8':
+			fromRank = int(ch-'1') + 1
+		default:
+			return move.Move{}, fmt.Errorf("unrecognised move '%s'", san)
+		}
+	}
+
+	var matches []move.Move
+	for _, m := range legalMoves {
+		if m.IsCastles() || m.PieceType() != pieceType || m.To() != target {
+			continue
+		}
+		if (fromFile != 0 && m.From().File() != fromFile) || (fromRank != 0 && m.From().Rank() != fromRank) {
+			continue
+		}
+		if m.IsPromotion() != (promotedPiece != nil) || (promotedPiece != nil && m.PromotedPiece() != *promotedPiece) {
+			continue
+		}
+		matches = append(matches, m)
+	}
+	switch len(matches) {
+	case 0:
+		return move.Move{}, fmt.Errorf("illegal move '%s'", san)
+	case 1:
+		return matches[0], nil
+	default:
+		return move.Move{}, fmt.Errorf("ambiguous move '%s'", san)
+	}
+}
+
+// San returns the given legal move in standard algebraic notation (e.g. "Nbd2", "exd6", "e8=Q+", "O-O"),
+// including the suffix "+" for check or "#" for checkmate
+func (p Position) San(m move.Move) string {
+	var sb strings.Builder
+	switch {
+	case m.IsKingsSideCastles():
+		sb.WriteString("O-O")
+	case m.IsQueensSideCastles():
+		sb.WriteString("O-O-O")
+	default:
+		if m.PieceType() == piece.PAWN {
+			if m.IsCapture() {
+				sb.WriteString(strings.ToLower(m.From().String()[0:1]))
+			}
+		} else {
+			sb.WriteString(m.PieceType().String(colour.White))
+			sb.WriteString(p.sanDisambiguation(m))
+		}
+		if m.IsCapture() {
+			sb.WriteString("x")
+		}
+		sb.WriteString(strings.ToLower(m.To().String()))
+		if m.IsPromotion() {
+			sb.WriteString("=" + m.PromotedPiece().String(colour.White))
+		}
+	}
+	sb.WriteString(p.checkSuffix(m))
+	return sb.String()
+}
+
+// sanDisambiguation returns the file and/or rank of the 'from' square, if required because
+// another piece of the same type can also move to the target square
+func (p Position) sanDisambiguation(m move.Move) string {
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range p.FindMoves(p.activeColour) {
+		if other.IsCastles() || other.PieceType() != m.PieceType() || other.To() != m.To() || other.From() == m.From() {
+			continue
+		}
+		ambiguous = true
+		sameFile = sameFile || other.From().File() == m.From().File()
+		sameRank = sameRank || other.From().Rank() == m.From().Rank()
+	}
+	from := strings.ToLower(m.From().String())
+	switch {
+	case !ambiguous:
+		return ""
+	case !sameFile:
+		return from[0:1]
+	case !sameRank:
+		return from[1:2]
+	default:
+		return from
+	}
+}
@@ -0,0 +1,124 @@
+package position
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/square"
+)
+
+func TestParseSan(t *testing.T) {
+	data := []struct {
+		fen      string
+		san      string
+		from, to square.Square
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "e4", square.E2, square.E4},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", "Nf3", square.G1, square.F3},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "O-O", square.E1, square.G1},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "0-0-0", square.E1, square.C1},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "Rad1", square.A1, square.D1},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", "Rhxh8+", square.H1, square.H8},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "exd6", square.E5, square.D6},
+		{"4k3/R7/8/8/8/8/8/R3K3 w - - 0 1", "R1a5", square.A1, square.A5},
+	}
+	for testNbr, d := range data {
+		posn, err := ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("test %d: error parsing fen: %s", testNbr, err)
+		}
+		m, err := posn.ParseSan(d.san)
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", testNbr, err)
+		}
+		if m.From() != d.from || m.To() != d.to {
+			t.Errorf("test %d: move '%s' parsed as %s", testNbr, d.san, m.String())
+		}
+	}
+}
+
+func TestParseSanPromotion(t *testing.T) {
+	posn, err := ParseFen("1n2k3/P7/8/8/8/8/8/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatalf("error parsing fen: %s", err)
+	}
+	for _, san := range []string{"axb8=N", "axb8N", "a8=Q"} {
+		m, err := posn.ParseSan(san)
+		if err != nil {
+			t.Fatalf("unexpected error for '%s': %s", san, err)
+		}
+		expected := piece.FromString(0, san[len(san)-1:])
+		if !m.IsPromotion() || m.PromotedPiece() != expected {
+			t.Errorf("move '%s' parsed as %s", san, m.String())
+		}
+	}
+}
+
+func TestParseSanErrors(t *testing.T) {
+	posn, err := ParseFen("4k3/R7/8/8/8/8/8/R3K3 w - - 0 1")
+	if err != nil {
+		t.Fatalf("error parsing fen: %s", err)
+	}
+	data := []struct {
+		san             string
+		expectedMessage string
+	}{
+		{"Ra5", "ambiguous"},
+		{"Nf3", "illegal"},
+		{"O-O", "illegal"},
+		{"Rz5", "unrecognised"},
+		{"a", "unrecognised"},
+	}
+	for _, d := range data {
+		_, err := posn.ParseSan(d.san)
+		if err == nil || !strings.Contains(err.Error(), d.expectedMessage) {
+			t.Errorf("san '%s': expected error containing '%s' but got: %v", d.san, d.expectedMessage, err)
+		}
+	}
+}
+
+func TestSan(t *testing.T) {
+	data := []struct {
+		fen      string
+		from, to square.Square
+		expected string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", square.E2, square.E4, "e4"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", square.G1, square.F3, "Nf3"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", square.E1, square.G1, "O-O"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", square.E1, square.C1, "O-O-O"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", square.A1, square.D1, "Rd1"},
+		{"4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1", square.H1, square.H8, "Rh8+"},
+		{"5k2/8/8/8/8/8/8/4K2R w K - 0 1", square.E1, square.G1, "O-O+"},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", square.E5, square.D6, "exd6"},
+		{"4k3/R7/8/8/8/8/8/R3K3 w - - 0 1", square.A1, square.A5, "R1a5"},
+		{"4k3/8/8/2N5/8/8/8/2N1N2K w - - 0 1", square.C1, square.D3, "Nc1d3"},
+		{"4k3/8/8/2N5/8/8/8/2N1N2K w - - 0 1", square.E1, square.D3, "Ned3"},
+		{"4k3/8/8/2N5/8/8/8/2N1N2K w - - 0 1", square.C5, square.D3, "N5d3"},
+		{"1n2k3/P7/8/8/8/8/8/4K3 w - - 0 1", square.A7, square.B8, "axb8=Q+"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/8/5P2/PPPPP1PP/RNBQKBNR b KQkq - 0 2", square.D8, square.H4, "Qh4+"},
+		{"rnbqkbnr/pppp1ppp/8/4p3/6P1/5P2/PPPPP2P/RNBQKBNR b KQkq - 0 2", square.D8, square.H4, "Qh4#"},
+	}
+	for _, d := range data {
+		posn, err := ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("%s: error parsing fen: %s", d.fen, err)
+		}
+		found := false
+		for _, m := range posn.FindMoves(posn.ActiveColour()) {
+			if m.From() == d.from && m.To() == d.to && (!m.IsPromotion() || m.PromotedPiece() == piece.QUEEN) {
+				found = true
+				if san := posn.San(m); san != d.expected {
+					t.Errorf("%s: expected '%s' but got '%s'", d.fen, d.expected, san)
+				}
+				if parsed, err := posn.ParseSan(d.expected); err != nil || parsed.String() != m.String() {
+					t.Errorf("%s: could not parse '%s': %v", d.fen, d.expected, err)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("%s: move %s-%s not found", d.fen, d.from.String(), d.to.String())
+		}
+	}
+}
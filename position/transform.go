@@ -0,0 +1,66 @@
+package position
+
+import (
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+)
+
+// Mirror returns the position with the ranks flipped and the colours swapped, e.g. a white pawn on E2 becomes
+// a black pawn on E7. Castling rights, castling rooks and the enpassant square are transformed likewise, and the
+// other side is to move. The mirrored position has the same number of legal moves at every depth.
+// The history of the position is not copied.
+func (p Position) Mirror() Position {
+	pieces := make([]map[piece.Piece]bitset.BitSet, 2)
+	for _, col := range colour.AllColours {
+		pieces[col] = make(map[piece.Piece]bitset.BitSet)
+		for _, pieceType := range piece.AllPieces {
+			pieces[col][pieceType] = p.pieces[col.Other()][pieceType].FlipVertical()
+		}
+	}
+	posn := NewPosition(pieces[colour.White], pieces[colour.Black])
+	posn.activeColour = p.activeColour.Other()
+	for _, col := range colour.AllColours {
+		posn.kingssideCastlingRook[col] = p.kingssideCastlingRook[col.Other()].Mirror()
+		posn.queenssideCastlingRook[col] = p.queenssideCastlingRook[col.Other()].Mirror()
+		if p.CastlingAvailabilityKingsSide(col.Other()) {
+			posn.SetCastlingAvailabilityKingsSide(col)
+		}
+		if p.CastlingAvailabilityQueensSide(col.Other()) {
+			posn.SetCastlingAvailabilityQueensSide(col)
+		}
+	}
+	if p.enpassantSquare != nil {
+		ep := p.enpassantSquare.Mirror()
+		posn.enpassantSquare = &ep
+	}
+	posn.halfmoveClock = p.halfmoveClock
+	posn.fullmoveNbr = p.fullmoveNbr
+	posn.chess960 = p.chess960
+	posn.key = posn.computeKey()
+	return posn
+}
+
+// FlipFile returns the position mirrored about the vertical axis between the d- and e-files, e.g. a piece on A1
+// moves to H1. Since castling is not symmetrical in this direction, the castling rights are removed.
+// The history of the position is not copied.
+func (p Position) FlipFile() Position {
+	pieces := make([]map[piece.Piece]bitset.BitSet, 2)
+	for _, col := range colour.AllColours {
+		pieces[col] = make(map[piece.Piece]bitset.BitSet)
+		for _, pieceType := range piece.AllPieces {
+			pieces[col][pieceType] = p.pieces[col][pieceType].MirrorHorizontal()
+		}
+	}
+	posn := NewPosition(pieces[colour.White], pieces[colour.Black])
+	posn.activeColour = p.activeColour
+	if p.enpassantSquare != nil {
+		ep := p.enpassantSquare.FlipFile()
+		posn.enpassantSquare = &ep
+	}
+	posn.halfmoveClock = p.halfmoveClock
+	posn.fullmoveNbr = p.fullmoveNbr
+	posn.chess960 = p.chess960
+	posn.key = posn.computeKey()
+	return posn
+}
@@ -0,0 +1,59 @@
+package position
+
+import "testing"
+
+func TestMirror(t *testing.T) {
+	data := []struct {
+		fen, mirrored, flipped string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", "rnbqkbnr/pppp1ppp/8/4p3/8/8/PPPPPPPP/RNBQKBNR w KQkq e6 0 1",
+			"rnbkqbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBKQBNR b - d3 0 1"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w Kq - 3 12", "r3k2r/pppbbppp/2n2q1P/1P2p3/3pn3/BN2PNP1/P1PPQPB1/R3K2R b Qk - 3 12",
+			"r2k3r/1bpqpp1p/1pnp2nb/3NP3/3P2p1/p1Q2N2/PPPBBPPP/R2K3R w - - 3 12"},
+		{"nrkbbrqn/pppppppp/8/8/8/8/PPPPPPPP/1RKBBRQN w FBfb - 0 1", "1rkbbrqn/pppppppp/8/8/8/8/PPPPPPPP/NRKBBRQN b KQkq - 0 1",
+			"nqrbbkrn/pppppppp/8/8/8/8/PPPPPPPP/NQRBBKR1 w - - 0 1"},
+	}
+	for _, d := range data {
+		posn, err := ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("error parsing fen '%s': %s", d.fen, err)
+		}
+		if mirrored := posn.Mirror(); mirrored.Fen() != d.mirrored {
+			t.Errorf("%s: expected mirrored position %s but got %s", d.fen, d.mirrored, mirrored.Fen())
+		}
+		if flipped := posn.FlipFile(); flipped.Fen() != d.flipped {
+			t.Errorf("%s: expected flipped position %s but got %s", d.fen, d.flipped, flipped.Fen())
+		}
+		if posn.Mirror().Mirror().Fen() != posn.Fen() {
+			t.Errorf("%s: Mirror should be self-inverse", d.fen)
+		}
+		// moves must be transformed accordingly
+		mirrored := posn.Mirror()
+		for _, m := range posn.FindMoves(posn.ActiveColour()) {
+			if _, err := mirrored.ParseUci(mirrored.UciString(m.Mirror())); err != nil {
+				t.Errorf("%s: mirrored move %s not legal in the mirrored position: %s", d.fen, m.String(), err)
+			}
+		}
+	}
+}
+
+// the mirrored position has the same number of moves at every depth.
+// Only positions which are not tested with doTest, since doTest checks the mirrored positions itself
+func TestMirrorPerft(t *testing.T) {
+	data := []moveData{
+		{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []int{6, 264, 9467}},
+		{"8/8/8/8/k2pP2R/8/8/4K3 b - e3 0 1", []int{6}}, // the enpassant capture would leave the king in check
+	}
+	for _, d := range data {
+		posn, err := ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("error parsing fen '%s': %s", d.fen, err)
+		}
+		mirrored := posn.Mirror()
+		for depth, expected := range d.expectedNbrMoves {
+			if nbrMoves := mirrored.Perft(depth + 1); nbrMoves != expected {
+				t.Errorf("%s: depth %d, expected %d moves but got %d for the mirrored position %s", d.fen, depth+1, expected, nbrMoves, mirrored.Fen())
+			}
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package position
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rjo67/chess/move"
+)
+
+// UciString returns the move in the notation of the UCI protocol.
+// For Chess960 positions castling moves are given as 'king takes rook', e.g. "e1h1" instead of "e1g1".
+func (p Position) UciString(m move.Move) string {
+	if p.chess960 {
+		return m.Chess960UciString()
+	}
+	return m.UciString()
+}
+
+// ParseUci returns the legal move in the current position which matches the given move in UCI notation (e.g. "e2e4", "e7e8q").
+// Castling moves are also accepted as 'king takes rook' (e.g. "e1h1"). For Chess960 positions this is the only accepted
+// notation, since e.g. "f1g1" could otherwise be either a king move or castles.
+func (p Position) ParseUci(str string) (move.Move, error) {
+	str = strings.ToLower(str)
+	for _, m := range p.FindMoves(p.activeColour) {
+		if m.IsCastles() {
+			if m.Chess960UciString() == str || (!p.chess960 && m.UciString() == str) {
+				return m, nil
+			}
+		} else if m.UciString() == str {
+			return m, nil
+		}
+	}
+	return move.Move{}, fmt.Errorf("illegal move '%s'", str)
+}
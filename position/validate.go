@@ -0,0 +1,224 @@
+package position
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+// the kinds of violations found by Validate. Use errors.Is to check for a particular kind
+var (
+	ErrKingCount       = errors.New("wrong number of kings")
+	ErrSquareOccupied  = errors.New("more than one piece on a square")
+	ErrPawnOnBackRank  = errors.New("pawn on first or last rank")
+	ErrTooManyPieces   = errors.New("too many pieces")
+	ErrOpponentInCheck = errors.New("side not to move is in check")
+	ErrTooManyCheckers = errors.New("king attacked by more than two pieces")
+	ErrCastlingRights  = errors.New("invalid castling rights")
+	ErrEnpassantSquare = errors.New("invalid enpassant square")
+)
+
+// Violation describes one reason why a position cannot occur in a game
+type Violation struct {
+	Kind   error  // one of the Err... values
+	Detail string // e.g. "white pawn on A8"
+}
+
+func (v Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Kind, v.Detail)
+}
+
+// Unwrap returns the kind of the violation
+func (v Violation) Unwrap() error {
+	return v.Kind
+}
+
+// ValidationError is returned by ParseFenStrict and Builder.Build for illegal positions
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Error()
+	}
+	return fmt.Sprintf("illegal position: %s", strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the violations, so that errors.Is finds the kinds of all violations
+func (e ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Violations))
+	for i, v := range e.Violations {
+		errs[i] = v
+	}
+	return errs
+}
+
+// ParseFenStrict creates a position from a FEN string like ParseFen, but also rejects positions which cannot occur
+// in a game (see Validate). The error is then a ValidationError.
+// ParseFen itself remains lenient, e.g. for study diagrams.
+func ParseFenStrict(fen string) (Position, error) {
+	posn, err := ParseFen(fen)
+	if err != nil {
+		return Position{}, err
+	}
+	if violations := posn.Validate(); len(violations) != 0 {
+		return Position{}, ValidationError{violations}
+	}
+	return posn, nil
+}
+
+// Validate checks whether the position can occur in a game and returns all violations found.
+// Returns nil for a legal position.
+//
+// The checks are: each side has one king, no square is occupied twice, no pawns on the first or last rank,
+// no more pieces than possible (taking promotions into account), the side not to move is not in check,
+// the side to move is not attacked by more than two pieces, the king and rooks are on their start squares
+// if castling is available, and the enpassant square is consistent with a preceding double pawn move.
+func (p Position) Validate() []Violation {
+	var violations []Violation
+	add := func(kind error, format string, args ...interface{}) {
+		violations = append(violations, Violation{Kind: kind, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	kingsOK := p.validateKings(add)
+	p.validateSquares(add)
+
+	for _, col := range colour.AllColours {
+		for _, sq := range p.Pieces(col, piece.PAWN).SetBits() {
+			if rank := square.Square(sq).Rank(); rank == 1 || rank == 8 {
+				add(ErrPawnOnBackRank, "%s pawn on %s", col.String(), square.Square(sq).String())
+			}
+		}
+		p.validatePieceCounts(col, add)
+	}
+
+	if kingsOK {
+		otherColour := p.activeColour.Other()
+		otherKing := square.Square(p.Pieces(otherColour, piece.KING).SetBits()[0])
+		if p.AnyPieceAttacksSquare(p.activeColour, otherKing) {
+			add(ErrOpponentInCheck, "%s king on %s is attacked", otherColour.String(), otherKing.String())
+		}
+		myKing := square.Square(p.Pieces(p.activeColour, piece.KING).SetBits()[0])
+		if n := p.Attacks(myKing, otherColour).Cardinality(); n > 2 {
+			add(ErrTooManyCheckers, "%s king on %s is attacked by %d pieces", p.activeColour.String(), myKing.String(), n)
+		}
+		p.validateCastlingRights(add)
+	}
+	p.validateEnpassantSquare(add)
+	return violations
+}
+
+// validateKings checks that each side has exactly one king. Returns false if not
+func (p Position) validateKings(add func(error, string, ...interface{})) bool {
+	kingsOK := true
+	for _, col := range colour.AllColours {
+		if n := p.Pieces(col, piece.KING).Cardinality(); n != 1 {
+			add(ErrKingCount, "%s has %d kings", col.String(), n)
+			kingsOK = false
+		}
+	}
+	return kingsOK
+}
+
+// validateSquares checks that no square is occupied by more than one piece
+func (p Position) validateSquares(add func(error, string, ...interface{})) {
+	for sq := square.Square(1); sq <= 64; sq++ {
+		var found []string
+		for _, col := range colour.AllColours {
+			for _, pieceType := range piece.AllPieces {
+				if p.Pieces(col, pieceType).IsSet(uint(sq)) {
+					found = append(found, pieceType.String(col))
+				}
+			}
+		}
+		if len(found) > 1 {
+			add(ErrSquareOccupied, "%s on %s", strings.Join(found, ", "), sq.String())
+		}
+	}
+}
+
+// validatePieceCounts checks that there are at most 8 pawns, and that the number of pieces exceeding the
+// initial number (i.e. promoted pieces) is not greater than the number of missing pawns
+func (p Position) validatePieceCounts(col colour.Colour, add func(error, string, ...interface{})) {
+	pawns := p.Pieces(col, piece.PAWN).Cardinality()
+	if pawns > 8 {
+		add(ErrTooManyPieces, "%s has %d pawns", col.String(), pawns)
+		return
+	}
+	initial := map[piece.Piece]int{piece.QUEEN: 1, piece.ROOK: 2, piece.BISHOP: 2, piece.KNIGHT: 2}
+	promoted := 0
+	for pieceType, n := range initial {
+		if extra := p.Pieces(col, pieceType).Cardinality() - n; extra > 0 {
+			promoted += extra
+		}
+	}
+	if promoted > 8-pawns {
+		add(ErrTooManyPieces, "%s has %d promoted pieces but only %d pawns are missing", col.String(), promoted, 8-pawns)
+	}
+}
+
+// validateCastlingRights checks that king and rook are on their start squares if castling is available
+func (p Position) validateCastlingRights(add func(error, string, ...interface{})) {
+	for _, col := range colour.AllColours {
+		backRank := 1
+		if col == colour.Black {
+			backRank = 8
+		}
+		king := square.Square(p.Pieces(col, piece.KING).SetBits()[0])
+		for _, kingsside := range []bool{true, false} {
+			var available bool
+			var rookSq square.Square
+			side := "kingsside"
+			if kingsside {
+				available = p.CastlingAvailabilityKingsSide(col)
+				rookSq = p.kingssideCastlingRook[col]
+			} else {
+				available = p.CastlingAvailabilityQueensSide(col)
+				rookSq = p.queenssideCastlingRook[col]
+				side = "queensside"
+			}
+			if !available {
+				continue
+			}
+			switch {
+			case king.Rank() != backRank || (!p.chess960 && king.File() != 5):
+				add(ErrCastlingRights, "%s can castle %s, but the king is on %s", col.String(), side, king.String())
+			case !p.Pieces(col, piece.ROOK).IsSet(uint(rookSq)):
+				add(ErrCastlingRights, "%s can castle %s, but there is no rook on %s", col.String(), side, rookSq.String())
+			case rookSq.Rank() != backRank || (kingsside != (rookSq.File() > king.File())):
+				add(ErrCastlingRights, "%s can castle %s with the rook on %s", col.String(), side, rookSq.String())
+			}
+		}
+	}
+}
+
+// validateEnpassantSquare checks that the enpassant square could have been created by a double pawn move
+// of the side not to move: the pawn must be in front of the enpassant square, and the square itself and the
+// start square of the pawn must be empty
+func (p Position) validateEnpassantSquare(add func(error, string, ...interface{})) {
+	if p.enpassantSquare == nil {
+		return
+	}
+	ep := *p.enpassantSquare
+	otherColour := p.activeColour.Other()
+	epRank, pawnRank, startRank := 6, 5, 7
+	if p.activeColour == colour.Black {
+		epRank, pawnRank, startRank = 3, 4, 2
+	}
+	pawnSq := square.FromRankAndFile(pawnRank, ep.File())
+	startSq := square.FromRankAndFile(startRank, ep.File())
+	switch {
+	case ep.Rank() != epRank:
+		add(ErrEnpassantSquare, "enpassant square %s not possible with %s to move", ep.String(), p.activeColour.String())
+	case !p.Pieces(otherColour, piece.PAWN).IsSet(uint(pawnSq)):
+		add(ErrEnpassantSquare, "enpassant square %s without %s pawn on %s", ep.String(), otherColour.String(), pawnSq.String())
+	case p.occupiedSquares.IsSet(uint(ep)) || p.occupiedSquares.IsSet(uint(startSq)):
+		add(ErrEnpassantSquare, "enpassant square %s, but %s or %s is occupied", ep.String(), ep.String(), startSq.String())
+	}
+}
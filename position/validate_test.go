@@ -0,0 +1,86 @@
+package position
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+func TestValidate(t *testing.T) {
+	data := []struct {
+		fen      string
+		expected []error
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", nil},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", nil},
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", nil},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", nil},
+		{"4k3/8/8/8/8/8/8/P3K2p w - - 0 1", []error{ErrPawnOnBackRank, ErrPawnOnBackRank}},
+		{"4k2R/8/8/8/8/8/8/4K3 w - - 0 1", []error{ErrOpponentInCheck}},
+		{"4k3/pppppppp/8/8/8/8/QQQQQQQQ/QQQK4 w - - 0 1", []error{ErrTooManyPieces}},
+		{"B7/8/8/3k4/8/4N3/8/3RK3 b - - 0 1", []error{ErrTooManyCheckers}},
+		{"4k3/pppppppp/p7/8/8/8/8/4K3 w - - 0 1", []error{ErrTooManyPieces}},
+		{"4k3/8/8/8/8/8/4K3/7R w K - 0 1", []error{ErrCastlingRights}},
+		{"4k3/8/8/8/8/8/8/4K3 w Q - 0 1", []error{ErrCastlingRights}},
+		{"4k3/8/8/8/8/8/4P3/4K3 w - e6 0 1", []error{ErrEnpassantSquare}},
+		{"4k3/8/4p3/4p3/8/8/8/4K3 w - e6 0 1", []error{ErrEnpassantSquare}},
+	}
+	for _, d := range data {
+		posn, err := ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("error parsing fen '%s': %s", d.fen, err)
+		}
+		violations := posn.Validate()
+		if len(violations) != len(d.expected) {
+			t.Errorf("%s: expected %d violations but got %v", d.fen, len(d.expected), violations)
+			continue
+		}
+		for i, v := range violations {
+			if !errors.Is(v, d.expected[i]) {
+				t.Errorf("%s: expected violation '%s' but got '%s'", d.fen, d.expected[i], v)
+			}
+		}
+	}
+}
+
+func TestValidateBuilder(t *testing.T) {
+	// no black king, two pieces on e1
+	kings := bitset.NewFromSquares(square.E1)
+	rooks := bitset.NewFromSquares(square.E1, square.A1)
+	posn := NewBuilder().AddPiece(colour.White, piece.KING, &kings).AddPiece(colour.White, piece.ROOK, &rooks).build()
+	violations := posn.Validate()
+	if len(violations) != 2 || !errors.Is(violations[0], ErrKingCount) || !errors.Is(violations[1], ErrSquareOccupied) {
+		t.Errorf("unexpected violations %v", violations)
+	}
+}
+
+func TestParseFenStrict(t *testing.T) {
+	fen := "4k3/8/8/8/8/8/4Q3/P3K3 w Q - 0 1"
+	if _, err := ParseFen(fen); err != nil {
+		t.Fatalf("lenient mode: unexpected error: %s", err)
+	}
+	_, err := ParseFenStrict(fen)
+	var validationError ValidationError
+	if !errors.As(err, &validationError) || len(validationError.Violations) != 3 {
+		t.Fatalf("expected ValidationError with 3 violations but got %v", err)
+	}
+	for _, kind := range []error{ErrPawnOnBackRank, ErrOpponentInCheck, ErrCastlingRights} {
+		if !errors.Is(err, kind) {
+			t.Errorf("expected error to contain '%s': %s", kind, err)
+		}
+	}
+	if errors.Is(err, ErrEnpassantSquare) {
+		t.Errorf("did not expect error '%s'", ErrEnpassantSquare)
+	}
+	if _, err := ParseFenStrict("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	// syntax errors are reported as before
+	if _, err := ParseFenStrict("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP w KQkq - 0 1"); err == nil || errors.As(err, &validationError) {
+		t.Errorf("expected parse error but got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package position
+
+import (
+	"math/bits"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/ray"
+	"github.com/rjo67/chess/square"
+)
+
+// random numbers for the Zobrist keys, indexed by colour, piece type and square
+var (
+	zobristPieces        [2][6][65]uint64
+	zobristCastling      [4]uint64 // see castling masks
+	zobristEnpassantFile [9]uint64
+	zobristBlackToMove   uint64
+)
+
+func init() {
+	// splitmix64 with a fixed seed, so that keys are stable across runs
+	state := uint64(0x5EED)
+	next := func() uint64 {
+		state += 0x9E3779B97F4A7C15
+		z := state
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		return z ^ (z >> 31)
+	}
+	for col := range zobristPieces {
+		for pieceType := range zobristPieces[col] {
+			for sq := 1; sq <= 64; sq++ {
+				zobristPieces[col][pieceType][sq] = next()
+			}
+		}
+	}
+	for i := range zobristCastling {
+		zobristCastling[i] = next()
+	}
+	for file := 1; file <= 8; file++ {
+		zobristEnpassantFile[file] = next()
+	}
+	zobristBlackToMove = next()
+}
+
+// Key returns a Zobrist hash of the position, suitable for detecting repetitions.
+// Two positions have the same key if the same pieces are on the same squares, the same side is to move,
+// and the castling rights and the possible enpassant captures are the same (FIDE laws, article 9.2.3).
+// The enpassant square is therefore only taken into account if an enpassant capture is legal.
+// The clocks are not part of the key.
+// The key is calculated when the position is created and kept up to date by MakeMove and UnmakeMove.
+func (p Position) Key() uint64 {
+	return p.key
+}
+
+// computeKey calculates the key from scratch, see Key
+func (p Position) computeKey() uint64 {
+	var key uint64
+	for _, col := range colour.AllColours {
+		for _, pieceType := range piece.AllPieces {
+			for val := p.pieces[col][pieceType].Val(); val != 0; val &= val - 1 {
+				key ^= zobristPieces[col][pieceType][bits.TrailingZeros64(val)+1]
+			}
+		}
+	}
+	for i, mask := range []uint32{whiteKingssideMask, whiteQueenssideMask, blackKingssideMask, blackQueenssideMask} {
+		if p.castlingAvailability&mask != 0 {
+			key ^= zobristCastling[i]
+		}
+	}
+	key ^= p.enpassantKey()
+	if p.activeColour == colour.Black {
+		key ^= zobristBlackToMove
+	}
+	return key
+}
+
+// enpassantKey returns the part of the key for the enpassant square, which is 0 if there is no legal enpassant capture
+func (p Position) enpassantKey() uint64 {
+	if p.enpassantSquare != nil && p.enpassantCapturePossible() {
+		return zobristEnpassantFile[p.enpassantSquare.File()]
+	}
+	return 0
+}
+
+// toggleCastlingAvailability toggles the castling rights given by the mask (see castling masks) and updates the key
+func (p *Position) toggleCastlingAvailability(mask uint32) {
+	p.castlingAvailability ^= mask
+	p.key ^= zobristCastling[bits.TrailingZeros32(mask)]
+}
+
+// enpassantCapturePossible returns true if the side to move has a legal enpassant capture.
+// The move is not made (MakeMove updates the key), instead the attacks on the king are calculated for the
+// squares the pieces occupy after the capture.
+func (p Position) enpassantCapturePossible() bool {
+	col := p.activeColour
+	otherColour := col.Other()
+	ep := *p.enpassantSquare
+	kings := p.pieces[col][piece.KING].SetBits()
+	if len(kings) != 1 {
+		return false
+	}
+	king := kings[0]
+	capturedSq := square.FromRankAndFile(4, ep.File())
+	if col == colour.White {
+		capturedSq = square.FromRankAndFile(5, ep.File())
+	}
+	if !p.pieces[otherColour][piece.PAWN].IsSet(uint(capturedSq)) {
+		return false
+	}
+	otherPawns := p.pieces[otherColour][piece.PAWN]
+	otherPawns.ClearSquare(capturedSq)
+
+	for _, from := range ray.PawnAttackBitSets[col][ep].And(p.pieces[col][piece.PAWN]).SetBits() {
+		occupied := p.occupiedSquares
+		occupied.Clear(uint(from))
+		occupied.ClearSquare(capturedSq)
+		occupied.SetSquare(ep)
+		if !ray.KnightAttackBitSets[king].And(p.pieces[otherColour][piece.KNIGHT]).IsEmpty() ||
+			!ray.PawnAttackBitSets[otherColour][king].And(otherPawns).IsEmpty() {
+			continue
+		}
+		diagonals, rankfiles := bitset.New(0), bitset.New(0)
+		for _, direction := range ray.AllBishopDirections {
+			bs, _ := move.Search2(king, direction, occupied)
+			diagonals = diagonals.Or(bs)
+		}
+		for _, direction := range ray.AllRookDirections {
+			bs, _ := move.Search2(king, direction, occupied)
+			rankfiles = rankfiles.Or(bs)
+		}
+		queens := p.pieces[otherColour][piece.QUEEN]
+		if diagonals.And(p.pieces[otherColour][piece.BISHOP].Or(queens)).IsEmpty() &&
+			rankfiles.And(p.pieces[otherColour][piece.ROOK].Or(queens)).IsEmpty() {
+			return true
+		}
+	}
+	return false
+}
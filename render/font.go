@@ -0,0 +1,47 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// size of the characters of the bitmap font
+const (
+	fontWidth  = 5
+	fontHeight = 7
+)
+
+// fontGlyphs is a bitmap font for the coordinates, each character has 7 rows of 5 pixels
+var fontGlyphs = map[rune][fontHeight]string{
+	'a': {".....", ".....", ".###.", "....#", ".####", "#...#", ".####"},
+	'b': {"#....", "#....", "####.", "#...#", "#...#", "#...#", "####."},
+	'c': {".....", ".....", ".###.", "#....", "#....", "#...#", ".###."},
+	'd': {"....#", "....#", ".####", "#...#", "#...#", "#...#", ".####"},
+	'e': {".....", ".....", ".###.", "#...#", "#####", "#....", ".###."},
+	'f': {"..##.", ".#..#", ".#...", "###..", ".#...", ".#...", ".#..."},
+	'g': {".....", ".####", "#...#", "#...#", ".####", "....#", ".###."},
+	'h': {"#....", "#....", "#.##.", "##..#", "#...#", "#...#", "#...#"},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+}
+
+// drawChar draws the character with its top left corner at the given point, each pixel of the font as a square of scale pixels
+func drawChar(dst draw.Image, topLeft image.Point, ch rune, scale int, c color.Color) {
+	src := image.NewUniform(c)
+	for row, line := range fontGlyphs[ch] {
+		for col, pixel := range line {
+			if pixel != '#' {
+				continue
+			}
+			r := image.Rect(col*scale, row*scale, (col+1)*scale, (row+1)*scale).Add(topLeft)
+			draw.Draw(dst, r, src, image.Point{}, draw.Over)
+		}
+	}
+}
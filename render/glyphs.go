@@ -0,0 +1,71 @@
+package render
+
+import "github.com/rjo67/chess/piece"
+
+// point in the coordinate system of a square: 0..100 from left to right and top to bottom
+type point struct {
+	x, y float64
+}
+
+// shape is one part of a piece glyph, either a polygon or (if radius > 0) a circle.
+// Shapes are filled with the colour of the piece and outlined, detail shapes are filled with the outline colour.
+type shape struct {
+	points []point
+	center point
+	radius float64
+	detail bool
+}
+
+func polygon(coords ...float64) shape {
+	s := shape{}
+	for i := 0; i+1 < len(coords); i += 2 {
+		s.points = append(s.points, point{coords[i], coords[i+1]})
+	}
+	return s
+}
+
+func circle(x, y, radius float64) shape {
+	return shape{center: point{x, y}, radius: radius}
+}
+
+func detail(s shape) shape {
+	s.detail = true
+	return s
+}
+
+// base of most pieces
+var glyphBase = polygon(24, 80, 76, 80, 76, 90, 24, 90)
+
+// glyphs are the vector drawings of the pieces, in the order in which the shapes are drawn
+var glyphs = map[piece.Piece][]shape{
+	piece.PAWN: {
+		circle(50, 30, 12),
+		polygon(40, 44, 60, 44, 66, 78, 34, 78),
+		polygon(26, 76, 74, 76, 74, 88, 26, 88),
+	},
+	piece.ROOK: {
+		polygon(33, 38, 67, 38, 68, 80, 32, 80),
+		polygon(26, 16, 36, 16, 36, 24, 45, 24, 45, 16, 55, 16, 55, 24, 64, 24, 64, 16, 74, 16, 74, 40, 26, 40),
+		glyphBase,
+	},
+	piece.KNIGHT: {
+		polygon(70, 90, 24, 90, 26, 60, 30, 40, 38, 24, 50, 16, 54, 8, 58, 18, 66, 24, 78, 44, 82, 56, 76, 62, 66, 56, 56, 50, 60, 62, 70, 78),
+		detail(circle(58, 30, 3)),
+	},
+	piece.BISHOP: {
+		circle(50, 13, 5),
+		polygon(50, 18, 60, 28, 66, 44, 62, 60, 66, 80, 34, 80, 38, 60, 34, 44, 40, 28),
+		detail(polygon(52, 30, 56, 34, 48, 44, 44, 40)),
+		glyphBase,
+	},
+	piece.QUEEN: {
+		polygon(26, 80, 16, 36, 32, 60, 34, 26, 44, 56, 50, 22, 56, 56, 66, 26, 68, 60, 84, 36, 74, 80),
+		circle(16, 32, 5), circle(34, 22, 5), circle(50, 18, 5), circle(66, 22, 5), circle(84, 32, 5),
+		glyphBase,
+	},
+	piece.KING: {
+		polygon(47, 8, 53, 8, 53, 16, 60, 16, 60, 22, 53, 22, 53, 36, 47, 36, 47, 22, 40, 22, 40, 16, 47, 16),
+		polygon(26, 80, 20, 50, 30, 40, 44, 44, 50, 36, 56, 44, 70, 40, 80, 50, 74, 80),
+		glyphBase,
+	},
+}
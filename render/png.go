@@ -0,0 +1,170 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// key of the sprite cache
+type spriteKey struct {
+	size      int
+	pieceType piece.Piece
+	col       colour.Colour
+}
+
+// sprites of the pieces, rasterised from the glyphs on first use for each size
+var (
+	sprites      = make(map[spriteKey]*image.RGBA)
+	spritesMutex sync.Mutex
+)
+
+// sprite returns the image of the piece for squares of the given size
+func sprite(size int, pieceType piece.Piece, col colour.Colour) *image.RGBA {
+	spritesMutex.Lock()
+	defer spritesMutex.Unlock()
+	key := spriteKey{size, pieceType, col}
+	if img, ok := sprites[key]; ok {
+		return img
+	}
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	fill, stroke := pieceColours(col)
+	rasterise(img, image.Point{}, glyphs[pieceType], float64(size)/100, fill, stroke, 3)
+	sprites[key] = img
+	return img
+}
+
+// Image draws a diagram of the position. Since all squares have the same size, the image can be up to 7 pixels smaller than
+// the requested size. The coordinates are drawn with a built-in bitmap font.
+func Image(posn position.Position, opts Options) (*image.RGBA, error) {
+	size, err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	sqSize := size / 8
+	if sqSize < 8 {
+		return nil, fmt.Errorf("size %d too small", size)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 8*sqSize, 8*sqSize))
+	theme := opts.theme()
+
+	squareRect := func(sq square.Square) image.Rectangle {
+		col, row := opts.topLeft(sq)
+		return image.Rect(col*sqSize, row*sqSize, (col+1)*sqSize, (row+1)*sqSize)
+	}
+	for sq := square.H1; sq <= square.A8; sq++ {
+		draw.Draw(img, squareRect(sq), image.NewUniform(theme.squareColour(sq)), image.Point{}, draw.Src)
+	}
+	for _, sq := range opts.highlights() {
+		draw.Draw(img, squareRect(sq), image.NewUniform(theme.Highlight), image.Point{}, draw.Over)
+	}
+	for _, overlay := range opts.Overlays {
+		src := image.NewUniform(colourOrDefault(overlay.Colour, OverlayColour))
+		for _, sq := range overlay.Squares.SetBits() {
+			draw.Draw(img, squareRect(square.Square(sq)), src, image.Point{}, draw.Over)
+		}
+	}
+	if opts.Coordinates {
+		drawCoordinates(img, opts, theme, sqSize)
+	}
+	for sq := square.H1; sq <= square.A8; sq++ {
+		if pieceType, col, ok := posn.PieceOn(sq); ok {
+			r := squareRect(sq)
+			draw.Draw(img, r, sprite(sqSize, pieceType, col), image.Point{}, draw.Over)
+		}
+	}
+	for _, arrow := range opts.Arrows {
+		c := colourOrDefault(arrow.Colour, ArrowColour)
+		rasterise(img, image.Point{}, []shape{opts.arrowShape(arrow)}, float64(sqSize)/100, c, c, 0)
+	}
+	return img, nil
+}
+
+// drawCoordinates draws the files along the bottom edge and the ranks along the left edge of the board
+func drawCoordinates(img *image.RGBA, opts Options, theme Theme, sqSize int) {
+	scale := sqSize / 30
+	if scale < 1 {
+		scale = 1
+	}
+	margin := scale * 2
+	for i := 0; i < 8; i++ {
+		// bottom row, from left to right
+		sq := square.FromRankAndFile(1, i+1)
+		if opts.Orientation == colour.Black {
+			sq = square.FromRankAndFile(8, 8-i)
+		}
+		topLeft := image.Pt((i+1)*sqSize-margin-fontWidth*scale, 8*sqSize-margin-fontHeight*scale)
+		drawChar(img, topLeft, rune('a'+sq.File()-1), scale, theme.coordinateColour(sq))
+		// left column, from top to bottom
+		sq = square.FromRankAndFile(8-i, 1)
+		if opts.Orientation == colour.Black {
+			sq = square.FromRankAndFile(i+1, 8)
+		}
+		drawChar(img, image.Pt(margin, i*sqSize+margin), rune('0'+sq.Rank()), scale, theme.coordinateColour(sq))
+	}
+}
+
+// PNG writes a diagram of the position as a PNG image, see Image
+func PNG(w io.Writer, posn position.Position, opts Options) error {
+	img, err := Image(posn, opts)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// GIF writes an animated GIF of the game starting in the given position, with one frame for the start position and one
+// for the position after each of the moves, which is shown for the given delay. The last move is highlighted in each frame,
+// opts.LastMove is ignored. The moves must be legal; the position is unchanged on return.
+func GIF(w io.Writer, start position.Position, moves []move.Move, opts Options, delay time.Duration) error {
+	posn := start
+	anim := gif.GIF{}
+	addFrame := func(lastMove *move.Move) error {
+		frameOpts := opts
+		frameOpts.LastMove = lastMove
+		img, err := Image(posn, frameOpts)
+		if err != nil {
+			return err
+		}
+		frame := image.NewPaletted(img.Bounds(), palette.Plan9)
+		draw.Draw(frame, frame.Bounds(), img, image.Point{}, draw.Src)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, int(delay/(10*time.Millisecond)))
+		return nil
+	}
+	if err := addFrame(nil); err != nil {
+		return err
+	}
+
+	// the moves are made on the position (which shares its state with the caller's copy) and are unmade afterwards
+	played := make([]move.Move, 0, len(moves))
+	defer func() {
+		for i := len(played) - 1; i >= 0; i-- {
+			posn.UnmakeMove(played[i])
+		}
+	}()
+	for i, m := range moves {
+		legal, err := posn.ParseUci(posn.UciString(m))
+		if err != nil || legal.Encode() != m.Encode() {
+			return fmt.Errorf("move %d (%s) is not legal", i+1, m.String())
+		}
+		posn.MakeMove(legal)
+		played = append(played, legal)
+		if err := addFrame(&legal); err != nil {
+			return err
+		}
+	}
+	return gif.EncodeAll(w, &anim)
+}
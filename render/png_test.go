@@ -0,0 +1,143 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+	"time"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// sameColour returns true if the colours are equal when converted to RGBA
+func sameColour(c1, c2 color.Color) bool {
+	return color.RGBAModel.Convert(c1) == color.RGBAModel.Convert(c2)
+}
+
+func TestImage(t *testing.T) {
+	posn := position.StartPosition()
+	for _, d := range []struct {
+		opts     Options
+		size     int
+		a1Corner image.Point // a pixel in a corner of the square a1, not covered by the piece
+		a1Colour color.Color
+	}{
+		{Options{}, DefaultSize, image.Pt(1, DefaultSize-2), BrownTheme.Dark},
+		{Options{Size: 205, Orientation: colour.Black, Theme: GreenTheme}, 200, image.Pt(198, 1), GreenTheme.Dark},
+		{Options{Size: 64, Theme: Theme{Dark: color.Black}}, 64, image.Pt(0, 63), color.Black},
+	} {
+		img, err := Image(posn, d.opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if img.Bounds() != image.Rect(0, 0, d.size, d.size) {
+			t.Errorf("expected size %d but got %v", d.size, img.Bounds())
+		}
+		if got := img.At(d.a1Corner.X, d.a1Corner.Y); !sameColour(got, d.a1Colour) {
+			t.Errorf("options %v: expected colour %v of a1 but got %v", d.opts, d.a1Colour, got)
+		}
+		// the centre of e1 is covered by the king
+		sqSize := d.size / 8
+		x, y := 4*sqSize+sqSize/2, 7*sqSize+sqSize/2
+		if d.opts.Orientation == colour.Black {
+			x, y = 3*sqSize+sqSize/2, sqSize/2
+		}
+		if got := img.At(x, y); !sameColour(got, color.White) {
+			t.Errorf("options %v: expected white king on e1 but got colour %v", d.opts, got)
+		}
+	}
+}
+
+func TestImageDecorations(t *testing.T) {
+	posn := position.StartPosition()
+	m := move.New(colour.White, square.E2, square.E4, piece.PAWN)
+	posn.MakeMove(m)
+	plain, _ := Image(posn, Options{Size: 160})
+	img, err := Image(posn, Options{Size: 160, Coordinates: true, LastMove: &m, Arrows: []Arrow{{From: square.B1, To: square.C3}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, d := range []struct {
+		p       image.Point
+		changed bool
+		what    string
+	}{
+		{image.Pt(81, 121), true, "highlighted e2"},
+		{image.Pt(81, 81), true, "highlighted e4"},
+		{image.Pt(101, 101), false, "f4"},
+		{image.Pt(40, 130), true, "arrow b1-c3"},
+		{image.Pt(2, 3), true, "coordinate '8'"},
+	} {
+		if changed := plain.At(d.p.X, d.p.Y) != img.At(d.p.X, d.p.Y); changed != d.changed {
+			t.Errorf("%s: expected changed=%t", d.what, d.changed)
+		}
+	}
+
+	if _, err := Image(posn, Options{Size: 32}); err == nil {
+		t.Errorf("expected error for size 32")
+	}
+	if _, err := Image(posn, Options{Arrows: []Arrow{{From: square.E2, To: square.E2}}}); err == nil {
+		t.Errorf("expected error for invalid arrow")
+	}
+}
+
+func TestPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PNG(&buf, position.StartPosition(), Options{Size: 80}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("cannot decode PNG: %s", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, 80, 80) {
+		t.Errorf("unexpected size %v", img.Bounds())
+	}
+}
+
+func TestGIF(t *testing.T) {
+	posn := position.StartPosition()
+	fen := posn.Fen()
+	var moves []move.Move
+	for _, uci := range []string{"e2e4", "e7e5", "g1f3"} {
+		m, err := posn.ParseUci(uci)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		moves = append(moves, m)
+		posn.MakeMove(m)
+	}
+	for i := len(moves) - 1; i >= 0; i-- {
+		posn.UnmakeMove(moves[i])
+	}
+
+	var buf bytes.Buffer
+	if err := GIF(&buf, posn, moves, Options{Size: 80}, 500*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if posn.Fen() != fen {
+		t.Errorf("position changed to %s", posn.Fen())
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatalf("cannot decode GIF: %s", err)
+	}
+	if len(anim.Image) != 4 || anim.Delay[0] != 50 {
+		t.Errorf("expected 4 frames with delay 50 but got %d frames, delays %v", len(anim.Image), anim.Delay)
+	}
+
+	// a move which is not legal after the first move
+	if err := GIF(&buf, posn, []move.Move{moves[0], moves[2], moves[1]}, Options{Size: 80}, time.Second); err == nil {
+		t.Errorf("expected error for illegal move")
+	}
+	if posn.Fen() != fen {
+		t.Errorf("position changed to %s after error", posn.Fen())
+	}
+}
@@ -0,0 +1,128 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// samples per pixel in each direction, for anti-aliasing
+const rasterSamples = 4
+
+// rasterise draws the shapes onto the image. The coordinates of the shapes are multiplied by scale and offset by the given origin.
+// Shapes are filled with the fill colour (detail shapes with the stroke colour) and outlined with the stroke colour,
+// later shapes are drawn over earlier ones. No outlines are drawn if strokeWidth is 0.
+func rasterise(dst *image.RGBA, origin image.Point, shapes []shape, scale float64, fill, stroke color.Color, strokeWidth float64) {
+	// bounding box of the shapes, in pixels
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, s := range shapes {
+		for _, p := range s.outline() {
+			minX, minY = math.Min(minX, p.x), math.Min(minY, p.y)
+			maxX, maxY = math.Max(maxX, p.x), math.Max(maxY, p.y)
+		}
+	}
+	margin := strokeWidth / 2
+	bounds := image.Rect(int((minX-margin)*scale)-1, int((minY-margin)*scale)-1, int((maxX+margin)*scale)+2, int((maxY+margin)*scale)+2).
+		Add(origin).Intersect(dst.Bounds())
+
+	fr, fg, fb, fa := fill.RGBA()
+	sr, sg, sb, sa := stroke.RGBA()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			nbrFill, nbrStroke := 0, 0
+			for i := 0; i < rasterSamples*rasterSamples; i++ {
+				// sample point in the coordinate system of the shapes
+				p := point{
+					(float64(x-origin.X) + (float64(i%rasterSamples)+0.5)/rasterSamples) / scale,
+					(float64(y-origin.Y) + (float64(i/rasterSamples)+0.5)/rasterSamples) / scale,
+				}
+				switch sampleShapes(shapes, p, strokeWidth) {
+				case sampleFill:
+					nbrFill++
+				case sampleStroke:
+					nbrStroke++
+				}
+			}
+			if nbrFill+nbrStroke == 0 {
+				continue
+			}
+			// colour of the shapes at this pixel (premultiplied), drawn over the existing pixel
+			const n = rasterSamples * rasterSamples
+			mix := func(f, s uint32) uint32 { return (uint32(nbrFill)*f + uint32(nbrStroke)*s) / n }
+			a := mix(fa, sa)
+			existing := dst.RGBAAt(x, y)
+			over := func(src uint32, dstVal uint8) uint8 {
+				return uint8((src + uint32(dstVal)*0x101*(0xFFFF-a)/0xFFFF) >> 8)
+			}
+			dst.SetRGBA(x, y, color.RGBA{over(mix(fr, sr), existing.R), over(mix(fg, sg), existing.G), over(mix(fb, sb), existing.B), over(a, existing.A)})
+		}
+	}
+}
+
+// the result of sampling the shapes at a point
+const (
+	sampleNone = iota
+	sampleFill
+	sampleStroke
+)
+
+// sampleShapes returns whether the point lies on the fill or the outline of the topmost shape covering it
+func sampleShapes(shapes []shape, p point, strokeWidth float64) int {
+	result := sampleNone
+	for _, s := range shapes {
+		switch {
+		case strokeWidth > 0 && s.edgeDistance(p) <= strokeWidth/2:
+			result = sampleStroke
+		case s.contains(p) && s.detail:
+			result = sampleStroke
+		case s.contains(p):
+			result = sampleFill
+		}
+	}
+	return result
+}
+
+// outline returns the points of a polygon, or the corners of the bounding box of a circle
+func (s shape) outline() []point {
+	if s.radius > 0 {
+		return []point{{s.center.x - s.radius, s.center.y - s.radius}, {s.center.x + s.radius, s.center.y + s.radius}}
+	}
+	return s.points
+}
+
+// contains returns true if the point lies inside the shape (even-odd rule for polygons)
+func (s shape) contains(p point) bool {
+	if s.radius > 0 {
+		return math.Hypot(p.x-s.center.x, p.y-s.center.y) <= s.radius
+	}
+	inside := false
+	for i, j := 0, len(s.points)-1; i < len(s.points); j, i = i, i+1 {
+		a, b := s.points[i], s.points[j]
+		if (a.y > p.y) != (b.y > p.y) && p.x < (b.x-a.x)*(p.y-a.y)/(b.y-a.y)+a.x {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// edgeDistance returns the distance of the point from the outline of the shape
+func (s shape) edgeDistance(p point) float64 {
+	if s.radius > 0 {
+		return math.Abs(math.Hypot(p.x-s.center.x, p.y-s.center.y) - s.radius)
+	}
+	dist := math.Inf(1)
+	for i, j := 0, len(s.points)-1; i < len(s.points); j, i = i, i+1 {
+		dist = math.Min(dist, segmentDistance(p, s.points[j], s.points[i]))
+	}
+	return dist
+}
+
+// segmentDistance returns the distance of the point p from the line segment a-b
+func segmentDistance(p, a, b point) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	t := 0.0
+	if lengthSq := dx*dx + dy*dy; lengthSq > 0 {
+		t = math.Max(0, math.Min(1, ((p.x-a.x)*dx+(p.y-a.y)*dy)/lengthSq))
+	}
+	return math.Hypot(p.x-(a.x+t*dx), p.y-(a.y+t*dy))
+}
@@ -0,0 +1,176 @@
+// Package render draws diagrams of positions
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/square"
+)
+
+// DefaultSize is the width and height of a diagram in pixels if Options.Size is not set
+const DefaultSize = 400
+
+// default colours of arrows and overlays
+var (
+	ArrowColour   = color.NRGBA{0x15, 0x78, 0x1B, 0xAA}
+	OverlayColour = color.NRGBA{0xD0, 0x30, 0x30, 0x66}
+)
+
+// Theme defines the colours of the board
+type Theme struct {
+	Light, Dark color.Color // colours of the light and dark squares
+	Highlight   color.Color // colour drawn over highlighted squares, should be translucent
+}
+
+// predefined themes
+var (
+	BrownTheme = Theme{Light: color.NRGBA{0xF0, 0xD9, 0xB5, 0xFF}, Dark: color.NRGBA{0xB5, 0x88, 0x63, 0xFF}, Highlight: color.NRGBA{0xCD, 0xD2, 0x6A, 0xAA}}
+	GreenTheme = Theme{Light: color.NRGBA{0xEE, 0xEE, 0xD2, 0xFF}, Dark: color.NRGBA{0x76, 0x96, 0x56, 0xFF}, Highlight: color.NRGBA{0xF6, 0xF6, 0x69, 0xAA}}
+	BlueTheme  = Theme{Light: color.NRGBA{0xDE, 0xE3, 0xE6, 0xFF}, Dark: color.NRGBA{0x8C, 0xA2, 0xAD, 0xFF}, Highlight: color.NRGBA{0x9B, 0xC7, 0x00, 0xAA}}
+	GreyTheme  = Theme{Light: color.NRGBA{0xE0, 0xE0, 0xE0, 0xFF}, Dark: color.NRGBA{0xA0, 0xA0, 0xA0, 0xFF}, Highlight: color.NRGBA{0xFF, 0xD7, 0x00, 0x88}}
+)
+
+// Options control how a diagram is drawn. The zero value draws the board with white at the bottom and no decorations
+type Options struct {
+	Size        int             // width and height of the diagram in pixels (DefaultSize if 0), not used for text
+	Theme       Theme           // colours of the board, unset colours are taken from BrownTheme
+	Orientation colour.Colour   // the colour at the bottom of the board (White or Black)
+	Coordinates bool            // draws the files a-h and ranks 1-8 at the edges of the board
+	LastMove    *move.Move      // highlights the From and To squares of the move
+	Highlights  []square.Square // further squares to highlight
+	Arrows      []Arrow         // arrows drawn on top of the pieces, not shown in text
+	Overlays    []Overlay       // bitsets drawn on top of the squares, e.g. the squares attacked by a piece
+	Unicode     bool            // text only: the pieces are shown as chess symbols instead of FEN letters
+	ANSI        bool            // text only: the squares are coloured with ANSI escape codes (24-bit colour)
+}
+
+// Arrow between the centres of two squares
+type Arrow struct {
+	From, To square.Square
+	Colour   color.Color // ArrowColour if nil
+}
+
+// Overlay marks all squares of a bitset
+type Overlay struct {
+	Squares bitset.BitSet
+	Colour  color.Color // OverlayColour if nil
+}
+
+// validate checks the options and returns the size of the diagram
+func (opts Options) validate() (int, error) {
+	if opts.Size < 0 {
+		return 0, fmt.Errorf("invalid size %d", opts.Size)
+	}
+	if opts.Orientation != colour.White && opts.Orientation != colour.Black {
+		return 0, fmt.Errorf("invalid orientation %d", opts.Orientation)
+	}
+	for _, sq := range opts.Highlights {
+		if !validSquare(sq) {
+			return 0, fmt.Errorf("invalid highlight square %d", sq)
+		}
+	}
+	for _, arrow := range opts.Arrows {
+		if !validSquare(arrow.From) || !validSquare(arrow.To) || arrow.From == arrow.To {
+			return 0, fmt.Errorf("invalid arrow from %d to %d", arrow.From, arrow.To)
+		}
+	}
+	if opts.Size == 0 {
+		return DefaultSize, nil
+	}
+	return opts.Size, nil
+}
+
+// highlights returns the squares to highlight, including those of the last move
+func (opts Options) highlights() []square.Square {
+	squares := make([]square.Square, 0, len(opts.Highlights)+2)
+	if opts.LastMove != nil {
+		squares = append(squares, opts.LastMove.From(), opts.LastMove.To())
+	}
+	return append(squares, opts.Highlights...)
+}
+
+// theme returns the colours of the board, using BrownTheme for unset colours
+func (opts Options) theme() Theme {
+	theme := opts.Theme
+	theme.Light = colourOrDefault(theme.Light, BrownTheme.Light)
+	theme.Dark = colourOrDefault(theme.Dark, BrownTheme.Dark)
+	theme.Highlight = colourOrDefault(theme.Highlight, BrownTheme.Highlight)
+	return theme
+}
+
+// squareColour returns the colour of the square in the theme
+func (theme Theme) squareColour(sq square.Square) color.Color {
+	if isLight(sq) {
+		return theme.Light
+	}
+	return theme.Dark
+}
+
+// coordinateColour returns the colour of the other squares, so that the coordinates are readable
+func (theme Theme) coordinateColour(sq square.Square) color.Color {
+	if isLight(sq) {
+		return theme.Dark
+	}
+	return theme.Light
+}
+
+// pieceColours returns the fill and outline colours of the pieces of the given colour
+func pieceColours(col colour.Colour) (color.Color, color.Color) {
+	if col == colour.White {
+		return color.White, color.Black
+	}
+	return color.Black, color.Gray{0x80}
+}
+
+// arrowShape returns the outline of the arrow, in the coordinate system of the board where each square is 100 units wide
+func (opts Options) arrowShape(arrow Arrow) shape {
+	const shaftWidth, headWidth, headLength = 16.0, 44.0, 36.0
+	col, row := opts.topLeft(arrow.From)
+	x1, y1 := float64(col*100+50), float64(row*100+50)
+	col, row = opts.topLeft(arrow.To)
+	x2, y2 := float64(col*100+50), float64(row*100+50)
+	length := math.Hypot(x2-x1, y2-y1)
+	// unit vectors along and across the arrow
+	dx, dy := (x2-x1)/length, (y2-y1)/length
+	nx, ny := -dy, dx
+	bx, by := x2-dx*headLength, y2-dy*headLength // base of the head
+	return shape{points: []point{
+		{x1 + nx*shaftWidth/2, y1 + ny*shaftWidth/2},
+		{bx + nx*shaftWidth/2, by + ny*shaftWidth/2},
+		{bx + nx*headWidth/2, by + ny*headWidth/2},
+		{x2, y2},
+		{bx - nx*headWidth/2, by - ny*headWidth/2},
+		{bx - nx*shaftWidth/2, by - ny*shaftWidth/2},
+		{x1 - nx*shaftWidth/2, y1 - ny*shaftWidth/2},
+	}}
+}
+
+// topLeft returns the column and row (0..7, from the top left of the diagram) of the square
+func (opts Options) topLeft(sq square.Square) (int, int) {
+	if opts.Orientation == colour.Black {
+		return 8 - sq.File(), sq.Rank() - 1
+	}
+	return sq.File() - 1, 8 - sq.Rank()
+}
+
+func validSquare(sq square.Square) bool {
+	return sq >= square.H1 && sq <= square.A8
+}
+
+// isLight returns true if the square is a light square
+func isLight(sq square.Square) bool {
+	return (sq.Rank()+sq.File())%2 == 1
+}
+
+// colourOrDefault returns c, or the default colour if c is nil
+func colourOrDefault(c color.Color, def color.Color) color.Color {
+	if c == nil {
+		return def
+	}
+	return c
+}
@@ -0,0 +1,147 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// pieceNames are used as class names of the pieces in the SVG
+var pieceNames = map[piece.Piece]string{piece.PAWN: "pawn", piece.ROOK: "rook", piece.KNIGHT: "knight", piece.BISHOP: "bishop", piece.QUEEN: "queen", piece.KING: "king"}
+
+// SVG writes a diagram of the position as an SVG image.
+// Each square is 100 units wide in the coordinate system of the image, the image is scaled to the requested size.
+func SVG(w io.Writer, posn position.Position, opts Options) error {
+	size, err := opts.validate()
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 800 800">`+"\n", size, size)
+
+	theme := opts.theme()
+	for sq := square.H1; sq <= square.A8; sq++ {
+		svgSquare(&sb, opts, sq, "square", theme.squareColour(sq))
+	}
+	for _, sq := range opts.highlights() {
+		svgSquare(&sb, opts, sq, "highlight", theme.Highlight)
+	}
+	for _, overlay := range opts.Overlays {
+		for _, sq := range overlay.Squares.SetBits() {
+			svgSquare(&sb, opts, square.Square(sq), "overlay", colourOrDefault(overlay.Colour, OverlayColour))
+		}
+	}
+	if opts.Coordinates {
+		svgCoordinates(&sb, opts, theme)
+	}
+	for sq := square.H1; sq <= square.A8; sq++ {
+		if pieceType, col, ok := posn.PieceOn(sq); ok {
+			svgPiece(&sb, opts, sq, pieceType, col)
+		}
+	}
+	for _, arrow := range opts.Arrows {
+		svgArrow(&sb, opts, arrow)
+	}
+
+	sb.WriteString("</svg>\n")
+	_, err = io.WriteString(w, sb.String())
+	return err
+}
+
+// svgSquare fills the square with the given colour
+func svgSquare(sb *strings.Builder, opts Options, sq square.Square, class string, c color.Color) {
+	col, row := opts.topLeft(sq)
+	fmt.Fprintf(sb, `<rect class="%s" x="%d" y="%d" width="100" height="100"%s/>`+"\n", class, col*100, row*100, svgFill(c))
+}
+
+// svgCoordinates writes the files along the bottom edge and the ranks along the left edge of the board
+func svgCoordinates(sb *strings.Builder, opts Options, theme Theme) {
+	for i := 0; i < 8; i++ {
+		// bottom row, from left to right
+		sq := square.FromRankAndFile(1, i+1)
+		if opts.Orientation == colour.Black {
+			sq = square.FromRankAndFile(8, 8-i)
+		}
+		fmt.Fprintf(sb, `<text x="%d" y="%d" font-family="sans-serif" font-size="20" text-anchor="end"%s>%c</text>`+"\n",
+			i*100+95, 795, svgFill(theme.coordinateColour(sq)), 'a'+sq.File()-1)
+		// left column, from top to bottom
+		sq = square.FromRankAndFile(8-i, 1)
+		if opts.Orientation == colour.Black {
+			sq = square.FromRankAndFile(i+1, 8)
+		}
+		fmt.Fprintf(sb, `<text x="%d" y="%d" font-family="sans-serif" font-size="20"%s>%d</text>`+"\n",
+			4, i*100+20, svgFill(theme.coordinateColour(sq)), sq.Rank())
+	}
+}
+
+// svgPiece draws the glyph of the piece on the square
+func svgPiece(sb *strings.Builder, opts Options, sq square.Square, pieceType piece.Piece, col colour.Colour) {
+	fill, stroke := pieceColours(col)
+	colName := "white"
+	if col == colour.Black {
+		colName = "black"
+	}
+	x, y := opts.topLeft(sq)
+	fmt.Fprintf(sb, `<g class="piece %s %s" transform="translate(%d,%d)" stroke-width="3" stroke-linejoin="round"%s>`+"\n",
+		colName, pieceNames[pieceType], x*100, y*100, svgStroke(stroke))
+	for _, s := range glyphs[pieceType] {
+		shapeFill := fill
+		if s.detail {
+			shapeFill = stroke
+		}
+		if s.radius > 0 {
+			fmt.Fprintf(sb, `<circle cx="%g" cy="%g" r="%g"%s/>`+"\n", s.center.x, s.center.y, s.radius, svgFill(shapeFill))
+			continue
+		}
+		coords := make([]string, len(s.points))
+		for i, p := range s.points {
+			coords[i] = fmt.Sprintf("%g,%g", p.x, p.y)
+		}
+		fmt.Fprintf(sb, `<polygon points="%s"%s/>`+"\n", strings.Join(coords, " "), svgFill(shapeFill))
+	}
+	sb.WriteString("</g>\n")
+}
+
+// svgArrow draws an arrow from the centre of one square to the centre of another
+func svgArrow(sb *strings.Builder, opts Options, arrow Arrow) {
+	s := opts.arrowShape(arrow)
+	coords := make([]string, len(s.points))
+	for i, p := range s.points {
+		coords[i] = fmt.Sprintf("%.1f,%.1f", p.x, p.y)
+	}
+	fmt.Fprintf(sb, `<polygon class="arrow" points="%s"%s/>`+"\n", strings.Join(coords, " "), svgFill(colourOrDefault(arrow.Colour, ArrowColour)))
+}
+
+// svgFill returns the fill attributes for the colour
+func svgFill(c color.Color) string {
+	rgb, opacity := svgColour(c)
+	if opacity != "" {
+		return fmt.Sprintf(` fill="%s" fill-opacity="%s"`, rgb, opacity)
+	}
+	return fmt.Sprintf(` fill="%s"`, rgb)
+}
+
+// svgStroke returns the stroke attributes for the colour
+func svgStroke(c color.Color) string {
+	rgb, opacity := svgColour(c)
+	if opacity != "" {
+		return fmt.Sprintf(` stroke="%s" stroke-opacity="%s"`, rgb, opacity)
+	}
+	return fmt.Sprintf(` stroke="%s"`, rgb)
+}
+
+// svgColour returns the colour as '#rrggbb' and the opacity (empty if the colour is opaque)
+func svgColour(c color.Color) (string, string) {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	rgb := fmt.Sprintf("#%02x%02x%02x", nrgba.R, nrgba.G, nrgba.B)
+	if nrgba.A == 0xFF {
+		return rgb, ""
+	}
+	return rgb, fmt.Sprintf("%.2f", float64(nrgba.A)/0xFF)
+}
@@ -0,0 +1,146 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// renderSVG renders the position and returns the elements of the SVG
+func renderSVG(t *testing.T, posn position.Position, opts Options) []xml.StartElement {
+	var buf bytes.Buffer
+	if err := SVG(&buf, posn, opts); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var elements []xml.StartElement
+	dec := xml.NewDecoder(&buf)
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			return elements
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %s", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			elements = append(elements, start.Copy())
+		}
+	}
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// find returns the elements with the given name and class
+func find(elements []xml.StartElement, name, class string) []xml.StartElement {
+	var found []xml.StartElement
+	for _, e := range elements {
+		if e.Name.Local == name && attr(e, "class") == class {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+// xy returns the x and y attributes of the element
+func xy(e xml.StartElement) string {
+	return attr(e, "x") + "," + attr(e, "y")
+}
+
+func TestSVG(t *testing.T) {
+	elements := renderSVG(t, position.StartPosition(), Options{})
+	if svg := elements[0]; svg.Name.Local != "svg" || attr(svg, "width") != fmt.Sprint(DefaultSize) {
+		t.Errorf("unexpected root element %v", svg)
+	}
+	if n := len(find(elements, "rect", "square")); n != 64 {
+		t.Errorf("expected 64 squares but got %d", n)
+	}
+	if n := len(find(elements, "g", "piece white pawn")); n != 8 {
+		t.Errorf("expected 8 white pawns but got %d", n)
+	}
+	if n := len(find(elements, "g", "piece black king")); n != 1 {
+		t.Errorf("expected 1 black king but got %d", n)
+	}
+	kings := find(elements, "g", "piece white king")
+	if len(kings) != 1 || attr(kings[0], "transform") != "translate(400,700)" {
+		t.Errorf("white king not on e1: %v", kings)
+	}
+	if n := len(find(elements, "text", "")); n != 0 {
+		t.Errorf("expected no coordinates but got %d", n)
+	}
+}
+
+func TestSVGOrientation(t *testing.T) {
+	posn := position.StartPosition()
+	m := move.New(colour.White, square.E2, square.E4, piece.PAWN)
+	posn.MakeMove(m)
+	for _, d := range []struct {
+		orientation colour.Colour
+		e2, e4      string
+	}{
+		{colour.White, "400,600", "400,400"},
+		{colour.Black, "300,100", "300,300"},
+	} {
+		elements := renderSVG(t, posn, Options{Size: 200, Orientation: d.orientation, Coordinates: true, LastMove: &m})
+		highlights := find(elements, "rect", "highlight")
+		if len(highlights) != 2 || xy(highlights[0]) != d.e2 || xy(highlights[1]) != d.e4 {
+			t.Errorf("orientation %s: unexpected highlights %v", d.orientation.String(), highlights)
+		}
+		texts := find(elements, "text", "")
+		if len(texts) != 16 {
+			t.Fatalf("orientation %s: expected 16 coordinates but got %d", d.orientation.String(), len(texts))
+		}
+	}
+}
+
+func TestSVGDecorations(t *testing.T) {
+	posn := position.StartPosition()
+	attacks := posn.Attacks(square.F3, colour.White) // knight on g1 and pawns on e2, g2
+	elements := renderSVG(t, posn, Options{
+		Highlights: []square.Square{square.A1},
+		Arrows:     []Arrow{{From: square.G1, To: square.F3}, {From: square.D1, To: square.H5, Colour: OverlayColour}},
+		Overlays:   []Overlay{{Squares: attacks}, {Squares: bitset.NewFromSquares(square.D4, square.D5)}},
+	})
+	if highlights := find(elements, "rect", "highlight"); len(highlights) != 1 || xy(highlights[0]) != "0,700" {
+		t.Errorf("unexpected highlights %v", highlights)
+	}
+	if n := len(find(elements, "rect", "overlay")); n != attacks.Cardinality()+2 {
+		t.Errorf("expected %d overlay squares but got %d", attacks.Cardinality()+2, n)
+	}
+	arrows := find(elements, "polygon", "arrow")
+	if len(arrows) != 2 {
+		t.Fatalf("expected 2 arrows but got %d", len(arrows))
+	}
+	if attr(arrows[0], "fill") != "#15781b" || attr(arrows[1], "fill") != "#d03030" {
+		t.Errorf("unexpected arrow colours %v", arrows)
+	}
+}
+
+func TestSVGErrors(t *testing.T) {
+	for _, opts := range []Options{
+		{Size: -1},
+		{Orientation: colour.AnyColour},
+		{Highlights: []square.Square{0}},
+		{Arrows: []Arrow{{From: square.E2, To: square.E2}}},
+		{Arrows: []Arrow{{From: square.E2}}},
+	} {
+		if err := SVG(io.Discard, position.StartPosition(), opts); err == nil {
+			t.Errorf("expected error for options %v", opts)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// unicodeGlyphs are the chess symbols, indexed by colour and piece type.
+// With ANSI colours the filled symbols (of the black pieces) are used for both colours.
+var unicodeGlyphs = [][]string{
+	{"♙", "♖", "♘", "♗", "♕", "♔"},
+	{"♟", "♜", "♞", "♝", "♛", "♚"},
+}
+
+const ansiReset = "\x1b[0m"
+
+// space between the boards of a text diagram
+const textPanelGap = "   "
+
+// textCell is the content of one square of a text diagram
+type textCell struct {
+	str        string      // a single character
+	foreground color.Color // ANSI only, nil for the default colour
+	background color.Color // ANSI only
+	marked     bool        // without ANSI, marked squares are shown in brackets
+}
+
+// Text writes a diagram of the position as text, e.g. for a terminal. Each square takes three characters.
+// Each bitset is drawn as a further board to the right of the position, with 'x' marking the squares in the bitset;
+// this is useful e.g. for debugging move generation.
+// Without ANSI colours, highlighted squares are shown in brackets and empty squares of overlays as '*'.
+func Text(w io.Writer, posn position.Position, opts Options, bitsets ...bitset.BitSet) error {
+	if _, err := opts.validate(); err != nil {
+		return err
+	}
+	theme := opts.theme()
+	highlighted := bitset.NewFromSquares(opts.highlights()...)
+
+	panels := [][]string{textPanel(opts, func(sq square.Square) textCell {
+		cell := textCell{str: ".", background: theme.squareColour(sq), marked: highlighted.IsSet(uint(sq))}
+		if cell.marked {
+			cell.background = blend(cell.background, theme.Highlight)
+		}
+		for _, overlay := range opts.Overlays {
+			if overlay.Squares.IsSet(uint(sq)) {
+				cell.background = blend(cell.background, colourOrDefault(overlay.Colour, OverlayColour))
+				cell.str = "*"
+			}
+		}
+		if opts.ANSI {
+			cell.str = " "
+		}
+		if pieceType, col, ok := posn.PieceOn(sq); ok {
+			cell.str = pieceType.String(col)
+			if opts.Unicode {
+				cell.str = unicodeGlyphs[col][pieceType]
+				if opts.ANSI {
+					cell.str = unicodeGlyphs[colour.Black][pieceType]
+				}
+			}
+			cell.foreground = color.Black
+			if col == colour.White {
+				cell.foreground = color.White
+			}
+		}
+		return cell
+	})}
+	for _, bs := range bitsets {
+		bs := bs
+		panels = append(panels, textPanel(opts, func(sq square.Square) textCell {
+			if bs.IsSet(uint(sq)) {
+				return textCell{str: "x", foreground: color.Black, background: blend(theme.squareColour(sq), OverlayColour)}
+			}
+			if opts.ANSI {
+				return textCell{str: " ", background: theme.squareColour(sq)}
+			}
+			return textCell{str: "."}
+		}))
+	}
+
+	var sb strings.Builder
+	for i := range panels[0] {
+		for j, panel := range panels {
+			if j > 0 {
+				sb.WriteString(textPanelGap)
+			}
+			sb.WriteString(panel[i])
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// textPanel returns the lines of one board, the content of the squares is given by the function cell
+func textPanel(opts Options, cell func(sq square.Square) textCell) []string {
+	lines := make([]string, 0, 9)
+	for row := 0; row < 8; row++ {
+		rank := 8 - row
+		if opts.Orientation == colour.Black {
+			rank = row + 1
+		}
+		var sb strings.Builder
+		if opts.Coordinates {
+			fmt.Fprintf(&sb, "%d ", rank)
+		}
+		for col := 0; col < 8; col++ {
+			file := col + 1
+			if opts.Orientation == colour.Black {
+				file = 8 - col
+			}
+			c := cell(square.FromRankAndFile(rank, file))
+			switch {
+			case opts.ANSI:
+				sb.WriteString(ansiColour(48, c.background))
+				if c.foreground != nil {
+					sb.WriteString(ansiColour(38, c.foreground))
+				}
+				sb.WriteString(" " + c.str + " " + ansiReset)
+			case c.marked:
+				sb.WriteString("[" + c.str + "]")
+			default:
+				sb.WriteString(" " + c.str + " ")
+			}
+		}
+		lines = append(lines, sb.String())
+	}
+	if opts.Coordinates {
+		var sb strings.Builder
+		sb.WriteString("  ")
+		for col := 0; col < 8; col++ {
+			file := 'a' + col
+			if opts.Orientation == colour.Black {
+				file = 'h' - col
+			}
+			fmt.Fprintf(&sb, " %c ", file)
+		}
+		lines = append(lines, sb.String())
+	}
+	return lines
+}
+
+// ansiColour returns the escape sequence to set the foreground (code 38) or background (code 48) colour
+func ansiColour(code int, c color.Color) string {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", code, nrgba.R, nrgba.G, nrgba.B)
+}
+
+// blend returns the colour resulting from drawing the (translucent) colour over the opaque base colour
+func blend(base, over color.Color) color.Color {
+	b := color.NRGBAModel.Convert(base).(color.NRGBA)
+	o := color.NRGBAModel.Convert(over).(color.NRGBA)
+	mix := func(bv, ov uint8) uint8 {
+		return uint8((uint32(ov)*uint32(o.A) + uint32(bv)*(0xFF-uint32(o.A))) / 0xFF)
+	}
+	return color.NRGBA{mix(b.R, o.R), mix(b.G, o.G), mix(b.B, o.B), 0xFF}
+}
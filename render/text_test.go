@@ -0,0 +1,102 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rjo67/chess/bitset"
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+func renderText(t *testing.T, posn position.Position, opts Options, bitsets ...bitset.BitSet) string {
+	var sb strings.Builder
+	if err := Text(&sb, posn, opts, bitsets...); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return sb.String()
+}
+
+func TestText(t *testing.T) {
+	posn, _ := position.ParseFen("4k3/8/8/8/8/8/4P3/4K2R w K - 0 1")
+	m := move.New(colour.White, square.E2, square.E4, piece.PAWN)
+	posn.MakeMove(m)
+
+	expected := ` .  .  .  .  k  .  .  . 
+ .  .  .  .  .  .  .  . 
+ .  .  .  .  .  .  .  . 
+ .  .  .  .  .  .  .  . 
+ .  .  .  .  P  .  .  . 
+ .  .  .  .  .  .  .  . 
+ .  .  .  .  .  .  .  . 
+ .  .  .  .  K  .  .  R 
+`
+	if got := renderText(t, posn, Options{}); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+
+	expected = `1  ♖  .  . [♔] .  .  .  . 
+2  .  .  .  .  .  .  .  . 
+3  .  .  .  .  .  .  .  . 
+4  .  .  . [♙] .  .  .  . 
+5  .  .  .  .  .  .  .  . 
+6  .  .  .  .  .  .  .  . 
+7  .  .  .  .  .  .  .  . 
+8  .  .  .  ♚  .  .  .  . 
+   h  g  f  e  d  c  b  a 
+`
+	opts := Options{Orientation: colour.Black, Coordinates: true, Unicode: true, Highlights: []square.Square{square.E1, square.E4}}
+	if got := renderText(t, posn, opts); got != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, got)
+	}
+}
+
+func TestTextBitSets(t *testing.T) {
+	posn := position.StartPosition()
+	knight := bitset.NewFromSquares(square.A3, square.C3)
+	got := renderText(t, posn, Options{Coordinates: true, Overlays: []Overlay{{Squares: knight}}}, knight, bitset.New(0))
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 9 {
+		t.Fatalf("expected 9 lines but got %d:\n%s", len(lines), got)
+	}
+	panel := "3  .  .  .  .  .  .  .  . "
+	expected := "3  *  .  *  .  .  .  .  . " + textPanelGap + "3  x  .  x  .  .  .  .  . " + textPanelGap + panel
+	if lines[5] != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, lines[5])
+	}
+}
+
+func TestTextANSI(t *testing.T) {
+	m := move.New(colour.White, square.E2, square.E4, piece.PAWN)
+	got := renderText(t, position.StartPosition(), Options{ANSI: true, Unicode: true, LastMove: &m}, bitset.New(0))
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("expected 8 lines but got %d", len(lines))
+	}
+	// 8 squares on each board, each ending with a reset
+	if n := strings.Count(lines[0], ansiReset); n != 16 {
+		t.Errorf("expected 16 squares but got %d", n)
+	}
+	// a8 is a light square with a black rook, shown as filled symbol
+	if !strings.HasPrefix(lines[0], ansiColour(48, BrownTheme.Light)+"\x1b[38;2;0;0;0m ♜ ") {
+		t.Errorf("unexpected first square %q", lines[0][:40])
+	}
+	// white pieces also use the filled symbols
+	if !strings.Contains(lines[7], "\x1b[38;2;255;255;255m ♜ ") {
+		t.Errorf("expected white rook in %q", lines[7])
+	}
+	// e2 is highlighted
+	if !strings.Contains(lines[6], ansiColour(48, blend(BrownTheme.Light, BrownTheme.Highlight))) {
+		t.Errorf("expected highlighted square in %q", lines[6])
+	}
+}
+
+func TestTextErrors(t *testing.T) {
+	var sb strings.Builder
+	if err := Text(&sb, position.StartPosition(), Options{Orientation: colour.AnyColour}); err == nil {
+		t.Errorf("expected error for invalid orientation")
+	}
+}
@@ -0,0 +1,223 @@
+// Package search finds the best move in a position with a minimax search, either to a fixed depth or with iterative deepening
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// MateScore is the score of a position where the side to move has been checkmated, from the view of the other side.
+// Mates found further from the root have lower scores, i.e. a mate in n plies scores MateScore-n.
+const MateScore = 100000
+
+// PieceValues are the material values of the pieces in centipawns
+var PieceValues = map[piece.Piece]int{
+	piece.PAWN:   100,
+	piece.KNIGHT: 320,
+	piece.BISHOP: 330,
+	piece.ROOK:   500,
+	piece.QUEEN:  900,
+	piece.KING:   0,
+}
+
+// MaxDepth is the maximum depth searched by Iterate
+const MaxDepth = 64
+
+// the search checks whether it has been cancelled every time this number of nodes has been searched
+const cancelCheckInterval = 1024
+
+// Result of a search
+type Result struct {
+	Move  move.Move   // the best move
+	Score int         // score in centipawns from the view of the side to move, see MateScore
+	PV    []move.Move // principal variation, starting with Move
+	Depth int         // depth of the search in plies
+	Nodes int         // number of positions searched
+}
+
+// IsMate returns true if the score means that one side can force mate
+func (r Result) IsMate() bool {
+	return r.Score > MateScore-1000 || r.Score < -MateScore+1000
+}
+
+// MateIn returns the number of moves until mate (negative if the side to move is mated), or 0 if IsMate is false
+func (r Result) MateIn() int {
+	switch {
+	case !r.IsMate():
+		return 0
+	case r.Score > 0:
+		return (MateScore - r.Score + 1) / 2
+	default:
+		return -(MateScore + r.Score + 1) / 2
+	}
+}
+
+// Search searches the position to the given depth (in plies, at least 1) using minimax with alpha-beta pruning
+// and returns the best move for the side to move. There is no quiescence search.
+// The moves are made on the position and unmade again, so the position (and any copies of it) must not be used concurrently.
+// Returns an error if the game is already over, i.e. the side to move has no legal moves.
+func Search(posn position.Position, depth int) (Result, error) {
+	if depth < 1 {
+		return Result{}, fmt.Errorf("invalid depth %d", depth)
+	}
+	s := searcher{posn: &posn, ctx: context.Background()}
+	return s.search(depth)
+}
+
+// Iterate searches the position with increasing depth until maxDepth (or MaxDepth if 0) has been searched, the game is
+// decided, or the context is done. The function report (if not nil) is called with the result of each completed iteration.
+// Returns the result of the last completed iteration; at least depth 1 is always searched, even if the context is already done.
+// As with Search, the position must not be used concurrently.
+func Iterate(ctx context.Context, posn position.Position, maxDepth int, report func(Result)) (Result, error) {
+	if maxDepth <= 0 || maxDepth > MaxDepth {
+		maxDepth = MaxDepth
+	}
+	s := searcher{posn: &posn, ctx: context.Background()}
+	best, err := s.search(1)
+	if err != nil {
+		return Result{}, err
+	}
+	if report != nil {
+		report(best)
+	}
+	s.ctx = ctx
+	for depth := 2; depth <= maxDepth && !best.IsMate() && ctx.Err() == nil; depth++ {
+		result, _ := s.search(depth)
+		if s.cancelled {
+			break
+		}
+		best = result
+		if report != nil {
+			report(best)
+		}
+	}
+	return best, nil
+}
+
+type searcher struct {
+	posn      *position.Position
+	ctx       context.Context
+	cancelled bool // set if the context was done during the search
+	nodes     int
+}
+
+// search searches to the given depth. The result is not valid if the search was cancelled
+func (s *searcher) search(depth int) (Result, error) {
+	score, pv := s.negamax(depth, 0, -MateScore-1, MateScore+1)
+	if len(pv) == 0 && !s.cancelled {
+		return Result{}, fmt.Errorf("no legal moves")
+	}
+	result := Result{Score: score, PV: pv, Depth: depth, Nodes: s.nodes}
+	if len(pv) > 0 {
+		result.Move = pv[0]
+	}
+	return result, nil
+}
+
+// negamax returns the score of the position from the view of the side to move, and the principal variation
+func (s *searcher) negamax(depth, ply, alpha, beta int) (int, []move.Move) {
+	s.nodes++
+	if s.nodes%cancelCheckInterval == 0 && s.ctx.Err() != nil {
+		s.cancelled = true
+	}
+	if s.cancelled {
+		return 0, nil
+	}
+	posn := s.posn
+	moves := posn.FindMoves(posn.ActiveColour())
+	if len(moves) == 0 {
+		if posn.InCheck() {
+			return -MateScore + ply, nil
+		}
+		return 0, nil
+	}
+	if ply > 0 && (posn.HalfmoveClock() >= 100 || posn.IsRepetition(2) || posn.IsInsufficientMaterial()) {
+		return 0, nil
+	}
+	if depth == 0 {
+		return Evaluate(*posn), nil
+	}
+
+	orderMoves(moves)
+	var bestPV []move.Move
+	for _, m := range moves {
+		posn.MakeMove(m)
+		score, pv := s.negamax(depth-1, ply+1, -beta, -alpha)
+		posn.UnmakeMove(m)
+		score = -score
+		if score > alpha || bestPV == nil {
+			bestPV = append([]move.Move{m}, pv...)
+		}
+		if score > alpha {
+			alpha = score
+			if alpha >= beta {
+				break
+			}
+		}
+	}
+	return alpha, bestPV
+}
+
+// orderMoves sorts the moves so that captures of valuable pieces by less valuable pieces and promotions are searched first
+func orderMoves(moves []move.Move) {
+	priority := func(m move.Move) int {
+		p := 0
+		if m.IsCapture() {
+			p += 10*PieceValues[m.CapturedPiece()] - PieceValues[m.PieceType()] + 1
+		}
+		if m.IsPromotion() {
+			p += PieceValues[m.PromotedPiece()]
+		}
+		return p
+	}
+	sort.SliceStable(moves, func(i, j int) bool { return priority(moves[i]) > priority(moves[j]) })
+}
+
+// Evaluate returns the static evaluation of the position in centipawns from the view of the side to move:
+// the material balance, plus small bonuses for advanced pawns and for knights and bishops near the centre
+func Evaluate(posn position.Position) int {
+	score := 0
+	for _, col := range colour.AllColours {
+		sign := 1
+		if col != posn.ActiveColour() {
+			sign = -1
+		}
+		for _, pieceType := range piece.AllPieces {
+			for _, sq := range posn.Pieces(col, pieceType).SetBits() {
+				score += sign * (PieceValues[pieceType] + positionalBonus(pieceType, col, square.Square(sq)))
+			}
+		}
+	}
+	return score
+}
+
+// positionalBonus returns the bonus for a piece on the given square
+func positionalBonus(pieceType piece.Piece, col colour.Colour, sq square.Square) int {
+	switch pieceType {
+	case piece.PAWN:
+		advance := sq.Rank() - 2
+		if col == colour.Black {
+			advance = 7 - sq.Rank()
+		}
+		return 5 * advance
+	case piece.KNIGHT, piece.BISHOP:
+		// distance from the centre, 0 for the squares d4, d5, e4, e5
+		distance := abs(2*sq.File()-9)/2 + abs(2*sq.Rank()-9)/2
+		return 15 - 5*distance
+	}
+	return 0
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rjo67/chess/position"
+)
+
+func TestSearch(t *testing.T) {
+	data := []struct {
+		fen      string
+		depth    int
+		expected string // best move in UCI notation, or with prefix '!' a move which must not be played
+		score    int    // expected score, only checked for mates
+	}{
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 1, "a1a8", MateScore - 1}, // back rank mate
+		{"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 3, "a1a8", MateScore - 1}, // shortest mate is preferred
+		{"r1bqkb1r/pppp1ppp/2n2n2/4p2Q/2B1P3/8/PPPP1PPP/RNB1K1NR w KQkq - 4 4", 1, "h5f7", MateScore - 1},
+		{"k7/8/1K6/8/8/8/8/7R w - - 0 1", 3, "h1h8", MateScore - 1},
+		{"4k3/8/8/3q4/8/8/8/3RK3 w - - 0 1", 1, "d1d5", 0},           // hanging queen
+		{"4k3/8/4p3/3r4/8/8/8/3QK3 w - - 0 1", 2, "!d1d5", 0},        // Qxd5 loses the queen
+		{"k7/8/1K6/8/8/8/8/2Q5 w - - 0 1", 1, "c1c8", MateScore - 1}, // not Qc7 stalemate
+	}
+	for _, d := range data {
+		posn, err := position.ParseFen(d.fen)
+		if err != nil {
+			t.Fatalf("error parsing fen '%s': %s", d.fen, err)
+		}
+		result, err := Search(posn, d.depth)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", d.fen, err)
+		}
+		if got := result.Move.UciString(); got != d.expected && (d.expected[0] != '!' || got == d.expected[1:]) {
+			t.Errorf("%s depth %d: expected %s but got %s (score %d)", d.fen, d.depth, d.expected, got, result.Score)
+		}
+		if d.score != 0 && result.Score != d.score {
+			t.Errorf("%s depth %d: expected score %d but got %d", d.fen, d.depth, d.score, result.Score)
+		}
+		if posn.Fen() != d.fen {
+			t.Errorf("position changed to %s", posn.Fen())
+		}
+		if len(result.PV) == 0 || result.PV[0].UciString() != result.Move.UciString() || result.Nodes == 0 {
+			t.Errorf("%s: unexpected result %v", d.fen, result)
+		}
+	}
+}
+
+func TestSearchErrors(t *testing.T) {
+	mated, _ := position.ParseFen("k7/1Q6/1K6/8/8/8/8/8 b - - 0 1")
+	stalemate, _ := position.ParseFen("k7/8/1Q6/8/8/8/8/7K b - - 0 1")
+	for _, posn := range []position.Position{mated, stalemate} {
+		if _, err := Search(posn, 2); err == nil {
+			t.Errorf("%s: expected error", posn.Fen())
+		}
+	}
+	if _, err := Search(position.StartPosition(), 0); err == nil {
+		t.Errorf("expected error for depth 0")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	if score := Evaluate(position.StartPosition()); score != 0 {
+		t.Errorf("expected 0 for the start position but got %d", score)
+	}
+	posn, _ := position.ParseFen("rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBN1 b Qkq - 0 1")
+	mirrored := posn.Mirror()
+	if Evaluate(posn) != Evaluate(mirrored) || Evaluate(posn) < 400 {
+		t.Errorf("expected the same score, about a rook up, for both sides but got %d and %d", Evaluate(posn), Evaluate(mirrored))
+	}
+}
+
+func TestIterate(t *testing.T) {
+	posn, _ := position.ParseFen("k7/8/1K6/8/8/8/8/7R w - - 0 1")
+	var depths []int
+	result, err := Iterate(context.Background(), posn, 0, func(r Result) { depths = append(depths, r.Depth) })
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the search stops as soon as the mate has been found
+	if result.Move.UciString() != "h1h8" || result.MateIn() != 1 || !reflect.DeepEqual(depths, []int{1}) {
+		t.Errorf("unexpected result %v after depths %v", result, depths)
+	}
+
+	depths = nil
+	result, err = Iterate(context.Background(), position.StartPosition(), 3, func(r Result) { depths = append(depths, r.Depth) })
+	if err != nil || result.Depth != 3 || !reflect.DeepEqual(depths, []int{1, 2, 3}) {
+		t.Errorf("unexpected result %v, depths %v, error %v", result, depths, err)
+	}
+
+	// depth 1 is searched even if the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result, err := Iterate(ctx, position.StartPosition(), 0, nil); err != nil || result.Depth != 1 {
+		t.Errorf("unexpected result %v, error %v", result, err)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err = Iterate(ctx, position.StartPosition(), 0, nil)
+	if err != nil || result.Depth < 1 || result.Depth >= MaxDepth {
+		t.Errorf("unexpected result %v, error %v", result, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("search not stopped after %s", elapsed)
+	}
+	if posn := position.StartPosition(); posn.Fen() != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1" {
+		t.Errorf("position changed")
+	}
+}
+
+func TestMateIn(t *testing.T) {
+	for _, d := range []struct{ score, mateIn int }{
+		{MateScore - 1, 1}, {MateScore - 3, 2}, {-MateScore + 2, -1}, {-MateScore + 4, -2}, {250, 0}, {-250, 0},
+	} {
+		if got := (Result{Score: d.score}).MateIn(); got != d.mateIn {
+			t.Errorf("score %d: expected %d but got %d", d.score, d.mateIn, got)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package square
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MarshalJSON implements json.Marshaler, the square is given in lowercase algebraic notation, e.g. "e4"
+func (sq Square) MarshalJSON() ([]byte, error) {
+	if sq < H1 || sq > A8 {
+		return nil, fmt.Errorf("cannot marshal square %d", sq)
+	}
+	return json.Marshal(strings.ToLower(sq.String()))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting only lowercase algebraic notation
+func (sq *Square) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("square must be a string: %w", err)
+	}
+	if str != strings.ToLower(str) {
+		return fmt.Errorf("unrecognised square '%s'", str)
+	}
+	s, err := FromString(str)
+	if err != nil {
+		return err
+	}
+	*sq = s
+	return nil
+}
@@ -118,6 +118,16 @@ func (sq Square) File() int {
 	return 8 - (int(sq)-1)%8
 }
 
+// Mirror returns the square mirrored about the horizontal axis between the 4th and 5th ranks, e.g. A1 becomes A8
+func (sq Square) Mirror() Square {
+	return FromRankAndFile(9-sq.Rank(), sq.File())
+}
+
+// FlipFile returns the square mirrored about the vertical axis between the d- and e-files, e.g. A1 becomes H1
+func (sq Square) FlipFile() Square {
+	return FromRankAndFile(sq.Rank(), 9-sq.File())
+}
+
 // IsAdjacentTo returns true when the given squares are adjacent to each other
 func (sq Square) IsAdjacentTo(otherSq Square) bool {
 	if math.Abs(float64(sq.File()-otherSq.File())) > 1 {
@@ -1,6 +1,8 @@
 package square
 
 import (
+	"encoding/json"
+	"reflect"
 	"testing"
 )
 
@@ -131,3 +133,42 @@ func TestFromString(t *testing.T) {
 		}
 	}
 }
+
+func TestMirror(t *testing.T) {
+	data := []struct {
+		sq, mirrored, flipped Square
+	}{
+		{A1, A8, H1},
+		{H1, H8, A1},
+		{E2, E7, D2},
+		{C6, C3, F6},
+	}
+	for _, d := range data {
+		if got := d.sq.Mirror(); got != d.mirrored {
+			t.Errorf("Mirror(%s): expected %s but got %s", d.sq.String(), d.mirrored.String(), got.String())
+		}
+		if got := d.sq.FlipFile(); got != d.flipped {
+			t.Errorf("FlipFile(%s): expected %s but got %s", d.sq.String(), d.flipped.String(), got.String())
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	got, err := json.Marshal([]Square{E4, A8})
+	if err != nil || string(got) != `["e4","a8"]` {
+		t.Errorf("unexpected result %s, error %v", got, err)
+	}
+	var squares []Square
+	if err := json.Unmarshal([]byte(`["h1","c7"]`), &squares); err != nil || !reflect.DeepEqual(squares, []Square{H1, C7}) {
+		t.Errorf("unexpected result %v, error %v", squares, err)
+	}
+	if _, err := json.Marshal(Square(0)); err == nil {
+		t.Errorf("expected error for square 0")
+	}
+	for _, str := range []string{`"E4"`, `"i1"`, `"e9"`, `4`, `null`} {
+		var sq Square
+		if err := json.Unmarshal([]byte(str), &sq); err == nil {
+			t.Errorf("expected error for %s", str)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// values stores the compressed values of a part, or a single value if all values are the same.
+//
+// The values are compressed in two steps: first, pairs of adjacent symbols which occur frequently are
+// repeatedly replaced by a new symbol, so that each symbol stands for a sequence of one or more values.
+// The symbols are then stored in blocks of a fixed size using a canonical Huffman code.
+// An index stores the block and the offset within the block of every 2^indexBits-th value.
+type values struct {
+	single bool
+	value  int // if single
+
+	blockBits   uint     // log2 of the block size in bytes
+	indexBits   uint     // log2 of the number of values between two index entries
+	blocks      int      // number of blocks containing data
+	blockCounts int      // number of entries in 'counts', may be greater than 'blocks'
+	indexSize   int      // number of entries in 'index'
+	minLen      int      // length of the shortest code in bits
+	firstSymbol []byte   // for each code length (from minLen): the symbol of the smallest code, uint16
+	lowestCode  []uint64 // for each code length (from minLen): the smallest code, left aligned to 64 bits
+	pairs       []byte   // for each symbol 3 bytes, containing two 12 bit symbols: the pair of symbols or the value of a leaf
+	lengths     []int    // for each symbol the number of values it stands for
+	index       []byte   // 6 bytes per entry: block (uint32) and offset within the block (uint16)
+	counts      []byte   // for each block the number of values - 1, uint16
+	data        []byte   // the blocks; may continue past the last block
+}
+
+// a symbol is a leaf if its second symbol has this value, the first symbol is then the value
+const leafSymbol = 0xFFF
+
+// first returns the first symbol of the pair (or the value of a leaf)
+func (v *values) first(sym int) int {
+	return int(v.pairs[3*sym+1]&0xF)<<8 | int(v.pairs[3*sym])
+}
+
+// second returns the second symbol of the pair
+func (v *values) second(sym int) int {
+	return int(v.pairs[3*sym+2])<<4 | int(v.pairs[3*sym+1]>>4)
+}
+
+// readHeader reads the description of the compressed values for a part with the given number of positions.
+// The single value is read if the part's flags are given as 'single'.
+func (v *values) readHeader(r *reader, single bool, size uint64) error {
+	if single {
+		v.single = true
+		v.value = int(r.u8())
+		return nil
+	}
+	v.blockBits = uint(r.u8())
+	v.indexBits = uint(r.u8())
+	extraCounts := int(r.u8())
+	v.blocks = int(r.u32())
+	v.blockCounts = v.blocks + extraCounts
+	maxLen := int(r.u8())
+	v.minLen = int(r.u8())
+	if v.blockBits > 30 || v.indexBits == 0 || v.indexBits > 60 {
+		return fmt.Errorf("invalid block size or index distance (%d, %d)", v.blockBits, v.indexBits)
+	}
+	if v.minLen == 0 || maxLen < v.minLen || maxLen > 32 {
+		return fmt.Errorf("invalid code lengths %d, %d", v.minLen, maxLen)
+	}
+	v.indexSize = int((size + 1<<v.indexBits - 1) >> v.indexBits)
+	nbrLengths := maxLen - v.minLen + 1
+	v.firstSymbol = r.bytes(2 * nbrLengths)
+
+	// longer codes have smaller values: the codes of each length follow the codes of the next longer length
+	v.lowestCode = make([]uint64, nbrLengths)
+	for i := nbrLengths - 2; i >= 0; i-- {
+		nbrCodes := v.symbol(i) - v.symbol(i+1) // number of codes of length i+1+minLen
+		v.lowestCode[i] = (v.lowestCode[i+1] + uint64(nbrCodes)) / 2
+	}
+	for i := range v.lowestCode {
+		v.lowestCode[i] <<= uint(64 - v.minLen - i)
+	}
+
+	nbrSymbols := int(r.u16())
+	v.pairs = r.bytes(3 * nbrSymbols)
+	r.skip(nbrSymbols & 1) // padding to an even number of symbols
+	v.lengths = make([]int, nbrSymbols)
+	for sym := range v.lengths {
+		if _, err := v.length(sym); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// symbol returns the symbol of the smallest code with the given length (counted from minLen)
+func (v *values) symbol(length int) int {
+	return int(binary.LittleEndian.Uint16(v.firstSymbol[2*length:]))
+}
+
+// length calculates (once) the number of values for which the symbol stands
+func (v *values) length(sym int) (int, error) {
+	switch v.lengths[sym] {
+	case 0:
+	case -1:
+		return 0, fmt.Errorf("symbol %d refers to itself", sym)
+	default:
+		return v.lengths[sym], nil
+	}
+	if v.second(sym) == leafSymbol {
+		v.lengths[sym] = 1
+		return 1, nil
+	}
+	v.lengths[sym] = -1
+	n1, err := v.length(v.first(sym))
+	if err != nil {
+		return 0, err
+	}
+	n2, err := v.length(v.second(sym))
+	if err != nil {
+		return 0, err
+	}
+	v.lengths[sym] = n1 + n2
+	return n1 + n2, nil
+}
+
+// get returns the value stored at the given position
+func (v *values) get(idx uint64) int {
+	if v.single {
+		return v.value
+	}
+
+	// the index entry refers to the value in the middle between two entries
+	entry := 6 * int(idx>>v.indexBits)
+	block := int(binary.LittleEndian.Uint32(v.index[entry:]))
+	offset := int(binary.LittleEndian.Uint16(v.index[entry+4:]))
+	offset += int(idx&(1<<v.indexBits-1)) - 1<<(v.indexBits-1)
+	for offset < 0 {
+		block--
+		offset += v.count(block)
+	}
+	for offset >= v.count(block) {
+		offset -= v.count(block)
+		block++
+	}
+
+	// read the codes of the block until reaching the symbol which contains the value
+	pos := block << v.blockBits
+	bits := binary.BigEndian.Uint64(v.data[pos:])
+	pos += 8
+	available := 64
+	var sym int
+	for {
+		length := 0
+		for bits < v.lowestCode[length] {
+			length++
+		}
+		sym = v.symbol(length) + int((bits-v.lowestCode[length])>>uint(64-v.minLen-length))
+		if offset < v.lengths[sym] {
+			break
+		}
+		offset -= v.lengths[sym]
+		bits <<= uint(v.minLen + length)
+		available -= v.minLen + length
+		if available <= 32 {
+			bits |= uint64(binary.BigEndian.Uint32(v.data[pos:])) << uint(32-available)
+			available += 32
+			pos += 4
+		}
+	}
+
+	// find the value within the sequence of values of the symbol
+	for v.second(sym) != leafSymbol {
+		if first := v.first(sym); offset < v.lengths[first] {
+			sym = first
+		} else {
+			offset -= v.lengths[first]
+			sym = v.second(sym)
+		}
+	}
+	return v.first(sym)
+}
+
+// count returns the number of values stored in the block
+func (v *values) count(block int) int {
+	return int(binary.LittleEndian.Uint16(v.counts[2*block:])) + 1
+}
+
+// reader reads the little endian data of a table file. Reading past the end panics, which is recovered
+// by the callers and reported as a corrupt table.
+type reader struct {
+	data []byte
+	pos  int
+}
+
+func (r *reader) u8() byte {
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *reader) u16() uint16 {
+	return binary.LittleEndian.Uint16(r.bytes(2))
+}
+
+func (r *reader) u32() uint32 {
+	return binary.LittleEndian.Uint32(r.bytes(4))
+}
+
+// bytes returns the next n bytes
+func (r *reader) bytes(n int) []byte {
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *reader) skip(n int) {
+	r.bytes(n)
+}
+
+// align moves to the next multiple of n (a power of 2)
+func (r *reader) align(n int) {
+	r.skip(-r.pos & (n - 1))
+}
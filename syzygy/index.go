@@ -0,0 +1,220 @@
+package syzygy
+
+import "sort"
+
+// Calculation of the position of a placement in a table.
+//
+// NB: in this package squares are numbered as in the tablebase files, i.e. a1=0, b1=1, ..., h8=63
+// (in contrast to square.Square, where h1=1 and a8=64).
+//
+// The symmetries of the board are used to reduce the size of the tables: the pieces are placed so that
+// the first piece is on the queens side (files a-d), and without pawns also in the a1-d1-d4 triangle.
+// The pieces are then numbered group by group, see part.
+
+// maxPieces is the maximum number of pieces supported by the tablebase format
+const maxPieces = 7
+
+func rank(sq int) int         { return sq >> 3 }
+func file(sq int) int         { return sq & 7 }
+func mirrorFile(sq int) int   { return sq ^ 7 }
+func mirrorRank(sq int) int   { return sq ^ 56 }
+func transpose(sq int) int    { return (sq>>3 | sq<<3) & 63 } // mirrored on the a1-h8 diagonal
+func diagonalDist(sq int) int { return rank(sq) - file(sq) }  // > 0 above the a1-h8 diagonal, < 0 below it
+
+// the leading group of pieces which do not include pawns
+const (
+	kingPairs         = 462   // number of placements of two kings, the first in the a1-d1-d4 triangle
+	uniqueTriples     = 31332 // number of placements of three unique pieces, the first in the a1-d1-d4 triangle
+	triangleOffDiag   = 6     // squares of the a1-d1-d4 triangle below the diagonal
+	belowDiagonalSize = 28    // squares below the a1-h8 diagonal
+)
+
+// the squares of the a1-d1-d4 triangle, first those below the diagonal
+var triangle = [10]int{1, 2, 3, 10, 11, 19, 0, 9, 18, 27}
+
+var (
+	triangleIndex [64]int               // position of the square in 'triangle', -1 if not in the triangle
+	belowDiagonal [64]int               // the squares below the a1-h8 diagonal numbered 0..27, otherwise -1
+	kingPair      [10][64]int           // number of the placement of two kings, by triangle index of the first king
+	choose        [maxPieces][64]uint64 // choose[k][n]: number of ways to choose k of n elements
+	pawnOrder     [64]int               // a2=47, h2=46, a3=45, ..., b2=35 etc.: the leading pawn has the highest value
+	pawnStart     [maxPieces][64]uint64 // start of the numbering of n leading pawns, by square of the first one
+	pawnCount     [maxPieces][4]uint64  // number of placements of n leading pawns, by file of the first one
+)
+
+func init() {
+	for sq := range triangleIndex {
+		triangleIndex[sq] = -1
+	}
+	for i, sq := range triangle {
+		triangleIndex[sq] = i
+	}
+	n := 0
+	for sq := range belowDiagonal {
+		belowDiagonal[sq] = -1
+		if diagonalDist(sq) < 0 {
+			belowDiagonal[sq] = n
+			n++
+		}
+	}
+
+	for n := 0; n < 64; n++ {
+		choose[0][n] = 1
+		for k := 1; k < maxPieces && k <= n; k++ {
+			choose[k][n] = choose[k-1][n-1] + choose[k][n-1]
+		}
+	}
+
+	// kings on adjacent squares are impossible. If the first king is on the diagonal, the other one is not above it.
+	// The placements with both kings on the diagonal come last
+	n = 0
+	var onDiagonal [][2]int
+	for i, k1 := range triangle {
+		for k2 := 0; k2 < 64; k2++ {
+			if abs(rank(k1)-rank(k2)) <= 1 && abs(file(k1)-file(k2)) <= 1 {
+				continue
+			}
+			if diagonalDist(k1) == 0 {
+				if diagonalDist(k2) > 0 {
+					continue
+				}
+				if diagonalDist(k2) == 0 {
+					onDiagonal = append(onDiagonal, [2]int{i, k2})
+					continue
+				}
+			}
+			kingPair[i][k2] = n
+			n++
+		}
+	}
+	for _, kk := range onDiagonal {
+		kingPair[kk[0]][kk[1]] = n
+		n++
+	}
+
+	// pawns nearer to the a- or h-file and to the 2nd rank come first
+	n = 47
+	for f := 0; f < 4; f++ {
+		for r := 1; r <= 6; r++ {
+			pawnOrder[8*r+f] = n
+			pawnOrder[8*r+7-f] = n - 1
+			n -= 2
+		}
+	}
+	// the other n-1 leading pawns are on squares with a lower pawnOrder than the first one
+	for n := 1; n < maxPieces; n++ {
+		for f := 0; f < 4; f++ {
+			var start uint64
+			for r := 1; r <= 6; r++ {
+				pawnStart[n][8*r+f] = start
+				start += choose[n-1][pawnOrder[8*r+f]]
+			}
+			pawnCount[n][f] = start
+		}
+	}
+}
+
+// uniqueTriple returns the number of the placement of three unique pieces.
+// The first piece must be in the a1-d1-d4 triangle, and the first piece which is not on the a1-h8 diagonal
+// must be below it. The placements are numbered depending on which piece is the first off the diagonal.
+func uniqueTriple(a, b, c int) uint64 {
+	// squares of b and c, not counting the squares occupied by the previous pieces
+	bFree := b - b2i(b > a)
+	cFree := c - b2i(c > a) - b2i(c > b)
+	const (
+		start2 = triangleOffDiag * 63 * 62
+		start3 = start2 + 4*belowDiagonalSize*62
+		start4 = start3 + 4*7*belowDiagonalSize
+	)
+	switch {
+	case diagonalDist(a) != 0:
+		return uint64((triangleIndex[a]*63+bFree)*62 + cFree)
+	case diagonalDist(b) != 0:
+		return uint64(start2 + (rank(a)*belowDiagonalSize+belowDiagonal[b])*62 + cFree)
+	case diagonalDist(c) != 0:
+		return uint64(start3 + (rank(a)*7+rank(b)-b2i(b > a))*belowDiagonalSize + belowDiagonal[c])
+	}
+	// all on the diagonal: the rank determines the square
+	return uint64(start4 + (rank(a)*7+rank(b)-b2i(b > a))*6 + rank(c) - b2i(c > a) - b2i(c > b))
+}
+
+// index returns the position in the part of the given placement. 'squares' are the squares of the pieces
+// in the order of part.order; the slice is modified.
+func (p *part) index(squares []int) uint64 {
+	lead := p.groups[0]
+	if file(squares[0]) > 3 {
+		for i := range squares {
+			squares[i] = mirrorFile(squares[i])
+		}
+	}
+
+	var idx uint64
+	if p.leader == leadPawns {
+		others := squares[1:lead]
+		sort.Slice(others, func(i, j int) bool { return pawnOrder[others[i]] < pawnOrder[others[j]] })
+		idx = pawnStart[lead][squares[0]]
+		for i, sq := range others {
+			idx += choose[i+1][pawnOrder[sq]]
+		}
+	} else {
+		if rank(squares[0]) > 3 {
+			for i := range squares {
+				squares[i] = mirrorRank(squares[i])
+			}
+		}
+		for _, sq := range squares[:lead] {
+			if d := diagonalDist(sq); d != 0 {
+				if d > 0 {
+					for i := range squares {
+						squares[i] = transpose(squares[i])
+					}
+				}
+				break
+			}
+		}
+		if p.leader == leadUnique {
+			idx = uniqueTriple(squares[0], squares[1], squares[2])
+		} else {
+			idx = uint64(kingPair[triangleIndex[squares[0]]][squares[1]])
+		}
+	}
+	idx *= p.factors[0]
+
+	// the squares of the other groups are numbered ignoring the squares of the previous groups
+	// (and the first rank for pawns)
+	start := lead
+	for g := 1; g < len(p.groups); g++ {
+		group := squares[start : start+p.groups[g]]
+		sort.Ints(group)
+		var n uint64
+		for i, sq := range group {
+			free := sq
+			for _, prev := range squares[:start] {
+				if prev < sq {
+					free--
+				}
+			}
+			if g == 1 && p.pawnGroup {
+				free -= 8
+			}
+			n += choose[i+1][free]
+		}
+		idx += n * p.factors[g]
+		start += p.groups[g]
+	}
+	return idx
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
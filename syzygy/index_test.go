@@ -0,0 +1,100 @@
+package syzygy
+
+import "testing"
+
+func TestKingPairs(t *testing.T) {
+	max := 0
+	seen := make(map[int]bool)
+	for i := range kingPair {
+		for sq := 0; sq < 64; sq++ {
+			if kingPair[i][sq] > max {
+				max = kingPair[i][sq]
+			}
+			seen[kingPair[i][sq]] = true
+		}
+	}
+	if max != kingPairs-1 || len(seen) != kingPairs {
+		t.Errorf("expected %d king positions but got max %d, %d different values", kingPairs, max, len(seen))
+	}
+}
+
+func TestChoose(t *testing.T) {
+	data := []struct {
+		k, n     int
+		expected uint64
+	}{
+		{0, 0, 1}, {1, 1, 1}, {2, 4, 6}, {3, 48, 17296}, {5, 63, 7028847}, {6, 20, 38760}, {4, 3, 0},
+	}
+	for _, d := range data {
+		if choose[d.k][d.n] != d.expected {
+			t.Errorf("choose(%d, %d): expected %d but got %d", d.k, d.n, d.expected, choose[d.k][d.n])
+		}
+	}
+}
+
+func TestPawnOrder(t *testing.T) {
+	// a2 is the 'best' leading pawn
+	if pawnOrder[8] != 47 || pawnOrder[15] != 46 || pawnOrder[16] != 45 || pawnOrder[9] != 35 {
+		t.Errorf("unexpected values for a2, h2, a3, b2: %d, %d, %d, %d", pawnOrder[8], pawnOrder[15], pawnOrder[16], pawnOrder[9])
+	}
+	if pawnCount[1][0] != 6 || pawnCount[2][0] != 47+45+43+41+39+37 {
+		t.Errorf("unexpected leading pawn counts: %d, %d", pawnCount[1][0], pawnCount[2][0])
+	}
+}
+
+// newTestPart returns a part of the table with the pieces and groups in the given order
+func newTestPart(t *testing.T, name string, pieces []int, orders [2]int, file int) *part {
+	t.Helper()
+	tbl, err := newTable(wdlTable, "", name)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p := &tbl.parts[0][file]
+	copy(p.order[:], pieces)
+	if err := p.init(tbl, orders, file); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return p
+}
+
+// every index of the part is used by at least one placement, and no placement gives an index outside the part
+func TestIndexRange(t *testing.T) {
+	data := []struct {
+		name   string
+		pieces []int
+		file   int
+	}{
+		{"KRvK", []int{wKing, wRook, bKing}, 0},
+		{"KvKN", []int{wKing, bKing, bKnight}, 0},
+		{"KPvK", []int{wPawn, wKing, bKing}, 0},
+		{"KPvK", []int{wPawn, wKing, bKing}, 2},
+	}
+	for _, d := range data {
+		p := newTestPart(t, d.name, d.pieces, [2]int{0, 1}, d.file)
+		seen := make(map[uint64]bool)
+		for s0 := 0; s0 < 64; s0++ {
+			if p.leader == leadPawns && (rank(s0) == 0 || rank(s0) == 7 || (file(s0) != d.file && file(s0) != 7-d.file)) {
+				continue
+			}
+			for s1 := 0; s1 < 64; s1++ {
+				for s2 := 0; s2 < 64; s2++ {
+					if s0 == s1 || s0 == s2 || s1 == s2 {
+						continue
+					}
+					// the kings may not touch if they are the leading group
+					if p.leader == leadKings && abs(rank(s0)-rank(s1)) <= 1 && abs(file(s0)-file(s1)) <= 1 {
+						continue
+					}
+					idx := p.index([]int{s0, s1, s2})
+					if idx >= p.size {
+						t.Fatalf("%s: index %d of %v out of range %d", d.name, idx, []int{s0, s1, s2}, p.size)
+					}
+					seen[idx] = true
+				}
+			}
+		}
+		if uint64(len(seen)) != p.size {
+			t.Errorf("%s, file %d: expected %d indexes but got %d", d.name, d.file, p.size, len(seen))
+		}
+	}
+}
@@ -0,0 +1,375 @@
+// Package syzygy probes Syzygy endgame tablebases (.rtbw and .rtbz files).
+//
+// The WDL tables store whether a position is won, drawn or lost, the DTZ tables the distance to the next
+// capture or pawn move. Positions where a capture is the best move may store any value, therefore the
+// captures are searched before the tables are probed.
+package syzygy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// WDL is the win/draw/loss value of a position from the point of view of the side to move
+type WDL int
+
+// WDL values. CursedWin and BlessedLoss denote a win or loss which cannot be achieved within the 50-move rule
+const (
+	Loss        WDL = -2
+	BlessedLoss WDL = -1
+	Draw        WDL = 0
+	CursedWin   WDL = 1
+	Win         WDL = 2
+)
+
+func (w WDL) String() string {
+	switch w {
+	case Loss:
+		return "loss"
+	case BlessedLoss:
+		return "blessed loss"
+	case Draw:
+		return "draw"
+	case CursedWin:
+		return "cursed win"
+	case Win:
+		return "win"
+	}
+	return fmt.Sprintf("WDL(%d)", int(w))
+}
+
+// file extensions, by tableKind
+var suffixes = [2]string{wdlTable: ".rtbw", dtzTable: ".rtbz"}
+
+// Tablebase stores the tables found in one or more directories.
+// The table files are only read when first needed.
+type Tablebase struct {
+	tables    [2]map[string]*table // by tableKind and name, both with the stronger and the weaker side first
+	maxPieces int
+}
+
+// Open scans the given directories for tablebase files
+func Open(dirs ...string) (*Tablebase, error) {
+	tb := &Tablebase{tables: [2]map[string]*table{make(map[string]*table), make(map[string]*table)}}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			for kind, suffix := range suffixes {
+				if ext != suffix {
+					continue
+				}
+				t, err := newTable(tableKind(kind), filepath.Join(dir, entry.Name()), strings.TrimSuffix(entry.Name(), ext))
+				if err != nil {
+					return nil, err
+				}
+				tb.tables[kind][t.name()] = t
+				tb.tables[kind][t.sides[1]+"v"+t.sides[0]] = t
+				if t.size > tb.maxPieces {
+					tb.maxPieces = t.size
+				}
+			}
+		}
+	}
+	return tb, nil
+}
+
+// MaxPieces returns the largest number of pieces (including kings) of the available tables
+func (tb *Tablebase) MaxPieces() int {
+	return tb.maxPieces
+}
+
+// ProbeWDL returns the win/draw/loss value of the given position from the point of view of the side to move.
+// The halfmove clock of the position is not taken into account.
+func (tb *Tablebase) ProbeWDL(p position.Position) (WDL, error) {
+	if err := tb.checkPosition(p); err != nil {
+		return Draw, err
+	}
+	wdl, _, err := tb.wdl(p, false)
+	return wdl, err
+}
+
+// ProbeDTZ returns the distance to zeroing (in plies) of the given position, i.e. the number of plies
+// until the next capture or pawn move which keeps the value of the position (assuming optimal play).
+// The value is positive for a win, negative for a loss, and 0 for a draw. For cursed wins and blessed losses
+// the absolute value is greater than 100.
+func (tb *Tablebase) ProbeDTZ(p position.Position) (int, error) {
+	if err := tb.checkPosition(p); err != nil {
+		return 0, err
+	}
+	return tb.dtz(p)
+}
+
+// RankedMove is a legal move together with the tablebase values of the resulting position,
+// from the point of view of the side making the move
+type RankedMove struct {
+	Move move.Move
+	WDL  WDL
+	DTZ  int
+}
+
+// RankMoves returns all legal moves of the given position ordered from best to worst.
+// Winning moves with the smallest DTZ come first, losing moves with the largest DTZ come last.
+func (tb *Tablebase) RankMoves(p position.Position) ([]RankedMove, error) {
+	if err := tb.checkPosition(p); err != nil {
+		return nil, err
+	}
+	moves := p.FindMoves(p.ActiveColour())
+	ranked := make([]RankedMove, 0, len(moves))
+	for _, m := range moves {
+		// work on a copy so that the enpassant square of 'p' is not affected
+		posn := p
+		posn.MakeMove(m)
+		rm, err := tb.rankMove(posn, m)
+		posn.UnmakeMove(m)
+		if err != nil {
+			return nil, err
+		}
+		ranked = append(ranked, rm)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].WDL != ranked[j].WDL {
+			return ranked[i].WDL > ranked[j].WDL
+		}
+		return ranked[i].DTZ < ranked[j].DTZ
+	})
+	return ranked, nil
+}
+
+// rankMove calculates the values for move 'm' which has already been played in 'p'
+func (tb *Tablebase) rankMove(p position.Position, m move.Move) (RankedMove, error) {
+	wdl, _, err := tb.wdl(p, false)
+	if err != nil {
+		return RankedMove{}, err
+	}
+	rm := RankedMove{Move: m, WDL: -wdl}
+	switch {
+	case p.IsCheckmate():
+		rm.DTZ = 1
+	case isZeroing(m):
+		rm.DTZ = zeroingDTZ(rm.WDL)
+	default:
+		dtz, err := tb.dtz(p)
+		if err != nil {
+			return RankedMove{}, err
+		}
+		rm.DTZ = -dtz + sign(-dtz) // one ply more than the resulting position
+	}
+	return rm, nil
+}
+
+// checkPosition returns an error if the position cannot be probed
+func (tb *Tablebase) checkPosition(p position.Position) error {
+	for _, col := range colour.AllColours {
+		if p.CastlingAvailabilityKingsSide(col) || p.CastlingAvailabilityQueensSide(col) {
+			return fmt.Errorf("cannot probe position with castling rights")
+		}
+	}
+	if n := p.OccupiedSquares().Cardinality(); n > maxPieces {
+		return fmt.Errorf("too many pieces (%d) for tablebase probe", n)
+	}
+	return nil
+}
+
+// wdl returns the WDL value of the position, and whether the best move is a capture (or, if withPawnMoves
+// is set, a pawn move). Since the tables may store any value for positions where a capture is the best move,
+// the captures are searched first.
+func (tb *Tablebase) wdl(p position.Position, withPawnMoves bool) (WDL, bool, error) {
+	moves := p.FindMoves(p.ActiveColour())
+	if len(moves) == 0 {
+		if p.InCheck() {
+			return Loss, false, nil
+		}
+		return Draw, false, nil
+	}
+	best, searched := Loss, 0
+	for _, m := range moves {
+		if !m.IsCapture() && !(withPawnMoves && m.PieceType() == piece.PAWN) {
+			continue
+		}
+		searched++
+		posn := p
+		posn.MakeMove(m)
+		value, _, err := tb.wdl(posn, false)
+		posn.UnmakeMove(m)
+		if err != nil {
+			return Draw, false, err
+		}
+		if -value > best {
+			best = -value
+			if best == Win {
+				return Win, true, nil
+			}
+		}
+	}
+	// the table is not needed if all moves have been searched (and would be wrong for an enpassant capture,
+	// since the tables do not store enpassant squares)
+	if searched == len(moves) {
+		return best, true, nil
+	}
+
+	stored, _, err := tb.lookup(wdlTable, p, Draw)
+	if err != nil {
+		return Draw, false, err
+	}
+	if searched > 0 && best >= WDL(stored) {
+		return best, best > Draw, nil
+	}
+	return WDL(stored), false, nil
+}
+
+// dtz returns the DTZ value of the position, see ProbeDTZ
+func (tb *Tablebase) dtz(p position.Position) (int, error) {
+	moves := p.FindMoves(p.ActiveColour())
+	if len(moves) == 0 {
+		if p.InCheck() {
+			return -1, nil
+		}
+		return 0, nil
+	}
+	wdl, zeroing, err := tb.wdl(p, true)
+	if err != nil || wdl == Draw {
+		return 0, err
+	}
+	if zeroing {
+		return zeroingDTZ(wdl), nil
+	}
+	dtz, ok, err := tb.lookup(dtzTable, p, wdl)
+	if err != nil || ok {
+		return dtz, err
+	}
+
+	// the table only stores the other side to move: take the best value after each move
+	best := 0
+	for _, m := range moves {
+		posn := p
+		posn.MakeMove(m)
+		var value int
+		if isZeroing(m) {
+			var v WDL
+			v, _, err = tb.wdl(posn, false)
+			value = -zeroingDTZ(v)
+		} else {
+			value, err = tb.dtz(posn)
+			value = -value
+			if !posn.IsCheckmate() {
+				value += sign(value)
+			}
+		}
+		posn.UnmakeMove(m)
+		if err != nil {
+			return 0, err
+		}
+		// for a win the smallest positive value, for a loss the smallest (i.e. longest) negative value
+		if sign(value) == sign(int(wdl)) && (best == 0 || value < best) {
+			best = value
+		}
+	}
+	if best == 0 {
+		return 0, fmt.Errorf("inconsistent tablebase values for %s", p.Fen())
+	}
+	return best, nil
+}
+
+// lookup returns the value which the table of the given kind stores for the position. For DTZ tables,
+// the value is converted to plies from the point of view of the side to move, using the position's wdl value;
+// ok is false if the table does not store the side to move.
+func (tb *Tablebase) lookup(kind tableKind, p position.Position, wdl WDL) (value int, ok bool, err error) {
+	pl := newPlacement(p)
+	if len(pl.codes) == 2 {
+		return 0, true, nil // KvK
+	}
+	t, found := tb.tables[kind][pl.name]
+	if !found {
+		return 0, false, fmt.Errorf("no tablebase available for %s", pl.name)
+	}
+	if err := t.load(); err != nil {
+		return 0, false, err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: corrupt table: %v", t.filename, r)
+		}
+	}()
+	value, leadFile, ok := t.lookup(pl)
+	switch {
+	case !ok:
+		return 0, false, nil
+	case kind == wdlTable:
+		return value - 2, true, nil // the values 0..4 are stored
+	}
+	dtz := t.part(0, leadFile).plies(t.maps, value, wdl)
+	if wdl == CursedWin || wdl == BlessedLoss {
+		dtz += 100
+	}
+	return dtz * sign(int(wdl)), true, nil
+}
+
+// the piece codes of the tables
+var pieceCodes = map[piece.Piece]int{piece.PAWN: pawnCode, piece.KNIGHT: knightCode, piece.BISHOP: bishopCode,
+	piece.ROOK: rookCode, piece.QUEEN: queenCode, piece.KING: kingCode}
+
+// newPlacement converts the position to the representation of the tables
+func newPlacement(p position.Position) placement {
+	pl := placement{blackToMove: p.ActiveColour() == colour.Black}
+	var counts [2][kingCode + 1]int
+	for _, col := range colour.AllColours {
+		for _, pt := range piece.AllPieces {
+			for _, sq := range p.Pieces(col, pt).SetBits() {
+				counts[col][pieceCodes[pt]]++
+				pl.codes = append(pl.codes, int(col)*blackCode+pieceCodes[pt])
+				pl.squares = append(pl.squares, tbSquare(square.Square(sq)))
+			}
+		}
+	}
+	pl.name = material(counts[colour.White]) + "v" + material(counts[colour.Black])
+	return pl
+}
+
+// tbSquare converts the square to the tablebase square numbering (a1=0, h8=63)
+func tbSquare(sq square.Square) int {
+	return (sq.Rank()-1)*8 + sq.File() - 1
+}
+
+// isZeroing returns true if the move resets the 50-move counter
+func isZeroing(m move.Move) bool {
+	return m.IsCapture() || m.PieceType() == piece.PAWN
+}
+
+// zeroingDTZ returns the dtz of a position where the best move is a capture or pawn move
+func zeroingDTZ(wdl WDL) int {
+	switch wdl {
+	case Win:
+		return 1
+	case CursedWin:
+		return 101
+	case BlessedLoss:
+		return -101
+	case Loss:
+		return -1
+	}
+	return 0
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
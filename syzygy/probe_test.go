@@ -0,0 +1,199 @@
+package syzygy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/square"
+)
+
+// openKRvK writes single-value KRvK tables: white to move wins (dtz stored as 10 moves), black to move loses
+func openKRvK(t *testing.T) *Tablebase {
+	t.Helper()
+	dir := t.TempDir()
+	pieces := []int{wKing, wRook, bKing}
+	writeTable(t, dir, "KRvK", wdlTable, pieces, [2]int{0, -1}, func(stm, file int) sideData {
+		if stm == 0 {
+			return sideData{single: true, value: int(Win) + 2}
+		}
+		return sideData{single: true, value: int(Loss) + 2}
+	})
+	writeTable(t, dir, "KRvK", dtzTable, pieces, [2]int{0, -1}, func(stm, file int) sideData {
+		return sideData{single: true, value: 10} // white to move
+	})
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return tb
+}
+
+func parseFen(t *testing.T, fen string) position.Position {
+	t.Helper()
+	posn, err := position.ParseFen(fen)
+	if err != nil {
+		t.Fatalf("error parsing fen '%s': %s", fen, err)
+	}
+	return posn
+}
+
+func TestProbe(t *testing.T) {
+	tb := openKRvK(t)
+	if tb.MaxPieces() != 3 {
+		t.Errorf("expected MaxPieces 3 but got %d", tb.MaxPieces())
+	}
+	data := []struct {
+		fen string
+		wdl WDL
+		dtz int
+	}{
+		{"8/8/8/3k4/8/8/8/R3K3 w - - 0 1", Win, 21},
+		{"8/8/8/3k4/8/8/8/R3K3 b - - 0 1", Loss, -22},
+		{"8/8/8/3K4/8/8/8/r3k3 b - - 0 1", Win, 21},    // colours swapped
+		{"8/8/8/8/8/2k5/3R4/7K b - - 0 1", Draw, 0},    // black captures the rook
+		{"8/8/8/8/8/2k5/3R4/3K4 b - - 0 1", Loss, -22}, // rook is defended
+		{"R5k1/8/6K1/8/8/8/8/8 b - - 0 1", Loss, -1},   // checkmate
+		{"8/8/8/3k4/8/8/8/4K3 w - - 0 1", Draw, 0},     // no table needed
+	}
+	for testNbr, d := range data {
+		posn := parseFen(t, d.fen)
+		wdl, err := tb.ProbeWDL(posn)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", testNbr, err)
+		} else if wdl != d.wdl {
+			t.Errorf("test %d: expected wdl %s but got %s", testNbr, d.wdl, wdl)
+		}
+		dtz, err := tb.ProbeDTZ(posn)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", testNbr, err)
+		} else if dtz != d.dtz {
+			t.Errorf("test %d: expected dtz %d but got %d", testNbr, d.dtz, dtz)
+		}
+	}
+}
+
+func TestProbeErrors(t *testing.T) {
+	tb := openKRvK(t)
+	data := []struct {
+		fen string
+		msg string
+	}{
+		{"8/8/8/3k4/8/8/8/Q3K3 w - - 0 1", "no tablebase available for KQvK"},
+		{"8/8/8/3k4/8/8/8/R3K3 w Q - 0 1", "castling rights"},
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1", "too many pieces"},
+	}
+	for testNbr, d := range data {
+		_, err := tb.ProbeWDL(parseFen(t, d.fen))
+		if err == nil || !strings.Contains(err.Error(), d.msg) {
+			t.Errorf("test %d: expected error '%s' but got: %v", testNbr, d.msg, err)
+		}
+	}
+	if _, err := Open(t.TempDir() + "/missing"); err == nil {
+		t.Errorf("expected error for missing directory")
+	}
+}
+
+func TestRankMoves(t *testing.T) {
+	tb := openKRvK(t)
+	posn := parseFen(t, "8/8/8/8/8/2k5/8/3R3K w - - 0 1")
+	nbrMoves := len(posn.FindMoves(posn.ActiveColour()))
+	moves, err := tb.RankMoves(posn)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(moves) != nbrMoves {
+		t.Fatalf("expected %d moves but got %d", nbrMoves, len(moves))
+	}
+	// moving the rook to d2, d3 or d4 loses it
+	for i, m := range moves {
+		if i < nbrMoves-3 {
+			if m.WDL != Win || m.DTZ != 23 {
+				t.Errorf("move %s: expected win with dtz 23 but got %s, %d", m.Move.String(), m.WDL, m.DTZ)
+			}
+		} else if m.WDL != Draw || m.DTZ != 0 || (m.Move.To() != square.D2 && m.Move.To() != square.D3 && m.Move.To() != square.D4) {
+			t.Errorf("move %s: expected draw but got %s, %d", m.Move.String(), m.WDL, m.DTZ)
+		}
+	}
+
+	// mate in 1
+	posn = parseFen(t, "6k1/8/6K1/8/8/8/8/R7 w - - 0 1")
+	moves, err = tb.RankMoves(posn)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if moves[0].Move.To() != square.A8 || moves[0].WDL != Win || moves[0].DTZ != 1 {
+		t.Errorf("expected Ra8# with dtz 1 as best move but got %s, %s, %d", moves[0].Move.String(), moves[0].WDL, moves[0].DTZ)
+	}
+}
+
+// realTables are the tables expected in testdata by TestProbeRealTables, taken unchanged from the standard
+// 3-4-5 piece set (e.g. https://tablebase.lichess.ovh/tables/standard/3-4-5/)
+var realTables = []string{"KQvK.rtbw", "KQvK.rtbz", "KRvK.rtbw", "KRvK.rtbz", "KPvK.rtbw", "KPvK.rtbz"}
+
+// TestProbeRealTables checks the reader against real tablebase files, which (unlike the synthetic tables of the other
+// tests) use symbol pairs and were written by the original generator. The expected values are known results of
+// these endings.
+func TestProbeRealTables(t *testing.T) {
+	for _, name := range realTables {
+		if _, err := os.Stat(filepath.Join("testdata", name)); err != nil {
+			t.Fatalf("tablebase file testdata/%s not available, see testdata/README", name)
+		}
+	}
+	tb, err := Open("testdata")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	data := []struct {
+		fen string
+		wdl WDL
+		dtz int
+	}{
+		{"k7/8/1K6/8/8/8/8/7Q w - - 0 1", Win, 1},     // Qh8#
+		{"k7/8/1K6/8/8/8/8/7R w - - 0 1", Win, 1},     // Rh8#
+		{"k7/1R6/1K6/8/8/8/8/8 b - - 0 1", Draw, 0},   // stalemate
+		{"8/8/8/8/8/8/6kR/K7 b - - 0 1", Draw, 0},     // black captures the rook
+		{"8/2K5/3P4/8/8/8/5k2/8 w - - 0 1", Win, 1},   // d7 and d8=Q
+		{"8/2K5/3P4/8/8/8/5k2/8 b - - 0 1", Loss, -2}, // d7 after any move
+		{"k7/8/8/P7/K7/8/8/8 w - - 0 1", Draw, 0},     // rook pawn, king in the corner
+		{"k7/8/8/P7/K7/8/8/8 b - - 0 1", Draw, 0},
+	}
+	for testNbr, d := range data {
+		posn := parseFen(t, d.fen)
+		wdl, err := tb.ProbeWDL(posn)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", testNbr, err)
+		} else if wdl != d.wdl {
+			t.Errorf("test %d: expected wdl %s but got %s", testNbr, d.wdl, wdl)
+		}
+		dtz, err := tb.ProbeDTZ(posn)
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", testNbr, err)
+		} else if dtz != d.dtz {
+			t.Errorf("test %d: expected dtz %d but got %d", testNbr, d.dtz, dtz)
+		}
+	}
+
+	// positions where the rook or queen cannot be captured are won, with a positive dtz for the winning side
+	for _, fen := range []string{"8/8/8/3k4/8/8/8/Q3K3 w - - 0 1", "8/8/8/3k4/8/8/8/Q3K3 b - - 0 1",
+		"8/8/8/3k4/8/8/8/R3K3 w - - 0 1", "8/8/8/3k4/8/8/8/R3K3 b - - 0 1"} {
+		posn := parseFen(t, fen)
+		wdl, err := tb.ProbeWDL(posn)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", fen, err)
+			continue
+		}
+		dtz, err := tb.ProbeDTZ(posn)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", fen, err)
+			continue
+		}
+		white := posn.ActiveColour() == colour.White
+		if (white && (wdl != Win || dtz <= 0)) || (!white && (wdl != Loss || dtz >= 0)) {
+			t.Errorf("%s: unexpected wdl %s, dtz %d", fen, wdl, dtz)
+		}
+	}
+}
@@ -0,0 +1,448 @@
+package syzygy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// tableKind distinguishes the WDL (.rtbw) and DTZ (.rtbz) tables
+type tableKind int
+
+const (
+	wdlTable tableKind = iota
+	dtzTable
+)
+
+// the first bytes of the table files
+var magic = [2][]byte{wdlTable: {0x71, 0xE8, 0x23, 0x5D}, dtzTable: {0xD7, 0x66, 0x0C, 0xA5}}
+
+// piece codes used by the table files: the type, plus blackCode for black pieces
+const (
+	pawnCode   = 1
+	knightCode = 2
+	bishopCode = 3
+	rookCode   = 4
+	queenCode  = 5
+	kingCode   = 6
+	blackCode  = 8
+)
+
+// the piece letters of the table names, indexed by code
+const pieceLetters = " PNBRQK"
+
+// flags of a part
+const (
+	flagBlackToMove = 1   // DTZ: the part stores the positions with black to move
+	flagMapped      = 2   // DTZ: the values are mapped to the number of moves or plies, see part.plies
+	flagWinPlies    = 4   // DTZ: wins are given in plies, not moves
+	flagLossPlies   = 8   // DTZ: losses are given in plies, not moves
+	flagWideMap     = 16  // DTZ: the map has 2 byte entries
+	flagSingleValue = 128 // all positions have the same value
+)
+
+// table stores one WDL or DTZ table, e.g. KRvK.rtbw.
+// The positions are stored with the first side of the name as white. The file is read when first probed.
+type table struct {
+	kind     tableKind
+	filename string
+	sides    [2]string // the material of the two sides, e.g. "KR" and "K"
+	size     int       // number of pieces
+	pawns    [2]int    // number of pawns of the leading colour and of the other colour, see newTable
+	unique   bool      // whether a piece other than a king is the only one of its type and colour
+
+	once  sync.Once
+	err   error
+	parts [2][4]part // by side to move and file of the leading pawn (a-d); only file 0 if there are no pawns
+	maps  []byte     // DTZ: the maps of all parts, see part.plies
+}
+
+// leader describes the leading group of a part
+type leader int
+
+const (
+	leadKings  leader = iota // the two kings
+	leadUnique               // three unique pieces
+	leadPawns                // the pawns of the leading colour
+)
+
+// part stores the values of a table for one side to move and (if there are pawns) one file of the leading pawn.
+//
+// The pieces are split into groups: the leading group, followed by groups of pieces of the same code.
+// If both sides have pawns, the second group contains the pawns of the other colour.
+// The position of a placement is the sum of the number of each group multiplied by the group's factor.
+type part struct {
+	flags     byte
+	order     [maxPieces]int // the piece codes in the order in which they are numbered
+	leader    leader
+	pawnGroup bool     // whether the second group contains pawns
+	groups    []int    // number of pieces of each group
+	factors   []uint64 // factor of each group
+	size      uint64   // number of positions
+	values    values
+	maps      [4]int // DTZ: start of the map for wins, losses, cursed wins and blessed losses in table.maps
+}
+
+// newTable creates a table for the given name, e.g. "KRvK"
+func newTable(kind tableKind, filename, name string) (*table, error) {
+	sides := strings.Split(name, "v")
+	if len(sides) != 2 || !strings.HasPrefix(sides[0], "K") || !strings.HasPrefix(sides[1], "K") {
+		return nil, fmt.Errorf("unrecognised table name '%s'", name)
+	}
+	t := &table{kind: kind, filename: filename}
+	var counts [2][kingCode + 1]int
+	for i, side := range sides {
+		for _, ch := range side {
+			code := strings.IndexRune(pieceLetters, ch)
+			if code < pawnCode {
+				return nil, fmt.Errorf("unrecognised table name '%s'", name)
+			}
+			counts[i][code]++
+			t.size++
+		}
+		t.sides[i] = material(counts[i])
+		for code := pawnCode; code < kingCode; code++ {
+			t.unique = t.unique || counts[i][code] == 1
+		}
+	}
+	if t.size > maxPieces {
+		return nil, fmt.Errorf("too many pieces in table '%s'", name)
+	}
+	// the leading colour has fewer pawns, but at least one
+	t.pawns = [2]int{counts[0][pawnCode], counts[1][pawnCode]}
+	if t.pawns[0] == 0 || (t.pawns[1] > 0 && t.pawns[1] < t.pawns[0]) {
+		t.pawns[0], t.pawns[1] = t.pawns[1], t.pawns[0]
+	}
+	return t, nil
+}
+
+// material returns the pieces in the order used by the table names, e.g. "KQRBNP"
+func material(counts [kingCode + 1]int) string {
+	var sb strings.Builder
+	for code := kingCode; code >= pawnCode; code-- {
+		sb.WriteString(strings.Repeat(pieceLetters[code:code+1], counts[code]))
+	}
+	return sb.String()
+}
+
+// name returns the name of the table, in the order in which the positions are stored
+func (t *table) name() string {
+	return t.sides[0] + "v" + t.sides[1]
+}
+
+// symmetric returns true if both sides have the same material
+func (t *table) symmetric() bool {
+	return t.sides[0] == t.sides[1]
+}
+
+func (t *table) hasPawns() bool {
+	return t.pawns[0] > 0
+}
+
+// files returns the number of files of the leading pawn for which parts are stored
+func (t *table) files() int {
+	if t.hasPawns() {
+		return 4
+	}
+	return 1
+}
+
+// sidesToMove returns the number of sides to move for which parts are stored
+func (t *table) sidesToMove() int {
+	if t.kind == wdlTable && !t.symmetric() {
+		return 2
+	}
+	return 1
+}
+
+// part returns the part for the side to move and file of the leading pawn
+func (t *table) part(stm, file int) *part {
+	if t.kind == dtzTable {
+		stm = 0
+	}
+	return &t.parts[stm][file]
+}
+
+// load reads the table file (only once)
+func (t *table) load() error {
+	t.once.Do(func() {
+		data, err := os.ReadFile(t.filename)
+		if err == nil {
+			err = t.read(data)
+		}
+		if err != nil {
+			t.err = fmt.Errorf("%s: %w", t.filename, err)
+		}
+	})
+	return t.err
+}
+
+// read parses the table file
+func (t *table) read(data []byte) (err error) {
+	// the offsets in the file are not checked in detail: a corrupt file leads to a panic when reading past its end
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("corrupt table: %v", r)
+		}
+	}()
+
+	r := &reader{data: data}
+	if string(r.bytes(4)) != string(magic[t.kind]) {
+		return fmt.Errorf("bad magic number")
+	}
+	const asymmetric, withPawns = 1, 2
+	flags := r.u8()
+	if (flags&withPawns != 0) != t.hasPawns() || (flags&asymmetric != 0) == t.symmetric() {
+		return fmt.Errorf("header does not match the table name")
+	}
+
+	// for each file: the order of the groups and the pieces of both sides
+	for f := 0; f < t.files(); f++ {
+		b := r.u8()
+		orders := [2][2]int{{int(b & 0xF), -1}, {int(b >> 4), -1}}
+		if t.pawns[1] > 0 {
+			b = r.u8()
+			orders[0][1], orders[1][1] = int(b&0xF), int(b>>4)
+		}
+		for i := 0; i < t.size; i++ {
+			b = r.u8()
+			t.parts[0][f].order[i] = int(b & 0xF)
+			t.parts[1][f].order[i] = int(b >> 4)
+		}
+		for stm := 0; stm < t.sidesToMove(); stm++ {
+			if err := t.parts[stm][f].init(t, orders[stm], f); err != nil {
+				return err
+			}
+		}
+	}
+	r.align(2)
+
+	for f := 0; f < t.files(); f++ {
+		for stm := 0; stm < t.sidesToMove(); stm++ {
+			p := &t.parts[stm][f]
+			p.flags = r.u8()
+			if err := p.values.readHeader(r, p.flags&flagSingleValue != 0, p.size); err != nil {
+				return err
+			}
+		}
+	}
+
+	if t.kind == dtzTable {
+		start := r.pos
+		for f := 0; f < t.files(); f++ {
+			p := &t.parts[0][f]
+			if p.flags&flagMapped == 0 {
+				continue
+			}
+			// each map starts with its number of entries
+			if p.flags&flagWideMap != 0 {
+				r.align(2)
+				for i := range p.maps {
+					n := int(r.u16())
+					p.maps[i] = r.pos - start
+					r.skip(2 * n)
+				}
+			} else {
+				for i := range p.maps {
+					n := int(r.u8())
+					p.maps[i] = r.pos - start
+					r.skip(n)
+				}
+			}
+		}
+		t.maps = data[start:r.pos]
+		r.align(2)
+	}
+
+	// the index, block counts and blocks of all parts
+	for f := 0; f < t.files(); f++ {
+		for stm := 0; stm < t.sidesToMove(); stm++ {
+			v := &t.parts[stm][f].values
+			if !v.single {
+				v.index = r.bytes(6 * v.indexSize)
+			}
+		}
+	}
+	for f := 0; f < t.files(); f++ {
+		for stm := 0; stm < t.sidesToMove(); stm++ {
+			v := &t.parts[stm][f].values
+			if !v.single {
+				v.counts = r.bytes(2 * v.blockCounts)
+			}
+		}
+	}
+	for f := 0; f < t.files(); f++ {
+		for stm := 0; stm < t.sidesToMove(); stm++ {
+			v := &t.parts[stm][f].values
+			if !v.single {
+				r.align(64)
+				v.data = data[r.pos:]
+				r.skip(v.blocks << v.blockBits)
+			}
+		}
+	}
+	return nil
+}
+
+// init calculates the groups and their factors. 'orders' gives the place of the leading group and
+// of the group of the other colour's pawns in the sequence of factors.
+func (p *part) init(t *table, orders [2]int, file int) error {
+	switch {
+	case t.hasPawns():
+		p.leader = leadPawns
+		p.groups = []int{t.pawns[0]}
+		if t.pawns[1] > 0 {
+			p.pawnGroup = true
+			p.groups = append(p.groups, t.pawns[1])
+		}
+	case t.unique:
+		p.leader = leadUnique
+		p.groups = []int{3}
+	default:
+		p.leader = leadKings
+		p.groups = []int{2}
+	}
+	for i := sum(p.groups); i < t.size; {
+		n := 1
+		for i+n < t.size && p.order[i+n] == p.order[i] {
+			n++
+		}
+		p.groups = append(p.groups, n)
+		i += n
+	}
+
+	// the remaining groups are placed on the squares not occupied by the leading and pawn groups
+	free := 64 - p.groups[0]
+	next := 1
+	if p.pawnGroup {
+		free -= p.groups[1]
+		next = 2
+	}
+	p.factors = make([]uint64, len(p.groups))
+	p.size = 1
+	for k := range p.groups {
+		switch {
+		case k == orders[0]:
+			p.factors[0] = p.size
+			switch p.leader {
+			case leadPawns:
+				p.size *= pawnCount[p.groups[0]][file]
+			case leadUnique:
+				p.size *= uniqueTriples
+			default:
+				p.size *= kingPairs
+			}
+		case p.pawnGroup && k == orders[1]:
+			p.factors[1] = p.size
+			p.size *= choose[p.groups[1]][48-p.groups[0]]
+		case next < len(p.groups):
+			p.factors[next] = p.size
+			p.size *= choose[p.groups[next]][free]
+			free -= p.groups[next]
+			next++
+		default:
+			return fmt.Errorf("invalid order of groups %v", orders)
+		}
+	}
+	return nil
+}
+
+func sum(numbers []int) int {
+	n := 0
+	for _, i := range numbers {
+		n += i
+	}
+	return n
+}
+
+// placement is a position in the representation of the tables
+type placement struct {
+	name        string // table name with white as the first side, e.g. "KRvK"
+	blackToMove bool
+	codes       []int // piece codes
+	squares     []int
+}
+
+// lookup returns the value stored for the placement, and the file of the leading pawn.
+// For DTZ tables, ok is false if the table does not store the side to move.
+func (t *table) lookup(pl placement) (value int, leadFile int, ok bool) {
+	// if the colours do not match the table, or if both sides have the same material and black is to move,
+	// the colours are swapped and the board mirrored
+	swap := pl.name != t.name() || (t.symmetric() && pl.blackToMove)
+	stm := b2i(pl.blackToMove != swap)
+	codes := make([]int, len(pl.codes))
+	squares := make([]int, len(pl.squares))
+	for i := range codes {
+		codes[i], squares[i] = pl.codes[i], pl.squares[i]
+		if swap {
+			codes[i] ^= blackCode
+			squares[i] = mirrorRank(squares[i])
+		}
+	}
+
+	// the parts are split by the file of the leading pawn, which is the leading colour's pawn with the highest pawnOrder
+	leading := -1
+	if t.hasPawns() {
+		for i := range codes {
+			if codes[i] == t.parts[0][0].order[0] && (leading < 0 || pawnOrder[squares[i]] > pawnOrder[squares[leading]]) {
+				leading = i
+			}
+		}
+		leadFile = file(squares[leading])
+		if leadFile > 3 {
+			leadFile = 7 - leadFile
+		}
+	}
+
+	p := t.part(stm, leadFile)
+	if t.kind == dtzTable && int(p.flags&flagBlackToMove) != stm && (t.hasPawns() || !t.symmetric()) {
+		return 0, leadFile, false
+	}
+
+	// the squares in the order of the part, starting with the leading pawn
+	ordered := make([]int, 0, len(squares))
+	used := make([]bool, len(squares))
+	if leading >= 0 {
+		ordered = append(ordered, squares[leading])
+		used[leading] = true
+	}
+	for _, code := range p.order[len(ordered):t.size] {
+		for i := range codes {
+			if !used[i] && codes[i] == code {
+				ordered = append(ordered, squares[i])
+				used[i] = true
+				break
+			}
+		}
+	}
+	return p.values.get(p.index(ordered)), leadFile, true
+}
+
+// plies converts a value from a DTZ table into plies
+func (p *part) plies(maps []byte, value int, wdl WDL) int {
+	if p.flags&flagMapped != 0 {
+		var m int
+		switch wdl {
+		case Win:
+			m = p.maps[0]
+		case Loss:
+			m = p.maps[1]
+		case CursedWin:
+			m = p.maps[2]
+		case BlessedLoss:
+			m = p.maps[3]
+		}
+		if p.flags&flagWideMap != 0 {
+			value = int(binary.LittleEndian.Uint16(maps[m+2*value:]))
+		} else {
+			value = int(maps[m+value])
+		}
+	}
+	if (wdl == Win && p.flags&flagWinPlies != 0) || (wdl == Loss && p.flags&flagLossPlies != 0) {
+		return value + 1
+	}
+	// the value is given in moves
+	return 2*value + 1
+}
@@ -0,0 +1,580 @@
+package syzygy
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/rjo67/chess/position"
+)
+
+// Apart from TestProbeRealTables (which reads real tables from testdata), the tests write synthetic tables.
+// The values of a part are either a single value, or given by a function of the index.
+// The latter are compressed as in the real tables, with pairs of symbols and a canonical Huffman code.
+type sideData struct {
+	flags  byte
+	single bool
+	value  int                  // if single
+	values func(idx uint64) int // otherwise; must be < leafSymbol
+	maps   [4][]int             // DTZ with flagMapped: the maps for wins, losses, cursed wins and blessed losses
+}
+
+const (
+	testBlockBits = 6 // 64 byte blocks
+	testIndexBits = 6
+	maxPairings   = 20 // number of times the most frequent pair of symbols is replaced
+)
+
+// writeTable writes a synthetic table to dir. 'pieces' is the order in which the pieces are encoded,
+// 'orders' the place of the leading group and of the other colour's pawns in the sequence of factors;
+// 'data' returns the contents for the given side to move and file.
+func writeTable(t *testing.T, dir, name string, kind tableKind, pieces []int, orders [2]int, data func(stm, file int) sideData) string {
+	t.Helper()
+	tbl, err := newTable(kind, "", name)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	buf := append([]byte(nil), magic[kind]...)
+	buf = append(buf, byte(b2i(!tbl.symmetric())|b2i(tbl.hasPawns())<<1))
+	for f := 0; f < tbl.files(); f++ {
+		buf = append(buf, byte(orders[0]|orders[0]<<4))
+		if tbl.pawns[1] > 0 {
+			buf = append(buf, byte(orders[1]|orders[1]<<4))
+		}
+		for _, pc := range pieces {
+			buf = append(buf, byte(pc|pc<<4))
+		}
+		for stm := 0; stm < tbl.sidesToMove(); stm++ {
+			p := &tbl.parts[stm][f]
+			copy(p.order[:], pieces)
+			if err := p.init(tbl, orders, f); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+	}
+	buf = append(buf, make([]byte, len(buf)&1)...)
+
+	var encodedData []encoded
+	var maps []byte
+	for f := 0; f < tbl.files(); f++ {
+		for stm := 0; stm < tbl.sidesToMove(); stm++ {
+			sd := data(stm, f)
+			if sd.single {
+				buf = append(buf, sd.flags|flagSingleValue, byte(sd.value))
+				continue
+			}
+			e := encodeValues(tbl.parts[stm][f].size, sd.values)
+			buf = append(buf, sd.flags)
+			buf = append(buf, e.header...)
+			encodedData = append(encodedData, e)
+			if sd.flags&flagMapped != 0 {
+				for _, m := range sd.maps {
+					maps = append(maps, byte(len(m)))
+					for _, v := range m {
+						maps = append(maps, byte(v))
+					}
+				}
+			}
+		}
+	}
+	if kind == dtzTable {
+		buf = append(buf, maps...)
+		buf = append(buf, make([]byte, len(buf)&1)...)
+	}
+	for _, e := range encodedData {
+		buf = append(buf, e.index...)
+	}
+	for _, e := range encodedData {
+		buf = append(buf, e.counts...)
+	}
+	for _, e := range encodedData {
+		buf = append(buf, make([]byte, -len(buf)&0x3F)...)
+		buf = append(buf, e.blocks...)
+	}
+	buf = append(buf, make([]byte, 16)...)
+
+	filename := filepath.Join(dir, name+suffixes[kind])
+	if err := os.WriteFile(filename, buf, 0644); err != nil {
+		t.Fatalf("error writing table: %s", err)
+	}
+	return filename
+}
+
+// encoded are the compressed values of a part
+type encoded struct {
+	header, index, counts, blocks []byte
+}
+
+// encodeValues compresses the values: frequent pairs of symbols are replaced by a new symbol, then the symbols
+// are stored with a canonical Huffman code
+func encodeValues(size uint64, values func(idx uint64) int) encoded {
+	// the symbols are numbered in the order of creation, with pair[1] == -1 for a leaf
+	var symbols [][2]int
+	leaves := make(map[int]int)
+	seq := make([]int, size)
+	for idx := range seq {
+		v := values(uint64(idx))
+		if _, ok := leaves[v]; !ok {
+			leaves[v] = len(symbols)
+			symbols = append(symbols, [2]int{v, -1})
+		}
+		seq[idx] = leaves[v]
+	}
+	for i := 0; i < maxPairings && len(symbols) < leafSymbol-1; i++ {
+		counts := make(map[[2]int]int)
+		var best [2]int
+		for j := 1; j < len(seq); j++ {
+			pair := [2]int{seq[j-1], seq[j]}
+			counts[pair]++
+			if counts[pair] > counts[best] || (counts[pair] == counts[best] && (pair[0] < best[0] || (pair[0] == best[0] && pair[1] < best[1]))) {
+				best = pair
+			}
+		}
+		if counts[best] < 4 {
+			break
+		}
+		sym := len(symbols)
+		symbols = append(symbols, best)
+		var paired []int
+		for j := 0; j < len(seq); j++ {
+			if j+1 < len(seq) && seq[j] == best[0] && seq[j+1] == best[1] {
+				paired = append(paired, sym)
+				j++
+			} else {
+				paired = append(paired, seq[j])
+			}
+		}
+		seq = paired
+	}
+	expands := make([]int, len(symbols))
+	for sym, pair := range symbols {
+		expands[sym] = 1
+		if pair[1] >= 0 {
+			expands[sym] = expands[pair[0]] + expands[pair[1]]
+		}
+	}
+
+	// the code lengths; the symbols with codes are numbered first, longer codes first
+	freq := make(map[int]int)
+	for _, sym := range seq {
+		freq[sym]++
+	}
+	codeLen := huffmanLengths(freq)
+	order := make([]int, len(symbols))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return codeLen[order[i]] > codeLen[order[j]] })
+	number := make([]int, len(symbols))
+	for n, sym := range order {
+		number[sym] = n
+	}
+	minLen, maxLen := 64, 0
+	for _, l := range codeLen {
+		if l < minLen {
+			minLen = l
+		}
+		if l > maxLen {
+			maxLen = l
+		}
+	}
+	nbrCodes := make([]int, maxLen+2)
+	for _, l := range codeLen {
+		nbrCodes[l]++
+	}
+	firstSymbol := make([]int, maxLen+1)
+	lowestCode := make([]uint64, maxLen+1)
+	for l := maxLen - 1; l >= minLen; l-- {
+		firstSymbol[l] = firstSymbol[l+1] + nbrCodes[l+1]
+		lowestCode[l] = (lowestCode[l+1] + uint64(nbrCodes[l+1])) / 2
+	}
+
+	var e encoded
+	e.header = append(e.header, testBlockBits, testIndexBits, 0, 0, 0, 0, 0, byte(maxLen), byte(minLen))
+	for l := minLen; l <= maxLen; l++ {
+		e.header = binary.LittleEndian.AppendUint16(e.header, uint16(firstSymbol[l]))
+	}
+	e.header = binary.LittleEndian.AppendUint16(e.header, uint16(len(symbols)))
+	for _, sym := range order {
+		first, second := symbols[sym][0], leafSymbol
+		if symbols[sym][1] >= 0 {
+			first, second = number[symbols[sym][0]], number[symbols[sym][1]]
+		}
+		e.header = append(e.header, byte(first), byte(first>>8)|byte(second<<4), byte(second>>4))
+	}
+	e.header = append(e.header, make([]byte, len(symbols)&1)...)
+
+	// the blocks, and the first value of each block
+	const blockSize = 1 << testBlockBits
+	var blockStart []uint64
+	var block []byte
+	bits, inBlock := blockSize*8, 0 // start with a new block
+	var idx uint64
+	closeBlock := func() {
+		if inBlock > 0 {
+			e.counts = binary.LittleEndian.AppendUint16(e.counts, uint16(inBlock-1))
+			e.blocks = append(e.blocks, block...)
+		}
+	}
+	for _, sym := range seq {
+		l := codeLen[sym]
+		if bits+l > blockSize*8 {
+			closeBlock()
+			block, bits, inBlock = make([]byte, blockSize), 0, 0
+			blockStart = append(blockStart, idx)
+		}
+		code := lowestCode[l] + uint64(number[sym]-firstSymbol[l])
+		for i := l - 1; i >= 0; i-- {
+			if code&(1<<i) != 0 {
+				block[bits/8] |= 0x80 >> (bits % 8)
+			}
+			bits++
+		}
+		inBlock += expands[sym]
+		idx += uint64(expands[sym])
+	}
+	closeBlock()
+	binary.LittleEndian.PutUint32(e.header[3:], uint32(len(blockStart)))
+
+	// the index refers to the value in the middle between two entries, which may be past the end
+	const spacing = 1 << testIndexBits
+	for k := uint64(0); k < (size+spacing-1)/spacing; k++ {
+		target := k*spacing + spacing/2
+		b := sort.Search(len(blockStart), func(i int) bool { return blockStart[i] > target }) - 1
+		e.index = binary.LittleEndian.AppendUint32(e.index, uint32(b))
+		e.index = binary.LittleEndian.AppendUint16(e.index, uint16(target-blockStart[b]))
+	}
+	return e
+}
+
+// huffmanLengths returns the lengths of the Huffman codes for the given frequencies
+func huffmanLengths(freq map[int]int) map[int]int {
+	lengths := make(map[int]int)
+	if len(freq) == 1 {
+		for sym := range freq {
+			lengths[sym] = 1
+		}
+		return lengths
+	}
+	h := &nodeHeap{}
+	for sym, n := range freq {
+		*h = append(*h, huffmanNode{n, []int{sym}})
+	}
+	sort.Slice(*h, func(i, j int) bool { return (*h)[i].symbols[0] < (*h)[j].symbols[0] })
+	heap.Init(h)
+	for h.Len() > 1 {
+		a, b := heap.Pop(h).(huffmanNode), heap.Pop(h).(huffmanNode)
+		for _, sym := range append(a.symbols, b.symbols...) {
+			lengths[sym]++
+		}
+		heap.Push(h, huffmanNode{a.freq + b.freq, append(append([]int(nil), a.symbols...), b.symbols...)})
+	}
+	return lengths
+}
+
+type huffmanNode struct {
+	freq    int
+	symbols []int
+}
+
+type nodeHeap []huffmanNode
+
+func (h nodeHeap) Len() int            { return len(h) }
+func (h nodeHeap) Less(i, j int) bool  { return h[i].freq < h[j].freq }
+func (h nodeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nodeHeap) Push(x interface{}) { *h = append(*h, x.(huffmanNode)) }
+func (h *nodeHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// piece codes
+const (
+	wPawn   = pawnCode
+	wKnight = knightCode
+	wRook   = rookCode
+	wKing   = kingCode
+	bPawn   = blackCode + pawnCode
+	bKnight = blackCode + knightCode
+	bKing   = blackCode + kingCode
+)
+
+// indexValue is a value which (almost) identifies the index
+func indexValue(idx uint64) int { return int(idx % 3000) }
+
+// repeatingValue gives values with repetitions, so that pairs of symbols are used
+func repeatingValue(idx uint64) int { return int(idx/5%7) + int(idx/31%3)*10 + int(idx%97/90)*100 }
+
+func loadTable(t *testing.T, filename, name string, kind tableKind) *table {
+	t.Helper()
+	tbl, err := newTable(kind, filename, name)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := tbl.load(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return tbl
+}
+
+func probeFen(t *testing.T, tbl *table, fen string) int {
+	t.Helper()
+	posn, err := position.ParseFen(fen)
+	if err != nil {
+		t.Fatalf("error parsing fen '%s': %s", fen, err)
+	}
+	value, _, ok := tbl.lookup(newPlacement(posn))
+	if !ok {
+		t.Fatalf("unexpected side to move, fen: %s", fen)
+	}
+	return value
+}
+
+func TestDecompress(t *testing.T) {
+	for _, values := range []func(uint64) int{indexValue, repeatingValue} {
+		dir := t.TempDir()
+		filename := writeTable(t, dir, "KRvK", wdlTable, []int{wKing, wRook, bKing}, [2]int{0, -1}, func(stm, file int) sideData {
+			if stm == 0 {
+				return sideData{values: values}
+			}
+			return sideData{single: true, value: 3}
+		})
+		tbl := loadTable(t, filename, "KRvK", wdlTable)
+		p := tbl.part(0, 0)
+		if p.size != uniqueTriples {
+			t.Fatalf("expected table size %d but got %d", uniqueTriples, p.size)
+		}
+		for idx := uint64(0); idx < p.size; idx++ {
+			if v := p.values.get(idx); v != values(idx) {
+				t.Fatalf("idx %d: expected %d but got %d", idx, values(idx), v)
+			}
+		}
+		if v := tbl.part(1, 0).values.get(1234); v != 3 {
+			t.Errorf("expected single value 3 but got %d", v)
+		}
+	}
+
+	// the repeating values are stored with pairs and codes of different lengths
+	tbl := loadTable(t, writeTable(t, t.TempDir(), "KRvK", wdlTable, []int{wKing, wRook, bKing}, [2]int{0, -1}, func(stm, file int) sideData {
+		return sideData{values: repeatingValue}
+	}), "KRvK", wdlTable)
+	v := tbl.part(0, 0).values
+	maxLength := 0
+	for _, l := range v.lengths {
+		if l > maxLength {
+			maxLength = l
+		}
+	}
+	if maxLength < 2 || len(v.lowestCode) < 2 {
+		t.Errorf("expected pairs and different code lengths, got max. length %d and %d code lengths", maxLength, len(v.lowestCode))
+	}
+}
+
+func TestBadTable(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "KRvK.rtbw")
+	if err := os.WriteFile(filename, []byte{0x71, 0xE8, 0x23, 0x5E, 0x01}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tbl, _ := newTable(wdlTable, filename, "KRvK")
+	if err := tbl.load(); err == nil {
+		t.Errorf("expected error for bad magic number")
+	}
+	// truncated table
+	if err := os.WriteFile(filename, []byte{0x71, 0xE8, 0x23, 0x5D, 0x01, 0x00, 0x66, 0x44}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	tbl, _ = newTable(wdlTable, filename, "KRvK")
+	if err := tbl.load(); err == nil {
+		t.Errorf("expected error for truncated table")
+	}
+	// a pair which refers to itself
+	data, err := os.ReadFile(writeTable(t, dir, "KRvK", wdlTable, []int{wKing, wRook, bKing}, [2]int{0, -1}, func(stm, file int) sideData {
+		return sideData{values: repeatingValue}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tbl, _ = newTable(wdlTable, filename, "KRvK")
+	if err := tbl.read(data); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	v := tbl.part(0, 0).values
+	for sym := range v.lengths {
+		if v.second(sym) != leafSymbol {
+			v.pairs[3*sym], v.pairs[3*sym+1] = byte(sym), v.pairs[3*sym+1]&0xF0|byte(sym>>8)
+			break
+		}
+	}
+	tbl, _ = newTable(wdlTable, filename, "KRvK")
+	if err := tbl.read(data); err == nil {
+		t.Errorf("expected error for recursive pair")
+	}
+}
+
+func TestNewTable(t *testing.T) {
+	tbl, err := newTable(wdlTable, "", "KRPvKBP")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tbl.name() != "KRPvKBP" || tbl.sides[1] != "KBP" || tbl.size != 6 || tbl.pawns != [2]int{1, 1} || !tbl.unique {
+		t.Errorf("unexpected table: %+v", tbl)
+	}
+	// the leading colour has fewer pawns
+	if tbl, _ = newTable(wdlTable, "", "KPPvKP"); tbl.pawns != [2]int{1, 2} {
+		t.Errorf("unexpected pawns %v", tbl.pawns)
+	}
+	if tbl, _ = newTable(wdlTable, "", "KvKP"); tbl.pawns != [2]int{1, 0} {
+		t.Errorf("unexpected pawns %v", tbl.pawns)
+	}
+	// non-canonical order of pieces
+	tbl, err = newTable(wdlTable, "", "KPQvK")
+	if err != nil || tbl.name() != "KQPvK" {
+		t.Errorf("unexpected name %s (%v)", tbl.name(), err)
+	}
+	for _, name := range []string{"KRK", "RKvK", "KXvK", "K vK", "KQQQQvKQQ"} {
+		if _, err := newTable(wdlTable, "", name); err == nil {
+			t.Errorf("expected error for table name '%s'", name)
+		}
+	}
+}
+
+func TestEncodingPieces(t *testing.T) {
+	dir := t.TempDir()
+	filename := writeTable(t, dir, "KRvK", wdlTable, []int{wKing, wRook, bKing}, [2]int{0, -1}, func(stm, file int) sideData {
+		return sideData{values: func(idx uint64) int { return indexValue(idx + uint64(stm)) }}
+	})
+	tbl := loadTable(t, filename, "KRvK", wdlTable)
+
+	// white king b1, white rook c3, black king h8: (triangle index of b1 * 63 + (c3 - 1)) * 62 + (h8 - 2)
+	if v := probeFen(t, tbl, "7k/8/8/8/8/2R5/8/1K6 w - - 0 1"); v != 17*62+61 {
+		t.Errorf("expected index %d but got %d", 17*62+61, v)
+	}
+
+	// the symmetries of the board must give the same index
+	fens := []string{
+		"8/8/8/3k4/8/8/1R6/K7 w - - 0 1",
+		"8/8/8/4k3/8/8/6R1/7K w - - 0 1", // mirrored files
+		"K7/1R6/8/8/3k4/8/8/8 w - - 0 1", // mirrored ranks
+		"7K/6R1/8/8/4k3/8/8/8 w - - 0 1", // mirrored files and ranks
+		"8/8/8/8/4k3/8/1R6/K7 w - - 0 1", // mirrored on the a1-h8 diagonal
+		"8/8/8/3K4/8/8/1r6/k7 b - - 0 1", // colours swapped
+		"k7/1r6/8/8/3K4/8/8/8 b - - 0 1", // colours swapped and mirrored ranks
+		"8/8/8/4K3/8/8/6r1/7k b - - 0 1", // colours swapped and mirrored files
+	}
+	expected := probeFen(t, tbl, fens[0])
+	for _, fen := range fens[1:] {
+		if v := probeFen(t, tbl, fen); v != expected {
+			t.Errorf("fen %s: expected %d but got %d", fen, expected, v)
+		}
+	}
+	// different position
+	if v := probeFen(t, tbl, "8/8/8/3k4/8/8/1R6/K7 b - - 0 1"); v == expected {
+		t.Errorf("expected different value for black to move")
+	}
+}
+
+func TestEncodingPawns(t *testing.T) {
+	dir := t.TempDir()
+	filename := writeTable(t, dir, "KPvK", wdlTable, []int{wPawn, wKing, bKing}, [2]int{0, -1}, func(stm, file int) sideData {
+		return sideData{values: func(idx uint64) int { return file*1000 + int(idx%1000) }}
+	})
+	tbl := loadTable(t, filename, "KPvK", wdlTable)
+
+	// the table is split by the file of the leading pawn
+	expectedFile := []int{0, 1, 2, 3, 3, 2, 1, 0}
+	for file, pawn := range []string{"P7", "1P6", "2P5", "3P4", "4P3", "5P2", "6P1", "7P"} {
+		fen := "8/8/8/8/8/k7/" + pawn + "/7K w - - 0 1"
+		if v := probeFen(t, tbl, fen); v/1000 != expectedFile[file] {
+			t.Errorf("fen %s: expected value from file %d but got %d", fen, expectedFile[file], v)
+		}
+	}
+
+	fens := []string{
+		"8/8/8/2k5/8/8/3P4/6K1 w - - 0 1",
+		"8/8/8/5k2/8/8/4P3/1K6 w - - 0 1", // mirrored files
+		"6k1/3p4/8/8/2K5/8/8/8 b - - 0 1", // colours swapped and mirrored ranks
+		"1k6/4p3/8/8/5K2/8/8/8 b - - 0 1", // colours swapped and mirrored ranks and files
+	}
+	expectedValue := probeFen(t, tbl, fens[0])
+	for _, fen := range fens[1:] {
+		if v := probeFen(t, tbl, fen); v != expectedValue {
+			t.Errorf("fen %s: expected %d but got %d", fen, expectedValue, v)
+		}
+	}
+	// the ranks cannot be mirrored if there are pawns
+	if v := probeFen(t, tbl, "6K1/3P4/8/8/2k5/8/8/8 w - - 0 1"); v == expectedValue {
+		t.Errorf("expected different value for mirrored ranks")
+	}
+}
+
+func TestEncodingGroups(t *testing.T) {
+	// pawns on both sides, the leading group is placed after the other groups
+	p := newTestPart(t, "KNPvKPP", []int{wPawn, bPawn, bPawn, wKing, bKing, wKnight}, [2]int{3, 0}, 0)
+	if len(p.groups) != 5 || p.groups[1] != 2 || !p.pawnGroup || p.factors[1] != 1 || p.factors[0] != 61*60*choose[2][47] ||
+		p.size != p.factors[0]*pawnCount[1][0]*59 {
+		t.Errorf("unexpected groups %v, factors %v, size %d", p.groups, p.factors, p.size)
+	}
+	// a2, c7, e7, g1, d6, g2
+	squares := []int{8, 50, 52, 6, 43, 14}
+	idx := p.index(append([]int(nil), squares...))
+	if idx >= p.size {
+		t.Errorf("index %d out of range", idx)
+	}
+	// the order of the pawns of the same colour does not matter
+	if i := p.index([]int{8, 52, 50, 6, 43, 14}); i != idx {
+		t.Errorf("expected index %d but got %d", idx, i)
+	}
+	// the files are mirrored if the leading pawn is on the kings side
+	mirrored := make([]int, len(squares))
+	for i, sq := range squares {
+		mirrored[i] = mirrorFile(sq)
+	}
+	if i := p.index(mirrored); i != idx {
+		t.Errorf("expected index %d but got %d", idx, i)
+	}
+	// the black pawns are numbered among the squares a2-h7 not occupied by the white pawn: c7, e7 -> 41, 43
+	if other := p.index([]int{8, 50, 51, 6, 43, 14}); other != idx-choose[2][43]+choose[2][42] {
+		t.Errorf("unexpected index %d for d7 instead of e7", other)
+	}
+}
+
+func TestDTZMap(t *testing.T) {
+	dir := t.TempDir()
+	pieces := []int{wKing, wRook, bKing}
+	writeTable(t, dir, "KRvK", wdlTable, pieces, [2]int{0, -1}, func(stm, file int) sideData {
+		return sideData{single: true, value: int(Win) + 2 - 4*stm}
+	})
+	// the values 0..2 are mapped to 4, 7, 9 moves for wins
+	writeTable(t, dir, "KRvK", dtzTable, pieces, [2]int{0, -1}, func(stm, file int) sideData {
+		return sideData{flags: flagMapped, values: func(idx uint64) int { return int(idx % 3) },
+			maps: [4][]int{{4, 7, 9}, {1}, {2}, {3}}}
+	})
+	tb, err := Open(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tbl := tb.tables[dtzTable]["KRvK"]
+	seen := make(map[int]bool)
+	for _, fen := range []string{"8/8/8/3k4/8/8/8/R3K3 w - - 0 1", "8/8/8/3k4/8/8/8/R2K4 w - - 0 1", "8/8/8/3k4/8/8/8/R1K5 w - - 0 1",
+		"8/8/8/3k4/8/8/R7/4K3 w - - 0 1", "8/8/8/3k4/8/R7/8/4K3 w - - 0 1", "8/8/8/4k3/8/R7/8/4K3 w - - 0 1"} {
+		posn := parseFen(t, fen)
+		dtz, err := tb.ProbeDTZ(posn)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		raw, _, _ := tbl.lookup(newPlacement(posn))
+		if expected := 2*[]int{4, 7, 9}[raw] + 1; dtz != expected {
+			t.Errorf("%s: expected dtz %d but got %d", fen, expected, dtz)
+		}
+		seen[dtz] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected different dtz values")
+	}
+}
@@ -0,0 +1,196 @@
+package tournament
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// Bye is the opponent of a player who does not play in a round
+const Bye = -1
+
+// pairing of two players, by index; Black is Bye if White does not play in the round
+type pairing struct {
+	White, Black int
+}
+
+// bergerRound returns the pairings of one round (from 0) of a round robin with n players according to the Berger tables.
+// n must be even; the last player is the fixed one, who alternates between white and black.
+func bergerRound(n, round int) []pairing {
+	m := n - 1
+	// opponent of the fixed player
+	p := (round * (n / 2)) % m
+	pairings := make([]pairing, 0, n/2)
+	if round%2 == 0 {
+		pairings = append(pairings, pairing{p, n - 1})
+	} else {
+		pairings = append(pairings, pairing{n - 1, p})
+	}
+	for k := 1; k < n/2; k++ {
+		pairings = append(pairings, pairing{(p + k) % m, (p - k + m) % m})
+	}
+	return pairings
+}
+
+// roundRobin returns the pairings of all rounds of a round robin with n players and the given number of cycles.
+// The colours are reversed in every second cycle. For an odd number of players, one player has a bye in each round.
+func roundRobin(n, cycles int) [][]pairing {
+	size := n
+	if n%2 == 1 {
+		size++
+	}
+	var rounds [][]pairing
+	for cycle := 0; cycle < cycles; cycle++ {
+		for r := 0; r < size-1; r++ {
+			var round []pairing
+			for _, p := range bergerRound(size, r) {
+				if cycle%2 == 1 {
+					p.White, p.Black = p.Black, p.White
+				}
+				switch {
+				case p.White == n:
+					round = append(round, pairing{p.Black, Bye})
+				case p.Black == n:
+					round = append(round, pairing{p.White, Bye})
+				default:
+					round = append(round, p)
+				}
+			}
+			// the bye is listed last
+			sort.SliceStable(round, func(i, j int) bool { return round[j].Black == Bye && round[i].Black != Bye })
+			rounds = append(rounds, round)
+		}
+	}
+	return rounds
+}
+
+// swissPlayer is the state of a player used for the Swiss pairings
+type swissPlayer struct {
+	index     int
+	points    float64
+	opponents map[int]bool
+	colours   int  // number of games with white minus number of games with black
+	lastWhite bool // colour of the last game
+	hadBye    bool
+}
+
+// swissRound returns the pairings of the next round of a Swiss tournament. The players are ranked by points (and by index
+// for equal points) and paired as described for pairPlayers, avoiding rematches
+// if at all possible. For an odd number of players the lowest ranked player who has not had a bye gets one.
+// The player who has had white less often (or black in the last game) gets white.
+func swissRound(players []swissPlayer) []pairing {
+	ranked := append([]swissPlayer(nil), players...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].points != ranked[j].points {
+			return ranked[i].points > ranked[j].points
+		}
+		return ranked[i].index < ranked[j].index
+	})
+
+	var bye []pairing
+	if len(ranked)%2 == 1 {
+		i := len(ranked) - 1
+		for i > 0 && ranked[i].hadBye {
+			i--
+		}
+		if ranked[i].hadBye {
+			i = len(ranked) - 1
+		}
+		bye = []pairing{{ranked[i].index, Bye}}
+		ranked = append(ranked[:i:i], ranked[i+1:]...)
+	}
+
+	matched, ok := pairPlayers(ranked, false)
+	if !ok {
+		matched, _ = pairPlayers(ranked, true)
+	}
+	pairings := make([]pairing, 0, len(matched)+1)
+	for _, m := range matched {
+		a, b := m[0], m[1]
+		if b.colours < a.colours || (b.colours == a.colours && !b.lastWhite && a.lastWhite) {
+			a, b = b, a
+		}
+		pairings = append(pairings, pairing{a.index, b.index})
+	}
+	return append(pairings, bye...)
+}
+
+// maxPairingSteps limits the search for pairings without rematches, which can take exponential time if there is none,
+// e.g. late in a tournament when a few players have already played most of the others
+const maxPairingSteps = 20000
+
+// pairPlayers pairs the ranked players recursively. As in the Dutch system, the first player is paired with the player
+// at the top of the lower half of the first player's score group if possible, otherwise with the next possible opponent.
+// Returns false if no pairing without rematches exists or none was found within maxPairingSteps (unless rematches
+// are allowed, in which case opponents who have not been played are still preferred).
+func pairPlayers(ranked []swissPlayer, allowRematches bool) ([][2]swissPlayer, bool) {
+	s := pairingSearch{allowRematches: allowRematches, failed: make(map[string]bool), steps: maxPairingSteps}
+	return s.pair(ranked)
+}
+
+// pairingSearch is the state of pairPlayers
+type pairingSearch struct {
+	allowRematches bool
+	failed         map[string]bool // the sets of players (see playersKey) for which no pairing was found
+	steps          int             // the number of sets of players which may still be examined
+}
+
+func (s *pairingSearch) pair(ranked []swissPlayer) ([][2]swissPlayer, bool) {
+	if len(ranked) == 0 {
+		return nil, true
+	}
+	key := playersKey(ranked)
+	if s.failed[key] || s.steps == 0 {
+		return nil, false
+	}
+	s.steps--
+	first := ranked[0]
+	groupSize := 1
+	for groupSize < len(ranked) && ranked[groupSize].points == first.points {
+		groupSize++
+	}
+	// the candidates: lower half of the score group, upper half of the score group, lower score groups
+	half := groupSize / 2
+	if half == 0 {
+		half = 1
+	}
+	var candidates []int
+	for i := half; i < groupSize; i++ {
+		candidates = append(candidates, i)
+	}
+	for i := 1; i < half; i++ {
+		candidates = append(candidates, i)
+	}
+	for i := groupSize; i < len(ranked); i++ {
+		candidates = append(candidates, i)
+	}
+	if s.allowRematches {
+		// rematches only if there is no other opponent
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return !first.opponents[ranked[candidates[i]].index] && first.opponents[ranked[candidates[j]].index]
+		})
+	}
+
+	for _, i := range candidates {
+		if !s.allowRematches && first.opponents[ranked[i].index] {
+			continue
+		}
+		rest := make([]swissPlayer, 0, len(ranked)-2)
+		rest = append(rest, ranked[1:i]...)
+		rest = append(rest, ranked[i+1:]...)
+		if matched, ok := s.pair(rest); ok {
+			return append([][2]swissPlayer{{first, ranked[i]}}, matched...), true
+		}
+	}
+	s.failed[key] = true
+	return nil, false
+}
+
+// playersKey identifies a set of ranked players. The order of the players in the subsets examined by pairPlayers
+// is always that of the ranking.
+func playersKey(ranked []swissPlayer) string {
+	key := make([]byte, 0, 4*len(ranked))
+	for _, p := range ranked {
+		key = binary.BigEndian.AppendUint32(key, uint32(p.index))
+	}
+	return string(key)
+}
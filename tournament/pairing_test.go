@@ -0,0 +1,150 @@
+package tournament
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBergerTables(t *testing.T) {
+	// the Berger tables for 6 players (numbered from 1)
+	expected := [][]pairing{
+		{{1, 6}, {2, 5}, {3, 4}},
+		{{6, 4}, {5, 3}, {1, 2}},
+		{{2, 6}, {3, 1}, {4, 5}},
+		{{6, 5}, {1, 4}, {2, 3}},
+		{{3, 6}, {4, 2}, {5, 1}},
+	}
+	for r, round := range expected {
+		for i := range round {
+			round[i].White--
+			round[i].Black--
+		}
+		if got := bergerRound(6, r); !reflect.DeepEqual(got, round) {
+			t.Errorf("round %d: expected %v but got %v", r+1, round, got)
+		}
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 5, 8} {
+		rounds := roundRobin(n, 2)
+		games := make(map[pairing]int)
+		byes := make(map[int]int)
+		for _, round := range rounds {
+			playing := make(map[int]bool)
+			for _, p := range round {
+				if playing[p.White] || playing[p.Black] {
+					t.Errorf("%d players: player plays twice in round %v", n, round)
+				}
+				playing[p.White] = true
+				if p.Black == Bye {
+					byes[p.White]++
+					continue
+				}
+				playing[p.Black] = true
+				games[p]++
+			}
+		}
+		// each player plays each other player once with each colour
+		for a := 0; a < n; a++ {
+			for b := 0; b < n; b++ {
+				if a != b && games[pairing{a, b}] != 1 {
+					t.Errorf("%d players: %d-%d played %d times", n, a, b, games[pairing{a, b}])
+				}
+			}
+			if n%2 == 1 && byes[a] != 2 {
+				t.Errorf("%d players: player %d has %d byes", n, a, byes[a])
+			}
+		}
+	}
+}
+
+func newSwissPlayers(n int) []swissPlayer {
+	players := make([]swissPlayer, n)
+	for i := range players {
+		players[i] = swissPlayer{index: i, opponents: make(map[int]bool)}
+	}
+	return players
+}
+
+func TestSwissRound(t *testing.T) {
+	// first round: top half against bottom half, bye for the last player
+	players := newSwissPlayers(5)
+	expected := []pairing{{0, 2}, {1, 3}, {4, Bye}}
+	if got := swissRound(players); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+
+	// 0 and 1 have won against 2 and 3 and player 4 has had a bye, so player 3 (lowest ranked) gets the bye;
+	// 0 plays 1 from the lower half of the score group, 4 floats down to 2, who has had black
+	players[0].points, players[1].points, players[4].points = 1, 1, 1
+	players[4].hadBye = true
+	players[0].opponents[2], players[2].opponents[0] = true, true
+	players[1].opponents[3], players[3].opponents[1] = true, true
+	players[0].colours, players[0].lastWhite = 1, true
+	players[1].colours, players[1].lastWhite = 1, true
+	players[2].colours, players[3].colours = -1, -1
+	expected = []pairing{{0, 1}, {2, 4}, {3, Bye}}
+	if got := swissRound(players); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+
+	// a rematch is unavoidable
+	players = newSwissPlayers(2)
+	players[0].opponents[1], players[1].opponents[0] = true, true
+	players[0].colours, players[0].lastWhite, players[1].colours = 1, true, -1
+	expected = []pairing{{1, 0}}
+	if got := swissRound(players); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
+
+func TestSwissRoundForcedRematches(t *testing.T) {
+	// a late round with an odd number of players: the three lowest ranked players, who have all had a bye, have played
+	// everyone apart from each other. Only two of them can be paired, so the third must play a rematch against one
+	// of the 37 others, which cannot be paired among themselves. Without a bound the search would try all pairings
+	// of the 37 players before giving up.
+	const n = 41
+	players := newSwissPlayers(n)
+	for i := range players {
+		players[i].points = float64(n-i) / 2
+	}
+	for i := n - 3; i < n; i++ {
+		players[i].hadBye = true
+		for j := 0; j < n-3; j++ {
+			players[i].opponents[j], players[j].opponents[i] = true, true
+		}
+	}
+
+	start := time.Now()
+	pairings := swissRound(players)
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("pairing took %s", d)
+	}
+	paired := make(map[int]bool)
+	rematches, byes := 0, 0
+	for _, p := range pairings {
+		if paired[p.White] || paired[p.Black] {
+			t.Fatalf("player paired twice: %v", pairings)
+		}
+		paired[p.White] = true
+		if p.Black == Bye {
+			byes++
+			if players[p.White].hadBye {
+				t.Errorf("player %d has a second bye", p.White)
+			}
+			continue
+		}
+		paired[p.Black] = true
+		if players[p.White].opponents[p.Black] {
+			rematches++
+		}
+	}
+	if len(paired) != n || byes != 1 {
+		t.Errorf("expected all players to be paired and one bye: %v", pairings)
+	}
+	if rematches != 1 {
+		t.Errorf("expected one rematch but got %d: %v", rematches, pairings)
+	}
+}
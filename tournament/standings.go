@@ -0,0 +1,179 @@
+package tournament
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Standing is the score of a player
+type Standing struct {
+	Rank            int // players with the same points and tie-breaks share a rank
+	Player          int // index of the player
+	Name            string
+	Points          float64
+	Games           int     // number of games played, without byes
+	SonnebornBerger float64 // sum of the points of the opponents beaten plus half the points of the opponents drawn
+	Buchholz        float64 // sum of the points of the opponents
+}
+
+// Standings returns the standings after the games played so far, ordered by points and then by the tie-breaks:
+// Sonneborn-Berger and Buchholz for a round robin, Buchholz and Sonneborn-Berger for a Swiss tournament.
+// Byes count as a win but are not included in the tie-breaks.
+func (t *Tournament) Standings() []Standing {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	standings := make([]Standing, len(t.Players))
+	for i, name := range t.Players {
+		standings[i] = Standing{Player: i, Name: name}
+	}
+	for _, g := range t.Games {
+		white, black := points(g.Result)
+		standings[g.White].Points += white
+		if !g.IsBye() && g.Played() {
+			standings[g.Black].Points += black
+			standings[g.White].Games++
+			standings[g.Black].Games++
+		}
+	}
+	for _, g := range t.Games {
+		if g.IsBye() || !g.Played() {
+			continue
+		}
+		white, black := points(g.Result)
+		standings[g.White].Buchholz += standings[g.Black].Points
+		standings[g.Black].Buchholz += standings[g.White].Points
+		standings[g.White].SonnebornBerger += white * standings[g.Black].Points
+		standings[g.Black].SonnebornBerger += black * standings[g.White].Points
+	}
+
+	tieBreaks := func(s Standing) []float64 {
+		if t.Kind == Swiss {
+			return []float64{s.Points, s.Buchholz, s.SonnebornBerger}
+		}
+		return []float64{s.Points, s.SonnebornBerger, s.Buchholz}
+	}
+	// compare returns a positive number if a is ranked higher than b, 0 if they are tied
+	compare := func(a, b Standing) float64 {
+		ta, tb := tieBreaks(a), tieBreaks(b)
+		for i := range ta {
+			if ta[i] != tb[i] {
+				return ta[i] - tb[i]
+			}
+		}
+		return 0
+	}
+	sort.SliceStable(standings, func(i, j int) bool { return compare(standings[i], standings[j]) > 0 })
+	for i := range standings {
+		standings[i].Rank = i + 1
+		if i > 0 && compare(standings[i-1], standings[i]) == 0 {
+			standings[i].Rank = standings[i-1].Rank
+		}
+	}
+	return standings
+}
+
+// Crosstable writes the standings as a table. For a round robin there is a column for each opponent (by rank) with the
+// results against this opponent: 1, = or 0, '*' on the diagonal, '.' for games not played yet. For a Swiss tournament
+// there is a column for each round with the result, the rank of the opponent and the colour, e.g. "+3w"; a bye is
+// shown as "+bye".
+func (t *Tournament) Crosstable(w io.Writer) error {
+	standings := t.Standings()
+	rankOf := make(map[int]int, len(standings))
+	nameWidth := len("Name")
+	for i, s := range standings {
+		rankOf[s.Player] = i
+		if len(s.Name) > nameWidth {
+			nameWidth = len(s.Name)
+		}
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var columns int
+	cells := make([][]string, len(standings))
+	if t.Kind == RoundRobin {
+		columns = len(standings)
+		for i := range cells {
+			cells[i] = make([]string, columns)
+			cells[i][i] = strings.Repeat("*", t.Rounds/(len(t.Players)-1+len(t.Players)%2))
+		}
+		for _, g := range t.Games {
+			if g.IsBye() {
+				continue
+			}
+			white, black := rankOf[g.White], rankOf[g.Black]
+			cells[white][black] += resultSymbol(g, true)
+			cells[black][white] += resultSymbol(g, false)
+		}
+	} else {
+		columns = t.Rounds
+		for i := range cells {
+			cells[i] = make([]string, columns)
+		}
+		for _, g := range t.Games {
+			white := rankOf[g.White]
+			if g.IsBye() {
+				cells[white][g.Round-1] = "+bye"
+				continue
+			}
+			black := rankOf[g.Black]
+			cells[white][g.Round-1] = swissSymbol(resultSymbol(g, true)) + fmt.Sprintf("%dw", black+1)
+			cells[black][g.Round-1] = swissSymbol(resultSymbol(g, false)) + fmt.Sprintf("%db", white+1)
+		}
+	}
+
+	cellWidth := 2
+	for _, row := range cells {
+		for _, cell := range row {
+			if len(cell) > cellWidth {
+				cellWidth = len(cell)
+			}
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%4s  %-*s %6s", "Rank", nameWidth, "Name", "Points")
+	for i := 0; i < columns; i++ {
+		fmt.Fprintf(&sb, " %*d", cellWidth, i+1)
+	}
+	fmt.Fprintf(&sb, " %6s %6s\n", "SB", "Buch")
+	for i, s := range standings {
+		fmt.Fprintf(&sb, "%4d  %-*s %6.1f", s.Rank, nameWidth, s.Name, s.Points)
+		for _, cell := range cells[i] {
+			fmt.Fprintf(&sb, " %*s", cellWidth, cell)
+		}
+		fmt.Fprintf(&sb, " %6.2f %6.1f\n", s.SonnebornBerger, s.Buchholz)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// resultSymbol returns the result of the game from the view of white or black: 1, = or 0; '.' if not played yet
+func resultSymbol(g Game, white bool) string {
+	if !g.Played() {
+		return "."
+	}
+	w, b := points(g.Result)
+	if !white {
+		w = b
+	}
+	switch w {
+	case 1:
+		return "1"
+	case 0:
+		return "0"
+	}
+	return "="
+}
+
+// swissSymbol converts the result symbol to the notation of the Swiss crosstable: +, = or -
+func swissSymbol(symbol string) string {
+	switch symbol {
+	case "1":
+		return "+"
+	case "0":
+		return "-"
+	}
+	return symbol
+}
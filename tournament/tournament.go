@@ -0,0 +1,311 @@
+// Package tournament runs round-robin and Swiss tournaments between engines (or other players).
+// The games themselves are played by a function supplied by the caller, e.g. using package engine.
+// The state of a tournament can be saved after each game, so that an interrupted tournament can be resumed.
+package tournament
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rjo67/chess/pgn"
+)
+
+// Kind is the pairing system of a tournament
+type Kind string
+
+// the pairing systems
+const (
+	RoundRobin Kind = "round-robin" // every player plays every other player once per cycle, paired according to the Berger tables
+	Swiss      Kind = "swiss"       // players with similar scores are paired in each round, without rematches if possible
+)
+
+// Game is a scheduled game of a tournament
+type Game struct {
+	Round  int       `json:"round"`  // from 1
+	Board  int       `json:"board"`  // from 1
+	White  int       `json:"white"`  // index of the player
+	Black  int       `json:"black"`  // index of the player, or Bye
+	Result string    `json:"result"` // pgn.WhiteWins, pgn.BlackWins or pgn.Draw; empty if the game has not been played yet
+	Game   *pgn.Game `json:"game,omitempty"`
+}
+
+// IsBye returns true if the game is a bye, which counts as a win for White
+func (g Game) IsBye() bool {
+	return g.Black == Bye
+}
+
+// Played returns true if the game has been played (or is a bye)
+func (g Game) Played() bool {
+	return g.Result != ""
+}
+
+// PlayFunc plays a game of the tournament between the given players and returns it, with the result set.
+// The tags Event, Round, White, Black and Result are set by the tournament.
+// If the context is done, the game should be abandoned and the context's error returned.
+type PlayFunc func(ctx context.Context, g Game, white, black string) (*pgn.Game, error)
+
+// Tournament stores the players, the schedule and the results of a tournament
+type Tournament struct {
+	Name    string   `json:"name"`
+	Kind    Kind     `json:"kind"`
+	Players []string `json:"players"`
+	Rounds  int      `json:"rounds"` // number of rounds; for a round robin this is a multiple of the rounds of one cycle
+	Games   []Game   `json:"games"`  // the games scheduled so far, ordered by round and board
+
+	mutex sync.Mutex // protects Games while games are played concurrently
+}
+
+// New creates a tournament. For a round robin, rounds is the number of cycles (2 for a double round robin) and all games
+// are scheduled at once. For a Swiss tournament the games of each round are scheduled when the previous round has finished.
+func New(name string, kind Kind, players []string, rounds int) (*Tournament, error) {
+	if len(players) < 2 {
+		return nil, fmt.Errorf("at least two players are required")
+	}
+	if rounds < 1 {
+		return nil, fmt.Errorf("invalid number of rounds %d", rounds)
+	}
+	t := &Tournament{Name: name, Kind: kind, Players: append([]string(nil), players...)}
+	switch kind {
+	case RoundRobin:
+		for r, round := range roundRobin(len(players), rounds) {
+			t.addRound(r+1, round)
+		}
+		t.Rounds = t.Games[len(t.Games)-1].Round
+	case Swiss:
+		if rounds >= len(players) {
+			return nil, fmt.Errorf("too many rounds for %d players", len(players))
+		}
+		t.Rounds = rounds
+		t.addRound(1, swissRound(t.swissPlayers()))
+	default:
+		return nil, fmt.Errorf("unknown tournament kind '%s'", kind)
+	}
+	return t, nil
+}
+
+// addRound schedules the games of a round, byes are scored at once
+func (t *Tournament) addRound(round int, pairings []pairing) {
+	for i, p := range pairings {
+		g := Game{Round: round, Board: i + 1, White: p.White, Black: p.Black}
+		if g.IsBye() {
+			g.Result = pgn.WhiteWins
+		}
+		t.Games = append(t.Games, g)
+	}
+}
+
+// swissPlayers returns the state of the players after the games played so far
+func (t *Tournament) swissPlayers() []swissPlayer {
+	players := make([]swissPlayer, len(t.Players))
+	for i := range players {
+		players[i] = swissPlayer{index: i, opponents: make(map[int]bool)}
+	}
+	for _, g := range t.Games {
+		white, black := points(g.Result)
+		players[g.White].points += white
+		if g.IsBye() {
+			players[g.White].hadBye = true
+			continue
+		}
+		players[g.Black].points += black
+		players[g.White].opponents[g.Black] = true
+		players[g.Black].opponents[g.White] = true
+		players[g.White].colours++
+		players[g.Black].colours--
+		players[g.White].lastWhite = true
+		players[g.Black].lastWhite = false
+	}
+	return players
+}
+
+// points returns the points of white and black for the result
+func points(result string) (float64, float64) {
+	switch result {
+	case pgn.WhiteWins:
+		return 1, 0
+	case pgn.BlackWins:
+		return 0, 1
+	case pgn.Draw:
+		return 0.5, 0.5
+	}
+	return 0, 0
+}
+
+// Finished returns true if all games of all rounds have been played
+func (t *Tournament) Finished() bool {
+	for _, g := range t.Games {
+		if !g.Played() {
+			return false
+		}
+	}
+	return len(t.Games) > 0 && t.Games[len(t.Games)-1].Round == t.Rounds
+}
+
+// Run plays the games which have not been played yet, up to the given number at the same time, round by round.
+// If stateFile is not empty, the tournament is saved to this file after each game, see Load.
+// Returns when all games have been played, the context is done, or a game returns an error;
+// the games which have finished are kept in any case.
+func (t *Tournament) Run(ctx context.Context, play PlayFunc, concurrency int, stateFile string) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	for !t.Finished() {
+		if err := t.runRound(ctx, play, concurrency, stateFile); err != nil {
+			return err
+		}
+		if last := t.Games[len(t.Games)-1].Round; t.Kind == Swiss && last < t.Rounds {
+			t.addRound(last+1, swissRound(t.swissPlayers()))
+			if err := t.Save(stateFile); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runRound plays the games of the first round which has not been completed
+func (t *Tournament) runRound(ctx context.Context, play PlayFunc, concurrency int, stateFile string) error {
+	round := 0
+	var pending []int
+	for i, g := range t.Games {
+		if !g.Played() && (round == 0 || g.Round == round) {
+			round = g.Round
+			pending = append(pending, i)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan int)
+	errs := make(chan error, len(pending))
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := t.playGame(ctx, play, i, stateFile); err != nil {
+					errs <- err
+					cancel()
+				}
+			}
+		}()
+	}
+	for _, i := range pending {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	if err := <-errs; err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// playGame plays the game with the given index and saves the tournament
+func (t *Tournament) playGame(ctx context.Context, play PlayFunc, index int, stateFile string) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	t.mutex.Lock()
+	g := t.Games[index]
+	t.mutex.Unlock()
+	game, err := play(ctx, g, t.Players[g.White], t.Players[g.Black])
+	if err != nil {
+		return err
+	}
+	if game == nil {
+		return fmt.Errorf("round %d, board %d: no game returned", g.Round, g.Board)
+	}
+	if game.Result != pgn.WhiteWins && game.Result != pgn.BlackWins && game.Result != pgn.Draw {
+		return fmt.Errorf("round %d, board %d: invalid result '%s'", g.Round, g.Board, game.Result)
+	}
+	if game.Tags == nil {
+		game.Tags = make(map[string]string)
+	}
+	game.Tags["Event"] = t.Name
+	game.Tags["Round"] = fmt.Sprintf("%d.%d", g.Round, g.Board)
+	game.Tags["White"], game.Tags["Black"] = t.Players[g.White], t.Players[g.Black]
+	game.Tags["Result"] = game.Result
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.Games[index].Result, t.Games[index].Game = game.Result, game
+	return t.saveLocked(stateFile)
+}
+
+// Save writes the tournament to the file (if not empty), see Load
+func (t *Tournament) Save(filename string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.saveLocked(filename)
+}
+
+// saveLocked writes the tournament as JSON to a temporary file which then replaces the file, so that the file
+// is always complete even if the program is interrupted
+func (t *Tournament) saveLocked(filename string) error {
+	if filename == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Load reads a tournament saved by Save or Run, e.g. to resume it with Run
+func Load(filename string) (*Tournament, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tournament{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	if t.Kind != RoundRobin && t.Kind != Swiss {
+		return nil, fmt.Errorf("%s: unknown tournament kind '%s'", filename, t.Kind)
+	}
+	for _, g := range t.Games {
+		if g.White < 0 || g.White >= len(t.Players) || g.Black < Bye || g.Black >= len(t.Players) {
+			return nil, fmt.Errorf("%s: invalid player in round %d, board %d", filename, g.Round, g.Board)
+		}
+	}
+	return t, nil
+}
+
+// WritePGN writes all games played so far (without byes) in the order of the rounds and boards
+func (t *Tournament) WritePGN(w io.Writer) error {
+	for _, g := range t.Games {
+		if g.Game == nil {
+			continue
+		}
+		if err := g.Game.Write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package tournament
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rjo67/chess/pgn"
+)
+
+// playByName plays games where the player whose name comes first in the alphabet wins, and "D" always draws
+func playByName(ctx context.Context, g Game, white, black string) (*pgn.Game, error) {
+	game := &pgn.Game{Moves: []string{"e4", "e5"}}
+	switch {
+	case white == "D" || black == "D":
+		game.Result = pgn.Draw
+	case white < black:
+		game.Result = pgn.WhiteWins
+	default:
+		game.Result = pgn.BlackWins
+	}
+	return game, nil
+}
+
+func TestRoundRobinTournament(t *testing.T) {
+	tournament, err := New("Test", RoundRobin, []string{"C", "A", "D", "B"}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tournament.Rounds != 6 || len(tournament.Games) != 12 {
+		t.Fatalf("expected 6 rounds and 12 games but got %d and %d", tournament.Rounds, len(tournament.Games))
+	}
+	if err := tournament.Run(context.Background(), playByName, 3, ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !tournament.Finished() {
+		t.Errorf("tournament not finished")
+	}
+
+	// A: 2+2+1 = 5, D: 3 draws = 3, B: 0+2+1 = 3, C: 0+0+1 = 1; D is ranked above B by Sonneborn-Berger
+	expected := []Standing{
+		{Rank: 1, Player: 1, Name: "A", Points: 5, Games: 6, SonnebornBerger: 2*3 + 2*1 + 2*0.5*3, Buchholz: 2 * (3 + 1 + 3)},
+		{Rank: 2, Player: 2, Name: "D", Points: 3, Games: 6, SonnebornBerger: 2 * 0.5 * (5 + 3 + 1), Buchholz: 2 * (5 + 3 + 1)},
+		{Rank: 3, Player: 3, Name: "B", Points: 3, Games: 6, SonnebornBerger: 2*1 + 2*0.5*3, Buchholz: 2 * (5 + 1 + 3)},
+		{Rank: 4, Player: 0, Name: "C", Points: 1, Games: 6, SonnebornBerger: 2 * 0.5 * 3, Buchholz: 2 * (5 + 3 + 3)},
+	}
+	if got := tournament.Standings(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected standings\n%v but got\n%v", expected, got)
+	}
+
+	var sb strings.Builder
+	if err := tournament.Crosstable(&sb); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expectedTable := `Rank  Name Points  1  2  3  4     SB   Buch
+   1  A       5.0 ** == 11 11  11.00   14.0
+   2  D       3.0 == ** == ==   9.00   18.0
+   3  B       3.0 00 == ** 11   5.00   18.0
+   4  C       1.0 00 == 00 **   3.00   22.0
+`
+	if sb.String() != expectedTable {
+		t.Errorf("expected crosstable\n%s\nbut got\n%s", expectedTable, sb.String())
+	}
+
+	sb.Reset()
+	if err := tournament.WritePGN(&sb); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	games, err := pgn.ReadAll(strings.NewReader(sb.String()))
+	if err != nil || len(games) != 12 {
+		t.Fatalf("expected 12 games but got %d, error %v", len(games), err)
+	}
+	if games[0].Tags["Event"] != "Test" || games[0].Tags["Round"] != "1.1" || games[11].Tags["Round"] != "6.2" {
+		t.Errorf("unexpected tags %v, %v", games[0].Tags, games[11].Tags)
+	}
+}
+
+func TestSwissTournament(t *testing.T) {
+	tournament, err := New("Swiss", Swiss, []string{"E", "A", "C", "B", "D"}, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := tournament.Run(context.Background(), playByName, 2, ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// 3 rounds with 2 games and a bye each
+	if len(tournament.Games) != 9 || !tournament.Finished() {
+		t.Fatalf("unexpected games %v", tournament.Games)
+	}
+	met := make(map[[2]int]bool)
+	byes := make(map[int]bool)
+	for _, g := range tournament.Games {
+		if g.IsBye() {
+			if byes[g.White] {
+				t.Errorf("second bye for player %d", g.White)
+			}
+			byes[g.White] = true
+			continue
+		}
+		key := [2]int{g.White, g.Black}
+		if g.Black < g.White {
+			key = [2]int{g.Black, g.White}
+		}
+		if met[key] {
+			t.Errorf("rematch %v", key)
+		}
+		met[key] = true
+	}
+	standings := tournament.Standings()
+	// A wins all games apart from the draw against D
+	if standings[0].Name != "A" || standings[0].Points != 2.5 {
+		t.Errorf("unexpected standings %v", standings)
+	}
+	var sb strings.Builder
+	if err := tournament.Crosstable(&sb); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(sb.String(), "+bye") || strings.Count(sb.String(), "\n") != 6 {
+		t.Errorf("unexpected crosstable\n%s", sb.String())
+	}
+}
+
+func TestResume(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "tournament.json")
+	tournament, err := New("Resume", Swiss, []string{"A", "B", "C", "D"}, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// interrupt the tournament in the second round
+	played := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupted := func(ctx context.Context, g Game, white, black string) (*pgn.Game, error) {
+		if played == 3 {
+			cancel()
+			return nil, ctx.Err()
+		}
+		played++
+		return playByName(ctx, g, white, black)
+	}
+	if err := tournament.Run(ctx, interrupted, 1, stateFile); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation but got %v", err)
+	}
+
+	resumed, err := Load(stateFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(resumed.Games) != 4 || !resumed.Games[2].Played() || resumed.Games[3].Played() {
+		t.Fatalf("unexpected games after loading: %v", resumed.Games)
+	}
+	if err := resumed.Run(context.Background(), playByName, 1, stateFile); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if played != 3 || len(resumed.Games) != 6 || !resumed.Finished() {
+		t.Errorf("unexpected games %v", resumed.Games)
+	}
+
+	// the result is the same as without interruption
+	uninterrupted, _ := New("Resume", Swiss, []string{"A", "B", "C", "D"}, 3)
+	if err := uninterrupted.Run(context.Background(), playByName, 1, ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(resumed.Standings(), uninterrupted.Standings()) {
+		t.Errorf("expected %v but got %v", uninterrupted.Standings(), resumed.Standings())
+	}
+	reloaded, err := Load(stateFile)
+	if err != nil || !reloaded.Finished() {
+		t.Errorf("state file not updated: %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, d := range []struct {
+		kind    Kind
+		players []string
+		rounds  int
+	}{
+		{RoundRobin, []string{"A"}, 1},
+		{RoundRobin, []string{"A", "B"}, 0},
+		{Swiss, []string{"A", "B", "C"}, 3},
+		{"knockout", []string{"A", "B"}, 1},
+	} {
+		if _, err := New("x", d.kind, d.players, d.rounds); err == nil {
+			t.Errorf("expected error for %v", d)
+		}
+	}
+	tournament, _ := New("x", RoundRobin, []string{"A", "B"}, 1)
+	invalid := func(ctx context.Context, g Game, white, black string) (*pgn.Game, error) {
+		return &pgn.Game{Result: pgn.Unfinished}, nil
+	}
+	if err := tournament.Run(context.Background(), invalid, 1, ""); err == nil {
+		t.Errorf("expected error for invalid result")
+	}
+	tournament, _ = New("x", RoundRobin, []string{"A", "B"}, 1)
+	noGame := func(ctx context.Context, g Game, white, black string) (*pgn.Game, error) {
+		return nil, nil
+	}
+	if err := tournament.Run(context.Background(), noGame, 1, ""); err == nil {
+		t.Errorf("expected error if no game is returned")
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Errorf("expected error for missing file")
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/search"
+)
+
+// uci implements the Universal Chess Interface, see https://www.shredderchess.com/download/div/uci.zip
+type uci struct {
+	e        *engine
+	chess960 bool // option UCI_Chess960
+}
+
+// handle executes one command, returns false after 'quit'
+func (u *uci) handle(line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "uci":
+		u.e.send("id name go-chess")
+		u.e.send("id author rjo67")
+		u.e.send("option name UCI_Chess960 type check default false")
+		u.e.send("uciok")
+	case "isready":
+		u.e.send("readyok")
+	case "setoption":
+		u.setOption(fields[1:])
+	case "ucinewgame":
+		u.e.stopSearch(false)
+		u.e.posn = position.StartPosition()
+	case "position":
+		u.e.stopSearch(false)
+		if err := u.setPosition(fields[1:]); err != nil {
+			u.e.send("info string %s", err)
+		}
+	case "go":
+		u.e.stopSearch(false)
+		if err := u.goSearch(fields[1:]); err != nil {
+			u.e.send("info string %s", err)
+		}
+	case "stop":
+		u.e.stopSearch(true)
+	case "quit":
+		u.e.stopSearch(false)
+		return false
+	case "debug", "register", "ponderhit":
+		// not supported, ignored
+	default:
+		u.e.send("info string unknown command '%s'", fields[0])
+	}
+	return true
+}
+
+// setOption handles 'setoption name <id> [value <x>]'
+func (u *uci) setOption(args []string) {
+	if len(args) == 4 && args[0] == "name" && args[1] == "UCI_Chess960" && args[2] == "value" {
+		u.chess960 = args[3] == "true"
+		u.e.stopSearch(false)
+		u.e.posn.SetChess960(u.chess960)
+		return
+	}
+	u.e.send("info string unknown option '%s'", strings.Join(args, " "))
+}
+
+// setPosition handles 'position [fen <fenstring> | startpos] moves <move1> ... <movei>'.
+// If the position or one of the moves is invalid, the current position is unchanged.
+func (u *uci) setPosition(args []string) error {
+	var posn position.Position
+	switch {
+	case len(args) > 0 && args[0] == "startpos":
+		posn = position.StartPosition()
+		args = args[1:]
+	case len(args) > 0 && args[0] == "fen":
+		end := len(args)
+		for i, arg := range args {
+			if arg == "moves" {
+				end = i
+				break
+			}
+		}
+		var err error
+		if posn, err = position.ParseFen(strings.Join(args[1:end], " ")); err != nil {
+			return err
+		}
+		args = args[end:]
+	default:
+		return fmt.Errorf("expected 'startpos' or 'fen'")
+	}
+	posn.SetChess960(posn.Chess960() || u.chess960)
+	if len(args) > 0 {
+		if args[0] != "moves" {
+			return fmt.Errorf("expected 'moves' but got '%s'", args[0])
+		}
+		for _, str := range args[1:] {
+			m, err := posn.ParseUci(str)
+			if err != nil {
+				return err
+			}
+			posn.MakeMove(m)
+		}
+	}
+	u.e.posn = posn
+	return nil
+}
+
+// goSearch handles 'go' with the parameters depth, movetime, wtime, btime, winc, binc, movestogo and infinite.
+// Other parameters (e.g. searchmoves, ponder) are ignored.
+func (u *uci) goSearch(args []string) error {
+	var l limits
+	var clock, increment [2]time.Duration
+	movesToGo := 0
+	timed := false
+	for i := 0; i < len(args); i++ {
+		if args[i] == "infinite" {
+			l.infinite = true
+			continue
+		}
+		var value int
+		switch args[i] {
+		case "depth", "movetime", "wtime", "btime", "winc", "binc", "movestogo":
+			if i+1 == len(args) {
+				return fmt.Errorf("missing value for '%s'", args[i])
+			}
+			var err error
+			if value, err = strconv.Atoi(args[i+1]); err != nil {
+				return fmt.Errorf("invalid value for '%s': %s", args[i], args[i+1])
+			}
+		default:
+			continue
+		}
+		ms := time.Duration(value) * time.Millisecond
+		switch args[i] {
+		case "depth":
+			l.depth = value
+		case "movetime":
+			l.moveTime = ms
+		case "wtime":
+			clock[colour.White], timed = ms, true
+		case "btime":
+			clock[colour.Black], timed = ms, true
+		case "winc":
+			increment[colour.White] = ms
+		case "binc":
+			increment[colour.Black] = ms
+		case "movestogo":
+			movesToGo = value
+		}
+		i++
+	}
+	if l.moveTime == 0 && timed {
+		col := u.e.posn.ActiveColour()
+		l.moveTime = allocateTime(clock[col], increment[col], movesToGo)
+		// at least one iteration is always searched, even if the time is nearly up
+		if l.moveTime <= 0 {
+			l.moveTime = time.Millisecond
+		}
+	}
+	if l.moveTime == 0 && l.depth == 0 && !l.infinite {
+		l.moveTime = defaultMoveTime
+	}
+
+	start := time.Now()
+	u.e.startSearch(l, func(result search.Result) {
+		elapsed := time.Since(start)
+		score := fmt.Sprintf("cp %d", result.Score)
+		if result.IsMate() {
+			score = fmt.Sprintf("mate %d", result.MateIn())
+		}
+		u.e.send("info depth %d score %s nodes %d time %d pv %s", result.Depth, score, result.Nodes, elapsed.Milliseconds(),
+			pvString(u.e.posn, result.PV, position.Position.UciString))
+	}, func(result search.Result) {
+		if len(result.PV) == 0 {
+			// no legal moves
+			u.e.send("bestmove 0000")
+			return
+		}
+		u.e.send("bestmove %s", u.e.posn.UciString(result.Move))
+	})
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer collects the output of the engine, which is also written by the search goroutine
+type syncBuffer struct {
+	buf     bytes.Buffer
+	pending []string // lines returned to the buffer by waitFor
+	mutex   sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+// lines returns the lines written so far and clears the buffer
+func (b *syncBuffer) lines() []string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	lines := b.pending
+	b.pending = nil
+	if str := strings.TrimSpace(b.buf.String()); str != "" {
+		lines = append(lines, strings.Split(str, "\n")...)
+	}
+	b.buf.Reset()
+	return lines
+}
+
+// waitFor waits until a line starting with prefix has been written and returns it, together with the lines before it.
+// The lines after it are kept for the next call.
+func (b *syncBuffer) waitFor(t *testing.T, prefix string) (string, []string) {
+	t.Helper()
+	var seen []string
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		lines := b.lines()
+		for i, line := range lines {
+			if strings.HasPrefix(line, prefix) {
+				b.mutex.Lock()
+				b.pending = append(lines[i+1:len(lines):len(lines)], b.pending...)
+				b.mutex.Unlock()
+				return line, append(seen, lines[:i]...)
+			}
+		}
+		seen = append(seen, lines...)
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("no line starting with '%s' received, got %v", prefix, seen)
+	return "", nil
+}
+
+func newTestUci() (*uci, *syncBuffer) {
+	out := &syncBuffer{}
+	return &uci{e: newEngine(out)}, out
+}
+
+// send passes the commands to the protocol
+func send(p protocol, commands ...string) {
+	for _, cmd := range commands {
+		p.handle(cmd)
+	}
+}
+
+func TestUciHandshake(t *testing.T) {
+	u, out := newTestUci()
+	send(u, "uci", "isready")
+	expected := []string{"id name go-chess", "id author rjo67", "option name UCI_Chess960 type check default false", "uciok", "readyok"}
+	if got := out.lines(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+	send(u, "foo")
+	if got := out.lines(); len(got) != 1 || got[0] != "info string unknown command 'foo'" {
+		t.Errorf("unexpected reply %v", got)
+	}
+	if u.handle("quit") {
+		t.Errorf("expected false after quit")
+	}
+}
+
+func TestUciPosition(t *testing.T) {
+	u, out := newTestUci()
+	send(u, "position startpos moves e2e4 e7e5 g1f3")
+	if fen := u.e.posn.Fen(); fen != "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2" {
+		t.Errorf("unexpected position %s", fen)
+	}
+	send(u, "position fen 7k/8/8/8/8/8/8/R6K w - - 0 1 moves a1a8")
+	if fen := u.e.posn.Fen(); fen != "R6k/8/8/8/8/8/8/7K b - - 1 1" {
+		t.Errorf("unexpected position %s", fen)
+	}
+	// an illegal move leaves the position unchanged
+	send(u, "position startpos moves e2e5")
+	if got := out.lines(); len(got) != 1 || !strings.HasPrefix(got[0], "info string") {
+		t.Errorf("expected an error but got %v", got)
+	}
+	if fen := u.e.posn.Fen(); fen != "R6k/8/8/8/8/8/8/7K b - - 1 1" {
+		t.Errorf("position changed to %s", fen)
+	}
+}
+
+func TestUciGoDepth(t *testing.T) {
+	u, out := newTestUci()
+	send(u, "position fen 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "go depth 3")
+	line, infos := out.waitFor(t, "bestmove")
+	if line != "bestmove a1a8" {
+		t.Errorf("expected bestmove a1a8 but got '%s'", line)
+	}
+	if len(infos) == 0 || !strings.HasPrefix(infos[0], "info depth 1 score mate 1 ") || !strings.HasSuffix(infos[0], "pv a1a8") {
+		t.Errorf("unexpected info lines %v", infos)
+	}
+
+	send(u, "position startpos moves e2e4", "go depth 2")
+	if line, _ := out.waitFor(t, "bestmove"); line == "bestmove 0000" {
+		t.Errorf("expected a move but got '%s'", line)
+	}
+	// no legal moves
+	send(u, "position fen 7k/6Q1/6K1/8/8/8/8/8 b - - 0 1", "go depth 2")
+	if line, _ := out.waitFor(t, "bestmove"); line != "bestmove 0000" {
+		t.Errorf("expected bestmove 0000 but got '%s'", line)
+	}
+}
+
+func TestUciStop(t *testing.T) {
+	u, out := newTestUci()
+	send(u, "position startpos", "go infinite")
+	// the search is not stopped by itself
+	out.waitFor(t, "info depth 2")
+	time.Sleep(20 * time.Millisecond)
+	for _, line := range out.lines() {
+		if strings.HasPrefix(line, "bestmove") {
+			t.Fatalf("unexpected bestmove before stop")
+		}
+	}
+	send(u, "stop")
+	// stop waits for the search to finish
+	if got := out.lines(); len(got) == 0 || !strings.HasPrefix(got[len(got)-1], "bestmove ") {
+		t.Errorf("expected bestmove after stop but got %v", got)
+	}
+}
+
+func TestUciSetOptionDuringSearch(t *testing.T) {
+	u, out := newTestUci()
+	// few pieces, so that the iterations finish (and are reported) quickly
+	send(u, "position fen 7k/8/8/8/8/8/8/R6K w - - 0 1", "go infinite")
+	out.waitFor(t, "info depth 1")
+	// the search must be stopped before the position is changed, otherwise the race detector finds the
+	// access to the position by the report of the next iteration
+	send(u, "setoption name UCI_Chess960 value true")
+	if !u.chess960 || !u.e.posn.Chess960() {
+		t.Errorf("Chess960 not set")
+	}
+	time.Sleep(50 * time.Millisecond)
+	send(u, "stop")
+	send(u, "setoption name Hash value 16")
+	if got := out.lines(); len(got) == 0 || got[len(got)-1] != "info string unknown option 'name Hash value 16'" {
+		t.Errorf("unexpected reply %v", got)
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rjo67/chess/move"
+	"github.com/rjo67/chess/piece/colour"
+	"github.com/rjo67/chess/position"
+	"github.com/rjo67/chess/search"
+)
+
+// the features sent in reply to 'protover'
+const xboardFeatures = `feature myname="go-chess" ping=1 setboard=1 usermove=1 san=0 playother=0 sigint=0 sigterm=0 analyze=1 colors=0 reuse=1 done=1`
+
+// commands which are accepted but have no effect
+var xboardIgnored = map[string]bool{
+	"xboard": true, "accepted": true, "rejected": true, "random": true, "hard": true, "easy": true, "computer": true,
+	"name": true, "rating": true, "result": true, "draw": true, "ics": true, ".": true,
+}
+
+// xboard implements the Chess Engine Communication Protocol (version 2) as used by XBoard and WinBoard,
+// see https://www.gnu.org/software/xboard/engine-intf.html
+type xboard struct {
+	e            *engine
+	moves        []move.Move // the moves made since 'new' or 'setboard', for 'undo'
+	force        bool        // in force mode the engine only records the moves, it does not play
+	engineColour colour.Colour
+	post         bool // send thinking output
+	analyzing    bool // in analyze mode the engine searches the current position until 'exit'
+
+	// time control
+	depth         int           // 'sd', 0: no limit
+	moveTime      time.Duration // 'st', 0: use the clock
+	movesPerLevel int           // 'level', 0: the whole game
+	increment     time.Duration // 'level'
+	clock         time.Duration // 'time', the remaining time of the engine; 0 if not known
+}
+
+func newXboard(e *engine) *xboard {
+	return &xboard{e: e, engineColour: colour.Black}
+}
+
+// handle executes one command, returns false after 'quit'
+func (x *xboard) handle(line string) bool {
+	fields := strings.Fields(line)
+	cmd, arg := fields[0], strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+	switch cmd {
+	case "protover":
+		x.e.send(xboardFeatures)
+	case "new":
+		x.e.stopSearch(false)
+		x.e.posn = position.StartPosition()
+		x.moves = nil
+		x.force, x.engineColour, x.depth = false, colour.Black, 0
+		x.analyse()
+	case "force":
+		x.e.stopSearch(false)
+		x.force = true
+	case "go":
+		x.e.stopSearch(false)
+		x.force, x.engineColour = false, x.e.posn.ActiveColour()
+		x.think()
+	case "usermove":
+		x.userMove(arg)
+	case "setboard":
+		x.e.stopSearch(false)
+		posn, err := position.ParseFen(arg)
+		if err != nil {
+			x.e.send("tellusererror Illegal position")
+			return true
+		}
+		x.e.posn, x.moves = posn, nil
+		x.analyse()
+	case "undo", "remove":
+		x.e.stopSearch(false)
+		n := 1
+		if cmd == "remove" {
+			n = 2
+		}
+		for ; n > 0 && len(x.moves) > 0; n-- {
+			x.e.posn.UnmakeMove(x.moves[len(x.moves)-1])
+			x.moves = x.moves[:len(x.moves)-1]
+		}
+		x.analyse()
+	case "level":
+		if err := x.level(fields[1:]); err != nil {
+			x.e.send("Error (%s): %s", err, line)
+		}
+	case "st", "sd", "time", "otim", "ping":
+		if len(fields) != 2 {
+			x.e.send("Error (expected one argument): %s", line)
+			return true
+		}
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			x.e.send("Error (invalid number): %s", line)
+			return true
+		}
+		switch cmd {
+		case "st":
+			x.moveTime = time.Duration(value) * time.Second
+		case "sd":
+			x.depth = value
+		case "time":
+			x.clock = time.Duration(value) * 10 * time.Millisecond
+		case "otim":
+			// the opponent's time is not used
+		case "ping":
+			x.e.send("pong %d", value)
+		}
+	case "post", "nopost":
+		x.post = cmd == "post"
+	case "analyze":
+		x.e.stopSearch(false)
+		x.analyzing = true
+		x.analyse()
+	case "exit":
+		x.e.stopSearch(false)
+		x.analyzing = false
+	case "?":
+		if !x.analyzing {
+			x.e.stopSearch(true)
+		}
+	case "quit":
+		x.e.stopSearch(false)
+		return false
+	default:
+		if !xboardIgnored[cmd] {
+			x.e.send("Error (unknown command): %s", cmd)
+		}
+	}
+	return true
+}
+
+// userMove makes the opponent's move, and replies if it is the engine's turn
+func (x *xboard) userMove(str string) {
+	x.e.stopSearch(false)
+	m, err := x.e.posn.ParseUci(str)
+	if err != nil {
+		if m, err = x.e.posn.ParseSan(str); err != nil {
+			x.e.send("Illegal move: %s", str)
+			x.analyse()
+			return
+		}
+	}
+	x.e.posn.MakeMove(m)
+	x.moves = append(x.moves, m)
+	if x.analyzing {
+		x.analyse()
+	} else if !x.force && x.e.posn.ActiveColour() == x.engineColour {
+		x.think()
+	}
+}
+
+// level handles 'level MPS BASE INC': the number of moves per time control (0 for the whole game),
+// the time in minutes or minutes:seconds, and the increment in seconds
+func (x *xboard) level(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("expected three arguments")
+	}
+	mps, err := strconv.Atoi(args[0])
+	if err != nil || mps < 0 {
+		return fmt.Errorf("invalid number of moves")
+	}
+	inc, err := strconv.ParseFloat(args[2], 64)
+	if err != nil || inc < 0 {
+		return fmt.Errorf("invalid increment")
+	}
+	minutes, seconds := args[1], "0"
+	if i := strings.Index(minutes, ":"); i >= 0 {
+		minutes, seconds = minutes[:i], minutes[i+1:]
+	}
+	if _, err := strconv.Atoi(minutes); err != nil {
+		return fmt.Errorf("invalid time")
+	}
+	if _, err := strconv.Atoi(seconds); err != nil {
+		return fmt.Errorf("invalid time")
+	}
+	x.movesPerLevel = mps
+	x.increment = time.Duration(inc * float64(time.Second))
+	// the clock is set by 'time' before each move
+	x.moveTime = 0
+	return nil
+}
+
+// think starts a search for the engine's move, unless the game is over
+func (x *xboard) think() {
+	if x.gameOver() {
+		return
+	}
+	l := limits{depth: x.depth}
+	switch {
+	case x.moveTime > 0:
+		l.moveTime = x.moveTime
+	case x.clock > 0:
+		movesToGo := 0
+		if x.movesPerLevel > 0 {
+			movesToGo = x.movesPerLevel - (x.e.posn.FullmoveNbr()-1)%x.movesPerLevel
+		}
+		l.moveTime = allocateTime(x.clock, x.increment, movesToGo)
+	case x.depth == 0:
+		l.moveTime = defaultMoveTime
+	}
+	x.e.startSearch(l, x.thinkingOutput(), func(result search.Result) {
+		if len(result.PV) == 0 {
+			return
+		}
+		x.e.send("move %s", x.e.posn.UciString(result.Move))
+		x.e.posn.MakeMove(result.Move)
+		x.moves = append(x.moves, result.Move)
+		x.gameOver()
+	})
+}
+
+// analyse starts an infinite search of the current position if the engine is in analyze mode
+func (x *xboard) analyse() {
+	if !x.analyzing || len(x.e.posn.FindMoves(x.e.posn.ActiveColour())) == 0 {
+		return
+	}
+	x.e.startSearch(limits{infinite: true}, x.thinkingOutput(), func(search.Result) {})
+}
+
+// thinkingOutput returns the function which reports the iterations of the search in the format 'ply score time nodes pv',
+// with the time in centiseconds and mates as +/-(100000 + number of moves); or nil if the output is switched off
+func (x *xboard) thinkingOutput() func(search.Result) {
+	if !x.post && !x.analyzing {
+		return nil
+	}
+	start := time.Now()
+	return func(result search.Result) {
+		score := result.Score
+		if n := result.MateIn(); n > 0 {
+			score = search.MateScore + n
+		} else if n < 0 {
+			score = -search.MateScore + n
+		}
+		x.e.send("%d %d %d %d %s", result.Depth, score, time.Since(start).Milliseconds()/10, result.Nodes,
+			pvString(x.e.posn, result.PV, position.Position.San))
+	}
+}
+
+// gameOver returns true if the side to move has no legal moves or the game is drawn, and sends the result
+func (x *xboard) gameOver() bool {
+	posn := x.e.posn
+	switch reason := posn.DrawReason(true); {
+	case posn.IsCheckmate() && posn.ActiveColour() == colour.White:
+		x.e.send("0-1 {Black mates}")
+	case posn.IsCheckmate():
+		x.e.send("1-0 {White mates}")
+	case reason != position.NoDraw:
+		x.e.send("1/2-1/2 {%s}", strings.ToUpper(reason.String()[:1])+reason.String()[1:])
+	default:
+		return false
+	}
+	return true
+}
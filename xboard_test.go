@@ -0,0 +1,144 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rjo67/chess/position"
+)
+
+func newTestXboard() (*xboard, *syncBuffer) {
+	out := &syncBuffer{}
+	return newXboard(newEngine(out)), out
+}
+
+func TestXboardProtover(t *testing.T) {
+	x, out := newTestXboard()
+	send(x, "xboard", "protover 2")
+	got := out.lines()
+	if len(got) != 1 || got[0] != xboardFeatures {
+		t.Fatalf("expected the features but got %v", got)
+	}
+	for _, feature := range []string{"ping=1", "setboard=1", "usermove=1", "san=0", "done=1"} {
+		if !strings.Contains(got[0], " "+feature) {
+			t.Errorf("feature %s missing", feature)
+		}
+	}
+	send(x, "accepted usermove", "ping 7", "foo")
+	if got := out.lines(); strings.Join(got, "|") != "pong 7|Error (unknown command): foo" {
+		t.Errorf("unexpected reply %v", got)
+	}
+	if x.handle("quit") {
+		t.Errorf("expected false after quit")
+	}
+}
+
+func TestXboardPlay(t *testing.T) {
+	x, out := newTestXboard()
+	// new resets the depth, so sd is sent afterwards
+	send(x, "new", "sd 2", "usermove e2e4")
+	if line, _ := out.waitFor(t, "move "); line == "" {
+		t.Fatalf("no move")
+	}
+	x.e.stopSearch(false)
+	if len(x.moves) != 2 || x.e.posn.ActiveColour() != x.engineColour.Other() {
+		t.Errorf("expected the engine's reply to be made, moves %v", x.moves)
+	}
+
+	// go: the engine plays the side to move
+	send(x, "new", "force", "usermove e2e4", "usermove e7e5", "sd 1", "go")
+	out.waitFor(t, "move ")
+	x.e.stopSearch(false)
+	if len(x.moves) != 3 {
+		t.Errorf("expected 3 moves but got %d", len(x.moves))
+	}
+
+	send(x, "force", "usermove e2e5")
+	if line, _ := out.waitFor(t, "Illegal move"); line != "Illegal move: e2e5" {
+		t.Errorf("unexpected reply '%s'", line)
+	}
+}
+
+func TestXboardMate(t *testing.T) {
+	x, out := newTestXboard()
+	send(x, "new", "setboard 6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "sd 2", "post", "go")
+	line, thinking := out.waitFor(t, "move ")
+	if line != "move a1a8" {
+		t.Errorf("expected move a1a8 but got '%s'", line)
+	}
+	// mate in 1 is reported as 100001
+	if len(thinking) == 0 || !strings.HasPrefix(thinking[0], "1 100001 ") || !strings.HasSuffix(thinking[0], " Ra8#") {
+		t.Errorf("unexpected thinking output %v", thinking)
+	}
+	if line, _ := out.waitFor(t, "1-0"); line != "1-0 {White mates}" {
+		t.Errorf("expected result but got '%s'", line)
+	}
+}
+
+func TestXboardUndoRemove(t *testing.T) {
+	x, _ := newTestXboard()
+	send(x, "new", "force", "usermove e2e4", "usermove e7e5", "usermove g1f3")
+	send(x, "undo")
+	if fen := x.e.posn.Fen(); fen != "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2" {
+		t.Errorf("unexpected position after undo: %s", fen)
+	}
+	send(x, "remove")
+	if fen := x.e.posn.Fen(); fen != position.StartPosition().Fen() || len(x.moves) != 0 {
+		t.Errorf("unexpected position after remove: %s", fen)
+	}
+	// nothing left to take back
+	send(x, "remove")
+	if fen := x.e.posn.Fen(); fen != position.StartPosition().Fen() {
+		t.Errorf("unexpected position: %s", fen)
+	}
+}
+
+func TestXboardSetboard(t *testing.T) {
+	x, out := newTestXboard()
+	send(x, "new", "force", "usermove e2e4", "setboard 7k/8/8/8/8/8/8/R6K w - - 0 1")
+	if fen := x.e.posn.Fen(); fen != "7k/8/8/8/8/8/8/R6K w - - 0 1" || len(x.moves) != 0 {
+		t.Errorf("unexpected position %s, moves %v", fen, x.moves)
+	}
+	send(x, "setboard 7k/8/8/8/8/8/8 w - - 0 1")
+	if got := out.lines(); len(got) != 1 || got[0] != "tellusererror Illegal position" {
+		t.Errorf("expected tellusererror but got %v", got)
+	}
+	if fen := x.e.posn.Fen(); fen != "7k/8/8/8/8/8/8/R6K w - - 0 1" {
+		t.Errorf("position changed to %s", fen)
+	}
+}
+
+func TestXboardTimeControl(t *testing.T) {
+	x, out := newTestXboard()
+	send(x, "st 10", "level 40 5 2.5")
+	if x.movesPerLevel != 40 || x.increment != 2500*time.Millisecond || x.moveTime != 0 {
+		t.Errorf("unexpected level %d %s %s", x.movesPerLevel, x.increment, x.moveTime)
+	}
+	send(x, "level 0 2:30 0", "st 10", "sd 4", "time 6000", "otim 5000")
+	if x.movesPerLevel != 0 || x.increment != 0 || x.moveTime != 10*time.Second || x.depth != 4 || x.clock != time.Minute {
+		t.Errorf("unexpected time control %d %s %s %d %s", x.movesPerLevel, x.increment, x.moveTime, x.depth, x.clock)
+	}
+	if got := out.lines(); len(got) != 0 {
+		t.Errorf("unexpected output %v", got)
+	}
+	for _, d := range []struct {
+		cmd, expected string
+	}{
+		{"level 40 5", "Error (expected three arguments): level 40 5"},
+		{"level x 5 0", "Error (invalid number of moves): level x 5 0"},
+		{"level 40 5:x 0", "Error (invalid time): level 40 5:x 0"},
+		{"level 40 5 -1", "Error (invalid increment): level 40 5 -1"},
+		{"st", "Error (expected one argument): st"},
+		{"sd x", "Error (invalid number): sd x"},
+		{"time 1 2", "Error (expected one argument): time 1 2"},
+	} {
+		send(x, d.cmd)
+		if got := out.lines(); len(got) != 1 || got[0] != d.expected {
+			t.Errorf("%s: expected '%s' but got %v", d.cmd, d.expected, got)
+		}
+	}
+	if x.depth != 4 || x.movesPerLevel != 0 {
+		t.Errorf("time control changed by invalid commands")
+	}
+}